@@ -13,6 +13,9 @@ import (
 func main() {
 	// 加载配置
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("配置错误: %v", err)
+	}
 
 	// 设置Gin模式
 	if cfg.Environment == "production" {
@@ -26,6 +29,7 @@ func main() {
 	r.Use(middleware.CORS())
 	r.Use(middleware.Logger())
 	r.Use(middleware.Recovery())
+	r.Use(middleware.TradingEnvironment(cfg.TradingEnvironment()))
 
 	// 设置静态文件路由
 	r.Static("/static", "./web/static")
@@ -50,7 +54,7 @@ func main() {
 	})
 
 	// 启动服务器
-	log.Printf("Server starting on port %s", cfg.Port)
+	log.Printf("Server starting on port %s (trading environment: %s)", cfg.Port, cfg.TradingEnvironment())
 	if err := r.Run(":" + cfg.Port); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
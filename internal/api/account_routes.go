@@ -1,92 +1,186 @@
 package api
 
 import (
-	"net/http"
+	"errors"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/database"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/middleware"
 	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
 	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
 	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
 )
 
 // SetupAccountRoutes 设置账户API路由
 func SetupAccountRoutes(r *gin.Engine, cfg *config.Config) {
-	accountService := service.NewAccountService(&cfg.OKX)
-
-	// 账户API路由组
-	account := r.Group("/api/v1/account")
-	{
-		// 获取账户余额
-		account.GET("/balance", func(c *gin.Context) {
-			GetAccountBalance(c, accountService)
-		})
+	accountStream := newAccountStream(&cfg.OKX)
+	if cfg.Paper.Enabled {
+		// 本地模拟交易模式下账户接口和推送使用模拟账户
+		accountStream = sharedPaperExchange(cfg)
+	}
+	accountService := newAccountServiceWithSnapshots(cfg, accountStream)
+	viewer := requireRole(cfg, models.RoleViewer)
+
+	// 账户状态实时推送，只推送默认账户
+	if accountStream != nil {
+		r.GET("/ws/account", append(viewer, rejectOKXAccountSelection("账户推送只支持默认账户"), func(c *gin.Context) {
+			HandleAccountWebSocket(c, accountStream, cfg.WebSocket)
+		})...)
+	}
+
+	// 请求指定OKX账户时使用该账户的账户服务，否则使用默认账户；默认币种取当前用户的设置
+	pool := sharedOKXAccountPool(cfg)
+	settingsService := sharedSettingsService(cfg)
+	accountServiceOf := func(c *gin.Context) service.AccountService {
+		selected := accountService
+		if session := selectedOKXAccount(c); session != nil {
+			selected = session.accountService
+		}
+		userID, _ := currentUserID(c)
+		return &userAccountService{AccountService: selected, settings: settingsService, c: c, userID: userID}
+	}
 
-		// 获取账户余额（指定币种）
-		account.GET("/balance/:currency", func(c *gin.Context) {
-			GetAccountBalanceWithCurrency(c, accountService)
-		})
+	// 账户API路由组，需要viewer角色，可以通过 X-Account-Id 请求头选择OKX账户
+	registerAccountHandlers(r.Group("/api/v1/account", append(viewer, selectOKXAccount(pool))...), accountServiceOf, settingsService)
 
-		// 获取盈亏信息
-		account.GET("/profit-loss", func(c *gin.Context) {
-			GetProfitLoss(c, accountService)
-		})
+	// 指定OKX账户的账户API，与 X-Account-Id 请求头等效
+	registerAccountHandlers(r.Group("/api/v1/accounts/:accountId", append(viewer, selectOKXAccount(pool))...), accountServiceOf, settingsService)
+}
 
-		// 获取账户汇总
-		account.GET("/summary", func(c *gin.Context) {
-			GetAccountSummary(c, accountService)
-		})
+// userAccountService 使用当前用户设置的默认币种的账户服务
+// 默认币种在首次使用时才读取，不需要默认币种的接口不会查询用户设置
+type userAccountService struct {
+	service.AccountService
+	settings service.SettingsService
+	c        *gin.Context
+	userID   int64
+}
 
-		// 获取账户汇总（指定币种）
-		account.GET("/summary/:currency", func(c *gin.Context) {
-			GetAccountSummaryWithCurrency(c, accountService)
-		})
+// GetDefaultCurrency 当前用户的默认币种
+func (s *userAccountService) GetDefaultCurrency() models.Currency {
+	return s.settings.DefaultCurrency(s.c.Request.Context(), s.userID)
+}
 
-		// 设置默认币种
-		account.POST("/currency", func(c *gin.Context) {
-			SetDefaultCurrency(c, accountService)
-		})
+// registerAccountHandlers 在路由组中注册账户API
+func registerAccountHandlers(group *gin.RouterGroup, accountServiceOf func(c *gin.Context) service.AccountService, settingsService service.SettingsService) {
+	// 获取账户余额
+	group.GET("/balance", func(c *gin.Context) {
+		GetAccountBalance(c, accountServiceOf(c))
+	})
+
+	// 获取账户余额（指定币种）
+	group.GET("/balance/:currency", func(c *gin.Context) {
+		GetAccountBalanceWithCurrency(c, accountServiceOf(c))
+	})
+
+	// 获取盈亏信息
+	group.GET("/profit-loss", func(c *gin.Context) {
+		GetProfitLoss(c, accountServiceOf(c))
+	})
+
+	// 获取账户汇总
+	group.GET("/summary", func(c *gin.Context) {
+		GetAccountSummary(c, accountServiceOf(c))
+	})
+
+	// 获取账户汇总（指定币种）
+	group.GET("/summary/:currency", func(c *gin.Context) {
+		GetAccountSummaryWithCurrency(c, accountServiceOf(c))
+	})
+
+	// 设置当前用户的默认币种，需要operator角色
+	group.POST("/currency", middleware.RequireRole(models.RoleOperator), func(c *gin.Context) {
+		SetDefaultCurrency(c, accountServiceOf(c), settingsService)
+	})
+
+	// 获取默认币种
+	group.GET("/currency", func(c *gin.Context) {
+		GetDefaultCurrency(c, accountServiceOf(c))
+	})
+
+	// 获取支持的币种列表
+	group.GET("/currencies", func(c *gin.Context) {
+		GetSupportedCurrencies(c, accountServiceOf(c))
+	})
+
+	// 获取汇率信息
+	group.GET("/exchange-rates", func(c *gin.Context) {
+		GetExchangeRates(c, accountServiceOf(c))
+	})
 
-		// 获取默认币种
-		account.GET("/currency", func(c *gin.Context) {
-			GetDefaultCurrency(c, accountService)
-		})
+	// 获取当前持仓信息
+	group.GET("/positions", func(c *gin.Context) {
+		GetPositions(c, accountServiceOf(c))
+	})
 
-		// 获取支持的币种列表
-		account.GET("/currencies", func(c *gin.Context) {
-			GetSupportedCurrencies(c)
-		})
+	// 获取历史持仓信息
+	group.GET("/positions-history", func(c *gin.Context) {
+		GetPositionsHistory(c, accountServiceOf(c))
+	})
+
+	// 获取持仓完整历史（基于当前持仓的更新时间）
+	group.GET("/positions/:posId/history", func(c *gin.Context) {
+		GetPositionHistoryByPosId(c, accountServiceOf(c))
+	})
+
+	// 获取永续合约持仓的资金费
+	group.GET("/funding", func(c *gin.Context) {
+		GetFunding(c, accountServiceOf(c))
+	})
+}
 
-		// 获取汇率信息
-		account.GET("/exchange-rates", func(c *gin.Context) {
-			GetExchangeRates(c, accountService)
-		})
+// newAccountStream 创建并启动私有WebSocket账户状态，未配置API密钥或私有WebSocket地址时返回nil
+func newAccountStream(cfg *config.OKXConfig) service.AccountStream {
+	if cfg.WSPrivateURL == "" || !cfg.HasKeys() {
+		return nil
+	}
 
-		// 获取当前持仓信息
-		account.GET("/positions", func(c *gin.Context) {
-			GetPositions(c, accountService)
-		})
+	accountStream := service.NewAccountStream(cfg)
+	accountStream.Start()
+	return accountStream
+}
 
-		// 获取历史持仓信息
-		account.GET("/positions-history", func(c *gin.Context) {
-			GetPositionsHistory(c, accountService)
-		})
+// newAccountServiceWithSnapshots 创建账户服务并启动权益快照记录
+// 数据库不可用时退化为不带历史数据的账户服务
+func newAccountServiceWithSnapshots(cfg *config.Config, accountStream service.AccountStream) service.AccountService {
+	if cfg.SQLitePath == "" {
+		return service.NewAccountServiceWithCurrencies(&cfg.OKX, &cfg.Currency, nil, accountStream)
+	}
 
-		// 获取持仓完整历史（基于当前持仓的更新时间）
-		account.GET("/positions/:posId/history", func(c *gin.Context) {
-			GetPositionHistoryByPosId(c, accountService)
-		})
+	db, err := database.Shared(cfg.SQLitePath)
+	if err != nil {
+		log.Printf("打开数据库失败，盈亏历史不可用: %v", err)
+		return service.NewAccountServiceWithCurrencies(&cfg.OKX, &cfg.Currency, nil, accountStream)
+	}
+
+	equityRepo := repository.NewEquityRepository(db, repository.DefaultEquityAccountID, cfg.TradingEnvironment())
+	accountService := service.NewAccountServiceWithCurrencies(&cfg.OKX, &cfg.Currency, equityRepo, accountStream)
+	service.NewEquityRecorder(accountService, equityRepo, equitySnapshotInterval(cfg)).Start()
+
+	return accountService
+}
+
+// equitySnapshotInterval 权益快照记录间隔，默认5分钟
+func equitySnapshotInterval(cfg *config.Config) time.Duration {
+	interval := time.Duration(cfg.EquitySnapshotInterval) * time.Minute
+	if interval <= 0 {
+		interval = 5 * time.Minute
 	}
+	return interval
 }
 
 // GetAccountBalance 获取账户余额（使用默认币种）
 func GetAccountBalance(c *gin.Context, accountService service.AccountService) {
 	currency := accountService.GetDefaultCurrency()
 	
-	balance, err := accountService.GetAccountBalance(currency)
+	balance, err := accountService.GetAccountBalance(c.Request.Context(), currency)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取账户余额失败: "+err.Error())
+		respondError(c, "获取账户余额失败", err)
 		return
 	}
 
@@ -99,14 +193,14 @@ func GetAccountBalanceWithCurrency(c *gin.Context, accountService service.Accoun
 	currency := models.Currency(currencyStr)
 
 	// 验证币种
-	if !isValidCurrency(currency) {
+	if !isValidCurrency(accountService, currency) {
 		utils.BadRequestResponse(c, "不支持的币种: "+currencyStr)
 		return
 	}
 
-	balance, err := accountService.GetAccountBalance(currency)
+	balance, err := accountService.GetAccountBalance(c.Request.Context(), currency)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取账户余额失败: "+err.Error())
+		respondError(c, "获取账户余额失败", err)
 		return
 	}
 
@@ -119,7 +213,7 @@ func GetProfitLoss(c *gin.Context, accountService service.AccountService) {
 	currencyStr := strings.ToUpper(c.DefaultQuery("currency", string(accountService.GetDefaultCurrency())))
 	currency := models.Currency(currencyStr)
 
-	if !isValidCurrency(currency) {
+	if !isValidCurrency(accountService, currency) {
 		utils.BadRequestResponse(c, "不支持的币种: "+currencyStr)
 		return
 	}
@@ -128,9 +222,9 @@ func GetProfitLoss(c *gin.Context, accountService service.AccountService) {
 	periodsStr := c.DefaultQuery("periods", "1d,1w,1m,6m")
 	periods := parsePeriods(periodsStr)
 
-	profitLoss, err := accountService.GetProfitLoss(currency, periods)
+	profitLoss, err := accountService.GetProfitLoss(c.Request.Context(), currency, periods)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取盈亏信息失败: "+err.Error())
+		respondError(c, "获取盈亏信息失败", err)
 		return
 	}
 
@@ -141,9 +235,9 @@ func GetProfitLoss(c *gin.Context, accountService service.AccountService) {
 func GetAccountSummary(c *gin.Context, accountService service.AccountService) {
 	currency := accountService.GetDefaultCurrency()
 	
-	summary, err := accountService.GetAccountSummary(currency)
+	summary, err := accountService.GetAccountSummary(c.Request.Context(), currency)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取账户汇总失败: "+err.Error())
+		respondError(c, "获取账户汇总失败", err)
 		return
 	}
 
@@ -155,22 +249,22 @@ func GetAccountSummaryWithCurrency(c *gin.Context, accountService service.Accoun
 	currencyStr := strings.ToUpper(c.Param("currency"))
 	currency := models.Currency(currencyStr)
 
-	if !isValidCurrency(currency) {
+	if !isValidCurrency(accountService, currency) {
 		utils.BadRequestResponse(c, "不支持的币种: "+currencyStr)
 		return
 	}
 
-	summary, err := accountService.GetAccountSummary(currency)
+	summary, err := accountService.GetAccountSummary(c.Request.Context(), currency)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取账户汇总失败: "+err.Error())
+		respondError(c, "获取账户汇总失败", err)
 		return
 	}
 
 	utils.SuccessResponse(c, summary, "获取账户汇总成功")
 }
 
-// SetDefaultCurrency 设置默认币种
-func SetDefaultCurrency(c *gin.Context, accountService service.AccountService) {
+// SetDefaultCurrency 设置当前用户的默认币种，保存在用户设置中，不影响其他用户
+func SetDefaultCurrency(c *gin.Context, accountService service.AccountService, settingsService service.SettingsService) {
 	var req struct {
 		Currency string `json:"currency" binding:"required"`
 	}
@@ -181,13 +275,15 @@ func SetDefaultCurrency(c *gin.Context, accountService service.AccountService) {
 	}
 
 	currency := models.Currency(strings.ToUpper(req.Currency))
-	if !isValidCurrency(currency) {
+	if !isValidCurrency(accountService, currency) {
 		utils.BadRequestResponse(c, "不支持的币种: "+req.Currency)
 		return
 	}
 
-	if err := accountService.SetDefaultCurrency(currency); err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "设置默认币种失败: "+err.Error())
+	userID, _ := currentUserID(c)
+	value := string(currency)
+	if _, err := settingsService.Update(c.Request.Context(), userID, &models.UpdateSettingsRequest{DefaultCurrency: &value}); err != nil {
+		respondSettingsError(c, "设置默认币种失败", err)
 		return
 	}
 
@@ -197,7 +293,7 @@ func SetDefaultCurrency(c *gin.Context, accountService service.AccountService) {
 	}, "设置默认币种成功")
 }
 
-// GetDefaultCurrency 获取默认币种
+// GetDefaultCurrency 获取当前用户的默认币种
 func GetDefaultCurrency(c *gin.Context, accountService service.AccountService) {
 	currency := accountService.GetDefaultCurrency()
 	
@@ -208,8 +304,8 @@ func GetDefaultCurrency(c *gin.Context, accountService service.AccountService) {
 }
 
 // GetSupportedCurrencies 获取支持的币种列表
-func GetSupportedCurrencies(c *gin.Context) {
-	currencies := models.SupportedCurrencies()
+func GetSupportedCurrencies(c *gin.Context, accountService service.AccountService) {
+	currencies := accountService.SupportedCurrencies()
 	
 	var currencyList []gin.H
 	for _, currency := range currencies {
@@ -217,6 +313,7 @@ func GetSupportedCurrencies(c *gin.Context) {
 			"currency": currency,
 			"symbol":   currency.GetCurrencySymbol(),
 			"name":     getCurrencyName(currency),
+			"decimals": currency.Decimals(),
 		})
 	}
 
@@ -225,9 +322,9 @@ func GetSupportedCurrencies(c *gin.Context) {
 
 // GetExchangeRates 获取汇率信息
 func GetExchangeRates(c *gin.Context, accountService service.AccountService) {
-	rates, err := accountService.GetExchangeRates()
+	rates, err := accountService.GetExchangeRates(c.Request.Context())
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取汇率信息失败: "+err.Error())
+		respondError(c, "获取汇率信息失败", err)
 		return
 	}
 
@@ -237,8 +334,8 @@ func GetExchangeRates(c *gin.Context, accountService service.AccountService) {
 // 辅助函数
 
 // isValidCurrency 验证币种是否有效
-func isValidCurrency(currency models.Currency) bool {
-	supportedCurrencies := models.SupportedCurrencies()
+func isValidCurrency(accountService service.AccountService, currency models.Currency) bool {
+	supportedCurrencies := accountService.SupportedCurrencies()
 	for _, supported := range supportedCurrencies {
 		if currency == supported {
 			return true
@@ -287,21 +384,50 @@ func GetPositions(c *gin.Context, accountService service.AccountService) {
 	currencyStr := strings.ToUpper(c.DefaultQuery("currency", string(accountService.GetDefaultCurrency())))
 	currency := models.Currency(currencyStr)
 
-	if !isValidCurrency(currency) {
+	if !isValidCurrency(accountService, currency) {
 		utils.BadRequestResponse(c, "不支持的币种: "+currencyStr)
 		return
 	}
 
 	// 获取当前持仓信息
-	response, err := accountService.GetPositions(&req, currency)
+	response, err := accountService.GetPositions(c.Request.Context(), &req, currency)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取当前持仓信息失败: "+err.Error())
+		respondError(c, "获取当前持仓信息失败", err)
 		return
 	}
 
 	utils.SuccessResponse(c, response, "获取当前持仓信息成功")
 }
 
+// GetFunding 获取永续合约持仓的资金费：结算记录、当前和历史费率、下次结算预计金额和年化成本
+func GetFunding(c *gin.Context, accountService service.AccountService) {
+	var req models.FundingRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if req.HistoryLimit != nil && (*req.HistoryLimit < 0 || *req.HistoryLimit > service.MaxFundingHistoryLimit) {
+		utils.BadRequestResponse(c, "请求参数错误: historyLimit 必须在0到100之间")
+		return
+	}
+
+	currencyStr := strings.ToUpper(c.DefaultQuery("currency", string(accountService.GetDefaultCurrency())))
+	currency := models.Currency(currencyStr)
+
+	if !isValidCurrency(accountService, currency) {
+		utils.BadRequestResponse(c, "不支持的币种: "+currencyStr)
+		return
+	}
+
+	response, err := accountService.GetFunding(c.Request.Context(), &req, currency)
+	if err != nil {
+		respondError(c, "获取资金费信息失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, response, "获取资金费信息成功")
+}
+
 // GetPositionsHistory 获取历史持仓信息
 func GetPositionsHistory(c *gin.Context, accountService service.AccountService) {
 	// 获取查询参数
@@ -315,11 +441,32 @@ func GetPositionsHistory(c *gin.Context, accountService service.AccountService)
 	currencyStr := strings.ToUpper(c.DefaultQuery("currency", string(accountService.GetDefaultCurrency())))
 	currency := models.Currency(currencyStr)
 
-	if !isValidCurrency(currency) {
+	if !isValidCurrency(accountService, currency) {
 		utils.BadRequestResponse(c, "不支持的币种: "+currencyStr)
 		return
 	}
 
+	// 游标分页：cursor/pageSize 翻页，all=true 由服务端遍历 from~to 范围内的全部页
+	if req.Paginated() {
+		if req.PageSize < 0 || req.From < 0 || req.To < 0 || (req.From > 0 && req.To > 0 && req.From > req.To) {
+			utils.BadRequestResponse(c, "请求参数错误: pageSize、from、to 不能为负数，from 不能晚于 to")
+			return
+		}
+
+		response, err := accountService.GetPositionsHistoryPage(c.Request.Context(), &req, currency)
+		if errors.Is(err, service.ErrInvalidCursor) {
+			utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+			return
+		}
+		if err != nil {
+			respondError(c, "获取历史持仓信息失败", err)
+			return
+		}
+
+		utils.SuccessResponse(c, response, "获取历史持仓信息成功")
+		return
+	}
+
 	// 如果设置了fromCurrentPositions参数，自动获取当前持仓的时间戳作为参考
 	if c.Query("fromCurrentPositions") == "true" {
 		// 获取当前持仓信息
@@ -328,7 +475,7 @@ func GetPositionsHistory(c *gin.Context, accountService service.AccountService)
 			InstId:   req.InstId,
 		}
 		
-		currentPositions, err := accountService.GetPositions(currentPosReq, currency)
+		currentPositions, err := accountService.GetPositions(c.Request.Context(), currentPosReq, currency)
 		if err == nil && len(currentPositions.Positions) > 0 {
 			// 使用最新的持仓更新时间作为before参数
 			if req.Before == "" {
@@ -338,9 +485,9 @@ func GetPositionsHistory(c *gin.Context, accountService service.AccountService)
 	}
 
 	// 获取历史持仓信息
-	response, err := accountService.GetPositionsHistory(&req, currency)
+	response, err := accountService.GetPositionsHistory(c.Request.Context(), &req, currency)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取历史持仓信息失败: "+err.Error())
+		respondError(c, "获取历史持仓信息失败", err)
 		return
 	}
 
@@ -359,7 +506,7 @@ func GetPositionHistoryByPosId(c *gin.Context, accountService service.AccountSer
 	currencyStr := strings.ToUpper(c.DefaultQuery("currency", string(accountService.GetDefaultCurrency())))
 	currency := models.Currency(currencyStr)
 
-	if !isValidCurrency(currency) {
+	if !isValidCurrency(accountService, currency) {
 		utils.BadRequestResponse(c, "不支持的币种: "+currencyStr)
 		return
 	}
@@ -369,9 +516,9 @@ func GetPositionHistoryByPosId(c *gin.Context, accountService service.AccountSer
 		PosId: posId,
 	}
 	
-	currentPositions, err := accountService.GetPositions(currentPosReq, currency)
+	currentPositions, err := accountService.GetPositions(c.Request.Context(), currentPosReq, currency)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取当前持仓信息失败: "+err.Error())
+		respondError(c, "获取当前持仓信息失败", err)
 		return
 	}
 
@@ -392,9 +539,9 @@ func GetPositionHistoryByPosId(c *gin.Context, accountService service.AccountSer
 	}
 
 	// 获取历史持仓信息
-	historyResponse, err := accountService.GetPositionsHistory(historyReq, currency)
+	historyResponse, err := accountService.GetPositionsHistory(c.Request.Context(), historyReq, currency)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取历史持仓信息失败: "+err.Error())
+		respondError(c, "获取历史持仓信息失败", err)
 		return
 	}
 
@@ -427,6 +574,12 @@ func getCurrencyName(currency models.Currency) string {
 		return "泰达币"
 	case models.CurrencyBTC:
 		return "比特币"
+	case models.CurrencyEUR:
+		return "欧元"
+	case models.CurrencyHKD:
+		return "港币"
+	case models.CurrencyETH:
+		return "以太坊"
 	default:
 		return string(currency)
 	}
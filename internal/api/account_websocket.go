@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/middleware"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// accountChannelRoles 接收账户推送各频道需要的最低角色，未列出的频道需要viewer角色
+// 订单属于交易数据，只推送给operator及以上角色
+var accountChannelRoles = map[string]models.Role{
+	"orders": models.RoleOperator,
+}
+
+// accountChannelRole 获取接收账户推送频道需要的最低角色
+func accountChannelRole(channel string) models.Role {
+	if role, ok := accountChannelRoles[channel]; ok {
+		return role
+	}
+	return models.RoleViewer
+}
+
+// HandleAccountWebSocket 账户状态WebSocket：连接后先推送全量快照，之后推送OKX私有频道的增量变化
+// 经过 Auth 中间件时按角色过滤频道，角色不足的频道增量不推送，快照中对应的数据置空
+func HandleAccountWebSocket(c *gin.Context, accountStream service.AccountStream, wsCfg config.WebSocketConfig) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("WebSocket升级失败: %v", err)
+		return
+	}
+
+	client := newWSConn(conn, newWSOptions(wsCfg), metricsFor("account"))
+	defer client.Close()
+
+	allows := func(channel string) bool { return true }
+	if claims := middleware.AuthClaims(c); claims != nil {
+		allows = func(channel string) bool {
+			return models.Role(claims.Role).Allows(accountChannelRole(channel))
+		}
+	}
+	send := func(message *models.AccountStreamMessage) {
+		if message = filterAccountMessage(message, allows); message != nil {
+			sendAccountMessage(client, message)
+		}
+	}
+
+	// 先注册监听再获取快照，避免两者之间的增量丢失；快照发出前的增量先缓存，随后按顺序补发
+	// 增量携带的是变化后的完整币种余额和持仓，快照已包含的增量重复应用不会改变状态
+	var (
+		mutex   sync.Mutex
+		pending []*models.AccountStreamMessage
+		ready   bool
+	)
+	remove := accountStream.AddListener(func(message *models.AccountStreamMessage) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if !ready {
+			pending = append(pending, message)
+			return
+		}
+		send(message)
+	})
+	defer remove()
+
+	snapshot := accountStream.Snapshot()
+	mutex.Lock()
+	send(snapshot)
+	for _, message := range pending {
+		send(message)
+	}
+	pending, ready = nil, true
+	mutex.Unlock()
+
+	// 账户推送为单向，读取仅用于处理pong和感知断开
+	client.readPump(nil)
+}
+
+// filterAccountMessage 按频道权限过滤账户推送，无权限的增量返回nil，快照去掉无权限频道的数据
+func filterAccountMessage(message *models.AccountStreamMessage, allows func(channel string) bool) *models.AccountStreamMessage {
+	if message.Type != "snapshot" {
+		if !allows(message.Channel) {
+			return nil
+		}
+		return message
+	}
+
+	state, ok := message.Data.(*service.AccountStreamState)
+	if !ok || state == nil || allows("orders") {
+		return message
+	}
+	filtered := *state
+	filtered.Orders = []models.Order{}
+	result := *message
+	result.Data = &filtered
+	return &result
+}
+
+// sendAccountMessage 序列化账户推送并放入发送队列
+func sendAccountMessage(client *wsConn, message *models.AccountStreamMessage) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("序列化账户推送失败: %v", err)
+		return
+	}
+	client.Send(data)
+}
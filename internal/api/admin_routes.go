@@ -0,0 +1,26 @@
+package api
+
+import (
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAdminRoutes 设置管理API路由
+func SetupAdminRoutes(r *gin.Engine, cfg *config.Config) {
+	// 管理API路由组
+	admin := r.Group("/api/v1/admin", requireRole(cfg, models.RoleAdmin)...)
+	{
+		// 获取OKX接口限速预算使用情况
+		admin.GET("/rate-limits", func(c *gin.Context) {
+			GetRateLimits(c, okx.DefaultRateLimiter())
+		})
+	}
+}
+
+// GetRateLimits 获取各OKX接口的限速预算使用情况
+func GetRateLimits(c *gin.Context, limiter *okx.RateLimiter) {
+	utils.SuccessResponse(c, limiter.Usage(), "获取限速预算成功")
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/middleware"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupArchiveRoutes 设置本地成交归档API路由，归档的是默认OKX账户的数据
+func SetupArchiveRoutes(r *gin.Engine, cfg *config.Config) {
+	archiveService := sharedArchiveService(cfg)
+
+	archive := r.Group("/api/v1/archive", append(requireRole(cfg, models.RoleViewer), rejectOKXAccountSelection("成交归档只支持默认账户"))...)
+	{
+		// 查询归档的成交明细
+		archive.GET("/fills", func(c *gin.Context) {
+			GetArchivedFills(c, archiveService)
+		})
+
+		// 查询归档的账单流水
+		archive.GET("/bills", func(c *gin.Context) {
+			GetArchivedBills(c, archiveService)
+		})
+
+		// 查询归档的历史持仓
+		archive.GET("/positions", func(c *gin.Context) {
+			GetArchivedPositions(c, archiveService)
+		})
+
+		// 获取同步进度
+		archive.GET("/status", func(c *gin.Context) {
+			GetArchiveStatus(c, archiveService)
+		})
+
+		// 立即同步，需要admin角色
+		archive.POST("/sync", middleware.RequireRole(models.RoleAdmin), func(c *gin.Context) {
+			SyncArchive(c, archiveService)
+		})
+	}
+}
+
+var (
+	archiveMutex    sync.Mutex
+	archiveServices = make(map[*config.Config]service.ArchiveService)
+)
+
+// sharedArchiveService 获取配置对应的归档服务，首次创建时按配置启动后台同步
+// 本地模拟交易模式下没有真实成交，不启动后台同步
+func sharedArchiveService(cfg *config.Config) service.ArchiveService {
+	archiveMutex.Lock()
+	defer archiveMutex.Unlock()
+
+	if archiveService, exists := archiveServices[cfg]; exists {
+		return archiveService
+	}
+
+	repo := repository.NewArchiveRepository(sharedUserDatabase(cfg))
+	archiveService := service.NewArchiveService(&cfg.OKX, &cfg.Archive, repo)
+	if cfg.Archive.Enabled && !cfg.Paper.Enabled && cfg.OKX.HasKeys() {
+		archiveService.Start()
+	}
+
+	archiveServices[cfg] = archiveService
+	return archiveService
+}
+
+// bindArchiveQuery 解析查询条件，参数无效时返回400
+func bindArchiveQuery(c *gin.Context) (*models.ArchiveQuery, bool) {
+	var query models.ArchiveQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return nil, false
+	}
+	if query.Begin < 0 || query.End < 0 || query.Limit < 0 || query.Offset < 0 {
+		utils.BadRequestResponse(c, "请求参数错误: begin、end、limit、offset 不能为负数")
+		return nil, false
+	}
+	if query.End > 0 && query.Begin > query.End {
+		utils.BadRequestResponse(c, "请求参数错误: begin 不能晚于 end")
+		return nil, false
+	}
+	return &query, true
+}
+
+// GetArchivedFills 查询归档的成交明细，按成交时间倒序
+func GetArchivedFills(c *gin.Context, archiveService service.ArchiveService) {
+	query, ok := bindArchiveQuery(c)
+	if !ok {
+		return
+	}
+
+	fills, err := archiveService.Fills(query)
+	if err != nil {
+		respondError(c, "查询成交明细失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, fills, "查询成交明细成功")
+}
+
+// GetArchivedBills 查询归档的账单流水，按账单时间倒序
+func GetArchivedBills(c *gin.Context, archiveService service.ArchiveService) {
+	query, ok := bindArchiveQuery(c)
+	if !ok {
+		return
+	}
+
+	bills, err := archiveService.Bills(query)
+	if err != nil {
+		respondError(c, "查询账单流水失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, bills, "查询账单流水成功")
+}
+
+// GetArchivedPositions 查询归档的历史持仓，按更新时间倒序
+func GetArchivedPositions(c *gin.Context, archiveService service.ArchiveService) {
+	query, ok := bindArchiveQuery(c)
+	if !ok {
+		return
+	}
+
+	positions, err := archiveService.Positions(query)
+	if err != nil {
+		respondError(c, "查询历史持仓失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, positions, "查询历史持仓成功")
+}
+
+// GetArchiveStatus 获取各数据来源的同步进度
+func GetArchiveStatus(c *gin.Context, archiveService service.ArchiveService) {
+	status, err := archiveService.Status()
+	if err != nil {
+		respondError(c, "获取同步进度失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, status, "获取同步进度成功")
+}
+
+// SyncArchive 立即同步一次，返回同步后的进度；部分来源失败时返回错误，已同步的数据仍然保留
+func SyncArchive(c *gin.Context, archiveService service.ArchiveService) {
+	if err := archiveService.SyncOnce(c.Request.Context()); err != nil {
+		if errors.Is(err, service.ErrArchiveUnavailable) {
+			utils.BadRequestResponse(c, "同步成交归档失败: "+err.Error())
+			return
+		}
+		respondError(c, "同步成交归档失败", err)
+		return
+	}
+
+	GetArchiveStatus(c, archiveService)
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/database"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/middleware"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAuthRoutes 设置用户认证API路由
+func SetupAuthRoutes(r *gin.Engine, cfg *config.Config) {
+	authService := sharedAuthService(cfg)
+
+	auth := r.Group("/api/v1/auth")
+	{
+		// 登录
+		auth.POST("/login", func(c *gin.Context) {
+			Login(c, authService)
+		})
+
+		// 刷新令牌
+		auth.POST("/refresh", func(c *gin.Context) {
+			RefreshToken(c, authService)
+		})
+
+		// 注销
+		auth.POST("/logout", middleware.Auth(authService), func(c *gin.Context) {
+			Logout(c, authService)
+		})
+
+		// 当前用户
+		auth.GET("/me", middleware.Auth(authService), GetCurrentUser)
+	}
+}
+
+var (
+	authMutex    sync.Mutex
+	authServices = make(map[*config.Config]service.AuthService)
+	userDBs      = make(map[*config.Config]*sql.DB)
+)
+
+// sharedAuthService 获取配置对应的用户认证服务，认证路由和受保护的路由共用同一个实例
+func sharedAuthService(cfg *config.Config) service.AuthService {
+	authMutex.Lock()
+	defer authMutex.Unlock()
+
+	if authService, exists := authServices[cfg]; exists {
+		return authService
+	}
+
+	authService := service.NewAuthService(cfg.JWTSecret, &cfg.Auth, repository.NewUserRepository(userDatabase(cfg)))
+	if err := authService.Bootstrap(context.Background(), cfg.Auth.AdminUsername, cfg.Auth.AdminPassword); err != nil {
+		log.Printf("创建初始用户失败: %v", err)
+	}
+
+	authServices[cfg] = authService
+	return authService
+}
+
+// sharedUserDatabase 获取配置对应的用户数据库，用户及其OKX账户保存在同一个数据库中
+func sharedUserDatabase(cfg *config.Config) *sql.DB {
+	authMutex.Lock()
+	defer authMutex.Unlock()
+
+	return userDatabase(cfg)
+}
+
+// userDatabase 打开用户数据库，调用方需持有 authMutex
+// 用户保存在 SQLITE_PATH 数据库中，未配置时使用内存数据库，重启后需要重新创建用户
+func userDatabase(cfg *config.Config) *sql.DB {
+	if db, exists := userDBs[cfg]; exists {
+		return db
+	}
+
+	var (
+		db  *sql.DB
+		err error
+	)
+	if cfg.SQLitePath == "" {
+		db, err = database.Open(":memory:")
+	} else {
+		db, err = database.Shared(cfg.SQLitePath)
+	}
+	if err != nil {
+		// 用户数据库不可用时无法认证，不能让受保护的接口继续对外开放
+		log.Fatalf("打开用户数据库失败: %v", err)
+	}
+
+	userDBs[cfg] = db
+	return db
+}
+
+// Login 用户登录，返回访问令牌和刷新令牌
+func Login(c *gin.Context, authService service.AuthService) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	tokens, err := authService.Login(c.Request.Context(), req.Username, req.Password)
+	if err != nil {
+		respondAuthError(c, "登录失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, tokens, "登录成功")
+}
+
+// RefreshToken 使用刷新令牌换取新的令牌
+func RefreshToken(c *gin.Context, authService service.AuthService) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	tokens, err := authService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		respondAuthError(c, "刷新令牌失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, tokens, "刷新令牌成功")
+}
+
+// Logout 注销当前访问令牌，请求体中提供刷新令牌时一并注销
+func Logout(c *gin.Context, authService service.AuthService) {
+	var req models.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+
+	if err := authService.Logout(c.Request.Context(), middleware.AuthClaims(c), req.RefreshToken); err != nil {
+		respondAuthError(c, "注销失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "注销成功")
+}
+
+// GetCurrentUser 获取当前登录用户
+func GetCurrentUser(c *gin.Context) {
+	claims := middleware.AuthClaims(c)
+	utils.SuccessResponse(c, gin.H{
+		"id":        claims.Subject,
+		"username":  claims.Username,
+		"role":      claims.Role,
+		"expiresAt": claims.ExpiresTime(),
+	}, "获取当前用户成功")
+}
+
+// respondAuthError 认证失败返回401，其他错误返回500
+func respondAuthError(c *gin.Context, message string, err error) {
+	if errors.Is(err, service.ErrInvalidCredentials) || errors.Is(err, service.ErrUnauthorized) {
+		utils.ErrorResponse(c, http.StatusUnauthorized, message+": "+err.Error())
+		return
+	}
+	respondError(c, message, err)
+}
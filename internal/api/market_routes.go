@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/database"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/indicator"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupMarketRoutes 设置行情API路由
+func SetupMarketRoutes(r *gin.Engine, cfg *config.Config) {
+	candleService := service.NewCandleService(&cfg.OKX, newCandleRepository(cfg))
+	indicatorService := service.NewIndicatorService(&cfg.OKX, candleService)
+	orderBookService := service.NewOrderBookService(&cfg.OKX, OKXTickSizeLookup(NewOKXClient(&cfg.OKX)))
+
+	// 行情API路由组
+	market := r.Group("/api/v1/market", requireRole(cfg, models.RoleViewer)...)
+	{
+		// 获取K线
+		market.GET("/candles/:instId", func(c *gin.Context) {
+			GetCandles(c, candleService)
+		})
+
+		// 获取技术指标
+		market.GET("/indicators/:instId", func(c *gin.Context) {
+			GetIndicators(c, indicatorService)
+		})
+
+		// 获取订单簿
+		market.GET("/books/:instId", func(c *gin.Context) {
+			GetOrderBook(c, orderBookService)
+		})
+	}
+}
+
+// newCandleRepository 创建K线缓存，数据库不可用时不缓存
+func newCandleRepository(cfg *config.Config) repository.CandleRepository {
+	if cfg.SQLitePath == "" {
+		return nil
+	}
+
+	db, err := database.Shared(cfg.SQLitePath)
+	if err != nil {
+		log.Printf("打开数据库失败，K线缓存不可用: %v", err)
+		return nil
+	}
+	return repository.NewCandleRepository(db)
+}
+
+// OKXTickSizeLookup 通过OKX公共接口查询交易对的下单价格精度
+func OKXTickSizeLookup(client *OKXClient) service.TickSizeLookup {
+	return func(ctx context.Context, instId string) (string, error) {
+		inst, err := client.GetInstrument(ctx, instId)
+		if err != nil {
+			return "", err
+		}
+		return inst.TickSz, nil
+	}
+}
+
+// GetCandles 获取K线
+func GetCandles(c *gin.Context, candleService service.CandleService) {
+	req := models.CandlesRequest{Bar: "1m", Limit: service.DefaultCandleLimit}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+	req.InstId = c.Param("instId")
+
+	if err := service.ValidateCandleBar(req.Bar); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	if req.Limit < 1 || req.Limit > service.MaxCandleLimit {
+		utils.BadRequestResponse(c, fmt.Sprintf("limit取值范围为1-%d", service.MaxCandleLimit))
+		return
+	}
+	if req.After < 0 || req.Before < 0 || (req.After > 0 && req.Before >= req.After) {
+		utils.BadRequestResponse(c, "无效的时间范围")
+		return
+	}
+
+	response, err := candleService.GetCandles(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, "获取K线失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, response, "获取K线成功")
+}
+
+// GetIndicators 获取技术指标
+func GetIndicators(c *gin.Context, indicatorService service.IndicatorService) {
+	req := models.IndicatorsRequest{
+		Bar:      "1H",
+		Lookback: service.DefaultIndicatorLookback,
+		Params:   indicator.DefaultParams(),
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+	req.InstId = c.Param("instId")
+
+	if err := service.ValidateCandleBar(req.Bar); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	if req.Lookback < 1 || req.Lookback > service.MaxIndicatorLookback {
+		utils.BadRequestResponse(c, fmt.Sprintf("lookback取值范围为1-%d", service.MaxIndicatorLookback))
+		return
+	}
+	if err := req.Params.Validate(); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	response, err := indicatorService.GetIndicators(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, "获取技术指标失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, response, "获取技术指标成功")
+}
+
+// GetOrderBook 获取订单簿
+func GetOrderBook(c *gin.Context, orderBookService service.OrderBookService) {
+	req := models.OrderBookRequest{Depth: service.DefaultOrderBookDepth, Step: 1}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+	req.InstId = c.Param("instId")
+
+	if req.Depth < 1 || req.Depth > service.MaxOrderBookDepth {
+		utils.BadRequestResponse(c, fmt.Sprintf("depth取值范围为1-%d", service.MaxOrderBookDepth))
+		return
+	}
+	if req.Step < 1 || req.Step > service.MaxOrderBookStep {
+		utils.BadRequestResponse(c, fmt.Sprintf("step取值范围为1-%d", service.MaxOrderBookStep))
+		return
+	}
+	for _, bps := range req.Bps {
+		if bps <= 0 || bps > service.MaxOrderBookBps {
+			utils.BadRequestResponse(c, fmt.Sprintf("bps取值范围为0-%d", service.MaxOrderBookBps))
+			return
+		}
+	}
+
+	response, err := orderBookService.GetOrderBook(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, "获取订单簿失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, response, "获取订单簿成功")
+}
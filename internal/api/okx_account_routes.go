@@ -0,0 +1,340 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/middleware"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AccountIDHeader 选择OKX账户的请求头，未指定时使用环境变量配置的默认账户
+const AccountIDHeader = "X-Account-Id"
+
+// okxAccountKey 上下文中保存所选OKX账户的键
+const okxAccountKey = "okxAccount"
+
+// SetupOKXAccountRoutes 设置OKX账户管理API路由
+func SetupOKXAccountRoutes(r *gin.Engine, cfg *config.Config) {
+	pool := sharedOKXAccountPool(cfg)
+
+	accounts := r.Group("/api/v1/accounts", requireRole(cfg, models.RoleViewer)...)
+	operator := middleware.RequireRole(models.RoleOperator)
+	{
+		// 获取当前用户的OKX账户列表
+		accounts.GET("", func(c *gin.Context) {
+			ListOKXAccounts(c, pool)
+		})
+
+		// 添加OKX账户
+		accounts.POST("", operator, func(c *gin.Context) {
+			CreateOKXAccount(c, pool)
+		})
+
+		// 删除OKX账户
+		accounts.DELETE("/:accountId", operator, func(c *gin.Context) {
+			DeleteOKXAccount(c, pool)
+		})
+	}
+}
+
+// okxAccountSession 单个OKX账户的账户服务、交易客户端和权益快照记录器
+// 每个账户使用独立的配置实例，汇率缓存、REST传输层和服务器时间同步与其他账户隔离；交易对缓存按接口地址和交易环境共享
+type okxAccountSession struct {
+	userID         int64
+	config         *config.OKXConfig
+	accountService service.AccountService
+	tradeClient    TradeClient
+	recorder       service.EquityRecorder // 未配置数据库时为nil
+}
+
+// okxAccountPool 按账户ID缓存已创建的账户会话，首次选择账户时创建，删除账户时移除
+// 配置了数据库时启动即为所有账户创建会话，每个账户按自己的账户ID和交易环境记录权益快照
+type okxAccountPool struct {
+	accounts         service.OKXAccountService
+	currency         *config.CurrencyConfig
+	equityDB         *sql.DB // 未配置 SQLITE_PATH 时为nil，不记录权益快照
+	snapshotInterval time.Duration
+
+	mutex     sync.Mutex
+	sessions  map[int64]*okxAccountSession
+	evictions uint64 // 已移除的会话数量，用于发现锁外创建会话期间账户被删除
+}
+
+var (
+	okxAccountPoolMutex sync.Mutex
+	okxAccountPools     = make(map[*config.Config]*okxAccountPool)
+)
+
+// sharedOKXAccountPool 获取配置对应的OKX账户会话池，账户管理路由和账户API共用同一个实例
+func sharedOKXAccountPool(cfg *config.Config) *okxAccountPool {
+	okxAccountPoolMutex.Lock()
+	defer okxAccountPoolMutex.Unlock()
+
+	if pool, exists := okxAccountPools[cfg]; exists {
+		return pool
+	}
+
+	db := sharedUserDatabase(cfg)
+	pool := &okxAccountPool{
+		accounts:         service.NewOKXAccountService(&cfg.OKX, cfg.CredentialsKey, repository.NewOKXAccountRepository(db)),
+		currency:         &cfg.Currency,
+		snapshotInterval: equitySnapshotInterval(cfg),
+		sessions:         make(map[int64]*okxAccountSession),
+	}
+	if cfg.SQLitePath != "" {
+		pool.equityDB = db
+		pool.recordAll(context.Background())
+	}
+	okxAccountPools[cfg] = pool
+	return pool
+}
+
+// recordAll 为所有用户的OKX账户创建会话，开始记录权益快照
+func (p *okxAccountPool) recordAll(ctx context.Context) {
+	accounts, err := p.accounts.ListAll(ctx)
+	if err != nil {
+		log.Printf("获取OKX账户列表失败，无法记录权益快照: %v", err)
+		return
+	}
+	for _, account := range accounts {
+		p.record(ctx, account.UserID, account.ID)
+	}
+}
+
+// record 为账户创建会话并开始记录权益快照，未配置数据库时不做处理
+func (p *okxAccountPool) record(ctx context.Context, userID, id int64) {
+	if p.equityDB == nil {
+		return
+	}
+	if _, err := p.session(ctx, userID, id); err != nil {
+		log.Printf("OKX账户%d无法记录权益快照: %v", id, err)
+	}
+}
+
+// session 获取用户的OKX账户会话，账户不属于该用户时返回 repository.ErrOKXAccountNotFound
+// 读取凭证和创建服务在锁外进行，一个账户的数据库查询和解密不会阻塞其他账户的请求
+func (p *okxAccountPool) session(ctx context.Context, userID, id int64) (*okxAccountSession, error) {
+	p.mutex.Lock()
+	session, exists := p.sessions[id]
+	evictions := p.evictions
+	p.mutex.Unlock()
+	if exists {
+		return ownedSession(session, userID)
+	}
+
+	built, err := p.newSession(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// 并发请求已创建了同一账户的会话时使用已有会话，丢弃本次创建的会话
+	if session, exists := p.sessions[id]; exists {
+		return ownedSession(session, userID)
+	}
+	// 创建期间有账户被删除时不缓存本次会话，下次选择时重新读取凭证
+	if p.evictions != evictions {
+		return built, nil
+	}
+
+	if built.recorder != nil {
+		built.recorder.Start()
+	}
+	p.sessions[id] = built
+	return built, nil
+}
+
+// newSession 读取账户凭证并创建账户会话，权益快照记录器由调用方在缓存会话后启动
+func (p *okxAccountPool) newSession(ctx context.Context, userID, id int64) (*okxAccountSession, error) {
+	okxCfg, err := p.accounts.Config(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 权益快照按账户ID和账户的交易环境记录，与默认账户及其他账户的盈亏历史互不混合
+	var equityRepo repository.EquityRepository
+	if p.equityDB != nil {
+		equityRepo = repository.NewEquityRepository(p.equityDB, id, okxCfg.TradingEnvironment())
+	}
+
+	client := NewOKXClient(okxCfg)
+	client.Instruments().Start()
+
+	session := &okxAccountSession{
+		userID:         userID,
+		config:         okxCfg,
+		accountService: service.NewAccountServiceWithCurrencies(okxCfg, p.currency, equityRepo, nil),
+		tradeClient:    client,
+	}
+	if equityRepo != nil {
+		session.recorder = service.NewEquityRecorder(session.accountService, equityRepo, p.snapshotInterval)
+	}
+	return session, nil
+}
+
+// ownedSession 会话属于该用户时返回会话，否则返回 repository.ErrOKXAccountNotFound
+func ownedSession(session *okxAccountSession, userID int64) (*okxAccountSession, error) {
+	if session.userID != userID {
+		return nil, repository.ErrOKXAccountNotFound
+	}
+	return session, nil
+}
+
+// evict 移除账户会话并停止记录权益快照，下次选择该账户时重新读取凭证
+func (p *okxAccountPool) evict(id int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if session, exists := p.sessions[id]; exists && session.recorder != nil {
+		session.recorder.Stop()
+	}
+	delete(p.sessions, id)
+	p.evictions++
+}
+
+// selectOKXAccount 根据路径参数 accountId 或 X-Account-Id 请求头选择当前用户的OKX账户，需要在 Auth 之后使用
+// 两者都未指定时不做处理，后续接口使用默认账户
+func selectOKXAccount(pool *okxAccountPool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.Param("accountId")
+		if value == "" {
+			value = c.GetHeader(AccountIDHeader)
+		}
+		if value == "" {
+			c.Next()
+			return
+		}
+
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			utils.BadRequestResponse(c, "无效的账户ID: "+value)
+			c.Abort()
+			return
+		}
+		userID, ok := currentUserID(c)
+		if !ok {
+			utils.UnauthorizedResponse(c, "未登录或登录已失效")
+			c.Abort()
+			return
+		}
+
+		session, err := pool.session(c.Request.Context(), userID, id)
+		if err != nil {
+			respondOKXAccountError(c, "选择OKX账户失败", err)
+			c.Abort()
+			return
+		}
+
+		c.Set(okxAccountKey, session)
+		c.Next()
+	}
+}
+
+// rejectOKXAccountSelection 拒绝选择OKX账户的请求，用于只支持默认账户的接口，避免请求被静默发往默认账户
+func rejectOKXAccountSelection(reason string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(AccountIDHeader) != "" {
+			utils.BadRequestResponse(c, reason+"，不支持 "+AccountIDHeader+" 请求头")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// selectedOKXAccount 当前请求选择的OKX账户会话，未选择时返回nil
+func selectedOKXAccount(c *gin.Context) *okxAccountSession {
+	if session, ok := c.Get(okxAccountKey); ok {
+		return session.(*okxAccountSession)
+	}
+	return nil
+}
+
+// currentUserID 当前登录用户的ID
+func currentUserID(c *gin.Context) (int64, bool) {
+	claims := middleware.AuthClaims(c)
+	if claims == nil {
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(claims.Subject, 10, 64)
+	return id, err == nil
+}
+
+// ListOKXAccounts 获取当前用户的OKX账户列表
+func ListOKXAccounts(c *gin.Context, pool *okxAccountPool) {
+	userID, _ := currentUserID(c)
+
+	accounts, err := pool.accounts.List(c.Request.Context(), userID)
+	if err != nil {
+		respondOKXAccountError(c, "获取OKX账户列表失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, accounts, "获取OKX账户列表成功")
+}
+
+// CreateOKXAccount 添加OKX账户，凭证校验通过后加密保存
+func CreateOKXAccount(c *gin.Context, pool *okxAccountPool) {
+	var req models.CreateOKXAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := currentUserID(c)
+	account, err := pool.accounts.Create(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondOKXAccountError(c, "添加OKX账户失败", err)
+		return
+	}
+	pool.record(c.Request.Context(), userID, account.ID)
+
+	utils.SuccessResponse(c, account, "添加OKX账户成功")
+}
+
+// DeleteOKXAccount 删除OKX账户
+func DeleteOKXAccount(c *gin.Context, pool *okxAccountPool) {
+	id, err := strconv.ParseInt(c.Param("accountId"), 10, 64)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的账户ID: "+c.Param("accountId"))
+		return
+	}
+
+	userID, _ := currentUserID(c)
+	if err := pool.accounts.Delete(c.Request.Context(), userID, id); err != nil {
+		respondOKXAccountError(c, "删除OKX账户失败", err)
+		return
+	}
+	pool.evict(id)
+
+	utils.SuccessResponse(c, nil, "删除OKX账户成功")
+}
+
+// respondOKXAccountError 账户不存在返回404，名称重复返回409，凭证无效返回400，其他错误返回500
+func respondOKXAccountError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrOKXAccountNotFound):
+		utils.NotFoundResponse(c, message+": "+err.Error())
+	case errors.Is(err, repository.ErrOKXAccountExists):
+		utils.ErrorResponse(c, http.StatusConflict, message+": "+err.Error())
+	case errors.Is(err, service.ErrInvalidOKXCredentials):
+		utils.BadRequestResponse(c, message+": "+err.Error())
+	default:
+		respondError(c, message, err)
+	}
+}
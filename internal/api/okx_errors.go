@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// okxErrorStatus 按OKX错误分类映射HTTP状态码
+// 限频返回429；余额不足等业务错误返回400；鉴权、时间戳和上游服务错误属于服务端配置或上游问题，返回502
+func okxErrorStatus(okxErr *okx.OKXError) int {
+	switch okxErr.Kind() {
+	case okx.ErrorKindRateLimit:
+		return http.StatusTooManyRequests
+	case okx.ErrorKindInsufficientBalance:
+		return http.StatusBadRequest
+	case okx.ErrorKindAuth, okx.ErrorKindTimestamp:
+		return http.StatusBadGateway
+	}
+
+	if okxErr.HTTPStatus >= http.StatusInternalServerError || okxErr.Code == "" {
+		return http.StatusBadGateway
+	}
+	return http.StatusBadRequest
+}
+
+// respondError 返回错误响应，OKX错误附带错误码和分类，其他错误返回500
+func respondError(c *gin.Context, message string, err error) {
+	var okxErr *okx.OKXError
+	if !errors.As(err, &okxErr) {
+		utils.ErrorResponse(c, http.StatusInternalServerError, message+": "+err.Error())
+		return
+	}
+
+	utils.ErrorResponseWithData(c, okxErrorStatus(okxErr), message+": "+err.Error(), gin.H{
+		"code": okxErr.Code,
+		"msg":  okxErr.Msg,
+		"kind": okxErr.Kind(),
+	})
+}
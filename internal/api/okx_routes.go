@@ -1,10 +1,15 @@
 package api
 
 import (
-	"net/http"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/middleware"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
 	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -12,22 +17,39 @@ import (
 // SetupOKXRoutes 设置OKX API路由
 func SetupOKXRoutes(r *gin.Engine, cfg *config.Config) {
 	okxClient := NewOKXClient(&cfg.OKX)
+	instruments := okxClient.Instruments()
+	instruments.Start()
 
 	// OKX API路由组
-	okx := r.Group("/api/v1/okx")
+	okx := r.Group("/api/v1/okx", requireRole(cfg, models.RoleViewer)...)
 	{
 		// 获取交易对信息
 		okx.GET("/instruments", func(c *gin.Context) {
 			GetInstruments(c, okxClient)
 		})
 
+		// 搜索交易对
+		okx.GET("/instruments/search", func(c *gin.Context) {
+			SearchInstruments(c, instruments)
+		})
+
+		// 获取交易对变化记录
+		okx.GET("/instruments/changes", func(c *gin.Context) {
+			GetInstrumentChanges(c, instruments)
+		})
+
+		// 获取单个交易对
+		okx.GET("/instrument/:instId", func(c *gin.Context) {
+			GetInstrumentByID(c, instruments)
+		})
+
 		// 获取特定类型的交易对
 		okx.GET("/instruments/:type", func(c *gin.Context) {
 			GetInstrumentsByType(c, okxClient)
 		})
 
-		// 获取API配置信息（仅显示非敏感信息）
-		okx.GET("/config", func(c *gin.Context) {
+		// 获取API配置信息（仅显示非敏感信息），需要管理员
+		okx.GET("/config", middleware.RequireRole(models.RoleAdmin), func(c *gin.Context) {
 			GetOKXConfig(c, cfg)
 		})
 
@@ -43,9 +65,9 @@ func GetInstruments(c *gin.Context, client *OKXClient) {
 	// 默认获取SPOT类型的交易对
 	instType := c.DefaultQuery("instType", "SPOT")
 
-	result, err := client.GetInstruments(instType)
+	result, err := client.GetInstruments(c.Request.Context(), instType)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取交易对信息失败: "+err.Error())
+		respondError(c, "获取交易对信息失败", err)
 		return
 	}
 
@@ -57,28 +79,82 @@ func GetInstrumentsByType(c *gin.Context, client *OKXClient) {
 	instType := c.Param("type")
 
 	// 验证交易对类型
-	validTypes := map[string]bool{
-		"SPOT":    true,
-		"MARGIN":  true,
-		"SWAP":    true,
-		"FUTURES": true,
-		"OPTION":  true,
-	}
-
-	if !validTypes[instType] {
+	if !isInstrumentType(instType) {
 		utils.BadRequestResponse(c, "无效的交易对类型，支持的类型: SPOT, MARGIN, SWAP, FUTURES, OPTION")
 		return
 	}
 
-	result, err := client.GetInstruments(instType)
+	result, err := client.GetInstruments(c.Request.Context(), instType)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取交易对信息失败: "+err.Error())
+		respondError(c, "获取交易对信息失败", err)
 		return
 	}
 
 	utils.SuccessResponse(c, result, "获取"+instType+"交易对信息成功")
 }
 
+// SearchInstruments 搜索交易对
+func SearchInstruments(c *gin.Context, instruments service.InstrumentRegistry) {
+	req := models.InstrumentSearchRequest{Limit: service.DefaultInstrumentSearchLimit}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if req.Type != "" && !isInstrumentType(req.Type) {
+		utils.BadRequestResponse(c, "无效的交易对类型，支持的类型: "+strings.Join(service.InstrumentTypes, ", "))
+		return
+	}
+	if req.Limit < 1 || req.Limit > service.MaxInstrumentSearchLimit {
+		utils.BadRequestResponse(c, fmt.Sprintf("limit取值范围为1-%d", service.MaxInstrumentSearchLimit))
+		return
+	}
+
+	result, err := instruments.Search(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, "搜索交易对失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, result, "搜索交易对成功")
+}
+
+// GetInstrumentChanges 获取交易对上线、下线、到期和状态变化记录
+func GetInstrumentChanges(c *gin.Context, instruments service.InstrumentRegistry) {
+	var since int64
+	if value := c.Query("since"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			utils.BadRequestResponse(c, "无效的since参数")
+			return
+		}
+		since = parsed
+	}
+
+	utils.SuccessResponse(c, instruments.Changes(since), "获取交易对变化记录成功")
+}
+
+// GetInstrumentByID 根据产品ID获取交易对
+func GetInstrumentByID(c *gin.Context, instruments service.InstrumentRegistry) {
+	inst, err := instruments.Get(c.Request.Context(), c.Param("instId"))
+	if err != nil {
+		respondError(c, "获取交易对信息失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, inst, "获取交易对信息成功")
+}
+
+// isInstrumentType 是否为支持的产品类型
+func isInstrumentType(instType string) bool {
+	for _, t := range service.InstrumentTypes {
+		if t == instType {
+			return true
+		}
+	}
+	return false
+}
+
 // GetOKXConfig 获取OKX配置信息（仅显示非敏感信息）
 func GetOKXConfig(c *gin.Context, cfg *config.Config) {
 	// 直接检查环境变量
@@ -92,6 +168,9 @@ func GetOKXConfig(c *gin.Context, cfg *config.Config) {
 		"permissions":   cfg.OKX.Permissions,
 		"baseUrl":       cfg.OKX.BaseURL,
 		"isTest":        cfg.OKX.IsTest,
+		"environment":   cfg.OKX.TradingEnvironment(),
+		"hasDemoApiKey": cfg.OKX.DemoAPIKey != "",
+		"allowLive":     cfg.OKX.AllowLiveTrading,
 		"hasApiKey":     apiKey != "",
 		"hasSecretKey":  secretKey != "",
 		"hasPassphrase": passphrase != "",
@@ -102,9 +181,9 @@ func GetOKXConfig(c *gin.Context, cfg *config.Config) {
 
 // GetSystemTime 获取系统时间
 func GetSystemTime(c *gin.Context, client *OKXClient) {
-	result, err := client.GetSystemTime()
+	result, err := client.GetSystemTime(c.Request.Context())
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取系统时间失败: "+err.Error())
+		respondError(c, "获取系统时间失败", err)
 		return
 	}
 
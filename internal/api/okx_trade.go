@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+)
+
+// PlaceOrder 下单
+func (c *OKXClient) PlaceOrder(ctx context.Context, order *models.OrderRequest) (*models.OrderResultResponse, error) {
+	return c.doOrderRequest(ctx, "/api/v5/trade/order", order)
+}
+
+// PlaceBatchOrders 批量下单（最多20个）
+func (c *OKXClient) PlaceBatchOrders(ctx context.Context, orders []models.OrderRequest) (*models.OrderResultResponse, error) {
+	return c.doOrderRequest(ctx, "/api/v5/trade/batch-orders", orders)
+}
+
+// CancelOrder 撤单
+func (c *OKXClient) CancelOrder(ctx context.Context, cancel *models.CancelOrderRequest) (*models.OrderResultResponse, error) {
+	return c.doOrderRequest(ctx, "/api/v5/trade/cancel-order", cancel)
+}
+
+// CancelBatchOrders 批量撤单（最多20个）
+func (c *OKXClient) CancelBatchOrders(ctx context.Context, cancels []models.CancelOrderRequest) (*models.OrderResultResponse, error) {
+	return c.doOrderRequest(ctx, "/api/v5/trade/cancel-batch-orders", cancels)
+}
+
+// AmendOrder 修改订单
+func (c *OKXClient) AmendOrder(ctx context.Context, amend *models.AmendOrderRequest) (*models.OrderResultResponse, error) {
+	return c.doOrderRequest(ctx, "/api/v5/trade/amend-order", amend)
+}
+
+// GetOrder 按订单ID或客户自定义订单ID查询订单，订单不存在时返回nil
+func (c *OKXClient) GetOrder(ctx context.Context, instId, ordId, clOrdId string) (*models.Order, error) {
+	query := url.Values{"instId": {instId}}
+	if ordId != "" {
+		query.Set("ordId", ordId)
+	} else {
+		query.Set("clOrdId", clOrdId)
+	}
+
+	orders, err := okx.Call[[]models.Order](ctx, c.rest, okx.Request{
+		Path:   "/api/v5/trade/order",
+		Query:  query,
+		Signed: true,
+	})
+	var okxErr *okx.OKXError
+	if errors.As(err, &okxErr) && okxErr.Code == orderNotExistCode {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	return &orders[0], nil
+}
+
+// GetPendingOrders 获取未成交订单列表
+func (c *OKXClient) GetPendingOrders(ctx context.Context, req *models.OrdersRequest) ([]models.Order, error) {
+	return okx.Call[[]models.Order](ctx, c.rest, okx.Request{
+		Path:   "/api/v5/trade/orders-pending",
+		Query:  encodeOrdersQuery(req),
+		Signed: true,
+	})
+}
+
+// GetOrdersHistory 获取历史订单记录（近七天）
+func (c *OKXClient) GetOrdersHistory(ctx context.Context, req *models.OrdersRequest) ([]models.Order, error) {
+	return okx.Call[[]models.Order](ctx, c.rest, okx.Request{
+		Path:   "/api/v5/trade/orders-history",
+		Query:  encodeOrdersQuery(req),
+		Signed: true,
+	})
+}
+
+// GetInstrument 获取单个交易对信息，优先使用交易对信息缓存
+func (c *OKXClient) GetInstrument(ctx context.Context, instId string) (*Instrument, error) {
+	return c.instruments.Get(ctx, instId)
+}
+
+// doOrderRequest 发送下单/撤单/改单请求
+// 全部或部分失败（code为"1"或"2"）时返回逐条结果而不是错误，由调用方按sCode处理
+func (c *OKXClient) doOrderRequest(ctx context.Context, requestPath string, payload interface{}) (*models.OrderResultResponse, error) {
+	resp, err := okx.Do[[]models.OrderResult](ctx, c.rest, okx.Request{
+		Method: http.MethodPost,
+		Path:   requestPath,
+		Body:   payload,
+		Signed: true,
+	})
+
+	var okxErr *okx.OKXError
+	if err != nil && !(errors.As(err, &okxErr) && isOrderItemFailure(okxErr) && len(resp.Data) > 0) {
+		return nil, err
+	}
+
+	return &models.OrderResultResponse{Code: resp.Code, Msg: resp.Msg, Data: resp.Data}, nil
+}
+
+// orderNotExistCode 订单不存在的错误码
+const orderNotExistCode = "51603"
+
+// isOrderItemFailure 是否为逐条返回结果的订单操作失败
+func isOrderItemFailure(err *okx.OKXError) bool {
+	return err.Code == "1" || err.Code == "2"
+}
+
+// encodeOrdersQuery 构建订单列表查询参数
+func encodeOrdersQuery(req *models.OrdersRequest) url.Values {
+	params := url.Values{}
+	if req.InstType != "" {
+		params.Set("instType", req.InstType)
+	}
+	if req.InstId != "" {
+		params.Set("instId", req.InstId)
+	}
+	if req.OrdType != "" {
+		params.Set("ordType", req.OrdType)
+	}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	if req.After != "" {
+		params.Set("after", req.After)
+	}
+	if req.Before != "" {
+		params.Set("before", req.Before)
+	}
+	if req.Limit != "" {
+		params.Set("limit", req.Limit)
+	}
+	return params
+}
+
+// InstTypeFromInstID 根据产品ID推断产品类型
+// BTC-USDT -> SPOT, BTC-USDT-SWAP -> SWAP, BTC-USD-250328 -> FUTURES, BTC-USD-250328-50000-C -> OPTION
+func InstTypeFromInstID(instId string) string {
+	return service.InstTypeFromInstID(instId)
+}
+
+// ValidateOrderPrecision 根据交易对的 TickSz、LotSz、MinSz 校验委托数量和价格
+func ValidateOrderPrecision(order *models.OrderRequest, inst *Instrument) error {
+	if inst.State != "" && inst.State != "live" {
+		return fmt.Errorf("交易对 %s 当前状态为 %s，不可交易", inst.InstID, inst.State)
+	}
+
+	sz, ok := parsePositiveRat(order.Sz)
+	if !ok {
+		return fmt.Errorf("无效的委托数量: %s", order.Sz)
+	}
+
+	// 现货市价买单默认按计价币种下单，数量不受 lotSz/minSz 约束
+	quoteSized := inst.InstType == "SPOT" && order.OrdType == "market" &&
+		(order.TgtCcy == "quote_ccy" || (order.TgtCcy == "" && order.Side == "buy"))
+
+	if !quoteSized {
+		if err := validateSize(sz, order.Sz, inst); err != nil {
+			return err
+		}
+	}
+
+	if orderTypeRequiresPrice(order.OrdType) {
+		if order.Px == "" {
+			return fmt.Errorf("%s 订单必须指定委托价格", order.OrdType)
+		}
+		if err := validatePrice(order.Px, inst); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateAmendPrecision 校验改单的新数量和新价格
+func ValidateAmendPrecision(amend *models.AmendOrderRequest, inst *Instrument) error {
+	if amend.NewSz == "" && amend.NewPx == "" {
+		return fmt.Errorf("newSz 和 newPx 至少需要指定一个")
+	}
+
+	if amend.NewSz != "" {
+		sz, ok := parsePositiveRat(amend.NewSz)
+		if !ok {
+			return fmt.Errorf("无效的委托数量: %s", amend.NewSz)
+		}
+		if err := validateSize(sz, amend.NewSz, inst); err != nil {
+			return err
+		}
+	}
+
+	if amend.NewPx != "" {
+		if err := validatePrice(amend.NewPx, inst); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateSize 校验委托数量满足最小数量和下单数量精度
+func validateSize(sz *big.Rat, raw string, inst *Instrument) error {
+	if minSz, ok := parsePositiveRat(inst.MinSz); ok && sz.Cmp(minSz) < 0 {
+		return fmt.Errorf("委托数量 %s 小于最小下单数量 %s", raw, inst.MinSz)
+	}
+	if lotSz, ok := parsePositiveRat(inst.LotSz); ok && !isMultipleOf(sz, lotSz) {
+		rounded, _ := service.RoundSize(inst, raw)
+		return fmt.Errorf("委托数量 %s 不是下单数量精度 %s 的整数倍，可使用 %s", raw, inst.LotSz, rounded)
+	}
+	return nil
+}
+
+// validatePrice 校验委托价格满足下单价格精度
+func validatePrice(raw string, inst *Instrument) error {
+	px, ok := parsePositiveRat(raw)
+	if !ok {
+		return fmt.Errorf("无效的委托价格: %s", raw)
+	}
+	if tickSz, ok := parsePositiveRat(inst.TickSz); ok && !isMultipleOf(px, tickSz) {
+		down, _ := service.RoundPrice(inst, raw, false)
+		up, _ := service.RoundPrice(inst, raw, true)
+		return fmt.Errorf("委托价格 %s 不是下单价格精度 %s 的整数倍，最接近的有效价格为 %s 或 %s", raw, inst.TickSz, down, up)
+	}
+	return nil
+}
+
+// orderTypeRequiresPrice 判断订单类型是否需要委托价格
+func orderTypeRequiresPrice(ordType string) bool {
+	switch ordType {
+	case "limit", "post_only", "fok", "ioc":
+		return true
+	default:
+		return false
+	}
+}
+
+// parsePositiveRat 将十进制字符串解析为精确有理数，仅接受正数
+func parsePositiveRat(value string) (*big.Rat, bool) {
+	if value == "" {
+		return nil, false
+	}
+	r, ok := new(big.Rat).SetString(value)
+	if !ok || r.Sign() <= 0 {
+		return nil, false
+	}
+	return r, true
+}
+
+// isMultipleOf 判断 value 是否为 step 的整数倍
+func isMultipleOf(value, step *big.Rat) bool {
+	return new(big.Rat).Quo(value, step).IsInt()
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+	"github.com/gin-gonic/gin"
+)
+
+// TradeClient 交易客户端，OKX客户端和本地模拟交易客户端都实现该接口
+type TradeClient interface {
+	GetInstrument(ctx context.Context, instId string) (*Instrument, error)
+	GetTicker(ctx context.Context, instId string) (*TickerResponse, error)
+	GetIndexTicker(ctx context.Context, instId string) (*IndexTickerResponse, error)
+	PlaceOrder(ctx context.Context, order *models.OrderRequest) (*models.OrderResultResponse, error)
+	PlaceBatchOrders(ctx context.Context, orders []models.OrderRequest) (*models.OrderResultResponse, error)
+	CancelOrder(ctx context.Context, cancel *models.CancelOrderRequest) (*models.OrderResultResponse, error)
+	CancelBatchOrders(ctx context.Context, cancels []models.CancelOrderRequest) (*models.OrderResultResponse, error)
+	AmendOrder(ctx context.Context, amend *models.AmendOrderRequest) (*models.OrderResultResponse, error)
+	GetOrder(ctx context.Context, instId, ordId, clOrdId string) (*models.Order, error)
+	GetPendingOrders(ctx context.Context, req *models.OrdersRequest) ([]models.Order, error)
+	GetOrdersHistory(ctx context.Context, req *models.OrdersRequest) ([]models.Order, error)
+}
+
+// paperTradeClient 本地模拟交易客户端，订单操作使用模拟交易所，交易对信息和行情使用OKX公共接口
+type paperTradeClient struct {
+	*OKXClient
+	paper service.PaperExchange
+}
+
+// NewPaperTradeClient 创建本地模拟交易客户端
+func NewPaperTradeClient(client *OKXClient, paper service.PaperExchange) TradeClient {
+	return &paperTradeClient{OKXClient: client, paper: paper}
+}
+
+// PlaceOrder 模拟下单
+func (c *paperTradeClient) PlaceOrder(ctx context.Context, order *models.OrderRequest) (*models.OrderResultResponse, error) {
+	return c.paper.PlaceOrder(ctx, order)
+}
+
+// PlaceBatchOrders 模拟批量下单
+func (c *paperTradeClient) PlaceBatchOrders(ctx context.Context, orders []models.OrderRequest) (*models.OrderResultResponse, error) {
+	return c.paper.PlaceBatchOrders(ctx, orders)
+}
+
+// CancelOrder 模拟撤单
+func (c *paperTradeClient) CancelOrder(ctx context.Context, cancel *models.CancelOrderRequest) (*models.OrderResultResponse, error) {
+	return c.paper.CancelOrder(ctx, cancel)
+}
+
+// CancelBatchOrders 模拟批量撤单
+func (c *paperTradeClient) CancelBatchOrders(ctx context.Context, cancels []models.CancelOrderRequest) (*models.OrderResultResponse, error) {
+	return c.paper.CancelBatchOrders(ctx, cancels)
+}
+
+// AmendOrder 模拟改单
+func (c *paperTradeClient) AmendOrder(ctx context.Context, amend *models.AmendOrderRequest) (*models.OrderResultResponse, error) {
+	return c.paper.AmendOrder(ctx, amend)
+}
+
+// GetOrder 查询模拟订单
+func (c *paperTradeClient) GetOrder(ctx context.Context, instId, ordId, clOrdId string) (*models.Order, error) {
+	return c.paper.GetOrder(ctx, instId, ordId, clOrdId)
+}
+
+// GetPendingOrders 获取模拟未成交订单
+func (c *paperTradeClient) GetPendingOrders(ctx context.Context, req *models.OrdersRequest) ([]models.Order, error) {
+	return c.paper.GetPendingOrders(ctx, req)
+}
+
+// GetOrdersHistory 获取模拟历史订单
+func (c *paperTradeClient) GetOrdersHistory(ctx context.Context, req *models.OrdersRequest) ([]models.Order, error) {
+	return c.paper.GetOrdersHistory(ctx, req)
+}
+
+var (
+	paperMutex     sync.Mutex
+	paperExchanges = make(map[*config.Config]service.PaperExchange)
+)
+
+// sharedPaperExchange 获取配置对应的本地模拟交易所，交易路由和账户路由共用同一个模拟账户
+func sharedPaperExchange(cfg *config.Config) service.PaperExchange {
+	paperMutex.Lock()
+	defer paperMutex.Unlock()
+
+	paper, exists := paperExchanges[cfg]
+	if !exists {
+		paper = service.NewPaperExchange(&cfg.Paper, service.NewPriceService(&cfg.OKX), PaperInstrumentLookup(NewOKXClient(&cfg.OKX)))
+		paperExchanges[cfg] = paper
+	}
+	return paper
+}
+
+// PaperInstrumentLookup 通过OKX公共接口查询模拟撮合所需的交易对信息
+func PaperInstrumentLookup(client *OKXClient) service.InstrumentLookup {
+	return func(ctx context.Context, instId string) (*service.PaperInstrument, error) {
+		inst, err := client.GetInstrument(ctx, instId)
+		if err != nil {
+			return nil, err
+		}
+
+		ctVal, err := decimal.Parse(inst.CtVal)
+		if err != nil {
+			ctVal = decimal.Zero
+		}
+		return &service.PaperInstrument{
+			InstType:  inst.InstType,
+			BaseCcy:   inst.BaseCcy,
+			QuoteCcy:  inst.QuoteCcy,
+			SettleCcy: inst.SettleCcy,
+			CtVal:     ctVal,
+			CtValCcy:  inst.CtValCcy,
+		}, nil
+	}
+}
+
+// PaperEnvironmentGuard 本地模拟交易模式下只接受 paper 交易环境的请求
+func PaperEnvironmentGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		environment := c.GetHeader("X-Trading-Environment")
+		if environment != "" && environment != config.TradingPaper {
+			utils.BadRequestResponse(c, "服务器运行在本地模拟交易模式，不支持"+environment+"交易请求")
+			c.Abort()
+			return
+		}
+
+		c.Set(utils.EnvironmentKey, config.TradingPaper)
+		c.Header("X-Trading-Environment", config.TradingPaper)
+		c.Next()
+	}
+}
+
+// ResetPaperAccount 重置模拟账户：撤销所有订单、清空持仓并恢复初始余额
+func ResetPaperAccount(c *gin.Context, paper service.PaperExchange) {
+	paper.Reset()
+	utils.SuccessResponse(c, paper.Snapshot().Data, "重置模拟账户成功")
+}
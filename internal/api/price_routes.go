@@ -1,10 +1,9 @@
 package api
 
 import (
-	"net/http"
-
 	"github.com/gin-gonic/gin"
 	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
 	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
 	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
 )
@@ -14,7 +13,7 @@ func SetupPriceRoutes(r *gin.Engine, cfg *config.Config) {
 	priceService := service.NewPriceService(&cfg.OKX)
 
 	// 价格API路由组
-	price := r.Group("/api/v1/price")
+	price := r.Group("/api/v1/price", requireRole(cfg, models.RoleViewer)...)
 	{
 		// 获取指定交易对价格
 		price.GET("/:symbol", func(c *gin.Context) {
@@ -32,9 +31,9 @@ func GetPrice(c *gin.Context, priceService service.PriceService) {
 		return
 	}
 
-	priceData, err := priceService.GetPrice(symbol)
+	priceData, err := priceService.GetPrice(c.Request.Context(), symbol)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "获取价格失败: "+err.Error())
+		respondError(c, "获取价格失败", err)
 		return
 	}
 
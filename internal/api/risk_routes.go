@@ -0,0 +1,80 @@
+package api
+
+import (
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/middleware"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupRiskRoutes 设置风控API路由
+func SetupRiskRoutes(r *gin.Engine, cfg *config.Config, riskService service.RiskService) {
+	// 风控API路由组，查看状态需要viewer角色，修改熔断和限额需要admin角色
+	risk := r.Group("/api/v1/risk", requireRole(cfg, models.RoleViewer)...)
+	admin := middleware.RequireRole(models.RoleAdmin)
+	{
+		// 获取风控状态
+		risk.GET("/status", func(c *gin.Context) {
+			GetRiskStatus(c, riskService)
+		})
+
+		// 开启/关闭交易熔断
+		risk.POST("/kill-switch", admin, func(c *gin.Context) {
+			SetKillSwitch(c, riskService)
+		})
+
+		// 更新风控限额
+		risk.PUT("/limits", admin, func(c *gin.Context) {
+			SetRiskLimits(c, riskService)
+		})
+	}
+}
+
+// GetRiskStatus 获取风控状态
+func GetRiskStatus(c *gin.Context, riskService service.RiskService) {
+	utils.SuccessResponse(c, riskService.GetStatus(), "获取风控状态成功")
+}
+
+// SetKillSwitch 开启或关闭交易熔断
+func SetKillSwitch(c *gin.Context, riskService service.RiskService) {
+	var req struct {
+		Enabled *bool  `json:"enabled" binding:"required"`
+		Reason  string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	reason := req.Reason
+	if *req.Enabled && reason == "" {
+		reason = "手动熔断"
+	}
+
+	riskService.SetKillSwitch(*req.Enabled, reason)
+
+	message := "交易熔断已关闭"
+	if *req.Enabled {
+		message = "交易熔断已开启"
+	}
+	utils.SuccessResponse(c, riskService.GetStatus(), message)
+}
+
+// SetRiskLimits 更新风控限额
+func SetRiskLimits(c *gin.Context, riskService service.RiskService) {
+	var limits models.RiskLimits
+	if err := c.ShouldBindJSON(&limits); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := riskService.SetLimits(limits); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, riskService.GetStatus(), "更新风控限额成功")
+}
@@ -2,10 +2,14 @@ package api
 
 import (
 	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/middleware"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRoutes 设置API路由
+// 除健康检查、登录和刷新令牌外，各路由组都需要登录并按角色授权：
+// viewer 查看账户、行情和订单并修改自己的设置，operator 管理OKX账户和交易，admin 查看OKX配置、调整风控、同步成交归档和管理用户
 func SetupRoutes(r *gin.Engine, cfg *config.Config) {
 	// API v1 路由组
 	v1 := r.Group("/api/v1")
@@ -18,15 +22,44 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config) {
 		})
 	}
 
+	// 设置用户认证API路由
+	SetupAuthRoutes(r, cfg)
+
 	// 设置OKX API路由
 	SetupOKXRoutes(r, cfg)
 
 	// 设置价格API路由
 	SetupPriceRoutes(r, cfg)
 
+	// 设置行情API路由
+	SetupMarketRoutes(r, cfg)
+
 	// 设置WebSocket路由
 	SetupWebSocketRoutes(r, cfg)
 
 	// 设置账户API路由
 	SetupAccountRoutes(r, cfg)
+
+	// 设置OKX账户管理API路由
+	SetupOKXAccountRoutes(r, cfg)
+
+	// 设置用户设置API路由
+	SetupSettingsRoutes(r, cfg)
+
+	// 设置本地成交归档API路由
+	SetupArchiveRoutes(r, cfg)
+
+	// 设置交易API路由
+	SetupTradeRoutes(r, cfg)
+
+	// 设置管理API路由
+	SetupAdminRoutes(r, cfg)
+
+	// 设置用户管理API路由
+	SetupUserRoutes(r, cfg)
+}
+
+// requireRole 要求登录且角色不低于 role 的中间件，用于路由组
+func requireRole(cfg *config.Config, role models.Role) gin.HandlersChain {
+	return gin.HandlersChain{middleware.Auth(sharedAuthService(cfg)), middleware.RequireRole(role)}
 }
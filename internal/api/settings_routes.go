@@ -0,0 +1,92 @@
+package api
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/middleware"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupSettingsRoutes 设置用户设置API路由，每个用户只能查看和修改自己的设置，修改需要operator角色
+func SetupSettingsRoutes(r *gin.Engine, cfg *config.Config) {
+	settingsService := sharedSettingsService(cfg)
+
+	settings := r.Group("/api/v1/settings", requireRole(cfg, models.RoleViewer)...)
+	{
+		// 获取当前用户的设置
+		settings.GET("", func(c *gin.Context) {
+			GetSettings(c, settingsService)
+		})
+
+		// 修改当前用户的设置，需要operator角色
+		settings.PUT("", middleware.RequireRole(models.RoleOperator), func(c *gin.Context) {
+			UpdateSettings(c, settingsService)
+		})
+	}
+}
+
+var (
+	settingsMutex    sync.Mutex
+	settingsServices = make(map[*config.Config]service.SettingsService)
+)
+
+// sharedSettingsService 获取配置对应的用户设置服务，设置路由和账户API共用同一个实例
+func sharedSettingsService(cfg *config.Config) service.SettingsService {
+	settingsMutex.Lock()
+	defer settingsMutex.Unlock()
+
+	if settingsService, exists := settingsServices[cfg]; exists {
+		return settingsService
+	}
+
+	repo := repository.NewSettingsRepository(sharedUserDatabase(cfg))
+	settingsService := service.NewSettingsService(repo, &cfg.Currency)
+	settingsServices[cfg] = settingsService
+	return settingsService
+}
+
+// GetSettings 获取当前用户的设置
+func GetSettings(c *gin.Context, settingsService service.SettingsService) {
+	userID, _ := currentUserID(c)
+
+	settings, err := settingsService.Get(c.Request.Context(), userID)
+	if err != nil {
+		respondSettingsError(c, "获取用户设置失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, settings, "获取用户设置成功")
+}
+
+// UpdateSettings 修改当前用户的设置，只修改请求中出现的字段
+func UpdateSettings(c *gin.Context, settingsService service.SettingsService) {
+	var req models.UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := currentUserID(c)
+	settings, err := settingsService.Update(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondSettingsError(c, "修改用户设置失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, settings, "修改用户设置成功")
+}
+
+// respondSettingsError 设置无效返回400，其他错误返回500
+func respondSettingsError(c *gin.Context, message string, err error) {
+	if errors.Is(err, service.ErrInvalidSettings) {
+		utils.BadRequestResponse(c, message+": "+err.Error())
+		return
+	}
+	respondError(c, message, err)
+}
@@ -0,0 +1,665 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxBatchOrders OKX批量下单/撤单的最大订单数
+const maxBatchOrders = 20
+
+// SetupTradeRoutes 设置交易API路由
+func SetupTradeRoutes(r *gin.Engine, cfg *config.Config) {
+	okxClient := NewOKXClient(&cfg.OKX)
+	guard := TradingEnvironmentGuard(&cfg.OKX)
+	riskService := service.NewRiskService(
+		&cfg.Risk,
+		service.NewAccountService(&cfg.OKX),
+		service.NewPriceService(&cfg.OKX),
+	)
+
+	// 模拟盘模式下显式允许实盘交易时，另建实盘客户端，实盘订单按实盘账户的持仓和余额做风控检查
+	var liveClient TradeClient = okxClient
+	liveRiskService := riskService
+	if cfg.OKX.IsTest && cfg.OKX.AllowLiveTrading {
+		liveCfg := cfg.OKX
+		liveCfg.IsTest = false
+		liveClient = NewOKXClient(&liveCfg)
+		liveRiskService = riskService.ForAccount(service.NewAccountService(&liveCfg))
+	}
+	// 通过 X-Account-Id 选择OKX账户时，订单发往该账户，并按该账户的持仓和余额做风控检查
+	tradeClient := func(c *gin.Context) TradeClient {
+		if session := selectedOKXAccount(c); session != nil {
+			return session.tradeClient
+		}
+		if c.GetString(utils.EnvironmentKey) == config.TradingLive {
+			return liveClient
+		}
+		return okxClient
+	}
+	riskServiceOf := func(c *gin.Context) service.RiskService {
+		if session := selectedOKXAccount(c); session != nil {
+			return riskService.ForAccount(session.accountService)
+		}
+		if c.GetString(utils.EnvironmentKey) == config.TradingLive {
+			return liveRiskService
+		}
+		return riskService
+	}
+	selectAccount := selectOKXAccount(sharedOKXAccountPool(cfg))
+
+	// 本地模拟交易模式下订单和风控使用模拟账户，不能选择OKX账户
+	var paper service.PaperExchange
+	if cfg.Paper.Enabled {
+		paper = sharedPaperExchange(cfg)
+		paperClient := NewPaperTradeClient(okxClient, paper)
+		riskService = riskService.ForAccount(service.NewAccountServiceWithStream(&cfg.OKX, nil, paper))
+		guard = PaperEnvironmentGuard()
+		selectAccount = rejectOKXAccountSelection("本地模拟交易模式下交易使用模拟账户")
+		tradeClient = func(c *gin.Context) TradeClient {
+			return paperClient
+		}
+		riskServiceOf = func(c *gin.Context) service.RiskService {
+			return riskService
+		}
+	}
+
+	// 交易API路由组，订单属于交易数据，查询订单与下单、撤单和改单一样需要operator角色（与 /ws/account 的订单推送一致）
+	trade := r.Group("/api/v1/trade", requireRole(cfg, models.RoleOperator)...)
+	trade.Use(selectAccount, guard)
+	{
+		// 下单
+		trade.POST("/orders", func(c *gin.Context) {
+			PlaceOrder(c, tradeClient(c), riskServiceOf(c))
+		})
+
+		// 批量下单
+		trade.POST("/orders/batch", func(c *gin.Context) {
+			PlaceBatchOrders(c, tradeClient(c), riskServiceOf(c))
+		})
+
+		// 撤单
+		trade.DELETE("/orders/:ordId", func(c *gin.Context) {
+			CancelOrder(c, tradeClient(c))
+		})
+
+		// 批量撤单
+		trade.POST("/orders/cancel-batch", func(c *gin.Context) {
+			CancelBatchOrders(c, tradeClient(c))
+		})
+
+		// 修改订单
+		trade.PUT("/orders/:ordId", func(c *gin.Context) {
+			AmendOrder(c, tradeClient(c), riskServiceOf(c))
+		})
+
+		// 获取未成交订单
+		trade.GET("/orders", func(c *gin.Context) {
+			GetPendingOrders(c, tradeClient(c))
+		})
+
+		// 获取历史订单
+		trade.GET("/orders/history", func(c *gin.Context) {
+			GetOrdersHistory(c, tradeClient(c))
+		})
+
+		// 重置模拟账户
+		if paper != nil {
+			trade.POST("/paper/reset", func(c *gin.Context) {
+				ResetPaperAccount(c, paper)
+			})
+		}
+	}
+
+	// 设置风控API路由（与交易路由共享风控状态）
+	SetupRiskRoutes(r, cfg, riskService)
+}
+
+// TradingEnvironmentGuard 交易环境守卫，请求可通过 X-Trading-Environment 头指定 demo/live
+// 服务器以模拟盘模式启动时拒绝实盘交易请求，除非配置了 OKX_ALLOW_LIVE_TRADING；实盘模式下不支持模拟盘请求
+// 选择了OKX账户时交易环境由账户决定，请求指定的环境必须与账户一致
+func TradingEnvironmentGuard(cfg *config.OKXConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := selectedOKXAccount(c)
+		accountEnvironment := cfg.TradingEnvironment()
+		if session != nil {
+			accountEnvironment = session.config.TradingEnvironment()
+		}
+
+		environment := c.GetHeader("X-Trading-Environment")
+		if environment == "" {
+			environment = accountEnvironment
+		}
+
+		switch {
+		case environment != config.TradingDemo && environment != config.TradingLive:
+			utils.BadRequestResponse(c, "无效的交易环境: "+environment+"，支持的环境: demo, live")
+		case environment == config.TradingLive && cfg.IsTest && !cfg.AllowLiveTrading:
+			utils.ErrorResponse(c, http.StatusForbidden, "服务器运行在模拟盘模式，拒绝实盘交易请求（设置 OKX_ALLOW_LIVE_TRADING=true 以允许）")
+		case session != nil && environment != accountEnvironment:
+			utils.BadRequestResponse(c, "所选OKX账户的交易环境为"+accountEnvironment+"，不支持"+environment+"交易请求")
+		case session == nil && environment == config.TradingDemo && !cfg.IsTest:
+			utils.BadRequestResponse(c, "服务器运行在实盘模式，不支持模拟盘交易请求")
+		default:
+			// 响应中标明本次请求实际使用的交易环境
+			c.Set(utils.EnvironmentKey, environment)
+			c.Header("X-Trading-Environment", environment)
+			c.Next()
+			return
+		}
+		c.Abort()
+	}
+}
+
+// PlaceOrder 下单
+func PlaceOrder(c *gin.Context, client TradeClient, riskService service.RiskService) {
+	var order models.OrderRequest
+	if err := c.ShouldBindJSON(&order); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := validateOrderFields(&order); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	inst, err := client.GetInstrument(c.Request.Context(), order.InstId)
+	if err != nil {
+		respondError(c, "获取交易对信息失败", err)
+		return
+	}
+
+	if err := ValidateOrderPrecision(&order, inst); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	notional, err := estimateOrderNotional(c.Request.Context(), client, &order, inst)
+	if err != nil {
+		respondError(c, "估算订单名义价值失败", err)
+		return
+	}
+
+	openOrders, err := countOpenOrders(c.Request.Context(), client, riskService)
+	if err != nil {
+		respondError(c, "获取挂单数量失败", err)
+		return
+	}
+
+	check := riskService.CheckOrders(c.Request.Context(), []models.OrderRequest{order}, []float64{notional}, openOrders)
+	if !check.Passed {
+		respondRiskRejection(c, check)
+		return
+	}
+
+	result, err := client.PlaceOrder(c.Request.Context(), &order)
+	if err != nil {
+		respondError(c, "下单失败", err)
+		return
+	}
+
+	respondOrderResult(c, result, "下单")
+}
+
+// PlaceBatchOrders 批量下单
+func PlaceBatchOrders(c *gin.Context, client TradeClient, riskService service.RiskService) {
+	var orders []models.OrderRequest
+	if err := c.ShouldBindJSON(&orders); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if len(orders) == 0 || len(orders) > maxBatchOrders {
+		utils.BadRequestResponse(c, fmt.Sprintf("批量下单数量必须在1到%d之间", maxBatchOrders))
+		return
+	}
+
+	instruments := make(map[string]*Instrument)
+	notionals := make([]float64, len(orders))
+	for i := range orders {
+		order := &orders[i]
+		if err := validateOrderFields(order); err != nil {
+			utils.BadRequestResponse(c, fmt.Sprintf("第%d个订单: %s", i+1, err.Error()))
+			return
+		}
+
+		inst, ok := instruments[order.InstId]
+		if !ok {
+			var err error
+			inst, err = client.GetInstrument(c.Request.Context(), order.InstId)
+			if err != nil {
+				respondError(c, "获取交易对信息失败", err)
+				return
+			}
+			instruments[order.InstId] = inst
+		}
+
+		if err := ValidateOrderPrecision(order, inst); err != nil {
+			utils.BadRequestResponse(c, fmt.Sprintf("第%d个订单: %s", i+1, err.Error()))
+			return
+		}
+
+		notional, err := estimateOrderNotional(c.Request.Context(), client, order, inst)
+		if err != nil {
+			respondError(c, "估算订单名义价值失败", err)
+			return
+		}
+		notionals[i] = notional
+	}
+
+	openOrders, err := countOpenOrders(c.Request.Context(), client, riskService)
+	if err != nil {
+		respondError(c, "获取挂单数量失败", err)
+		return
+	}
+
+	check := riskService.CheckOrders(c.Request.Context(), orders, notionals, openOrders)
+	if !check.Passed {
+		respondRiskRejection(c, check)
+		return
+	}
+
+	result, err := client.PlaceBatchOrders(c.Request.Context(), orders)
+	if err != nil {
+		respondError(c, "批量下单失败", err)
+		return
+	}
+
+	respondOrderResult(c, result, "批量下单")
+}
+
+// CancelOrder 撤单
+func CancelOrder(c *gin.Context, client TradeClient) {
+	cancel := models.CancelOrderRequest{
+		InstId: c.Query("instId"),
+		OrdId:  c.Param("ordId"),
+	}
+
+	if cancel.InstId == "" {
+		utils.BadRequestResponse(c, "instId不能为空")
+		return
+	}
+
+	result, err := client.CancelOrder(c.Request.Context(), &cancel)
+	if err != nil {
+		respondError(c, "撤单失败", err)
+		return
+	}
+
+	respondOrderResult(c, result, "撤单")
+}
+
+// CancelBatchOrders 批量撤单
+func CancelBatchOrders(c *gin.Context, client TradeClient) {
+	var cancels []models.CancelOrderRequest
+	if err := c.ShouldBindJSON(&cancels); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if len(cancels) == 0 || len(cancels) > maxBatchOrders {
+		utils.BadRequestResponse(c, fmt.Sprintf("批量撤单数量必须在1到%d之间", maxBatchOrders))
+		return
+	}
+
+	for i, cancel := range cancels {
+		if cancel.InstId == "" || (cancel.OrdId == "" && cancel.ClOrdId == "") {
+			utils.BadRequestResponse(c, fmt.Sprintf("第%d个撤单请求: instId和ordId/clOrdId不能为空", i+1))
+			return
+		}
+	}
+
+	result, err := client.CancelBatchOrders(c.Request.Context(), cancels)
+	if err != nil {
+		respondError(c, "批量撤单失败", err)
+		return
+	}
+
+	respondOrderResult(c, result, "批量撤单")
+}
+
+// AmendOrder 修改订单
+func AmendOrder(c *gin.Context, client TradeClient, riskService service.RiskService) {
+	var amend models.AmendOrderRequest
+	if err := c.ShouldBindJSON(&amend); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+	amend.OrdId = c.Param("ordId")
+
+	if amend.InstId == "" {
+		utils.BadRequestResponse(c, "instId不能为空")
+		return
+	}
+
+	inst, err := client.GetInstrument(c.Request.Context(), amend.InstId)
+	if err != nil {
+		respondError(c, "获取交易对信息失败", err)
+		return
+	}
+
+	if err := ValidateAmendPrecision(&amend, inst); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	// 按新价格和新数量重建改单后的订单，与新订单执行相同的风控检查
+	order, err := amendedOrder(c.Request.Context(), client, &amend)
+	if err != nil {
+		respondError(c, "获取待修改订单失败", err)
+		return
+	}
+	if order == nil {
+		utils.NotFoundResponse(c, "未找到待修改的挂单")
+		return
+	}
+
+	notional, err := estimateOrderNotional(c.Request.Context(), client, order, inst)
+	if err != nil {
+		respondError(c, "估算订单名义价值失败", err)
+		return
+	}
+
+	check := riskService.CheckAmend(c.Request.Context(), order, notional)
+	if !check.Passed {
+		respondRiskRejection(c, check)
+		return
+	}
+
+	result, err := client.AmendOrder(c.Request.Context(), &amend)
+	if err != nil {
+		respondError(c, "修改订单失败", err)
+		return
+	}
+
+	respondOrderResult(c, result, "修改订单")
+}
+
+// GetPendingOrders 获取未成交订单
+func GetPendingOrders(c *gin.Context, client TradeClient) {
+	var req models.OrdersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	orders, err := client.GetPendingOrders(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, "获取未成交订单失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, orders, "获取未成交订单成功")
+}
+
+// GetOrdersHistory 获取历史订单
+func GetOrdersHistory(c *gin.Context, client TradeClient) {
+	var req models.OrdersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if req.InstType == "" {
+		utils.BadRequestResponse(c, "instType不能为空")
+		return
+	}
+
+	orders, err := client.GetOrdersHistory(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, "获取历史订单失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, orders, "获取历史订单成功")
+}
+
+// 辅助函数
+
+// validateOrderFields 校验下单请求的必填字段
+func validateOrderFields(order *models.OrderRequest) error {
+	switch {
+	case order.InstId == "":
+		return fmt.Errorf("instId不能为空")
+	case order.Sz == "":
+		return fmt.Errorf("sz不能为空")
+	case order.OrdType == "":
+		return fmt.Errorf("ordType不能为空")
+	}
+
+	switch order.Side {
+	case "buy", "sell":
+	default:
+		return fmt.Errorf("无效的订单方向: %s，支持: buy, sell", order.Side)
+	}
+
+	switch order.TdMode {
+	case "cash", "cross", "isolated", "spot_isolated":
+	default:
+		return fmt.Errorf("无效的交易模式: %s，支持: cash, cross, isolated, spot_isolated", order.TdMode)
+	}
+
+	return nil
+}
+
+// estimateOrderNotional 估算订单名义价值（USD）
+// 未指定价格时使用最新成交价；合约按面值和合约乘数换算，币本位合约面值本身即为USD
+func estimateOrderNotional(ctx context.Context, client TradeClient, order *models.OrderRequest, inst *Instrument) (float64, error) {
+	sz, err := strconv.ParseFloat(order.Sz, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的委托数量: %s", order.Sz)
+	}
+
+	// 现货市价买单按计价币种下单时，数量即为计价币金额
+	quoteSized := inst.InstType == "SPOT" && order.OrdType == "market" &&
+		(order.TgtCcy == "quote_ccy" || (order.TgtCcy == "" && order.Side == "buy"))
+
+	switch inst.InstType {
+	case "SWAP", "FUTURES", "OPTION":
+		ctVal, _ := strconv.ParseFloat(inst.CtVal, 64)
+		if ctVal == 0 {
+			ctVal = 1
+		}
+		if ctMult, _ := strconv.ParseFloat(inst.CtMult, 64); ctMult > 0 {
+			ctVal *= ctMult
+		}
+		if isUSDLike(inst.CtValCcy) {
+			return sz * ctVal, nil
+		}
+
+		// 期权委托价格是期权费而不是标的价格，按标的指数价格计算名义价值
+		var price float64
+		if inst.InstType == "OPTION" {
+			price, err = indexPrice(ctx, client, inst)
+		} else {
+			price, err = referencePrice(ctx, client, order.InstId, order.Px)
+		}
+		if err != nil {
+			return 0, err
+		}
+		return sz * ctVal * price, nil
+	}
+
+	notional := sz
+	if !quoteSized {
+		price, err := referencePrice(ctx, client, order.InstId, order.Px)
+		if err != nil {
+			return 0, err
+		}
+		notional = sz * price
+	}
+
+	// 非美元计价的现货（如 ETH-BTC）按计价币种的USDT价格换算
+	if !isUSDLike(inst.QuoteCcy) && inst.QuoteCcy != "" {
+		quotePrice, err := referencePrice(ctx, client, inst.QuoteCcy+"-USDT", "")
+		if err != nil {
+			return 0, err
+		}
+		notional *= quotePrice
+	}
+
+	return notional, nil
+}
+
+// referencePrice 获取参考价格，优先使用委托价格
+func referencePrice(ctx context.Context, client TradeClient, instId, px string) (float64, error) {
+	if px != "" {
+		if price, err := strconv.ParseFloat(px, 64); err == nil && price > 0 {
+			return price, nil
+		}
+	}
+
+	ticker, err := client.GetTicker(ctx, instId)
+	if err != nil {
+		return 0, err
+	}
+	if len(ticker.Data) == 0 {
+		return 0, fmt.Errorf("未找到 %s 的行情数据", instId)
+	}
+
+	price, err := strconv.ParseFloat(ticker.Data[0].Last, 64)
+	if err != nil || price <= 0 {
+		return 0, fmt.Errorf("无效的 %s 最新价格: %s", instId, ticker.Data[0].Last)
+	}
+	return price, nil
+}
+
+// indexPrice 获取期权标的指数价格
+func indexPrice(ctx context.Context, client TradeClient, inst *Instrument) (float64, error) {
+	uly := inst.Uly
+	if uly == "" {
+		uly = inst.InstFamily
+	}
+
+	ticker, err := client.GetIndexTicker(ctx, uly)
+	if err != nil {
+		return 0, err
+	}
+	if len(ticker.Data) == 0 {
+		return 0, fmt.Errorf("未找到 %s 的指数行情", uly)
+	}
+
+	price, err := strconv.ParseFloat(ticker.Data[0].IdxPx, 64)
+	if err != nil || price <= 0 {
+		return 0, fmt.Errorf("无效的 %s 指数价格: %s", uly, ticker.Data[0].IdxPx)
+	}
+	return price, nil
+}
+
+// amendedOrder 按订单ID或客户自定义订单ID查询待修改的挂单，返回改单后的订单：价格和数量使用改单请求中的新值，未修改时沿用原值
+// 订单不存在或已不是挂单状态时返回nil
+func amendedOrder(ctx context.Context, client TradeClient, amend *models.AmendOrderRequest) (*models.OrderRequest, error) {
+	original, err := client.GetOrder(ctx, amend.InstId, amend.OrdId, amend.ClOrdId)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil || (original.State != "live" && original.State != "partially_filled") {
+		return nil, nil
+	}
+
+	order := &models.OrderRequest{
+		InstId:     original.InstId,
+		TdMode:     original.TdMode,
+		Side:       original.Side,
+		PosSide:    original.PosSide,
+		OrdType:    original.OrdType,
+		Sz:         original.Sz,
+		Px:         original.Px,
+		TgtCcy:     original.TgtCcy,
+		ReduceOnly: original.ReduceOnly == "true",
+	}
+	if amend.NewSz != "" {
+		order.Sz = amend.NewSz
+	}
+	if amend.NewPx != "" {
+		order.Px = amend.NewPx
+	}
+	return order, nil
+}
+
+// pendingOrdersPageSize 查询挂单时每页的数量（OKX上限）
+const pendingOrdersPageSize = 100
+
+// countOpenOrders 获取当前挂单数量，未限制挂单数量时跳过查询
+// 按 after 逐页查询全部挂单，数量达到上限后不再继续翻页
+func countOpenOrders(ctx context.Context, client TradeClient, riskService service.RiskService) (int, error) {
+	limit := riskService.GetStatus().Limits.MaxOpenOrders
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	count := 0
+	req := &models.OrdersRequest{Limit: strconv.Itoa(pendingOrdersPageSize)}
+	for {
+		orders, err := client.GetPendingOrders(ctx, req)
+		if err != nil {
+			return 0, err
+		}
+		count += len(orders)
+		if len(orders) < pendingOrdersPageSize || count >= limit {
+			return count, nil
+		}
+		req.After = orders[len(orders)-1].OrdId
+	}
+}
+
+// isUSDLike 判断币种是否按美元计价
+func isUSDLike(ccy string) bool {
+	switch ccy {
+	case "USD", "USDT", "USDC":
+		return true
+	default:
+		return false
+	}
+}
+
+// respondRiskRejection 返回风控拒单结果
+func respondRiskRejection(c *gin.Context, check *models.RiskCheckResult) {
+	var messages []string
+	for _, violation := range check.Violations {
+		messages = append(messages, violation.Message)
+	}
+	utils.ErrorResponseWithData(c, http.StatusForbidden, "风控拒绝: "+strings.Join(messages, "; "), check)
+}
+
+// respondOrderResult 根据OKX返回的code输出下单类接口结果
+// code为0表示全部成功，2表示批量操作部分成功，其余表示失败
+func respondOrderResult(c *gin.Context, result *models.OrderResultResponse, action string) {
+	switch result.Code {
+	case "0":
+		utils.SuccessResponse(c, result.Data, action+"成功")
+	case "2":
+		utils.SuccessResponse(c, result.Data, action+"部分成功: "+describeOrderFailures(result))
+	default:
+		utils.BadRequestResponse(c, action+"失败: "+describeOrderFailures(result))
+	}
+}
+
+// describeOrderFailures 汇总失败订单的错误信息
+func describeOrderFailures(result *models.OrderResultResponse) string {
+	var messages []string
+	for _, item := range result.Data {
+		if item.SCode != "" && item.SCode != "0" {
+			id := item.OrdId
+			if id == "" {
+				id = item.ClOrdId
+			}
+			messages = append(messages, fmt.Sprintf("[%s] %s (%s)", id, item.SMsg, item.SCode))
+		}
+	}
+
+	if len(messages) == 0 {
+		return result.Msg
+	}
+	return strings.Join(messages, "; ")
+}
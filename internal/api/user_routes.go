@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupUserRoutes 设置用户管理API路由，需要admin角色
+func SetupUserRoutes(r *gin.Engine, cfg *config.Config) {
+	authService := sharedAuthService(cfg)
+
+	users := r.Group("/api/v1/users", requireRole(cfg, models.RoleAdmin)...)
+	{
+		// 获取用户列表
+		users.GET("", func(c *gin.Context) {
+			ListUsers(c, authService)
+		})
+
+		// 创建用户
+		users.POST("", func(c *gin.Context) {
+			CreateUser(c, authService)
+		})
+
+		// 修改用户角色
+		users.PUT("/:id/role", func(c *gin.Context) {
+			UpdateUserRole(c, authService)
+		})
+	}
+}
+
+// ListUsers 获取用户列表
+func ListUsers(c *gin.Context, authService service.AuthService) {
+	users, err := authService.ListUsers(c.Request.Context())
+	if err != nil {
+		respondUserError(c, "获取用户列表失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, users, "获取用户列表成功")
+}
+
+// CreateUser 创建用户
+func CreateUser(c *gin.Context, authService service.AuthService) {
+	var req models.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	user, err := authService.CreateUser(c.Request.Context(), req.Username, req.Password, req.Role)
+	if err != nil {
+		respondUserError(c, "创建用户失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, user, "创建用户成功")
+}
+
+// UpdateUserRole 修改用户角色，立即生效
+func UpdateUserRole(c *gin.Context, authService service.AuthService) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的用户ID: "+c.Param("id"))
+		return
+	}
+
+	var req models.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	user, err := authService.SetUserRole(c.Request.Context(), id, req.Role)
+	if err != nil {
+		respondUserError(c, "修改用户角色失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, user, "修改用户角色成功")
+}
+
+// respondUserError 用户不存在返回404，用户名重复或最后一个管理员返回409，参数不符合要求返回400，其他错误返回500
+func respondUserError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		utils.NotFoundResponse(c, message+": "+err.Error())
+	case errors.Is(err, repository.ErrUserExists), errors.Is(err, service.ErrLastAdmin):
+		utils.ErrorResponse(c, http.StatusConflict, message+": "+err.Error())
+	case errors.Is(err, service.ErrInvalidUser), errors.Is(err, service.ErrInvalidRole):
+		utils.BadRequestResponse(c, message+": "+err.Error())
+	default:
+		respondError(c, message, err)
+	}
+}
@@ -1,15 +1,23 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
+	"regexp"
 	"sync"
+	"time"
 
-	"github.com/gin-gonic/gin"
-	"github.com/gorilla/websocket"
 	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/middleware"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
 	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/indicator"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 )
 
 var upgrader = websocket.Upgrader{
@@ -18,79 +26,115 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// WebSocketManager WebSocket连接管理器
+// 价格推送支持的频道
+const (
+	priceChannelTicker     = "ticker"     // 最新价格
+	priceChannelIndicators = "indicators" // K线完结时的技术指标，需指定bar
+)
+
+// priceChannelRoles 订阅各频道需要的最低角色
+var priceChannelRoles = map[string]models.Role{
+	priceChannelTicker:     models.RoleViewer,
+	priceChannelIndicators: models.RoleViewer,
+}
+
+// maxTopicsPerClient 单个连接最多订阅的主题数
+const maxTopicsPerClient = 50
+
+// initialPriceTimeout 订阅时获取初始价格的超时时间
+const initialPriceTimeout = 10 * time.Second
+
+// instIdPattern 交易对格式，如 BTC-USDT、BTC-USDT-SWAP
+var instIdPattern = regexp.MustCompile(`^[A-Z0-9]+(-[A-Z0-9]+)+$`)
+
+// 订阅协议错误码
+const (
+	wsErrInvalidMessage = "invalid_message"
+	wsErrInvalidOp      = "invalid_op"
+	wsErrInvalidChannel = "invalid_channel"
+	wsErrInvalidInstId  = "invalid_inst_id"
+	wsErrInvalidBar     = "invalid_bar"
+	wsErrTooManyTopics  = "too_many_topics"
+	wsErrForbidden      = "forbidden"
+)
+
+// priceTopic 订阅主题
+type priceTopic struct {
+	Channel string `json:"channel"`
+	InstId  string `json:"instId"`
+	Bar     string `json:"bar,omitempty"` // K线周期，仅indicators频道使用
+}
+
+// priceRequest 客户端订阅请求，如 {"op":"subscribe","args":[{"channel":"ticker","instId":"ETH-USDT"}]}
+type priceRequest struct {
+	Op   string       `json:"op"`
+	Args []priceTopic `json:"args"`
+}
+
+// priceEvent 订阅应答或错误
+type priceEvent struct {
+	Event string      `json:"event"`
+	Arg   *priceTopic `json:"arg,omitempty"`
+	Code  string      `json:"code,omitempty"`
+	Msg   string      `json:"msg,omitempty"`
+}
+
+// pricePush 主题数据推送，ticker频道为 *service.PriceData，indicators频道为 *indicator.Snapshot
+type pricePush struct {
+	Arg  priceTopic  `json:"arg"`
+	Data interface{} `json:"data"`
+}
+
+// wsClient 价格推送客户端连接
+type wsClient struct {
+	*wsConn
+	topics map[priceTopic]bool
+	claims *jwt.Claims // 连接时的令牌载荷，路由未经过 Auth 中间件时为nil，不校验频道角色
+}
+
+// allows 客户端是否可以订阅频道
+func (client *wsClient) allows(channel string) bool {
+	return client.claims == nil || models.Role(client.claims.Role).Allows(priceChannelRoles[channel])
+}
+
+// WebSocketManager WebSocket连接管理器，按主题分发价格推送
+// 同一主题只向上游订阅一次，按订阅该主题的客户端数量引用计数
 type WebSocketManager struct {
-	clients      map[*websocket.Conn]bool
-	broadcast    chan []byte
-	register     chan *websocket.Conn
-	unregister   chan *websocket.Conn
-	mutex        sync.RWMutex
-	priceService service.PriceService
+	clients          map[*wsClient]bool
+	subscribers      map[priceTopic]map[*wsClient]bool
+	lastData         map[priceTopic]interface{}
+	mutex            sync.RWMutex
+	priceService     service.PriceService
+	indicatorService service.IndicatorService
+	options          wsOptions
+	metrics          *wsMetrics
 }
 
 // NewWebSocketManager 创建WebSocket管理器
-func NewWebSocketManager(cfg *config.OKXConfig) *WebSocketManager {
-	return &WebSocketManager{
-		clients:      make(map[*websocket.Conn]bool),
-		broadcast:    make(chan []byte),
-		register:     make(chan *websocket.Conn),
-		unregister:   make(chan *websocket.Conn),
-		priceService: service.NewPriceService(cfg),
-	}
+func NewWebSocketManager(cfg *config.Config) *WebSocketManager {
+	candleService := service.NewCandleService(&cfg.OKX, newCandleRepository(cfg))
+	return NewWebSocketManagerWithServices(
+		service.NewPriceService(&cfg.OKX),
+		service.NewIndicatorService(&cfg.OKX, candleService),
+		cfg.WebSocket,
+	)
 }
 
-// Run 启动WebSocket管理器
-func (manager *WebSocketManager) Run() {
-	// 启动价格数据流
-	manager.priceService.StartPriceStream("BTC-USDT", func(priceData *service.PriceData) {
-		data, err := json.Marshal(priceData)
-		if err != nil {
-			log.Printf("序列化价格数据失败: %v", err)
-			return
-		}
-		
-		select {
-		case manager.broadcast <- data:
-		default:
-			// 如果广播通道满了，跳过这次广播
-		}
-	})
+// NewWebSocketManagerWithService 使用指定价格服务创建WebSocket管理器，不支持indicators频道
+func NewWebSocketManagerWithService(priceService service.PriceService, wsCfg config.WebSocketConfig) *WebSocketManager {
+	return NewWebSocketManagerWithServices(priceService, nil, wsCfg)
+}
 
-	for {
-		select {
-		case client := <-manager.register:
-			manager.mutex.Lock()
-			manager.clients[client] = true
-			manager.mutex.Unlock()
-			log.Printf("WebSocket客户端连接，当前连接数: %d", len(manager.clients))
-
-		case client := <-manager.unregister:
-			manager.mutex.Lock()
-			if _, ok := manager.clients[client]; ok {
-				delete(manager.clients, client)
-				client.Close()
-			}
-			manager.mutex.Unlock()
-			log.Printf("WebSocket客户端断开，当前连接数: %d", len(manager.clients))
-
-		case message := <-manager.broadcast:
-			manager.mutex.Lock()
-			for client := range manager.clients {
-				go func(c *websocket.Conn, msg []byte) {
-					err := c.WriteMessage(websocket.TextMessage, msg)
-					if err != nil {
-						log.Printf("发送消息失败: %v", err)
-						manager.mutex.Lock()
-						if _, exists := manager.clients[c]; exists {
-							delete(manager.clients, c)
-							c.Close()
-						}
-						manager.mutex.Unlock()
-					}
-				}(client, message)
-			}
-			manager.mutex.Unlock()
-		}
+// NewWebSocketManagerWithServices 使用指定价格服务和技术指标服务创建WebSocket管理器
+func NewWebSocketManagerWithServices(priceService service.PriceService, indicatorService service.IndicatorService, wsCfg config.WebSocketConfig) *WebSocketManager {
+	return &WebSocketManager{
+		clients:          make(map[*wsClient]bool),
+		subscribers:      make(map[priceTopic]map[*wsClient]bool),
+		lastData:         make(map[priceTopic]interface{}),
+		priceService:     priceService,
+		indicatorService: indicatorService,
+		options:          newWSOptions(wsCfg),
+		metrics:          metricsFor("price"),
 	}
 }
 
@@ -102,50 +146,256 @@ func (manager *WebSocketManager) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	// 注册新客户端
-	manager.register <- conn
+	client := &wsClient{
+		wsConn: newWSConn(conn, manager.options, manager.metrics),
+		topics: make(map[priceTopic]bool),
+		claims: middleware.AuthClaims(c),
+	}
+	manager.register(client)
+	defer manager.unregister(client)
+
+	client.readPump(func(data []byte) {
+		manager.handleRequest(client, data)
+	})
+}
+
+// register 注册客户端
+func (manager *WebSocketManager) register(client *wsClient) {
+	manager.mutex.Lock()
+	manager.clients[client] = true
+	count := len(manager.clients)
+	manager.mutex.Unlock()
+
+	log.Printf("WebSocket客户端连接，当前连接数: %d", count)
+}
+
+// unregister 注销客户端并释放其全部订阅
+func (manager *WebSocketManager) unregister(client *wsClient) {
+	manager.mutex.Lock()
+	for topic := range client.topics {
+		manager.removeSubscriber(topic, client)
+	}
+	delete(manager.clients, client)
+	count := len(manager.clients)
+	manager.mutex.Unlock()
+
+	client.Close()
+	log.Printf("WebSocket客户端断开，当前连接数: %d", count)
+}
+
+// handleRequest 处理客户端请求
+func (manager *WebSocketManager) handleRequest(client *wsClient, data []byte) {
+	var req priceRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		manager.sendError(client, wsErrInvalidMessage, "消息格式错误，应为JSON: "+err.Error())
+		return
+	}
 
-	// 发送初始价格数据
+	if req.Op != "subscribe" && req.Op != "unsubscribe" {
+		manager.sendError(client, wsErrInvalidOp, "不支持的操作: "+req.Op)
+		return
+	}
+
+	if len(req.Args) == 0 {
+		manager.sendError(client, wsErrInvalidMessage, "args不能为空")
+		return
+	}
+
+	for i := range req.Args {
+		topic := req.Args[i]
+
+		switch {
+		case topic.Channel == priceChannelTicker:
+			topic.Bar = ""
+		case topic.Channel == priceChannelIndicators && manager.indicatorService != nil:
+		default:
+			manager.sendTopicError(client, &topic, wsErrInvalidChannel, "不支持的频道: "+topic.Channel)
+			continue
+		}
+		if !instIdPattern.MatchString(topic.InstId) {
+			manager.sendTopicError(client, &topic, wsErrInvalidInstId, "交易对格式错误: "+topic.InstId)
+			continue
+		}
+		if topic.Channel == priceChannelIndicators && !service.IsNativeCandleBar(topic.Bar) {
+			manager.sendTopicError(client, &topic, wsErrInvalidBar, "不支持实时推送的K线周期: "+topic.Bar)
+			continue
+		}
+
+		if req.Op == "subscribe" && !client.allows(topic.Channel) {
+			manager.sendTopicError(client, &topic, wsErrForbidden, "权限不足，需要 "+string(priceChannelRoles[topic.Channel])+" 角色")
+			continue
+		}
+
+		if req.Op == "subscribe" {
+			manager.subscribe(client, topic)
+		} else {
+			manager.unsubscribe(client, topic)
+		}
+	}
+}
+
+// subscribe 订阅主题，首个订阅者启动上游数据流
+func (manager *WebSocketManager) subscribe(client *wsClient, topic priceTopic) {
+	manager.mutex.Lock()
+	if !client.topics[topic] && len(client.topics) >= maxTopicsPerClient {
+		manager.mutex.Unlock()
+		manager.sendTopicError(client, &topic, wsErrTooManyTopics, "订阅数量超过上限")
+		return
+	}
+
+	client.topics[topic] = true
+	subscribers, exists := manager.subscribers[topic]
+	if !exists {
+		subscribers = make(map[*wsClient]bool)
+		manager.subscribers[topic] = subscribers
+		manager.startStream(topic)
+	}
+	subscribers[client] = true
+	lastData := manager.lastData[topic]
+	manager.mutex.Unlock()
+
+	manager.sendEvent(client, priceEvent{Event: "subscribe", Arg: &topic})
+
+	// 推送最新数据，避免等待下一次变化
+	if lastData != nil {
+		manager.sendPush(client, topic, lastData)
+		return
+	}
+	if topic.Channel != priceChannelTicker {
+		// 指标流加载历史K线后会推送给所有订阅者
+		return
+	}
 	go func() {
-		priceData, err := manager.priceService.GetPrice("BTC-USDT")
+		ctx, cancel := context.WithTimeout(context.Background(), initialPriceTimeout)
+		defer cancel()
+
+		priceData, err := manager.priceService.GetPrice(ctx, topic.InstId)
 		if err != nil {
 			log.Printf("获取初始价格失败: %v", err)
 			return
 		}
+		manager.sendPush(client, topic, priceData)
+	}()
+}
 
-		data, err := json.Marshal(priceData)
-		if err != nil {
-			log.Printf("序列化初始价格数据失败: %v", err)
-			return
-		}
+// unsubscribe 取消订阅主题，最后一个订阅者离开时停止上游数据流
+func (manager *WebSocketManager) unsubscribe(client *wsClient, topic priceTopic) {
+	manager.mutex.Lock()
+	if client.topics[topic] {
+		delete(client.topics, topic)
+		manager.removeSubscriber(topic, client)
+	}
+	manager.mutex.Unlock()
 
-		conn.WriteMessage(websocket.TextMessage, data)
-	}()
+	manager.sendEvent(client, priceEvent{Event: "unsubscribe", Arg: &topic})
+}
 
-	// 监听客户端断开
-	defer func() {
-		manager.unregister <- conn
-	}()
+// removeSubscriber 移除订阅者，调用方需持有锁
+func (manager *WebSocketManager) removeSubscriber(topic priceTopic, client *wsClient) {
+	subscribers, exists := manager.subscribers[topic]
+	if !exists {
+		return
+	}
 
-	// 保持连接活跃
-	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket错误: %v", err)
-			}
-			break
-		}
+	delete(subscribers, client)
+	if len(subscribers) == 0 {
+		delete(manager.subscribers, topic)
+		delete(manager.lastData, topic)
+		manager.stopStream(topic)
+	}
+}
+
+// startStream 启动主题的上游数据流，调用方需持有锁
+func (manager *WebSocketManager) startStream(topic priceTopic) {
+	if topic.Channel == priceChannelIndicators {
+		manager.indicatorService.StartIndicatorStream(topic.InstId, topic.Bar, func(snapshot *indicator.Snapshot) {
+			manager.publish(topic, snapshot)
+		})
+		return
 	}
+	manager.priceService.StartPriceStream(topic.InstId, func(priceData *service.PriceData) {
+		manager.publish(topic, priceData)
+	})
+}
+
+// stopStream 停止主题的上游数据流，调用方需持有锁
+func (manager *WebSocketManager) stopStream(topic priceTopic) {
+	if topic.Channel == priceChannelIndicators {
+		manager.indicatorService.StopIndicatorStream(topic.InstId, topic.Bar)
+		return
+	}
+	manager.priceService.StopSymbolStream(topic.InstId)
+}
+
+// publish 向主题订阅者推送数据
+func (manager *WebSocketManager) publish(topic priceTopic, payload interface{}) {
+	data, err := json.Marshal(pricePush{Arg: topic, Data: payload})
+	if err != nil {
+		log.Printf("序列化推送数据失败: %v", err)
+		return
+	}
+
+	manager.mutex.Lock()
+	if _, exists := manager.subscribers[topic]; !exists {
+		manager.mutex.Unlock()
+		return
+	}
+	manager.lastData[topic] = payload
+	clients := make([]*wsClient, 0, len(manager.subscribers[topic]))
+	for client := range manager.subscribers[topic] {
+		clients = append(clients, client)
+	}
+	manager.mutex.Unlock()
+
+	// 入队不阻塞，慢客户端不影响其他订阅者
+	for _, client := range clients {
+		client.Send(data)
+	}
+}
+
+// sendPush 向单个客户端推送主题数据
+func (manager *WebSocketManager) sendPush(client *wsClient, topic priceTopic, payload interface{}) {
+	manager.send(client, pricePush{Arg: topic, Data: payload})
+}
+
+// sendEvent 发送订阅应答
+func (manager *WebSocketManager) sendEvent(client *wsClient, event priceEvent) {
+	manager.send(client, event)
+}
+
+// sendError 发送错误
+func (manager *WebSocketManager) sendError(client *wsClient, code, msg string) {
+	manager.send(client, priceEvent{Event: "error", Code: code, Msg: msg})
+}
+
+// sendTopicError 发送针对某个主题的错误
+func (manager *WebSocketManager) sendTopicError(client *wsClient, topic *priceTopic, code, msg string) {
+	manager.send(client, priceEvent{Event: "error", Arg: topic, Code: code, Msg: msg})
+}
+
+// send 序列化并发送消息
+func (manager *WebSocketManager) send(client *wsClient, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("序列化WebSocket消息失败: %v", err)
+		return
+	}
+
+	client.Send(data)
 }
 
 // SetupWebSocketRoutes 设置WebSocket路由
 func SetupWebSocketRoutes(r *gin.Engine, cfg *config.Config) {
-	manager := NewWebSocketManager(&cfg.OKX)
-	
-	// 启动WebSocket管理器
-	go manager.Run()
-
-	// WebSocket路由
-	r.GET("/ws/price", manager.HandleWebSocket)
-}
\ No newline at end of file
+	manager := NewWebSocketManager(cfg)
+
+	// WebSocket路由，需要登录，浏览器通过查询参数 token 传递访问令牌，订阅时按频道校验角色
+	r.GET("/ws/price", middleware.Auth(sharedAuthService(cfg)), manager.HandleWebSocket)
+
+	// WebSocket推送统计
+	r.GET("/api/v1/ws/metrics", append(requireRole(cfg, models.RoleViewer), GetWebSocketMetrics)...)
+}
+
+// GetWebSocketMetrics 获取WebSocket推送统计（连接数、发送数、丢弃数、慢消费者断开数）
+func GetWebSocketMetrics(c *gin.Context) {
+	utils.SuccessResponse(c, allWSMetrics(), "获取WebSocket统计成功")
+}
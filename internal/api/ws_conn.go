@@ -0,0 +1,268 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/gorilla/websocket"
+)
+
+// 浏览器WebSocket连接参数默认值
+const (
+	defaultSendQueueSize      = 256
+	defaultMaxDroppedMessages = 100
+	defaultWSPingInterval     = 30 * time.Second
+	defaultWSPongTimeout      = 10 * time.Second
+	wsWriteWait               = 10 * time.Second
+	wsMaxMessageSize          = 4096
+)
+
+// 发送队列满时的处理策略
+const (
+	slowConsumerDrop       = "drop"
+	slowConsumerDisconnect = "disconnect"
+)
+
+// wsOptions 连接参数
+type wsOptions struct {
+	sendQueueSize      int
+	policy             string
+	maxDroppedMessages int
+	pingInterval       time.Duration
+	pongTimeout        time.Duration
+}
+
+// newWSOptions 根据配置生成连接参数，未配置的项使用默认值
+func newWSOptions(cfg config.WebSocketConfig) wsOptions {
+	options := wsOptions{
+		sendQueueSize:      cfg.SendQueueSize,
+		policy:             cfg.SlowConsumerPolicy,
+		maxDroppedMessages: cfg.MaxDroppedMessages,
+		pingInterval:       time.Duration(cfg.PingInterval) * time.Second,
+		pongTimeout:        time.Duration(cfg.PongTimeout) * time.Second,
+	}
+
+	if options.sendQueueSize <= 0 {
+		options.sendQueueSize = defaultSendQueueSize
+	}
+	if options.policy != slowConsumerDisconnect {
+		options.policy = slowConsumerDrop
+	}
+	if options.maxDroppedMessages < 0 {
+		options.maxDroppedMessages = defaultMaxDroppedMessages
+	}
+	if options.pingInterval <= 0 {
+		options.pingInterval = defaultWSPingInterval
+	}
+	if options.pongTimeout <= 0 {
+		options.pongTimeout = defaultWSPongTimeout
+	}
+	return options
+}
+
+// wsMetrics 单个推送端点的统计
+type wsMetrics struct {
+	endpoint                string
+	connections             atomic.Int64
+	totalConnections        atomic.Uint64
+	messagesSent            atomic.Uint64
+	messagesDropped         atomic.Uint64
+	slowConsumerDisconnects atomic.Uint64
+	pongTimeouts            atomic.Uint64
+}
+
+// snapshot 导出统计
+func (m *wsMetrics) snapshot() models.WebSocketMetrics {
+	return models.WebSocketMetrics{
+		Endpoint:                m.endpoint,
+		Connections:             m.connections.Load(),
+		TotalConnections:        m.totalConnections.Load(),
+		MessagesSent:            m.messagesSent.Load(),
+		MessagesDropped:         m.messagesDropped.Load(),
+		SlowConsumerDisconnects: m.slowConsumerDisconnects.Load(),
+		PongTimeouts:            m.pongTimeouts.Load(),
+	}
+}
+
+// wsMetricsRegistry 各推送端点的统计
+var wsMetricsRegistry = struct {
+	sync.Mutex
+	endpoints map[string]*wsMetrics
+}{endpoints: make(map[string]*wsMetrics)}
+
+// metricsFor 获取推送端点的统计，不存在时创建
+func metricsFor(endpoint string) *wsMetrics {
+	wsMetricsRegistry.Lock()
+	defer wsMetricsRegistry.Unlock()
+
+	metrics, exists := wsMetricsRegistry.endpoints[endpoint]
+	if !exists {
+		metrics = &wsMetrics{endpoint: endpoint}
+		wsMetricsRegistry.endpoints[endpoint] = metrics
+	}
+	return metrics
+}
+
+// allWSMetrics 导出全部推送端点的统计
+func allWSMetrics() []models.WebSocketMetrics {
+	wsMetricsRegistry.Lock()
+	defer wsMetricsRegistry.Unlock()
+
+	result := make([]models.WebSocketMetrics, 0, len(wsMetricsRegistry.endpoints))
+	for _, metrics := range wsMetricsRegistry.endpoints {
+		result = append(result, metrics.snapshot())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Endpoint < result[j].Endpoint })
+	return result
+}
+
+// wsConn 浏览器WebSocket连接，所有写操作都经由发送队列和唯一的写协程完成
+type wsConn struct {
+	conn    *websocket.Conn
+	send    chan []byte
+	done    chan struct{}
+	options wsOptions
+	metrics *wsMetrics
+
+	closeOnce        sync.Once
+	closeCode        int
+	closeReason      string
+	consecutiveDrops atomic.Int64
+	dropped          atomic.Uint64
+}
+
+// newWSConn 包装连接并启动写协程
+func newWSConn(conn *websocket.Conn, options wsOptions, metrics *wsMetrics) *wsConn {
+	c := &wsConn{
+		conn:    conn,
+		send:    make(chan []byte, options.sendQueueSize),
+		done:    make(chan struct{}),
+		options: options,
+		metrics: metrics,
+	}
+
+	metrics.connections.Add(1)
+	metrics.totalConnections.Add(1)
+
+	go c.writePump()
+	return c
+}
+
+// Send 将消息放入发送队列，队列已满时按慢消费者策略丢弃消息或断开连接
+func (c *wsConn) Send(data []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- data:
+		c.consecutiveDrops.Store(0)
+		return true
+	default:
+	}
+
+	c.dropped.Add(1)
+	c.metrics.messagesDropped.Add(1)
+	drops := c.consecutiveDrops.Add(1)
+
+	if c.options.policy == slowConsumerDisconnect ||
+		(c.options.maxDroppedMessages > 0 && drops >= int64(c.options.maxDroppedMessages)) {
+		c.metrics.slowConsumerDisconnects.Add(1)
+		log.Printf("WebSocket客户端消费过慢，已丢弃%d条消息，断开连接", c.dropped.Load())
+		c.CloseWithReason(websocket.CloseTryAgainLater, "slow consumer")
+	}
+	return false
+}
+
+// Close 关闭连接
+func (c *wsConn) Close() {
+	c.CloseWithReason(websocket.CloseNormalClosure, "")
+}
+
+// CloseWithReason 关闭连接并向客户端发送关闭原因
+func (c *wsConn) CloseWithReason(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.done)
+	})
+}
+
+// Done 连接关闭通知
+func (c *wsConn) Done() <-chan struct{} {
+	return c.done
+}
+
+// readPump 读取客户端消息直到连接关闭，收到pong时延长读取期限
+func (c *wsConn) readPump(handle func(data []byte)) {
+	defer c.Close()
+
+	readTimeout := c.options.pingInterval + c.options.pongTimeout
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(readTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				c.metrics.pongTimeouts.Add(1)
+				log.Printf("WebSocket客户端心跳超时")
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNormalClosure) {
+				log.Printf("WebSocket错误: %v", err)
+			}
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		if handle != nil {
+			handle(data)
+		}
+	}
+}
+
+// writePump 唯一的写协程：发送队列消息和定时ping，连接关闭时发送关闭帧
+func (c *wsConn) writePump() {
+	ticker := time.NewTicker(c.options.pingInterval)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+		c.metrics.connections.Add(-1)
+	}()
+
+	for {
+		select {
+		case data := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.Close()
+				return
+			}
+			c.metrics.messagesSent.Add(1)
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.Close()
+				return
+			}
+
+		case <-c.done:
+			message := websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+			c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(wsWriteWait))
+			return
+		}
+	}
+}
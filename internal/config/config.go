@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -13,7 +15,28 @@ type Config struct {
 	Port        string
 	DatabaseURL string
 	JWTSecret   string
+	Auth        AuthConfig
 	OKX         OKXConfig
+	Risk        RiskConfig
+	WebSocket   WebSocketConfig
+	Paper       PaperConfig
+	Currency    CurrencyConfig
+	Archive     ArchiveConfig
+
+	SQLitePath             string // 本地SQLite数据库路径
+	EquitySnapshotInterval int    // 权益快照记录间隔（分钟）
+	CredentialsKey         string // 加密保存用户OKX凭证的主密钥，为空时不能添加OKX账户
+}
+
+// DefaultJWTSecret 未配置 JWT_SECRET 时使用的默认密钥，生产环境禁止使用
+const DefaultJWTSecret = "your-secret-key"
+
+// AuthConfig 用户认证配置
+type AuthConfig struct {
+	AccessTokenTTL  int    // 访问令牌有效期（分钟）
+	RefreshTokenTTL int    // 刷新令牌有效期（分钟）
+	AdminUsername   string // 没有任何用户时自动创建的初始用户
+	AdminPassword   string
 }
 
 // OKXConfig OKX API配置
@@ -25,7 +48,108 @@ type OKXConfig struct {
 	Remark      string
 	Permissions string
 	BaseURL     string
-	IsTest      bool
+	IsTest      bool // 模拟盘模式，私有接口请求附带 x-simulated-trading: 1
+
+	DemoAPIKey       string // 模拟盘API Key，为空时使用 APIKey
+	DemoSecretKey    string
+	DemoPassphrase   string
+	AllowLiveTrading bool // 模拟盘模式下是否允许请求实盘交易
+
+	WSPublicURL   string // 公共WebSocket地址（行情、成交、订单簿）
+	WSBusinessURL string // 业务WebSocket地址（K线）
+	WSPrivateURL  string // 私有WebSocket地址（账户、持仓、订单），为空时不启用
+
+	InstrumentRefreshInterval int // 交易对信息刷新间隔（分钟）
+}
+
+// 交易环境
+const (
+	TradingDemo  = "demo"  // 模拟盘
+	TradingLive  = "live"  // 实盘
+	TradingPaper = "paper" // 本地模拟交易
+)
+
+// TradingEnvironment 当前交易环境：demo 模拟盘 / live 实盘
+func (c *OKXConfig) TradingEnvironment() string {
+	if c.IsTest {
+		return TradingDemo
+	}
+	return TradingLive
+}
+
+// Validate 检查启动前必须满足的配置，生产环境不允许使用默认的JWT密钥
+func (c *Config) Validate() error {
+	if c.Environment == "production" && (c.JWTSecret == "" || c.JWTSecret == DefaultJWTSecret) {
+		return fmt.Errorf("生产环境必须通过 JWT_SECRET 设置JWT密钥")
+	}
+	return nil
+}
+
+// TradingEnvironment 当前交易环境，启用本地模拟交易时为 paper，否则由OKX配置决定
+func (c *Config) TradingEnvironment() string {
+	if c.Paper.Enabled {
+		return TradingPaper
+	}
+	return c.OKX.TradingEnvironment()
+}
+
+// Keys 当前交易环境使用的API凭证，模拟盘优先使用 OKX_DEMO_* 凭证
+func (c *OKXConfig) Keys() (apiKey, secretKey, passphrase string) {
+	if c.IsTest && c.DemoAPIKey != "" {
+		return c.DemoAPIKey, c.DemoSecretKey, c.DemoPassphrase
+	}
+	return c.APIKey, c.SecretKey, c.Passphrase
+}
+
+// HasKeys 当前交易环境的API凭证是否完整
+func (c *OKXConfig) HasKeys() bool {
+	apiKey, secretKey, passphrase := c.Keys()
+	return apiKey != "" && secretKey != "" && passphrase != ""
+}
+
+// RiskConfig 交易风控配置，数值为0表示不限制
+type RiskConfig struct {
+	MaxNotionalPerInstrument float64
+	MaxLeverage              float64
+	MaxOpenOrders            int
+	DailyLossLimit           float64
+	KillSwitch               bool
+}
+
+// PaperConfig 本地模拟交易配置
+type PaperConfig struct {
+	Enabled         bool    // 启用后交易和账户接口使用本地模拟账户，按实时行情撮合
+	InitialBalance  float64 // 初始USDT余额
+	TakerFeeRate    float64 // 吃单手续费率
+	MakerFeeRate    float64 // 挂单手续费率
+	DefaultLeverage float64 // 合约杠杆倍数
+}
+
+// CurrencyConfig 显示币种与汇率配置
+type CurrencyConfig struct {
+	DisplayCurrencies []string // 可选的显示币种，可以是法币或任意OKX现货资产
+	RateProviders     []string // 汇率来源及优先级：okx / http / file，同一对资产使用排在前面的来源
+	FiatRatesURLs     []string // http来源的法币汇率接口，以USD为基准，返回 {"rates": {"CNY": 7.2, ...}}，按顺序尝试
+	FiatRatesFile     string   // file来源的本地汇率文件，用于离线兜底或手动指定汇率
+	OKXRatesMaxAge    int      // okx来源报价的最长有效时间（分钟），0表示不限制
+	HTTPRatesMaxAge   int      // http来源报价的最长有效时间（分钟），0表示不限制
+	FileRatesMaxAge   int      // file来源报价的最长有效时间（分钟），0表示不限制
+}
+
+// ArchiveConfig 本地成交归档配置，定期将OKX成交明细、账单和历史持仓同步到 SQLITE_PATH 数据库
+type ArchiveConfig struct {
+	Enabled      bool     // 是否启用定期同步，需要配置API密钥，本地模拟交易模式下不启用
+	SyncInterval int      // 同步间隔（分钟）
+	InstTypes    []string // 同步成交明细的产品类型，OKX成交明细接口必须指定产品类型
+}
+
+// WebSocketConfig 浏览器WebSocket推送配置
+type WebSocketConfig struct {
+	SendQueueSize      int    // 每个连接的发送队列长度
+	SlowConsumerPolicy string // 发送队列满时的处理策略：drop 丢弃消息 / disconnect 立即断开
+	MaxDroppedMessages int    // drop策略下连续丢弃达到该数量后断开，0表示不断开
+	PingInterval       int    // 心跳间隔（秒）
+	PongTimeout        int    // 心跳后等待pong的超时时间（秒）
 }
 
 // Load 加载配置
@@ -33,11 +157,24 @@ func Load() *Config {
 	// 加载.env文件
 	godotenv.Load()
 
+	// 模拟盘的私有WebSocket使用独立地址
+	isTest := getEnvBool("OKX_IS_TEST", false)
+	wsPrivateURL := "wss://ws.okx.com:8443/ws/v5/private"
+	if isTest {
+		wsPrivateURL = "wss://wspap.okx.com:8443/ws/v5/private"
+	}
+
 	return &Config{
 		Environment: getEnv("ENVIRONMENT", "development"),
 		Port:        getEnv("PORT", "8080"),
 		DatabaseURL: getEnv("DATABASE_URL", ""),
-		JWTSecret:   getEnv("JWT_SECRET", "your-secret-key"),
+		JWTSecret:   getEnv("JWT_SECRET", DefaultJWTSecret),
+		Auth: AuthConfig{
+			AccessTokenTTL:  getEnvInt("AUTH_ACCESS_TOKEN_TTL", 15),
+			RefreshTokenTTL: getEnvInt("AUTH_REFRESH_TOKEN_TTL", 7*24*60),
+			AdminUsername:   getEnv("AUTH_ADMIN_USERNAME", ""),
+			AdminPassword:   getEnv("AUTH_ADMIN_PASSWORD", ""),
+		},
 		OKX: OKXConfig{
 			APIKey:      getEnv("OKX_API_KEY", ""),
 			SecretKey:   getEnv("OKX_SECRET_KEY", ""),
@@ -46,8 +183,57 @@ func Load() *Config {
 			Remark:      getEnv("OKX_REMARK", "Gin项目"),
 			Permissions: getEnv("OKX_PERMISSIONS", "读取/提现/交易"),
 			BaseURL:     getEnv("OKX_BASE_URL", "https://www.okx.com"),
-			IsTest:      getEnvBool("OKX_IS_TEST", false),
+			IsTest:      isTest,
+
+			DemoAPIKey:       getEnv("OKX_DEMO_API_KEY", ""),
+			DemoSecretKey:    getEnv("OKX_DEMO_SECRET_KEY", ""),
+			DemoPassphrase:   getEnv("OKX_DEMO_PASSPHRASE", ""),
+			AllowLiveTrading: getEnvBool("OKX_ALLOW_LIVE_TRADING", false),
+
+			WSPublicURL:   getEnv("OKX_WS_PUBLIC_URL", "wss://ws.okx.com:8443/ws/v5/public"),
+			WSBusinessURL: getEnv("OKX_WS_BUSINESS_URL", "wss://ws.okx.com:8443/ws/v5/business"),
+			WSPrivateURL:  getEnv("OKX_WS_PRIVATE_URL", wsPrivateURL),
+
+			InstrumentRefreshInterval: getEnvInt("OKX_INSTRUMENT_REFRESH_INTERVAL", 60),
+		},
+		Risk: RiskConfig{
+			MaxNotionalPerInstrument: getEnvFloat("RISK_MAX_NOTIONAL", 10000),
+			MaxLeverage:              getEnvFloat("RISK_MAX_LEVERAGE", 5),
+			MaxOpenOrders:            getEnvInt("RISK_MAX_OPEN_ORDERS", 50),
+			DailyLossLimit:           getEnvFloat("RISK_DAILY_LOSS_LIMIT", 1000),
+			KillSwitch:               getEnvBool("RISK_KILL_SWITCH", false),
+		},
+		WebSocket: WebSocketConfig{
+			SendQueueSize:      getEnvInt("WS_SEND_QUEUE_SIZE", 256),
+			SlowConsumerPolicy: getEnv("WS_SLOW_CONSUMER_POLICY", "drop"),
+			MaxDroppedMessages: getEnvInt("WS_MAX_DROPPED_MESSAGES", 100),
+			PingInterval:       getEnvInt("WS_PING_INTERVAL", 30),
+			PongTimeout:        getEnvInt("WS_PONG_TIMEOUT", 10),
 		},
+		Paper: PaperConfig{
+			Enabled:         getEnvBool("PAPER_TRADING", false),
+			InitialBalance:  getEnvFloat("PAPER_INITIAL_BALANCE", 10000),
+			TakerFeeRate:    getEnvFloat("PAPER_TAKER_FEE_RATE", 0.0005),
+			MakerFeeRate:    getEnvFloat("PAPER_MAKER_FEE_RATE", 0.0002),
+			DefaultLeverage: getEnvFloat("PAPER_LEVERAGE", 3),
+		},
+		Currency: CurrencyConfig{
+			DisplayCurrencies: getEnvList("DISPLAY_CURRENCIES", "CNY,USD,USDT,BTC"),
+			RateProviders:     getEnvList("RATE_PROVIDERS", "okx,http,file"),
+			FiatRatesURLs:     getEnvList("FIAT_RATES_URLS", "https://api.exchangerate-api.com/v4/latest/USD,https://open.er-api.com/v6/latest/USD"),
+			FiatRatesFile:     getEnv("FIAT_RATES_FILE", "configs/exchange_rates.json"),
+			OKXRatesMaxAge:    getEnvInt("RATE_MAX_AGE_OKX", 10),
+			HTTPRatesMaxAge:   getEnvInt("RATE_MAX_AGE_HTTP", 2880),
+			FileRatesMaxAge:   getEnvInt("RATE_MAX_AGE_FILE", 0),
+		},
+		Archive: ArchiveConfig{
+			Enabled:      getEnvBool("ARCHIVE_ENABLED", true),
+			SyncInterval: getEnvInt("ARCHIVE_SYNC_INTERVAL", 60),
+			InstTypes:    getEnvList("ARCHIVE_INST_TYPES", "SPOT,MARGIN,SWAP,FUTURES,OPTION"),
+		},
+		SQLitePath:             getEnv("SQLITE_PATH", "data/alphaark.db"),
+		EquitySnapshotInterval: getEnvInt("EQUITY_SNAPSHOT_INTERVAL", 5),
+		CredentialsKey:         getEnv("CREDENTIALS_MASTER_KEY", ""),
 	}
 }
 
@@ -69,6 +255,16 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvFloat 获取浮点数环境变量
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 // getEnvBool 获取布尔环境变量
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
@@ -78,3 +274,14 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getEnvList 获取逗号分隔的列表环境变量，忽略空项
+func getEnvList(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
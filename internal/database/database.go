@@ -0,0 +1,180 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	_ "modernc.org/sqlite"
+)
+
+var (
+	sharedMutex sync.Mutex
+	sharedDBs   = make(map[string]*sql.DB)
+)
+
+// migrations 数据库表结构，按顺序执行，所有语句必须可重复执行
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS equity_snapshots (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		currency     TEXT    NOT NULL,
+		total_equity TEXT    NOT NULL,
+		recorded_at  INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_equity_snapshots_currency_time
+		ON equity_snapshots (currency, recorded_at)`,
+	`CREATE TABLE IF NOT EXISTS candles (
+		inst_id       TEXT    NOT NULL,
+		bar           TEXT    NOT NULL,
+		ts            INTEGER NOT NULL,
+		open          TEXT    NOT NULL,
+		high          TEXT    NOT NULL,
+		low           TEXT    NOT NULL,
+		close         TEXT    NOT NULL,
+		vol           TEXT    NOT NULL,
+		vol_ccy       TEXT    NOT NULL,
+		vol_ccy_quote TEXT    NOT NULL,
+		PRIMARY KEY (inst_id, bar, ts)
+	)`,
+	`CREATE TABLE IF NOT EXISTS users (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		username      TEXT    NOT NULL UNIQUE,
+		password_hash TEXT    NOT NULL,
+		created_at    INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS revoked_tokens (
+		jti        TEXT    PRIMARY KEY,
+		expires_at INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS okx_accounts (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id    INTEGER NOT NULL,
+		name       TEXT    NOT NULL,
+		api_key    TEXT    NOT NULL,
+		secret_key TEXT    NOT NULL,
+		passphrase TEXT    NOT NULL,
+		key_hint   TEXT    NOT NULL,
+		is_test    INTEGER NOT NULL,
+		created_at INTEGER NOT NULL,
+		UNIQUE (user_id, name)
+	)`,
+	`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'viewer'`,
+	// 添加角色前创建的用户默认为viewer，没有管理员时将最早的用户设为管理员
+	`UPDATE users SET role = 'admin'
+		WHERE id = (SELECT MIN(id) FROM users)
+		AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'admin')`,
+	`CREATE TABLE IF NOT EXISTS user_settings (
+		user_id              INTEGER PRIMARY KEY,
+		default_currency     TEXT    NOT NULL,
+		favorite_instruments TEXT    NOT NULL,
+		dashboard_layout     TEXT    NOT NULL,
+		alert_preferences    TEXT    NOT NULL,
+		updated_at           INTEGER NOT NULL
+	)`,
+	// 本地成交归档，data 保存完整记录的JSON，其余列用于去重和查询
+	`CREATE TABLE IF NOT EXISTS archive_fills (
+		inst_id   TEXT    NOT NULL,
+		trade_id  TEXT    NOT NULL,
+		inst_type TEXT    NOT NULL,
+		ts        INTEGER NOT NULL,
+		data      TEXT    NOT NULL,
+		PRIMARY KEY (inst_id, trade_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_archive_fills_ts ON archive_fills (ts)`,
+	`CREATE TABLE IF NOT EXISTS archive_bills (
+		bill_id   TEXT    PRIMARY KEY,
+		inst_type TEXT    NOT NULL,
+		inst_id   TEXT    NOT NULL,
+		ccy       TEXT    NOT NULL,
+		ts        INTEGER NOT NULL,
+		data      TEXT    NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_archive_bills_ts ON archive_bills (ts)`,
+	`CREATE TABLE IF NOT EXISTS archive_positions (
+		pos_id    TEXT    NOT NULL,
+		u_time    INTEGER NOT NULL,
+		inst_type TEXT    NOT NULL,
+		inst_id   TEXT    NOT NULL,
+		data      TEXT    NOT NULL,
+		PRIMARY KEY (pos_id, u_time)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_archive_positions_u_time ON archive_positions (u_time)`,
+	`CREATE TABLE IF NOT EXISTS archive_checkpoints (
+		source     TEXT    PRIMARY KEY,
+		floor      TEXT    NOT NULL,
+		top        TEXT    NOT NULL,
+		cursor     TEXT    NOT NULL,
+		synced     INTEGER NOT NULL,
+		last_error TEXT    NOT NULL,
+		updated_at INTEGER NOT NULL
+	)`,
+	`ALTER TABLE archive_bills ADD COLUMN type TEXT NOT NULL DEFAULT ''`,
+	// 添加账单类型前归档的账单从完整记录中补齐类型
+	`UPDATE archive_bills SET type = COALESCE(json_extract(data, '$.type'), '') WHERE type = ''`,
+	`CREATE INDEX IF NOT EXISTS idx_archive_bills_type_ts ON archive_bills (type, ts)`,
+	// 权益快照按账户和交易环境分开记录，account_id 为0表示环境变量配置的默认账户
+	// 添加这两列前记录的快照无法确定交易环境，environment 为空，不再参与盈亏计算
+	`ALTER TABLE equity_snapshots ADD COLUMN account_id INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE equity_snapshots ADD COLUMN environment TEXT NOT NULL DEFAULT ''`,
+	`CREATE INDEX IF NOT EXISTS idx_equity_snapshots_account_time
+		ON equity_snapshots (account_id, environment, currency, recorded_at)`,
+}
+
+// Open 打开SQLite数据库并执行迁移
+func Open(path string) (*sql.DB, error) {
+	if dir := filepath.Dir(path); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("创建数据目录失败: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %w", err)
+	}
+
+	// SQLite同一时间只允许一个写连接
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Shared 获取指定路径的共享数据库连接，同一路径只打开一次
+func Shared(path string) (*sql.DB, error) {
+	sharedMutex.Lock()
+	defer sharedMutex.Unlock()
+
+	if db, ok := sharedDBs[path]; ok {
+		return db, nil
+	}
+
+	db, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedDBs[path] = db
+	return db, nil
+}
+
+// migrate 执行数据库迁移
+// SQLite不支持 ADD COLUMN IF NOT EXISTS，列已存在的错误视为已执行
+func migrate(db *sql.DB) error {
+	for _, stmt := range migrations {
+		if _, err := db.Exec(stmt); err != nil {
+			if strings.Contains(err.Error(), "duplicate column name") {
+				continue
+			}
+			return fmt.Errorf("数据库迁移失败: %w", err)
+		}
+	}
+	return nil
+}
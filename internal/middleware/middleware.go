@@ -1,10 +1,16 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/jwt"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 )
 
 // CORS 跨域中间件
@@ -12,7 +18,8 @@ func CORS() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Trading-Environment, X-Account-Id")
+		c.Header("Access-Control-Expose-Headers", "X-Trading-Environment")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -40,21 +47,89 @@ func Logger() gin.HandlerFunc {
 	})
 }
 
+// TradingEnvironment 交易环境标识中间件，在响应头 X-Trading-Environment 和标准响应的 environment 字段中标明 demo/live
+func TradingEnvironment(environment string) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		c.Set(utils.EnvironmentKey, environment)
+		c.Header("X-Trading-Environment", environment)
+		c.Next()
+	})
+}
+
 // Recovery 恢复中间件
 func Recovery() gin.HandlerFunc {
 	return gin.Recovery()
 }
 
-// Auth 认证中间件
-func Auth() gin.HandlerFunc {
+// TokenAuthenticator 校验访问令牌，由 service.AuthService 实现
+type TokenAuthenticator interface {
+	Authenticate(ctx context.Context, accessToken string) (*jwt.Claims, error)
+}
+
+// Auth 认证中间件，校验 Authorization: Bearer <token> 中的JWT访问令牌
+// 浏览器WebSocket无法设置请求头，也可以通过查询参数 token 传递
+func Auth(authenticator TokenAuthenticator) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
-		token := c.GetHeader("Authorization")
+		token := BearerToken(c)
 		if token == "" {
-			c.JSON(401, gin.H{"error": "Authorization header required"})
+			utils.UnauthorizedResponse(c, "缺少访问令牌")
+			c.Abort()
+			return
+		}
+
+		claims, err := authenticator.Authenticate(c.Request.Context(), token)
+		if err != nil {
+			utils.UnauthorizedResponse(c, err.Error())
 			c.Abort()
 			return
 		}
-		// TODO: 验证JWT token
+
+		c.Set(utils.AuthClaimsKey, claims)
 		c.Next()
 	})
-} 
\ No newline at end of file
+}
+
+// RequireRole 角色校验中间件，需要在 Auth 之后使用，当前用户角色低于 role 时返回403
+func RequireRole(role models.Role) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		claims := AuthClaims(c)
+		if claims == nil {
+			utils.UnauthorizedResponse(c, "缺少访问令牌")
+			c.Abort()
+			return
+		}
+
+		if !models.Role(claims.Role).Allows(role) {
+			utils.ForbiddenResponse(c, "权限不足，需要 "+string(role)+" 角色")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	})
+}
+
+// BearerToken 从 Authorization 请求头读取访问令牌
+// 浏览器WebSocket无法设置请求头，只有WebSocket升级请求可以使用查询参数 token，
+// REST接口不接受查询参数中的令牌，避免令牌写入访问日志和浏览器历史
+func BearerToken(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		return c.Query("token")
+	}
+	return ""
+}
+
+// AuthClaims 当前请求的令牌载荷，未经过 Auth 中间件时返回nil
+func AuthClaims(c *gin.Context) *jwt.Claims {
+	if claims, ok := c.Get(utils.AuthClaimsKey); ok {
+		return claims.(*jwt.Claims)
+	}
+	return nil
+}
@@ -1,8 +1,13 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
 
-// Currency 支持的币种单位
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+)
+
+// Currency 显示币种单位，可以是法币或任意OKX现货资产
 type Currency string
 
 const (
@@ -10,8 +15,18 @@ const (
 	CurrencyUSD  Currency = "USD"
 	CurrencyUSDT Currency = "USDT"
 	CurrencyBTC  Currency = "BTC"
+	CurrencyEUR  Currency = "EUR"
+	CurrencyHKD  Currency = "HKD"
+	CurrencyETH  Currency = "ETH"
 )
 
+// cashLikeCurrencies 按2位小数显示的常见法币和稳定币
+var cashLikeCurrencies = map[Currency]bool{
+	"CNY": true, "USD": true, "EUR": true, "HKD": true, "JPY": true, "GBP": true,
+	"KRW": true, "SGD": true, "AUD": true, "CAD": true, "CHF": true, "TWD": true,
+	"USDT": true, "USDC": true, "DAI": true, "FDUSD": true, "PYUSD": true,
+}
+
 // TimePeriod 时间周期
 type TimePeriod string
 
@@ -24,30 +39,30 @@ const (
 
 // AccountBalance 账户余额信息
 type AccountBalance struct {
-	TotalEquity    string    `json:"totalEquity"`    // 总资产
-	Currency       Currency  `json:"currency"`       // 当前显示币种
-	LastUpdateTime time.Time `json:"lastUpdateTime"` // 最后更新时间
-	Details        []Balance `json:"details"`        // 详细余额
+	TotalEquity    decimal.Decimal `json:"totalEquity"`    // 总资产，显示币种为USDT时与OKX totalEq完全一致
+	Currency       Currency        `json:"currency"`       // 当前显示币种
+	LastUpdateTime time.Time       `json:"lastUpdateTime"` // 最后更新时间
+	Details        []Balance       `json:"details"`        // 详细余额
 }
 
 // Balance 单币种余额
 type Balance struct {
-	Currency  string `json:"currency"`  // 币种
-	Balance   string `json:"balance"`   // 余额
-	Available string `json:"available"` // 可用余额
-	Frozen    string `json:"frozen"`    // 冻结余额
-	Equity    string `json:"equity"`    // 权益（按当前显示币种计算）
+	Currency  string           `json:"currency"`  // 币种
+	Balance   decimal.Decimal  `json:"balance"`   // 余额
+	Available decimal.Decimal  `json:"available"` // 可用余额
+	Frozen    decimal.Decimal  `json:"frozen"`    // 冻结余额
+	Equity    *decimal.Decimal `json:"equity"`    // 权益（按当前显示币种计算），没有汇率时为null
 }
 
 // ProfitLoss 盈亏信息
 type ProfitLoss struct {
-	Period         TimePeriod `json:"period"`         // 时间周期
-	ProfitAmount   string     `json:"profitAmount"`   // 盈亏金额
-	ProfitPercent  string     `json:"profitPercent"`  // 盈亏百分比
-	Currency       Currency   `json:"currency"`       // 币种单位
-	IsProfit       bool       `json:"isProfit"`       // 是否盈利
-	StartTime      time.Time  `json:"startTime"`      // 开始时间
-	EndTime        time.Time  `json:"endTime"`        // 结束时间
+	Period        TimePeriod      `json:"period"`        // 时间周期
+	ProfitAmount  decimal.Decimal `json:"profitAmount"`  // 盈亏金额
+	ProfitPercent decimal.Decimal `json:"profitPercent"` // 盈亏百分比
+	Currency      Currency        `json:"currency"`      // 币种单位
+	IsProfit      bool            `json:"isProfit"`      // 是否盈利
+	StartTime     time.Time       `json:"startTime"`     // 开始时间
+	EndTime       time.Time       `json:"endTime"`       // 结束时间
 }
 
 // AccountSummary 账户汇总信息
@@ -60,16 +75,58 @@ type AccountSummary struct {
 
 // CurrencySettings 币种设置
 type CurrencySettings struct {
-	DefaultCurrency Currency           `json:"defaultCurrency"` // 默认币种
-	ExchangeRates   map[string]float64 `json:"exchangeRates"`   // 汇率信息
-	LastUpdate      time.Time          `json:"lastUpdate"`      // 汇率更新时间
+	DefaultCurrency Currency                 `json:"defaultCurrency"` // 默认币种
+	ExchangeRates   map[string]*ExchangeRate `json:"exchangeRates"`   // 汇率信息
+	LastUpdate      time.Time                `json:"lastUpdate"`      // 汇率更新时间
+}
+
+// ExchangeRate 汇率及其来源，1 Base = Rate Quote
+type ExchangeRate struct {
+	Base      Currency        `json:"base"`      // 基准币种
+	Quote     Currency        `json:"quote"`     // 计价币种
+	Rate      decimal.Decimal `json:"rate"`      // 汇率
+	Path      []string        `json:"path"`      // 换算经过的资产
+	Sources   []string        `json:"sources"`   // 路径上各段报价的来源：okx / http / file / peg
+	UpdatedAt time.Time       `json:"updatedAt"` // 路径上最早的报价时间
+	Live      bool            `json:"live"`      // 路径上的报价是否均为实时数据，使用缓存或本地文件时为false
 }
 
-// SupportedCurrencies 获取支持的币种列表
+// EquitySnapshot 权益快照
+type EquitySnapshot struct {
+	Currency    Currency  `json:"currency"`    // 显示币种
+	TotalEquity string    `json:"totalEquity"` // 总资产
+	RecordedAt  time.Time `json:"recordedAt"`  // 记录时间
+}
+
+// AccountStreamMessage 账户状态推送消息
+type AccountStreamMessage struct {
+	Type      string      `json:"type"`                // snapshot 全量状态 / delta 增量变化
+	Channel   string      `json:"channel,omitempty"`   // OKX私有频道
+	EventType string      `json:"eventType,omitempty"` // OKX推送事件类型
+	Data      interface{} `json:"data"`                // 状态数据或OKX推送的变化数据
+	Timestamp int64       `json:"timestamp"`           // 推送时间（毫秒）
+}
+
+// SupportedCurrencies 获取默认的显示币种列表，未配置 DISPLAY_CURRENCIES 时使用
 func SupportedCurrencies() []Currency {
 	return []Currency{CurrencyCNY, CurrencyUSD, CurrencyUSDT, CurrencyBTC}
 }
 
+// ParseCurrencies 解析币种代码列表，统一转为大写并去重，忽略空项
+func ParseCurrencies(codes []string) []Currency {
+	var currencies []Currency
+	seen := make(map[Currency]bool)
+	for _, code := range codes {
+		currency := Currency(strings.ToUpper(strings.TrimSpace(code)))
+		if currency == "" || seen[currency] {
+			continue
+		}
+		seen[currency] = true
+		currencies = append(currencies, currency)
+	}
+	return currencies
+}
+
 // SupportedPeriods 获取支持的时间周期列表
 func SupportedPeriods() []TimePeriod {
 	return []TimePeriod{Period1Day, Period1Week, Period1Month, Period6Month}
@@ -118,9 +175,29 @@ func (c Currency) GetCurrencySymbol() string {
 		return "₮"
 	case CurrencyBTC:
 		return "₿"
+	case CurrencyEUR:
+		return "€"
+	case CurrencyHKD:
+		return "HK$"
+	case CurrencyETH:
+		return "Ξ"
 	default:
-		return "$"
+		return string(c)
+	}
+}
+
+// IsCashLike 是否为常见法币或稳定币
+func (c Currency) IsCashLike() bool {
+	return cashLikeCurrencies[c]
+}
+
+// Decimals 默认显示精度，法币和稳定币保留2位小数，其他加密资产保留8位
+// 账户服务优先使用交易对信息中的数量精度
+func (c Currency) Decimals() int {
+	if c.IsCashLike() {
+		return 2
 	}
+	return 8
 }
 
 // PositionHistory 历史持仓信息
@@ -165,13 +242,37 @@ type PositionsHistoryRequest struct {
 	After    string `json:"after,omitempty" form:"after"`       // 查询之前的内容
 	Before   string `json:"before,omitempty" form:"before"`     // 查询之后的内容
 	Limit    string `json:"limit,omitempty" form:"limit"`       // 分页数量
+
+	// 游标分页，设置任一字段时忽略 after/before/limit
+	Cursor   string `json:"cursor,omitempty" form:"cursor"`     // 上一页返回的 nextCursor，为空表示第一页
+	PageSize int    `json:"pageSize,omitempty" form:"pageSize"` // 每页数量
+	All      bool   `json:"all,omitempty" form:"all"`           // 是否一次返回时间范围内的全部持仓
+	From     int64  `json:"from,omitempty" form:"from"`         // 更新时间下限（毫秒时间戳，含）
+	To       int64  `json:"to,omitempty" form:"to"`             // 更新时间上限（毫秒时间戳，含）
+}
+
+// Paginated 是否使用游标分页
+func (r *PositionsHistoryRequest) Paginated() bool {
+	return r.Cursor != "" || r.PageSize != 0 || r.All || r.From != 0 || r.To != 0
 }
 
 // PositionsHistoryResponse 历史持仓查询响应
 type PositionsHistoryResponse struct {
-	Positions []*PositionHistory `json:"positions"` // 历史持仓列表
-	HasMore   bool               `json:"hasMore"`   // 是否有更多数据
-	Currency  Currency           `json:"currency"`  // 显示币种
+	Positions  []*PositionHistory       `json:"positions"`            // 历史持仓列表
+	HasMore    bool                     `json:"hasMore"`              // 是否有更多数据
+	NextCursor string                   `json:"nextCursor,omitempty"` // 下一页的游标，游标分页且有更多数据时返回
+	Currency   Currency                 `json:"currency"`             // 显示币种
+	Summary    *PositionsHistorySummary `json:"summary,omitempty"`    // 本页持仓按显示币种汇总
+}
+
+// PositionsHistorySummary 历史持仓汇总，各持仓按保证金币种换算为显示币种后精确累加
+type PositionsHistorySummary struct {
+	RealizedPnl decimal.Decimal `json:"realizedPnl"`           // 已实现收益合计
+	Pnl         decimal.Decimal `json:"pnl"`                   // 平仓收益合计
+	Fee         decimal.Decimal `json:"fee"`                   // 手续费合计
+	FundingFee  decimal.Decimal `json:"fundingFee"`            // 资金费用合计
+	Count       int             `json:"count"`                 // 参与汇总的持仓数
+	Unconverted []string        `json:"unconverted,omitempty"` // 没有汇率、未参与汇总的持仓ID
 }
 
 // Position 当前持仓信息
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Fill 成交明细（字段与OKX /api/v5/trade/fills-history 一致）
+type Fill struct {
+	InstType string `json:"instType"` // 产品类型
+	InstId   string `json:"instId"`   // 产品ID
+	TradeId  string `json:"tradeId"`  // 成交ID，同一产品内唯一
+	OrdId    string `json:"ordId"`    // 订单ID
+	ClOrdId  string `json:"clOrdId"`  // 客户自定义订单ID
+	BillId   string `json:"billId"`   // 账单ID，按时间递增，用于分页
+	Tag      string `json:"tag"`      // 订单标签
+	FillPx   string `json:"fillPx"`   // 成交价格
+	FillSz   string `json:"fillSz"`   // 成交数量
+	FillPnl  string `json:"fillPnl"`  // 成交收益
+	Side     string `json:"side"`     // 订单方向
+	PosSide  string `json:"posSide"`  // 持仓方向
+	ExecType string `json:"execType"` // 流动性方向 T(taker) / M(maker)
+	FeeCcy   string `json:"feeCcy"`   // 手续费币种
+	Fee      string `json:"fee"`      // 手续费，负数表示扣除
+	Ts       string `json:"ts"`       // 成交时间，Unix时间戳(毫秒)
+}
+
+// Bill 账单流水（字段与OKX /api/v5/account/bills-archive 一致）
+type Bill struct {
+	BillId   string `json:"billId"`   // 账单ID
+	InstType string `json:"instType"` // 产品类型
+	InstId   string `json:"instId"`   // 产品ID
+	Ccy      string `json:"ccy"`      // 账户余额币种
+	Type     string `json:"type"`     // 账单类型，如 2 交易、8 资金费
+	SubType  string `json:"subType"`  // 账单子类型
+	BalChg   string `json:"balChg"`   // 账户层面的余额变动数量
+	Bal      string `json:"bal"`      // 账户层面的余额数量
+	Sz       string `json:"sz"`       // 数量
+	Px       string `json:"px"`       // 价格
+	Pnl      string `json:"pnl"`      // 收益
+	Fee      string `json:"fee"`      // 手续费
+	MgnMode  string `json:"mgnMode"`  // 保证金模式
+	OrdId    string `json:"ordId"`    // 订单ID
+	TradeId  string `json:"tradeId"`  // 成交ID
+	Notes    string `json:"notes"`    // 备注
+	Ts       string `json:"ts"`       // 账单创建时间，Unix时间戳(毫秒)
+}
+
+// ArchiveQuery 查询本地归档的条件，结果按时间倒序
+type ArchiveQuery struct {
+	InstType string `form:"instType"` // 产品类型
+	InstId   string `form:"instId"`   // 产品ID
+	Ccy      string `form:"ccy"`      // 币种，只用于账单
+	Type     string `form:"type"`     // 账单类型，只用于账单，如 8 资金费
+	Begin    int64  `form:"begin"`    // 时间下限（含），Unix时间戳(毫秒)
+	End      int64  `form:"end"`      // 时间上限（含），Unix时间戳(毫秒)
+	Limit    int    `form:"limit"`    // 返回数量，默认100，最大1000
+	Offset   int    `form:"offset"`   // 跳过的数量
+}
+
+// ArchiveCheckpoint 归档同步进度，每个数据来源一条
+// 一轮同步从最新的数据向前翻页，直到遇到上一轮已同步的位置；中断后从 Cursor 继续
+type ArchiveCheckpoint struct {
+	Source    string    `json:"source"`           // 数据来源，如 fills:SWAP、bills、positions
+	Floor     string    `json:"floor"`            // 已完整同步到的位置，该位置及更早的数据都已归档
+	Top       string    `json:"top,omitempty"`    // 本轮同步开始时最新数据的位置，本轮完成后成为 Floor
+	Cursor    string    `json:"cursor,omitempty"` // 本轮同步已翻到的位置，为空表示没有进行中的同步
+	Synced    int64     `json:"synced"`           // 累计写入的记录数，重复同步的记录也计入
+	LastError string    `json:"lastError,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
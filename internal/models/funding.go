@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+)
+
+// BillTypeFunding 资金费结算的账单类型
+const BillTypeFunding = "8"
+
+// FundingRate 永续合约当前和预测资金费率（字段与OKX /api/v5/public/funding-rate 一致）
+type FundingRate struct {
+	InstId          string `json:"instId"`          // 产品ID
+	FundingRate     string `json:"fundingRate"`     // 当前资金费率，下次结算按此费率收付
+	NextFundingRate string `json:"nextFundingRate"` // 预测的下一期资金费率，部分合约不提供
+	FundingTime     string `json:"fundingTime"`     // 下次结算时间，Unix时间戳(毫秒)
+	NextFundingTime string `json:"nextFundingTime"` // 下下次结算时间，Unix时间戳(毫秒)
+}
+
+// FundingRateRecord 历史资金费率（字段与OKX /api/v5/public/funding-rate-history 一致）
+type FundingRateRecord struct {
+	FundingRate  string `json:"fundingRate"`  // 预计资金费率
+	RealizedRate string `json:"realizedRate"` // 实际收付的资金费率
+	FundingTime  string `json:"fundingTime"`  // 结算时间，Unix时间戳(毫秒)
+}
+
+// FundingSettlement 一次资金费结算，来自账单流水中类型为资金费的记录
+type FundingSettlement struct {
+	BillId  string          `json:"billId"`  // 账单ID
+	InstId  string          `json:"instId"`  // 产品ID
+	MgnMode string          `json:"mgnMode"` // 保证金模式
+	Ccy     string          `json:"ccy"`     // 结算币种
+	Amount  decimal.Decimal `json:"amount"`  // 结算金额，正数为收取，负数为支付
+	Ts      string          `json:"ts"`      // 结算时间，Unix时间戳(毫秒)
+}
+
+// FundingRequest 资金费查询参数
+type FundingRequest struct {
+	InstId       string `form:"instId"`       // 产品ID，为空时返回全部永续合约持仓
+	HistoryLimit *int   `form:"historyLimit"` // 每个合约返回的历史费率条数，默认24，最大100，0表示不返回
+}
+
+// PositionFunding 单个永续合约持仓的资金费
+// 金额均为合约结算币种，正数为收取，负数为支付；年化成本正数为支付
+type PositionFunding struct {
+	InstId          string               `json:"instId"`          // 产品ID
+	PosId           string               `json:"posId"`           // 持仓ID
+	MgnMode         string               `json:"mgnMode"`         // 保证金模式
+	PosSide         string               `json:"posSide"`         // 持仓方向
+	Pos             string               `json:"pos"`             // 持仓数量（张）
+	MarkPx          string               `json:"markPx"`          // 标记价格
+	SettleCcy       string               `json:"settleCcy"`       // 结算币种
+	Notional        decimal.Decimal      `json:"notional"`        // 按标记价格计算的持仓价值
+	FundingFee      string               `json:"fundingFee"`      // OKX统计的累计资金费用
+	Accrued         decimal.Decimal      `json:"accrued"`         // 开仓以来资金费结算合计
+	Settlements     []*FundingSettlement `json:"settlements"`     // 开仓以来的资金费结算，从新到旧
+	FundingRate     string               `json:"fundingRate"`     // 当前资金费率
+	NextFundingRate string               `json:"nextFundingRate"` // 预测的下一期资金费率
+	FundingTime     time.Time            `json:"fundingTime"`     // 下次结算时间
+	FundingInterval int64                `json:"fundingInterval"` // 结算周期（毫秒）
+	ExpectedPayment decimal.Decimal      `json:"expectedPayment"` // 按当前持仓和费率预计下次结算的金额
+	AnnualizedRate  decimal.Decimal      `json:"annualizedRate"`  // 当前费率按结算周期换算的年化费率
+	AnnualizedCost  decimal.Decimal      `json:"annualizedCost"`  // 按当前持仓和费率估算的年化资金费成本
+	RateHistory     []FundingRateRecord  `json:"rateHistory"`     // 历史资金费率，从新到旧
+}
+
+// FundingSummary 按显示币种汇总的资金费
+type FundingSummary struct {
+	Accrued         decimal.Decimal `json:"accrued"`               // 开仓以来资金费结算合计
+	ExpectedPayment decimal.Decimal `json:"expectedPayment"`       // 下次结算预计金额合计
+	AnnualizedCost  decimal.Decimal `json:"annualizedCost"`        // 年化资金费成本合计
+	Unconverted     []string        `json:"unconverted,omitempty"` // 没有汇率、未参与汇总的持仓ID
+}
+
+// FundingResponse 资金费响应
+type FundingResponse struct {
+	Positions []*PositionFunding `json:"positions"`
+	Currency  Currency           `json:"currency"`
+	Summary   *FundingSummary    `json:"summary"`
+}
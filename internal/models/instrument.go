@@ -0,0 +1,60 @@
+package models
+
+// Instrument 交易对信息（字段与OKX /api/v5/public/instruments 一致）
+type Instrument struct {
+	InstType   string `json:"instType"`   // 产品类型
+	InstID     string `json:"instId"`     // 产品ID
+	InstFamily string `json:"instFamily"` // 交易品种（交割/永续/期权）
+	BaseCcy    string `json:"baseCcy"`    // 交易货币（币币）
+	QuoteCcy   string `json:"quoteCcy"`   // 计价货币（币币）
+	SettleCcy  string `json:"settleCcy"`  // 盈亏结算和保证金币种
+	CtVal      string `json:"ctVal"`      // 合约面值
+	CtMult     string `json:"ctMult"`     // 合约乘数
+	CtValCcy   string `json:"ctValCcy"`   // 合约面值计价币种
+	CtType     string `json:"ctType"`     // 合约类型 linear 正向 / inverse 反向
+	OptType    string `json:"optType"`    // 期权类型 C/P
+	Stk        string `json:"stk"`        // 行权价格
+	ListTime   string `json:"listTime"`   // 上线时间（毫秒）
+	ExpTime    string `json:"expTime"`    // 交割/行权日期（毫秒）
+	TickSz     string `json:"tickSz"`     // 下单价格精度
+	LotSz      string `json:"lotSz"`      // 下单数量精度
+	MinSz      string `json:"minSz"`      // 最小下单数量
+	MaxSz      string `json:"maxSz"`      // 最大下单数量
+	Uly        string `json:"uly"`        // 标的指数
+	Category   string `json:"category"`   // 手续费档位
+	State      string `json:"state"`      // 产品状态 live/suspend/preopen/test
+}
+
+// 交易对变化类型
+const (
+	InstrumentListed   = "listed"   // 新上线
+	InstrumentDelisted = "delisted" // 下线
+	InstrumentExpired  = "expired"  // 到期交割/行权
+	InstrumentState    = "state"    // 状态变化
+)
+
+// InstrumentChange 交易对变化记录
+type InstrumentChange struct {
+	Kind       string `json:"kind"`                // 变化类型 listed/delisted/expired/state
+	InstType   string `json:"instType"`            // 产品类型
+	InstId     string `json:"instId"`              // 产品ID
+	State      string `json:"state"`               // 当前状态
+	PrevState  string `json:"prevState,omitempty"` // 之前的状态
+	ExpTime    string `json:"expTime,omitempty"`   // 交割/行权日期（毫秒）
+	DetectedAt int64  `json:"detectedAt"`          // 发现变化的时间（毫秒）
+}
+
+// InstrumentSearchRequest 交易对搜索请求
+type InstrumentSearchRequest struct {
+	Query string `form:"q"`     // 关键字，匹配产品ID、币种和交易品种，不区分大小写
+	Type  string `form:"type"`  // 产品类型
+	State string `form:"state"` // 产品状态
+	Limit int    `form:"limit"` // 返回数量
+}
+
+// InstrumentSearchResponse 交易对搜索结果
+type InstrumentSearchResponse struct {
+	Instruments []Instrument `json:"instruments"` // 匹配的交易对，完全匹配和前缀匹配优先
+	Total       int          `json:"total"`       // 匹配总数
+	UpdatedAt   int64        `json:"updatedAt"`   // 交易对列表最近刷新时间（毫秒）
+}
@@ -0,0 +1,106 @@
+package models
+
+import "github.com/cardchoosen/AlphaArk_Gin/pkg/indicator"
+
+// Candle K线
+type Candle struct {
+	Ts          int64  `json:"ts"`          // 开始时间（毫秒）
+	Open        string `json:"open"`        // 开盘价
+	High        string `json:"high"`        // 最高价
+	Low         string `json:"low"`         // 最低价
+	Close       string `json:"close"`       // 收盘价
+	Vol         string `json:"vol"`         // 交易量（张或交易货币）
+	VolCcy      string `json:"volCcy"`      // 交易量（币）
+	VolCcyQuote string `json:"volCcyQuote"` // 交易量（计价货币）
+	Confirm     bool   `json:"confirm"`     // 是否已完结
+}
+
+// CandleGap K线缺口，[Start, End) 区间内没有K线
+type CandleGap struct {
+	Start   int64 `json:"start"`   // 第一根缺失K线的开始时间（毫秒）
+	End     int64 `json:"end"`     // 缺口之后第一根K线的开始时间（毫秒）
+	Missing int   `json:"missing"` // 缺失的K线数量
+}
+
+// CandlesRequest K线查询请求
+type CandlesRequest struct {
+	InstId string `form:"-"`      // 产品ID
+	Bar    string `form:"bar"`    // K线周期，如 1m、5m、1H、1D
+	After  int64  `form:"after"`  // 返回早于该时间戳的K线（毫秒），0表示从最新开始
+	Before int64  `form:"before"` // 返回晚于该时间戳的K线（毫秒），0表示不限制
+	Limit  int    `form:"limit"`  // 返回数量
+}
+
+// CandlesResponse K线查询结果
+type CandlesResponse struct {
+	InstId     string      `json:"instId"`     // 产品ID
+	Bar        string      `json:"bar"`        // K线周期
+	Candles    []*Candle   `json:"candles"`    // K线列表，按时间升序
+	Gaps       []CandleGap `json:"gaps"`       // 区间内的K线缺口
+	Aggregated bool        `json:"aggregated"` // 是否由更小周期的K线聚合而成
+	SourceBar  string      `json:"sourceBar"`  // 数据来源的OKX K线周期
+}
+
+// IndicatorsRequest 技术指标查询请求，未指定的指标参数使用默认值
+type IndicatorsRequest struct {
+	InstId   string `form:"-"`        // 产品ID
+	Bar      string `form:"bar"`      // K线周期
+	Lookback int    `form:"lookback"` // 返回最近多少根已完结K线的指标值
+	indicator.Params
+}
+
+// IndicatorsResponse 技术指标查询结果
+type IndicatorsResponse struct {
+	InstId string               `json:"instId"` // 产品ID
+	Bar    string               `json:"bar"`    // K线周期
+	Params indicator.Params     `json:"params"` // 指标参数
+	Values []indicator.Snapshot `json:"values"` // 每根K线完结时的指标值，按时间升序
+}
+
+// OrderBookRequest 订单簿查询请求
+type OrderBookRequest struct {
+	InstId string    `form:"-"`     // 产品ID
+	Depth  int       `form:"depth"` // 每侧返回的档位数量（聚合后）
+	Step   int       `form:"step"`  // 价格聚合步长，为下单价格精度 TickSz 的倍数，1表示不聚合
+	Bps    []float64 `form:"bps"`   // 统计深度的价格范围（相对中间价的基点），可重复
+}
+
+// OrderBookLevel 订单簿档位
+type OrderBookLevel struct {
+	Px     string `json:"px"`     // 价格，聚合后为档位边界价格
+	Sz     string `json:"sz"`     // 数量
+	Orders int    `json:"orders"` // 订单数量
+}
+
+// OrderBookDepth 中间价上下一定范围内的挂单量
+type OrderBookDepth struct {
+	Bps         float64 `json:"bps"`         // 相对中间价的范围（基点）
+	BidSz       string  `json:"bidSz"`       // 范围内买单数量
+	AskSz       string  `json:"askSz"`       // 范围内卖单数量
+	BidNotional string  `json:"bidNotional"` // 范围内买单金额（价格 × 数量）
+	AskNotional string  `json:"askNotional"` // 范围内卖单金额（价格 × 数量）
+}
+
+// OrderBookMetrics 订单簿衍生指标，基于完整订单簿计算
+type OrderBookMetrics struct {
+	BestBid   string           `json:"bestBid"`   // 买一价
+	BestAsk   string           `json:"bestAsk"`   // 卖一价
+	Spread    string           `json:"spread"`    // 买卖价差
+	SpreadBps float64          `json:"spreadBps"` // 价差相对中间价（基点）
+	Mid       string           `json:"mid"`       // 中间价
+	Imbalance float64          `json:"imbalance"` // 返回档位内的买卖量失衡 (买量-卖量)/(买量+卖量)，取值 -1 到 1
+	Depth     []OrderBookDepth `json:"depth"`     // 各价格范围内的挂单量
+}
+
+// OrderBookResponse 订单簿查询结果
+type OrderBookResponse struct {
+	InstId  string            `json:"instId"`  // 产品ID
+	Ts      string            `json:"ts"`      // 订单簿更新时间（毫秒）
+	SeqId   int64             `json:"seqId"`   // 推送序列号，REST快照为0
+	Source  string            `json:"source"`  // 数据来源：websocket 本地订单簿，rest 接口快照
+	TickSz  string            `json:"tickSz"`  // 下单价格精度
+	Step    string            `json:"step"`    // 聚合后的档位价格间隔
+	Asks    []*OrderBookLevel `json:"asks"`    // 卖单，价格升序
+	Bids    []*OrderBookLevel `json:"bids"`    // 买单，价格降序
+	Metrics *OrderBookMetrics `json:"metrics"` // 衍生指标，任一侧为空时为null
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// OKXAccount 用户添加的OKX账户
+type OKXAccount struct {
+	ID         int64     `json:"id"`         // 账户ID，请求时通过 X-Account-Id 或路径参数选择
+	UserID     int64     `json:"userId"`     // 所属用户
+	Name       string    `json:"name"`       // 账户名称，同一用户下唯一
+	APIKeyHint string    `json:"apiKeyHint"` // 脱敏后的API Key
+	IsTest     bool      `json:"isTest"`     // 是否为模拟盘账户
+	CreatedAt  time.Time `json:"createdAt"`  // 创建时间
+
+	APIKey     string `json:"-"` // 加密后的API Key
+	SecretKey  string `json:"-"` // 加密后的Secret Key
+	Passphrase string `json:"-"` // 加密后的Passphrase
+}
+
+// CreateOKXAccountRequest 添加OKX账户请求
+type CreateOKXAccountRequest struct {
+	Name       string `json:"name" binding:"required"`       // 账户名称
+	APIKey     string `json:"apiKey" binding:"required"`     // API Key
+	SecretKey  string `json:"secretKey" binding:"required"`  // Secret Key
+	Passphrase string `json:"passphrase" binding:"required"` // Passphrase
+	IsTest     bool   `json:"isTest"`                        // 是否为模拟盘账户
+}
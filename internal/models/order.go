@@ -0,0 +1,102 @@
+package models
+
+// OrderRequest 下单请求（字段与OKX /api/v5/trade/order 一致）
+type OrderRequest struct {
+	InstId     string `json:"instId"`               // 产品ID
+	TdMode     string `json:"tdMode"`               // 交易模式 cash/cross/isolated
+	Ccy        string `json:"ccy,omitempty"`        // 保证金币种
+	ClOrdId    string `json:"clOrdId,omitempty"`    // 客户自定义订单ID
+	Tag        string `json:"tag,omitempty"`        // 订单标签
+	Side       string `json:"side"`                 // 订单方向 buy/sell
+	PosSide    string `json:"posSide,omitempty"`    // 持仓方向
+	OrdType    string `json:"ordType"`              // 订单类型
+	Sz         string `json:"sz"`                   // 委托数量
+	Px         string `json:"px,omitempty"`         // 委托价格
+	ReduceOnly bool   `json:"reduceOnly,omitempty"` // 是否只减仓
+	TgtCcy     string `json:"tgtCcy,omitempty"`     // 市价单委托数量的类型 base_ccy/quote_ccy
+}
+
+// AmendOrderRequest 修改订单请求
+type AmendOrderRequest struct {
+	InstId    string `json:"instId"`              // 产品ID
+	OrdId     string `json:"ordId,omitempty"`     // 订单ID
+	ClOrdId   string `json:"clOrdId,omitempty"`   // 客户自定义订单ID
+	CxlOnFail bool   `json:"cxlOnFail,omitempty"` // 修改失败时是否自动撤单
+	ReqId     string `json:"reqId,omitempty"`     // 用户自定义修改事件ID
+	NewSz     string `json:"newSz,omitempty"`     // 修改后的数量
+	NewPx     string `json:"newPx,omitempty"`     // 修改后的价格
+}
+
+// CancelOrderRequest 撤单请求
+type CancelOrderRequest struct {
+	InstId  string `json:"instId"`            // 产品ID
+	OrdId   string `json:"ordId,omitempty"`   // 订单ID
+	ClOrdId string `json:"clOrdId,omitempty"` // 客户自定义订单ID
+}
+
+// OrderResult 下单/撤单/改单结果
+type OrderResult struct {
+	OrdId   string `json:"ordId"`           // 订单ID
+	ClOrdId string `json:"clOrdId"`         // 客户自定义订单ID
+	Tag     string `json:"tag,omitempty"`   // 订单标签
+	ReqId   string `json:"reqId,omitempty"` // 用户自定义修改事件ID
+	Ts      string `json:"ts,omitempty"`    // 系统完成处理的时间戳
+	SCode   string `json:"sCode"`           // 事件执行结果的code，0代表成功
+	SMsg    string `json:"sMsg"`            // 事件执行失败时的msg
+}
+
+// OrderResultResponse OKX下单/撤单/改单响应
+type OrderResultResponse struct {
+	Code string        `json:"code"`
+	Msg  string        `json:"msg"`
+	Data []OrderResult `json:"data"`
+}
+
+// Order 订单信息
+type Order struct {
+	InstType   string `json:"instType"`   // 产品类型
+	InstId     string `json:"instId"`     // 产品ID
+	OrdId      string `json:"ordId"`      // 订单ID
+	ClOrdId    string `json:"clOrdId"`    // 客户自定义订单ID
+	Tag        string `json:"tag"`        // 订单标签
+	Px         string `json:"px"`         // 委托价格
+	Sz         string `json:"sz"`         // 委托数量
+	OrdType    string `json:"ordType"`    // 订单类型
+	Side       string `json:"side"`       // 订单方向
+	PosSide    string `json:"posSide"`    // 持仓方向
+	TdMode     string `json:"tdMode"`     // 交易模式
+	TgtCcy     string `json:"tgtCcy"`     // 市价单委托数量的类型
+	AccFillSz  string `json:"accFillSz"`  // 累计成交数量
+	FillPx     string `json:"fillPx"`     // 最新成交价格
+	TradeId    string `json:"tradeId"`    // 最新成交ID
+	FillSz     string `json:"fillSz"`     // 最新成交数量
+	FillTime   string `json:"fillTime"`   // 最新成交时间
+	AvgPx      string `json:"avgPx"`      // 成交均价
+	State      string `json:"state"`      // 订单状态
+	Lever      string `json:"lever"`      // 杠杆倍数
+	Fee        string `json:"fee"`        // 手续费
+	FeeCcy     string `json:"feeCcy"`     // 手续费币种
+	Pnl        string `json:"pnl"`        // 收益
+	Category   string `json:"category"`   // 订单种类
+	ReduceOnly string `json:"reduceOnly"` // 是否只减仓
+	CTime      string `json:"cTime"`      // 订单创建时间
+	UTime      string `json:"uTime"`      // 订单更新时间
+}
+
+// OrdersResponse OKX订单列表响应
+type OrdersResponse struct {
+	Code string  `json:"code"`
+	Msg  string  `json:"msg"`
+	Data []Order `json:"data"`
+}
+
+// OrdersRequest 订单列表查询请求
+type OrdersRequest struct {
+	InstType string `json:"instType,omitempty" form:"instType"` // 产品类型
+	InstId   string `json:"instId,omitempty" form:"instId"`     // 产品ID
+	OrdType  string `json:"ordType,omitempty" form:"ordType"`   // 订单类型
+	State    string `json:"state,omitempty" form:"state"`       // 订单状态
+	After    string `json:"after,omitempty" form:"after"`       // 查询之前的内容
+	Before   string `json:"before,omitempty" form:"before"`     // 查询之后的内容
+	Limit    string `json:"limit,omitempty" form:"limit"`       // 分页数量
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// RiskLimits 风控限额，数值为0表示不限制
+type RiskLimits struct {
+	MaxNotionalPerInstrument float64 `json:"maxNotionalPerInstrument"` // 单个产品最大名义价值（USD）
+	MaxLeverage              float64 `json:"maxLeverage"`              // 最大杠杆倍数
+	MaxOpenOrders            int     `json:"maxOpenOrders"`            // 最大挂单数量
+	DailyLossLimit           float64 `json:"dailyLossLimit"`           // 当日最大已实现亏损（USD）
+}
+
+// RiskViolation 风控拒单原因
+type RiskViolation struct {
+	Rule    string `json:"rule"`             // 触发的规则
+	Message string `json:"message"`          // 说明
+	InstId  string `json:"instId,omitempty"` // 相关产品
+	Limit   string `json:"limit,omitempty"`  // 限额
+	Actual  string `json:"actual,omitempty"` // 实际值
+}
+
+// RiskCheckResult 风控检查结果
+type RiskCheckResult struct {
+	Passed     bool            `json:"passed"`     // 是否通过
+	Violations []RiskViolation `json:"violations"` // 拒单原因
+}
+
+// RiskStatus 风控状态
+type RiskStatus struct {
+	KillSwitch       bool       `json:"killSwitch"`                 // 是否已熔断
+	KillSwitchReason string     `json:"killSwitchReason,omitempty"` // 熔断原因
+	KillSwitchTime   *time.Time `json:"killSwitchTime,omitempty"`   // 熔断时间
+	Limits           RiskLimits `json:"limits"`                     // 当前限额
+}
+
+// 风控规则标识
+const (
+	RiskRuleKillSwitch       = "kill_switch"
+	RiskRuleMaxNotional      = "max_notional_per_instrument"
+	RiskRuleMaxLeverage      = "max_leverage"
+	RiskRuleMaxOpenOrders    = "max_open_orders"
+	RiskRuleDailyLossLimit   = "daily_loss_limit"
+	RiskRuleStateUnavailable = "state_unavailable"
+)
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// UserSettings 用户个人设置，每个用户独立保存，互不影响
+type UserSettings struct {
+	UserID              int64           `json:"userId"`              // 所属用户
+	DefaultCurrency     Currency        `json:"defaultCurrency"`     // 默认显示币种
+	FavoriteInstruments []string        `json:"favoriteInstruments"` // 收藏的交易对
+	DashboardLayout     json.RawMessage `json:"dashboardLayout"`     // 仪表盘布局，由前端定义的JSON对象
+	AlertPreferences    json.RawMessage `json:"alertPreferences"`    // 提醒偏好，由前端定义的JSON对象
+	UpdatedAt           time.Time       `json:"updatedAt"`           // 最后修改时间，未保存过时为零值
+}
+
+// UpdateSettingsRequest 修改用户设置请求，只修改请求中出现的字段
+type UpdateSettingsRequest struct {
+	DefaultCurrency     *string         `json:"defaultCurrency"`     // 默认显示币种，必须是支持的显示币种
+	FavoriteInstruments *[]string       `json:"favoriteInstruments"` // 收藏的交易对，整体替换
+	DashboardLayout     json.RawMessage `json:"dashboardLayout"`     // 仪表盘布局，整体替换
+	AlertPreferences    json.RawMessage `json:"alertPreferences"`    // 提醒偏好，整体替换
+}
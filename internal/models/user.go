@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Role 用户角色，高级角色拥有低级角色的全部权限
+type Role string
+
+// 用户角色，权限依次递增
+const (
+	RoleViewer   Role = "viewer"   // 查看余额、持仓和行情
+	RoleOperator Role = "operator" // 管理OKX账户、交易
+	RoleAdmin    Role = "admin"    // 查看OKX配置、调整风控、管理用户
+)
+
+// roleLevels 角色权限等级
+var roleLevels = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Valid 是否为已知角色
+func (r Role) Valid() bool {
+	return roleLevels[r] > 0
+}
+
+// Allows 是否拥有 required 角色的权限，未知角色没有任何权限
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && roleLevels[r] >= roleLevels[required]
+}
+
+// User 用户
+type User struct {
+	ID           int64     `json:"id"`        // 用户ID
+	Username     string    `json:"username"`  // 用户名
+	Role         Role      `json:"role"`      // 角色
+	PasswordHash string    `json:"-"`         // bcrypt密码哈希
+	CreatedAt    time.Time `json:"createdAt"` // 创建时间
+}
+
+// CreateUserRequest 创建用户请求
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required"` // 用户名
+	Password string `json:"password" binding:"required"` // 密码，至少8位
+	Role     Role   `json:"role" binding:"required"`     // 角色：viewer / operator / admin
+}
+
+// UpdateUserRoleRequest 修改用户角色请求
+type UpdateUserRoleRequest struct {
+	Role Role `json:"role" binding:"required"` // 角色：viewer / operator / admin
+}
+
+// LoginRequest 登录请求
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"` // 用户名
+	Password string `json:"password" binding:"required"` // 密码
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"` // 刷新令牌
+}
+
+// LogoutRequest 注销请求
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"` // 同时注销的刷新令牌，可为空
+}
+
+// TokenResponse 登录和刷新令牌的响应
+type TokenResponse struct {
+	AccessToken      string    `json:"accessToken"`      // 访问令牌，请求时放在 Authorization: Bearer <token>
+	RefreshToken     string    `json:"refreshToken"`     // 刷新令牌，每次刷新后失效
+	TokenType        string    `json:"tokenType"`        // 固定为 Bearer
+	ExpiresAt        time.Time `json:"expiresAt"`        // 访问令牌过期时间
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"` // 刷新令牌过期时间
+	User             *User     `json:"user"`             // 当前用户
+}
@@ -0,0 +1,12 @@
+package models
+
+// WebSocketMetrics WebSocket推送统计
+type WebSocketMetrics struct {
+	Endpoint                string `json:"endpoint"`                // 推送端点
+	Connections             int64  `json:"connections"`             // 当前连接数
+	TotalConnections        uint64 `json:"totalConnections"`        // 累计连接数
+	MessagesSent            uint64 `json:"messagesSent"`            // 已发送消息数
+	MessagesDropped         uint64 `json:"messagesDropped"`         // 因发送队列已满丢弃的消息数
+	SlowConsumerDisconnects uint64 `json:"slowConsumerDisconnects"` // 因消费过慢被断开的连接数
+	PongTimeouts            uint64 `json:"pongTimeouts"`            // 心跳超时断开的连接数
+}
@@ -0,0 +1,112 @@
+package okx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrorKind OKX错误分类
+type ErrorKind string
+
+// 已知错误分类
+const (
+	ErrorKindUnknown             ErrorKind = "unknown"
+	ErrorKindTimestamp           ErrorKind = "timestamp"            // 时间戳过期或无效，需重新同步服务器时间
+	ErrorKindRateLimit           ErrorKind = "rate_limit"           // 请求过于频繁
+	ErrorKindAuth                ErrorKind = "auth"                 // API Key、签名、口令、IP白名单或权限错误
+	ErrorKindInsufficientBalance ErrorKind = "insufficient_balance" // 余额或保证金不足
+)
+
+// codeKinds 已知错误码分类，参考 https://www.okx.com/docs-v5/zh/#error-code
+var codeKinds = map[string]ErrorKind{
+	"50102": ErrorKindTimestamp, // Timestamp request expired
+	"50107": ErrorKindTimestamp, // OK-ACCESS-TIMESTAMP 不能为空
+	"50112": ErrorKindTimestamp, // Invalid OK-ACCESS-TIMESTAMP
+
+	"50011": ErrorKindRateLimit, // 用户请求频率过快
+	"50061": ErrorKindRateLimit, // 子账户请求频率过快
+
+	"50100": ErrorKindAuth, // API已被冻结
+	"50101": ErrorKindAuth, // APIKey与当前环境不匹配（实盘/模拟盘）
+	"50103": ErrorKindAuth, // OK-ACCESS-KEY 不能为空
+	"50104": ErrorKindAuth, // OK-ACCESS-PASSPHRASE 不能为空
+	"50105": ErrorKindAuth, // OK-ACCESS-PASSPHRASE 错误
+	"50106": ErrorKindAuth, // OK-ACCESS-SIGN 不能为空
+	"50110": ErrorKindAuth, // IP不在白名单内
+	"50111": ErrorKindAuth, // 无效的OK-ACCESS-KEY
+	"50113": ErrorKindAuth, // 无效的签名
+	"50114": ErrorKindAuth, // 无效的授权
+	"50120": ErrorKindAuth, // API Key没有该接口权限
+
+	"51008": ErrorKindInsufficientBalance, // 余额不足
+	"51119": ErrorKindInsufficientBalance, // 保证金不足
+	"51131": ErrorKindInsufficientBalance, // 余额不足
+	"51502": ErrorKindInsufficientBalance, // 改单后余额不足
+	"58350": ErrorKindInsufficientBalance, // 资金账户余额不足
+}
+
+// ClassifyCode 对OKX错误码分类，也适用于批量操作中逐条返回的sCode
+func ClassifyCode(code string) ErrorKind {
+	if kind, exists := codeKinds[code]; exists {
+		return kind
+	}
+	return ErrorKindUnknown
+}
+
+// OKXError OKX接口返回的业务错误（code不为"0"或HTTP状态码非2xx）
+type OKXError struct {
+	Code       string          `json:"code"`
+	Msg        string          `json:"msg"`
+	HTTPStatus int             `json:"httpStatus"`
+	Data       json.RawMessage `json:"-"` // 原始data字段，批量操作失败时包含逐条结果
+}
+
+// Error 实现error接口
+func (e *OKXError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("OKX API错误: HTTP %d %s", e.HTTPStatus, e.Msg)
+	}
+	return fmt.Sprintf("OKX API错误[%s]: %s", e.Code, e.Msg)
+}
+
+// Kind 错误分类，HTTP 429 视为限频
+func (e *OKXError) Kind() ErrorKind {
+	if kind := ClassifyCode(e.Code); kind != ErrorKindUnknown {
+		return kind
+	}
+	if e.HTTPStatus == http.StatusTooManyRequests {
+		return ErrorKindRateLimit
+	}
+	return ErrorKindUnknown
+}
+
+// ErrorKindOf 获取错误链中OKXError的分类，非OKX错误返回ErrorKindUnknown
+func ErrorKindOf(err error) ErrorKind {
+	var okxErr *OKXError
+	if errors.As(err, &okxErr) {
+		return okxErr.Kind()
+	}
+	return ErrorKindUnknown
+}
+
+// IsTimestampError 是否为时间戳错误
+func IsTimestampError(err error) bool {
+	return ErrorKindOf(err) == ErrorKindTimestamp
+}
+
+// IsRateLimited 是否触发限频
+func IsRateLimited(err error) bool {
+	return ErrorKindOf(err) == ErrorKindRateLimit
+}
+
+// IsAuthError 是否为鉴权错误
+func IsAuthError(err error) bool {
+	return ErrorKindOf(err) == ErrorKindAuth
+}
+
+// IsInsufficientBalance 是否为余额不足
+func IsInsufficientBalance(err error) bool {
+	return ErrorKindOf(err) == ErrorKindInsufficientBalance
+}
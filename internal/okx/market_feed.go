@@ -0,0 +1,252 @@
+package okx
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// 默认公共WebSocket地址，K线频道位于business地址
+const (
+	DefaultWSPublicURL   = "wss://ws.okx.com:8443/ws/v5/public"
+	DefaultWSBusinessURL = "wss://ws.okx.com:8443/ws/v5/business"
+)
+
+// Ticker OKX行情数据结构
+type Ticker struct {
+	InstType  string `json:"instType"`
+	InstId    string `json:"instId"`
+	Last      string `json:"last"`
+	LastSz    string `json:"lastSz"`
+	AskPx     string `json:"askPx"`
+	AskSz     string `json:"askSz"`
+	BidPx     string `json:"bidPx"`
+	BidSz     string `json:"bidSz"`
+	Open24h   string `json:"open24h"`
+	High24h   string `json:"high24h"`
+	Low24h    string `json:"low24h"`
+	Vol24h    string `json:"vol24h"`
+	VolCcy24h string `json:"volCcy24h"`
+	SodUtc0   string `json:"sodUtc0"`
+	SodUtc8   string `json:"sodUtc8"`
+	Ts        string `json:"ts"`
+}
+
+// Trade 成交数据
+type Trade struct {
+	InstId  string `json:"instId"`
+	TradeId string `json:"tradeId"`
+	Px      string `json:"px"`
+	Sz      string `json:"sz"`
+	Side    string `json:"side"`
+	Ts      string `json:"ts"`
+	Count   string `json:"count,omitempty"`
+}
+
+// Candle K线数据
+type Candle struct {
+	InstId      string `json:"instId"`
+	Bar         string `json:"bar"`
+	Ts          string `json:"ts"`
+	Open        string `json:"open"`
+	High        string `json:"high"`
+	Low         string `json:"low"`
+	Close       string `json:"close"`
+	Vol         string `json:"vol"`
+	VolCcy      string `json:"volCcy"`
+	VolCcyQuote string `json:"volCcyQuote"`
+	Confirm     bool   `json:"confirm"`
+}
+
+// bookChannels 支持的订单簿频道，books为增量频道，其余为全量推送
+var bookChannels = map[string]bool{
+	"books":   true,
+	"books5":  true,
+	"bbo-tbt": true,
+}
+
+// MarketFeed OKX公共行情订阅，无需登录
+type MarketFeed struct {
+	public   *WSClient
+	business *WSClient
+
+	mutex sync.Mutex
+	books map[WSArg]*OrderBook
+}
+
+// NewMarketFeed 创建公共行情订阅
+func NewMarketFeed(publicURL, businessURL string) *MarketFeed {
+	if publicURL == "" {
+		publicURL = DefaultWSPublicURL
+	}
+	if businessURL == "" {
+		businessURL = DefaultWSBusinessURL
+	}
+
+	feed := &MarketFeed{
+		public:   NewWSClient(publicURL),
+		business: NewWSClient(businessURL),
+		books:    make(map[WSArg]*OrderBook),
+	}
+	// 断线后本地订单簿失效，重新订阅后等待新快照
+	feed.public.OnDisconnect = feed.resetBooks
+	return feed
+}
+
+// Public 公共频道连接
+func (f *MarketFeed) Public() *WSClient {
+	return f.public
+}
+
+// Business 业务频道连接（K线）
+func (f *MarketFeed) Business() *WSClient {
+	return f.business
+}
+
+// Stop 关闭全部连接
+func (f *MarketFeed) Stop() {
+	f.public.Stop()
+	f.business.Stop()
+}
+
+// SubscribeTickers 订阅行情频道
+func (f *MarketFeed) SubscribeTickers(instId string, handler func(*Ticker)) error {
+	return f.subscribe(f.public, WSArg{Channel: "tickers", InstId: instId}, func(push *WSPush) {
+		var tickers []Ticker
+		if err := json.Unmarshal(push.Data, &tickers); err != nil {
+			log.Printf("解析行情推送失败: %v", err)
+			return
+		}
+		for i := range tickers {
+			handler(&tickers[i])
+		}
+	})
+}
+
+// SubscribeTrades 订阅成交频道
+func (f *MarketFeed) SubscribeTrades(instId string, handler func([]Trade)) error {
+	return f.subscribe(f.public, WSArg{Channel: "trades", InstId: instId}, func(push *WSPush) {
+		var trades []Trade
+		if err := json.Unmarshal(push.Data, &trades); err != nil {
+			log.Printf("解析成交推送失败: %v", err)
+			return
+		}
+		handler(trades)
+	})
+}
+
+// SubscribeBooks 订阅订单簿频道，推送经过序列号和校验和验证后的本地订单簿快照
+func (f *MarketFeed) SubscribeBooks(channel, instId string, handler func(*OrderBookSnapshot)) error {
+	if !bookChannels[channel] {
+		return fmt.Errorf("不支持的订单簿频道: %s", channel)
+	}
+
+	arg := WSArg{Channel: channel, InstId: instId}
+	book := NewOrderBook(instId)
+
+	f.mutex.Lock()
+	f.books[arg] = book
+	f.mutex.Unlock()
+
+	return f.subscribe(f.public, arg, func(push *WSPush) {
+		var data []BookData
+		if err := json.Unmarshal(push.Data, &data); err != nil {
+			log.Printf("解析订单簿推送失败: %v", err)
+			return
+		}
+
+		f.mutex.Lock()
+		for i := range data {
+			if err := book.Apply(push.Action, &data[i]); err != nil {
+				book.Reset()
+				f.mutex.Unlock()
+
+				log.Printf("%v，等待新快照", err)
+				if push.Action != "" {
+					// 增量频道需要重新订阅才能拿到新快照；全量频道等待下一次推送即可
+					if err := f.public.Resubscribe(arg); err != nil {
+						log.Printf("重新订阅订单簿失败: %v", err)
+					}
+				}
+				return
+			}
+		}
+		snapshot := book.Snapshot(0)
+		f.mutex.Unlock()
+
+		handler(snapshot)
+	})
+}
+
+// SubscribeCandles 订阅K线频道，bar如 1m、5m、1H、1D
+func (f *MarketFeed) SubscribeCandles(bar, instId string, handler func([]Candle)) error {
+	return f.subscribe(f.business, WSArg{Channel: "candle" + bar, InstId: instId}, func(push *WSPush) {
+		var rows [][]string
+		if err := json.Unmarshal(push.Data, &rows); err != nil {
+			log.Printf("解析K线推送失败: %v", err)
+			return
+		}
+
+		handler(ParseCandleRows(instId, bar, rows))
+	})
+}
+
+// ParseCandleRows 解析K线数组 [ts,o,h,l,c,vol,volCcy,volCcyQuote,confirm]，WebSocket推送和REST接口格式相同
+func ParseCandleRows(instId, bar string, rows [][]string) []Candle {
+	candles := make([]Candle, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 9 {
+			continue
+		}
+		candles = append(candles, Candle{
+			InstId:      instId,
+			Bar:         bar,
+			Ts:          row[0],
+			Open:        row[1],
+			High:        row[2],
+			Low:         row[3],
+			Close:       row[4],
+			Vol:         row[5],
+			VolCcy:      row[6],
+			VolCcyQuote: row[7],
+			Confirm:     row[8] == "1",
+		})
+	}
+	return candles
+}
+
+// Unsubscribe 取消订阅
+func (f *MarketFeed) Unsubscribe(channel, instId string) error {
+	arg := WSArg{Channel: channel, InstId: instId}
+
+	if strings.HasPrefix(channel, "candle") {
+		return f.business.Unsubscribe(arg)
+	}
+
+	f.mutex.Lock()
+	delete(f.books, arg)
+	f.mutex.Unlock()
+
+	return f.public.Unsubscribe(arg)
+}
+
+// subscribe 注册订阅并确保连接已启动
+func (f *MarketFeed) subscribe(client *WSClient, arg WSArg, handler WSHandler) error {
+	if err := client.Subscribe(arg, handler); err != nil {
+		return fmt.Errorf("订阅%s失败: %w", arg.Channel, err)
+	}
+	client.Start()
+	return nil
+}
+
+// resetBooks 清空全部本地订单簿
+func (f *MarketFeed) resetBooks() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, book := range f.books {
+		book.Reset()
+	}
+}
@@ -0,0 +1,197 @@
+package okx
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// checksumDepth OKX校验和使用的档位数
+const checksumDepth = 25
+
+// BookLevel 订单簿档位，保留原始字符串用于校验和计算
+type BookLevel struct {
+	Px     string `json:"px"`
+	Sz     string `json:"sz"`
+	Orders string `json:"orders,omitempty"`
+	price  float64
+}
+
+// UnmarshalJSON 解析OKX档位数组 [价格, 数量, 已弃用字段, 订单数]
+func (l *BookLevel) UnmarshalJSON(data []byte) error {
+	var fields []string
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) < 2 {
+		return fmt.Errorf("订单簿档位格式错误: %s", string(data))
+	}
+
+	price, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return fmt.Errorf("订单簿价格格式错误: %w", err)
+	}
+
+	l.Px = fields[0]
+	l.Sz = fields[1]
+	l.price = price
+	if len(fields) >= 4 {
+		l.Orders = fields[3]
+	}
+	return nil
+}
+
+// BookData OKX订单簿推送数据
+type BookData struct {
+	Asks      []BookLevel `json:"asks"`
+	Bids      []BookLevel `json:"bids"`
+	Ts        string      `json:"ts"`
+	Checksum  int32       `json:"checksum"`
+	SeqId     int64       `json:"seqId"`
+	PrevSeqId int64       `json:"prevSeqId"`
+}
+
+// OrderBookSnapshot 订单簿快照
+type OrderBookSnapshot struct {
+	InstId string      `json:"instId"`
+	Asks   []BookLevel `json:"asks"`
+	Bids   []BookLevel `json:"bids"`
+	Ts     string      `json:"ts"`
+	SeqId  int64       `json:"seqId"`
+}
+
+// OrderBook 本地订单簿，按快照+增量维护并校验序列号和校验和
+type OrderBook struct {
+	InstId string
+	asks   []BookLevel // 价格升序
+	bids   []BookLevel // 价格降序
+	ts     string
+	seqId  int64
+	ready  bool
+}
+
+// NewOrderBook 创建本地订单簿
+func NewOrderBook(instId string) *OrderBook {
+	return &OrderBook{InstId: instId}
+}
+
+// Ready 是否已收到快照
+func (b *OrderBook) Ready() bool {
+	return b.ready
+}
+
+// Reset 清空订单簿，等待下一次快照
+func (b *OrderBook) Reset() {
+	b.asks = nil
+	b.bids = nil
+	b.seqId = 0
+	b.ready = false
+}
+
+// Apply 应用一条推送，action为空或snapshot时整体替换，update时合并增量
+func (b *OrderBook) Apply(action string, data *BookData) error {
+	if action == "update" {
+		if !b.ready {
+			return fmt.Errorf("订单簿 %s 尚未收到快照", b.InstId)
+		}
+		// 无变化时 seqId 与 prevSeqId 相同，仍然是连续的
+		if data.PrevSeqId != 0 && data.PrevSeqId != b.seqId {
+			return fmt.Errorf("订单簿 %s 序列号不连续: 期望 %d，实际 %d", b.InstId, b.seqId, data.PrevSeqId)
+		}
+		b.asks = mergeLevels(b.asks, data.Asks, func(a, c float64) bool { return a < c })
+		b.bids = mergeLevels(b.bids, data.Bids, func(a, c float64) bool { return a > c })
+	} else {
+		b.asks = sortLevels(data.Asks, func(a, c float64) bool { return a < c })
+		b.bids = sortLevels(data.Bids, func(a, c float64) bool { return a > c })
+	}
+
+	b.ts = data.Ts
+	b.seqId = data.SeqId
+	b.ready = true
+
+	if data.Checksum != 0 {
+		if actual := b.Checksum(); actual != data.Checksum {
+			return fmt.Errorf("订单簿 %s 校验和不一致: 期望 %d，实际 %d", b.InstId, data.Checksum, actual)
+		}
+	}
+	return nil
+}
+
+// Checksum 按OKX规则计算前25档校验和（买卖交替拼接后取CRC32）
+func (b *OrderBook) Checksum() int32 {
+	parts := make([]string, 0, checksumDepth*4)
+	for i := 0; i < checksumDepth; i++ {
+		if i < len(b.bids) {
+			parts = append(parts, b.bids[i].Px, b.bids[i].Sz)
+		}
+		if i < len(b.asks) {
+			parts = append(parts, b.asks[i].Px, b.asks[i].Sz)
+		}
+	}
+	return int32(crc32.ChecksumIEEE([]byte(strings.Join(parts, ":"))))
+}
+
+// Snapshot 返回前depth档的副本，depth<=0时返回全部
+func (b *OrderBook) Snapshot(depth int) *OrderBookSnapshot {
+	return &OrderBookSnapshot{
+		InstId: b.InstId,
+		Asks:   copyLevels(b.asks, depth),
+		Bids:   copyLevels(b.bids, depth),
+		Ts:     b.ts,
+		SeqId:  b.seqId,
+	}
+}
+
+// sortLevels 复制并排序档位，过滤数量为0的档位
+func sortLevels(levels []BookLevel, less func(a, c float64) bool) []BookLevel {
+	result := make([]BookLevel, 0, len(levels))
+	for _, level := range levels {
+		if isZeroSize(level.Sz) {
+			continue
+		}
+		result = append(result, level)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return less(result[i].price, result[j].price) })
+	return result
+}
+
+// mergeLevels 合并增量档位，数量为0表示删除该价格档位
+func mergeLevels(book, updates []BookLevel, less func(a, c float64) bool) []BookLevel {
+	for _, update := range updates {
+		index := sort.Search(len(book), func(i int) bool { return !less(book[i].price, update.price) })
+		exists := index < len(book) && book[index].price == update.price
+
+		switch {
+		case isZeroSize(update.Sz):
+			if exists {
+				book = append(book[:index], book[index+1:]...)
+			}
+		case exists:
+			book[index] = update
+		default:
+			book = append(book, BookLevel{})
+			copy(book[index+1:], book[index:])
+			book[index] = update
+		}
+	}
+	return book
+}
+
+// copyLevels 复制前depth档
+func copyLevels(levels []BookLevel, depth int) []BookLevel {
+	if depth <= 0 || depth > len(levels) {
+		depth = len(levels)
+	}
+	result := make([]BookLevel, depth)
+	copy(result, levels[:depth])
+	return result
+}
+
+// isZeroSize 判断数量是否为0
+func isZeroSize(sz string) bool {
+	value, err := strconv.ParseFloat(sz, 64)
+	return err == nil && value == 0
+}
@@ -0,0 +1,258 @@
+package okx
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RateLimit 接口限速规则：每Interval最多Requests次请求
+type RateLimit struct {
+	Requests int
+	Interval time.Duration
+}
+
+// DefaultRateLimit 未列出的接口使用的限速规则
+var DefaultRateLimit = RateLimit{Requests: 10, Interval: 2 * time.Second}
+
+// DefaultRateLimits OKX各接口的限速规则，参考 https://www.okx.com/docs-v5/zh/
+// 公共接口按IP限速，私有接口按账户（API Key）限速
+var DefaultRateLimits = map[string]RateLimit{
+	"/api/v5/public/time":                 {Requests: 10, Interval: 2 * time.Second},
+	"/api/v5/public/instruments":          {Requests: 20, Interval: 2 * time.Second},
+	"/api/v5/public/funding-rate":         {Requests: 20, Interval: 2 * time.Second},
+	"/api/v5/public/funding-rate-history": {Requests: 10, Interval: 2 * time.Second},
+	"/api/v5/market/ticker":               {Requests: 20, Interval: 2 * time.Second},
+	"/api/v5/market/tickers":              {Requests: 20, Interval: 2 * time.Second},
+	"/api/v5/market/index-tickers":        {Requests: 20, Interval: 2 * time.Second},
+	"/api/v5/market/books":                {Requests: 40, Interval: 2 * time.Second},
+	"/api/v5/market/candles":              {Requests: 40, Interval: 2 * time.Second},
+	"/api/v5/market/history-candles":      {Requests: 20, Interval: 2 * time.Second},
+	"/api/v5/account/balance":             {Requests: 10, Interval: 2 * time.Second},
+	"/api/v5/account/positions":           {Requests: 10, Interval: 2 * time.Second},
+	"/api/v5/account/positions-history":   {Requests: 10, Interval: 2 * time.Second},
+	"/api/v5/account/bills-archive":       {Requests: 5, Interval: 2 * time.Second},
+	"/api/v5/trade/order":                 {Requests: 60, Interval: 2 * time.Second},
+	"/api/v5/trade/batch-orders":          {Requests: 60, Interval: 2 * time.Second},
+	"/api/v5/trade/cancel-order":          {Requests: 60, Interval: 2 * time.Second},
+	"/api/v5/trade/cancel-batch-orders":   {Requests: 60, Interval: 2 * time.Second},
+	"/api/v5/trade/amend-order":           {Requests: 60, Interval: 2 * time.Second},
+	"/api/v5/trade/orders-pending":        {Requests: 60, Interval: 2 * time.Second},
+	"/api/v5/trade/orders-history":        {Requests: 40, Interval: 2 * time.Second},
+	"/api/v5/trade/fills-history":         {Requests: 10, Interval: 2 * time.Second},
+}
+
+// 触发限频后的退避参数
+const (
+	rateLimitBackoffBase = 500 * time.Millisecond
+	rateLimitBackoffMax  = 10 * time.Second
+)
+
+// EndpointUsage 接口限速预算使用情况
+type EndpointUsage struct {
+	Endpoint     string     `json:"endpoint"`
+	Account      string     `json:"account,omitempty"` // 私有接口的API Key（脱敏）
+	Limit        int        `json:"limit"`
+	IntervalMs   int64      `json:"intervalMs"`
+	Available    float64    `json:"available"`    // 当前剩余令牌数
+	Waiting      int        `json:"waiting"`      // 排队等待中的请求数
+	Requests     int64      `json:"requests"`     // 累计放行请求数
+	Throttled    int64      `json:"throttled"`    // 累计需要排队的请求数
+	RateLimited  int64      `json:"rateLimited"`  // 累计收到的429/50011次数
+	BackoffUntil *time.Time `json:"backoffUntil"` // 退避结束时间
+}
+
+// bucketKey 令牌桶索引，私有接口按API Key区分
+type bucketKey struct {
+	endpoint string
+	account  string
+}
+
+// tokenBucket 单个接口的令牌桶
+type tokenBucket struct {
+	limit        RateLimit
+	tokens       float64
+	last         time.Time
+	backoffUntil time.Time
+	failures     int // 连续限频次数，用于指数退避
+	waiting      int
+	requests     int64
+	throttled    int64
+	rateLimited  int64
+}
+
+// refill 按经过的时间补充令牌，调用方需持有锁
+func (b *tokenBucket) refill(now time.Time) {
+	if now.Before(b.last) {
+		return
+	}
+	rate := float64(b.limit.Requests) / b.limit.Interval.Seconds()
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if max := float64(b.limit.Requests); b.tokens > max {
+		b.tokens = max
+	}
+	b.last = now
+}
+
+// delay 获取令牌前需要等待的时间，0表示已获取，调用方需持有锁
+func (b *tokenBucket) delay(now time.Time) time.Duration {
+	if now.Before(b.backoffUntil) {
+		return b.backoffUntil.Sub(now)
+	}
+
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	rate := float64(b.limit.Requests) / b.limit.Interval.Seconds()
+	return time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// RateLimiter 按接口划分的令牌桶限速器，超出预算的请求排队等待
+type RateLimiter struct {
+	mutex   sync.Mutex
+	limits  map[string]RateLimit
+	buckets map[bucketKey]*tokenBucket
+}
+
+// defaultLimiter 所有REST传输层共享的限速器
+var defaultLimiter = NewRateLimiter(DefaultRateLimits)
+
+// NewRateLimiter 创建限速器
+func NewRateLimiter(limits map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{
+		limits:  limits,
+		buckets: make(map[bucketKey]*tokenBucket),
+	}
+}
+
+// DefaultRateLimiter 获取所有服务共享的限速器
+func DefaultRateLimiter() *RateLimiter {
+	return defaultLimiter
+}
+
+// bucket 获取或创建令牌桶，调用方需持有锁
+func (l *RateLimiter) bucket(endpoint, account string) *tokenBucket {
+	key := bucketKey{endpoint: endpoint, account: account}
+	b, exists := l.buckets[key]
+	if !exists {
+		limit, ok := l.limits[endpoint]
+		if !ok {
+			limit = DefaultRateLimit
+		}
+		b = &tokenBucket{limit: limit, tokens: float64(limit.Requests), last: time.Now()}
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// Wait 等待接口预算，account为空表示公共接口；context取消时返回其错误
+func (l *RateLimiter) Wait(ctx context.Context, endpoint, account string) error {
+	queued := false
+	defer func() {
+		if queued {
+			l.mutex.Lock()
+			l.bucket(endpoint, account).waiting--
+			l.mutex.Unlock()
+		}
+	}()
+
+	for {
+		l.mutex.Lock()
+		b := l.bucket(endpoint, account)
+		wait := b.delay(time.Now())
+		if wait == 0 {
+			b.requests++
+			l.mutex.Unlock()
+			return nil
+		}
+		if !queued {
+			queued = true
+			b.waiting++
+			b.throttled++
+		}
+		l.mutex.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Backoff 记录一次限频响应，清空令牌并按指数退避（带随机抖动）暂停该接口，返回退避时长
+func (l *RateLimiter) Backoff(endpoint, account string) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b := l.bucket(endpoint, account)
+	b.rateLimited++
+	b.failures++
+	b.tokens = 0
+
+	backoff := rateLimitBackoffBase << (b.failures - 1)
+	if backoff <= 0 || backoff > rateLimitBackoffMax {
+		backoff = rateLimitBackoffMax
+	}
+	// 抖动范围为退避时长的50%~100%，避免多个请求同时恢复
+	backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+
+	now := time.Now()
+	if until := now.Add(backoff); until.After(b.backoffUntil) {
+		b.backoffUntil = until
+	}
+	// 退避期间不补充令牌
+	b.last = b.backoffUntil
+	return b.backoffUntil.Sub(now)
+}
+
+// Success 记录一次成功响应，重置连续限频计数
+func (l *RateLimiter) Success(endpoint, account string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.bucket(endpoint, account).failures = 0
+}
+
+// Usage 获取各接口当前的预算使用情况
+func (l *RateLimiter) Usage() []EndpointUsage {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	usage := make([]EndpointUsage, 0, len(l.buckets))
+	for key, b := range l.buckets {
+		b.refill(now)
+		item := EndpointUsage{
+			Endpoint:    key.endpoint,
+			Account:     MaskAPIKey(key.account),
+			Limit:       b.limit.Requests,
+			IntervalMs:  b.limit.Interval.Milliseconds(),
+			Available:   b.tokens,
+			Waiting:     b.waiting,
+			Requests:    b.requests,
+			Throttled:   b.throttled,
+			RateLimited: b.rateLimited,
+		}
+		if now.Before(b.backoffUntil) {
+			until := b.backoffUntil
+			item.BackoffUntil = &until
+		}
+		usage = append(usage, item)
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Endpoint != usage[j].Endpoint {
+			return usage[i].Endpoint < usage[j].Endpoint
+		}
+		return usage[i].Account < usage[j].Account
+	})
+	return usage
+}
@@ -0,0 +1,343 @@
+package okx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// timeSyncInterval 服务器时间同步间隔
+const timeSyncInterval = 5 * time.Minute
+
+// maxRateLimitRetries 触发限频后的最大重试次数
+const maxRateLimitRetries = 3
+
+// httpClient 所有OKX REST请求共享的http.Client，复用连接池
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Credentials API凭证
+type Credentials struct {
+	APIKey     string
+	SecretKey  string
+	Passphrase string
+	Simulated  bool // 模拟盘凭证，私有接口请求附带 x-simulated-trading: 1
+}
+
+// Complete 凭证是否完整
+func (c Credentials) Complete() bool {
+	return c.APIKey != "" && c.SecretKey != "" && c.Passphrase != ""
+}
+
+// Request REST请求
+type Request struct {
+	Method string      // 请求方法，默认GET
+	Path   string      // 接口路径，如 /api/v5/account/balance
+	Query  url.Values  // 查询参数
+	Body   interface{} // 请求体，序列化为JSON
+	Signed bool        // 是否为私有接口（需要签名）
+}
+
+// RequestPath 带查询参数的请求路径，同时用于URL和签名
+func (r Request) RequestPath() string {
+	if len(r.Query) == 0 {
+		return r.Path
+	}
+	return r.Path + "?" + r.Query.Encode()
+}
+
+// Response OKX响应信封 {code,msg,data}
+type Response[T any] struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data T      `json:"data"`
+}
+
+// Client OKX REST传输层，负责签名、服务器时间同步和响应解码
+type Client struct {
+	baseURL     string
+	credentials Credentials
+	httpClient  *http.Client
+	limiter     *RateLimiter
+
+	mutex      sync.Mutex
+	timeOffset int64     // 与OKX服务器的时间偏移量（毫秒）
+	lastSync   time.Time // 上次同步时间
+}
+
+// sharedKey 共享传输层的索引
+type sharedKey struct {
+	baseURL     string
+	credentials Credentials
+}
+
+var (
+	sharedMutex   sync.Mutex
+	sharedClients = make(map[sharedKey]*Client)
+)
+
+// NewClient 创建REST传输层，使用所有服务共享的限速器
+func NewClient(baseURL string, credentials Credentials) *Client {
+	return NewClientWithLimiter(baseURL, credentials, defaultLimiter)
+}
+
+// NewClientWithLimiter 使用指定限速器创建REST传输层
+func NewClientWithLimiter(baseURL string, credentials Credentials, limiter *RateLimiter) *Client {
+	return &Client{
+		baseURL:     baseURL,
+		credentials: credentials,
+		httpClient:  httpClient,
+		limiter:     limiter,
+	}
+}
+
+// SharedClient 获取共享的REST传输层，相同地址和凭证的服务共用同一实例
+func SharedClient(baseURL string, credentials Credentials) *Client {
+	sharedMutex.Lock()
+	defer sharedMutex.Unlock()
+
+	key := sharedKey{baseURL: baseURL, credentials: credentials}
+	client, exists := sharedClients[key]
+	if !exists {
+		client = NewClient(baseURL, credentials)
+		sharedClients[key] = client
+	}
+	return client
+}
+
+// BaseURL 获取接口地址
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Credentials 获取API凭证
+func (c *Client) Credentials() Credentials {
+	return c.credentials
+}
+
+// Timestamp 按服务器时间偏移生成ISO 8601格式的时间戳
+func (c *Client) Timestamp() string {
+	c.mutex.Lock()
+	offset := c.timeOffset
+	c.mutex.Unlock()
+
+	syncedTime := time.Now().Add(time.Duration(offset) * time.Millisecond)
+	return syncedTime.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// Simulated 是否为模拟盘传输层
+func (c *Client) Simulated() bool {
+	return c.credentials.Simulated
+}
+
+// SignedHeaders 生成私有接口签名头，模拟盘附带 x-simulated-trading 头
+func (c *Client) SignedHeaders(method, requestPath, body string) map[string]string {
+	timestamp := c.Timestamp()
+
+	headers := map[string]string{
+		"OK-ACCESS-KEY":        c.credentials.APIKey,
+		"OK-ACCESS-SIGN":       Sign(c.credentials.SecretKey, timestamp, method, requestPath, body),
+		"OK-ACCESS-TIMESTAMP":  timestamp,
+		"OK-ACCESS-PASSPHRASE": c.credentials.Passphrase,
+		"Content-Type":         "application/json",
+	}
+	if c.credentials.Simulated {
+		headers["x-simulated-trading"] = "1"
+	}
+	return headers
+}
+
+// ServerTime 获取OKX服务器时间
+func (c *Client) ServerTime(ctx context.Context) (time.Time, error) {
+	data, err := Call[[]struct {
+		Ts string `json:"ts"`
+	}](ctx, c, Request{Path: "/api/v5/public/time"})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(data) == 0 {
+		return time.Time{}, fmt.Errorf("服务器时间数据为空")
+	}
+
+	serverTs, err := strconv.ParseInt(data[0].Ts, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("解析服务器时间戳失败: %w", err)
+	}
+	return time.UnixMilli(serverTs), nil
+}
+
+// SyncTime 与OKX服务器时间同步，force为false时同步间隔内跳过
+func (c *Client) SyncTime(ctx context.Context, force bool) error {
+	c.mutex.Lock()
+	fresh := !c.lastSync.IsZero() && time.Since(c.lastSync) < timeSyncInterval
+	c.mutex.Unlock()
+	if fresh && !force {
+		return nil
+	}
+
+	serverTime, err := c.ServerTime(ctx)
+	if err != nil {
+		return fmt.Errorf("获取服务器时间失败: %w", err)
+	}
+
+	// 时间偏移量 = 服务器时间 - 本地时间
+	c.mutex.Lock()
+	c.timeOffset = serverTime.UnixMilli() - time.Now().UnixMilli()
+	c.lastSync = time.Now()
+	offset := c.timeOffset
+	c.mutex.Unlock()
+
+	log.Printf("时间同步完成: 偏移量=%dms", offset)
+	return nil
+}
+
+// Do 发送请求并解码响应信封，code不为"0"时返回*OKXError
+// 批量操作失败时（code为"1"或"2"）同时返回已解码的信封，便于读取逐条结果
+// 请求按接口限速排队；触发限频时退避后重试，私有接口遇到时间戳错误时强制重新同步服务器时间并重试一次
+func Do[T any](ctx context.Context, c *Client, req Request) (*Response[T], error) {
+	endpoint, account := req.Path, c.rateLimitAccount(req)
+
+	resynced := false
+	for attempt := 0; ; attempt++ {
+		resp, err := do[T](ctx, c, req)
+
+		switch {
+		case IsRateLimited(err) && attempt < maxRateLimitRetries:
+			// 退避期间该接口的所有请求都在限速器中排队
+			backoff := c.limiter.Backoff(endpoint, account)
+			log.Printf("OKX接口 %s 触发限频，%v 后重试", endpoint, backoff)
+			continue
+		case req.Signed && !resynced && IsTimestampError(err):
+			resynced = true
+			if syncErr := c.SyncTime(ctx, true); syncErr != nil {
+				log.Printf("时间同步失败: %v", syncErr)
+				return resp, err
+			}
+			continue
+		}
+
+		if !IsRateLimited(err) {
+			c.limiter.Success(endpoint, account)
+		}
+		return resp, err
+	}
+}
+
+// Call 发送请求并返回data字段
+func Call[T any](ctx context.Context, c *Client, req Request) (T, error) {
+	resp, err := Do[T](ctx, c, req)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return resp.Data, nil
+}
+
+// do 发送单次请求并解码
+func do[T any](ctx context.Context, c *Client, req Request) (*Response[T], error) {
+	status, body, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope Response[json.RawMessage]
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Code == "" {
+		if status < 200 || status >= 300 {
+			return nil, &OKXError{Msg: truncate(string(body), 200), HTTPStatus: status}
+		}
+		if err == nil {
+			err = fmt.Errorf("缺少code字段")
+		}
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	resp := &Response[T]{Code: envelope.Code, Msg: envelope.Msg}
+	if len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, &resp.Data); err != nil && envelope.Code == "0" {
+			return nil, fmt.Errorf("解析响应数据失败: %w", err)
+		}
+	}
+
+	if envelope.Code != "0" || status < 200 || status >= 300 {
+		return resp, &OKXError{Code: envelope.Code, Msg: envelope.Msg, HTTPStatus: status, Data: envelope.Data}
+	}
+	return resp, nil
+}
+
+// rateLimitAccount 限速账户，公共接口按IP限速返回空
+func (c *Client) rateLimitAccount(req Request) string {
+	if req.Signed {
+		return c.credentials.APIKey
+	}
+	return ""
+}
+
+// send 按接口限速排队后构建并发送HTTP请求，返回状态码和响应体
+func (c *Client) send(ctx context.Context, req Request) (int, []byte, error) {
+	if err := c.limiter.Wait(ctx, req.Path, c.rateLimitAccount(req)); err != nil {
+		return 0, nil, fmt.Errorf("等待限速预算失败: %w", err)
+	}
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body []byte
+	if req.Body != nil {
+		data, err := json.Marshal(req.Body)
+		if err != nil {
+			return 0, nil, fmt.Errorf("序列化请求失败: %w", err)
+		}
+		body = data
+	}
+
+	requestPath := req.RequestPath()
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+requestPath, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	if req.Signed {
+		if !c.credentials.Complete() {
+			return 0, nil, fmt.Errorf("OKX API配置不完整，请检查环境变量 OKX_API_KEY, OKX_SECRET_KEY, OKX_PASSPHRASE")
+		}
+		if err := c.SyncTime(ctx, false); err != nil {
+			// 同步失败时使用上次的偏移量继续请求
+			log.Printf("时间同步失败: %v", err)
+		}
+		for key, value := range c.SignedHeaders(method, requestPath, string(body)) {
+			httpReq.Header.Set(key, value)
+		}
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return 0, nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	return resp.StatusCode, respBody, nil
+}
+
+// truncate 截断过长的文本
+func truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	return text[:max] + "..."
+}
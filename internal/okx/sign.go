@@ -0,0 +1,42 @@
+package okx
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"time"
+)
+
+// Sign 生成OKX API签名：Base64(HMAC-SHA256(timestamp + method + requestPath + body))
+func Sign(secretKey, timestamp, method, requestPath, body string) string {
+	message := timestamp + method + requestPath + body
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewLoginRequest 创建私有WebSocket登录请求，时间戳为Unix秒
+func NewLoginRequest(apiKey, secretKey, passphrase string, now time.Time) interface{} {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	return wsRequest{
+		Op: "login",
+		Args: []interface{}{map[string]string{
+			"apiKey":     apiKey,
+			"passphrase": passphrase,
+			"timestamp":  timestamp,
+			"sign":       Sign(secretKey, timestamp, "GET", "/users/self/verify", ""),
+		}},
+	}
+}
+
+// MaskAPIKey API Key脱敏，只保留首尾各4位，日志和接口响应统一使用此格式
+func MaskAPIKey(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	if len(apiKey) <= 8 {
+		return "****"
+	}
+	return apiKey[:4] + "****" + apiKey[len(apiKey)-4:]
+}
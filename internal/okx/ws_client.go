@@ -0,0 +1,409 @@
+package okx
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 默认WebSocket参数，OKX在30秒内没有任何消息时会断开连接
+const (
+	defaultPingInterval    = 20 * time.Second
+	defaultPongTimeout     = 10 * time.Second
+	defaultReconnectMin    = time.Second
+	defaultReconnectMax    = 30 * time.Second
+	defaultWSHandshakeWait = 10 * time.Second
+)
+
+// WSArg 订阅参数
+type WSArg struct {
+	Channel    string `json:"channel"`
+	InstId     string `json:"instId,omitempty"`
+	InstType   string `json:"instType,omitempty"`
+	InstFamily string `json:"instFamily,omitempty"`
+	Ccy        string `json:"ccy,omitempty"`
+}
+
+// WSPush WebSocket推送消息（事件响应或数据推送）
+type WSPush struct {
+	Event     string          `json:"event,omitempty"`
+	Arg       WSArg           `json:"arg"`
+	Action    string          `json:"action,omitempty"`
+	EventType string          `json:"eventType,omitempty"` // 私有频道：snapshot / event_update
+	CurPage   int             `json:"curPage,omitempty"`   // 私有频道快照分页
+	LastPage  *bool           `json:"lastPage,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Code      string          `json:"code,omitempty"`
+	Msg       string          `json:"msg,omitempty"`
+	ConnId    string          `json:"connId,omitempty"`
+}
+
+// WSHandler 数据推送处理函数
+type WSHandler func(push *WSPush)
+
+// wsRequest WebSocket操作请求
+type wsRequest struct {
+	Op   string        `json:"op"`
+	Args []interface{} `json:"args"`
+}
+
+// WSClient OKX WebSocket客户端，负责连接保活、断线重连和重新订阅
+type WSClient struct {
+	url    string
+	dialer *websocket.Dialer
+
+	mutex         sync.Mutex
+	conn          *websocket.Conn
+	subscriptions map[WSArg]WSHandler
+	started       bool
+	stopChan      chan struct{}
+	doneChan      chan struct{}
+
+	writeMutex sync.Mutex
+
+	// Login 返回登录请求，设置后每次连接先登录成功再重新订阅（私有频道）
+	Login func() interface{}
+	// OnDisconnect 连接断开后调用，可用于清理依赖连接的本地状态
+	OnDisconnect func()
+	// OnEvent 收到事件响应（subscribe/unsubscribe/login/error）时调用
+	OnEvent func(push *WSPush)
+
+	PingInterval time.Duration
+	PongTimeout  time.Duration
+	ReconnectMin time.Duration
+	ReconnectMax time.Duration
+}
+
+// NewWSClient 创建WebSocket客户端
+func NewWSClient(url string) *WSClient {
+	return &WSClient{
+		url:           url,
+		dialer:        &websocket.Dialer{HandshakeTimeout: defaultWSHandshakeWait},
+		subscriptions: make(map[WSArg]WSHandler),
+		PingInterval:  defaultPingInterval,
+		PongTimeout:   defaultPongTimeout,
+		ReconnectMin:  defaultReconnectMin,
+		ReconnectMax:  defaultReconnectMax,
+	}
+}
+
+// Start 启动连接循环，重复调用无副作用
+func (c *WSClient) Start() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.started {
+		return
+	}
+	c.started = true
+	c.stopChan = make(chan struct{})
+	c.doneChan = make(chan struct{})
+
+	go c.run(c.stopChan, c.doneChan)
+}
+
+// Stop 关闭连接并停止重连，订阅关系保留，再次Start后自动恢复
+func (c *WSClient) Stop() {
+	c.mutex.Lock()
+	if !c.started {
+		c.mutex.Unlock()
+		return
+	}
+	c.started = false
+	close(c.stopChan)
+	conn := c.conn
+	done := c.doneChan
+	c.mutex.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
+	<-done
+}
+
+// Connected 是否已建立连接
+func (c *WSClient) Connected() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.conn != nil
+}
+
+// Subscribe 订阅频道，未连接时在连接建立后自动发送
+func (c *WSClient) Subscribe(arg WSArg, handler WSHandler) error {
+	c.mutex.Lock()
+	c.subscriptions[arg] = handler
+	connected := c.conn != nil
+	c.mutex.Unlock()
+
+	if !connected {
+		return nil
+	}
+	return c.Send(wsRequest{Op: "subscribe", Args: []interface{}{arg}})
+}
+
+// Unsubscribe 取消订阅
+func (c *WSClient) Unsubscribe(arg WSArg) error {
+	c.mutex.Lock()
+	_, exists := c.subscriptions[arg]
+	delete(c.subscriptions, arg)
+	connected := c.conn != nil
+	c.mutex.Unlock()
+
+	if !exists || !connected {
+		return nil
+	}
+	return c.Send(wsRequest{Op: "unsubscribe", Args: []interface{}{arg}})
+}
+
+// Resubscribe 重新发送某个频道的订阅（用于订单簿校验失败后获取新快照）
+func (c *WSClient) Resubscribe(arg WSArg) error {
+	if err := c.Send(wsRequest{Op: "unsubscribe", Args: []interface{}{arg}}); err != nil {
+		return err
+	}
+	return c.Send(wsRequest{Op: "subscribe", Args: []interface{}{arg}})
+}
+
+// Send 发送JSON消息
+func (c *WSClient) Send(message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("序列化WebSocket消息失败: %w", err)
+	}
+	return c.writeMessage(data)
+}
+
+// writeMessage 写入一条文本消息，gorilla/websocket不允许并发写
+func (c *WSClient) writeMessage(data []byte) error {
+	c.mutex.Lock()
+	conn := c.conn
+	c.mutex.Unlock()
+
+	if conn == nil {
+		return fmt.Errorf("WebSocket未连接")
+	}
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(c.PongTimeout))
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// run 连接循环，断线后按指数退避重连
+func (c *WSClient) run(stop, done chan struct{}) {
+	defer close(done)
+
+	backoff := c.ReconnectMin
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		conn, _, err := c.dialer.Dial(c.url, nil)
+		if err != nil {
+			log.Printf("连接OKX WebSocket失败(%s): %v，%s后重试", c.url, err, backoff)
+			if !sleepOrStop(backoff, stop) {
+				return
+			}
+			backoff *= 2
+			if backoff > c.ReconnectMax {
+				backoff = c.ReconnectMax
+			}
+			continue
+		}
+
+		backoff = c.ReconnectMin
+		c.serve(conn, stop)
+
+		if c.OnDisconnect != nil {
+			c.OnDisconnect()
+		}
+
+		if !sleepOrStop(c.ReconnectMin, stop) {
+			return
+		}
+	}
+}
+
+// serve 处理单个连接直到断开
+func (c *WSClient) serve(conn *websocket.Conn, stop chan struct{}) {
+	c.mutex.Lock()
+	select {
+	case <-stop:
+		c.mutex.Unlock()
+		conn.Close()
+		return
+	default:
+	}
+	c.conn = conn
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		c.conn = nil
+		c.mutex.Unlock()
+		conn.Close()
+	}()
+
+	// 读取超时：超过 PingInterval + PongTimeout 没有任何消息视为连接失效
+	readTimeout := c.PingInterval + c.PongTimeout
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+	// serve 返回后关闭 done，读协程不再阻塞在已无人接收的 messages 上
+	messages := make(chan []byte, 64)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(readTimeout))
+			select {
+			case messages <- data:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	if c.Login != nil {
+		if err := c.login(messages, readErr, stop); err != nil {
+			log.Printf("OKX WebSocket登录失败: %v", err)
+			return
+		}
+	}
+
+	if err := c.resubscribeAll(); err != nil {
+		log.Printf("OKX WebSocket重新订阅失败: %v", err)
+		return
+	}
+
+	pingTicker := time.NewTicker(c.PingInterval)
+	defer pingTicker.Stop()
+	lastMessage := time.Now()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case err := <-readErr:
+			log.Printf("OKX WebSocket连接断开: %v", err)
+			return
+		case data := <-messages:
+			lastMessage = time.Now()
+			c.handleMessage(data)
+		case <-pingTicker.C:
+			// 一段时间没有收到消息时发送ping，服务端回复pong
+			if time.Since(lastMessage) >= c.PingInterval {
+				if err := c.writeMessage([]byte("ping")); err != nil {
+					log.Printf("发送ping失败: %v", err)
+					return
+				}
+			}
+		}
+	}
+}
+
+// login 发送登录请求并等待登录结果
+func (c *WSClient) login(messages chan []byte, readErr chan error, stop chan struct{}) error {
+	if err := c.Send(c.Login()); err != nil {
+		return err
+	}
+
+	timeout := time.NewTimer(c.PongTimeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return fmt.Errorf("连接已停止")
+		case err := <-readErr:
+			return err
+		case <-timeout.C:
+			return fmt.Errorf("等待登录响应超时")
+		case data := <-messages:
+			var push WSPush
+			if err := json.Unmarshal(data, &push); err == nil {
+				switch push.Event {
+				case "login":
+					if push.Code != "" && push.Code != "0" {
+						return fmt.Errorf("code=%s msg=%s", push.Code, push.Msg)
+					}
+					return nil
+				case "error":
+					return fmt.Errorf("code=%s msg=%s", push.Code, push.Msg)
+				}
+			}
+			c.handleMessage(data)
+		}
+	}
+}
+
+// resubscribeAll 重新发送全部订阅
+func (c *WSClient) resubscribeAll() error {
+	c.mutex.Lock()
+	args := make([]interface{}, 0, len(c.subscriptions))
+	for arg := range c.subscriptions {
+		args = append(args, arg)
+	}
+	c.mutex.Unlock()
+
+	if len(args) == 0 {
+		return nil
+	}
+	return c.Send(wsRequest{Op: "subscribe", Args: args})
+}
+
+// handleMessage 分发收到的消息
+func (c *WSClient) handleMessage(data []byte) {
+	if string(data) == "pong" {
+		return
+	}
+
+	var push WSPush
+	if err := json.Unmarshal(data, &push); err != nil {
+		log.Printf("解析OKX WebSocket消息失败: %v", err)
+		return
+	}
+
+	if push.Event != "" {
+		if push.Event == "error" {
+			log.Printf("OKX WebSocket错误: code=%s msg=%s", push.Code, push.Msg)
+		}
+		if c.OnEvent != nil {
+			c.OnEvent(&push)
+		}
+		return
+	}
+
+	c.mutex.Lock()
+	handler := c.subscriptions[push.Arg]
+	c.mutex.Unlock()
+
+	if handler != nil && len(push.Data) > 0 {
+		handler(&push)
+	}
+}
+
+// sleepOrStop 等待指定时间，期间收到停止信号返回false
+func sleepOrStop(d time.Duration, stop chan struct{}) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+)
+
+const (
+	// defaultArchiveQueryLimit 未指定 limit 时的返回数量
+	defaultArchiveQueryLimit = 100
+	// maxArchiveQueryLimit 单次查询最多返回的数量
+	maxArchiveQueryLimit = 1000
+)
+
+// ArchiveRepository 本地成交归档存储接口
+// 成交明细按 instId+tradeId、账单按 billId、历史持仓按 posId+uTime 去重，重复保存时覆盖
+type ArchiveRepository interface {
+	SaveFills(fills []*models.Fill) error
+	SaveBills(bills []*models.Bill) error
+	SavePositions(positions []*models.PositionHistory) error
+	Fills(query *models.ArchiveQuery) ([]*models.Fill, error)
+	Bills(query *models.ArchiveQuery) ([]*models.Bill, error)
+	Positions(query *models.ArchiveQuery) ([]*models.PositionHistory, error)
+	Checkpoint(source string) (*models.ArchiveCheckpoint, error)
+	SaveCheckpoint(checkpoint *models.ArchiveCheckpoint) error
+	Checkpoints() ([]*models.ArchiveCheckpoint, error)
+}
+
+// archiveRepository 基于SQLite的本地成交归档
+type archiveRepository struct {
+	db *sql.DB
+}
+
+// NewArchiveRepository 创建本地成交归档存储
+func NewArchiveRepository(db *sql.DB) ArchiveRepository {
+	return &archiveRepository{db: db}
+}
+
+// SaveFills 保存成交明细
+func (r *archiveRepository) SaveFills(fills []*models.Fill) error {
+	return r.upsert(
+		`INSERT OR REPLACE INTO archive_fills (inst_id, trade_id, inst_type, ts, data) VALUES (?, ?, ?, ?, ?)`,
+		len(fills), func(i int) ([]interface{}, interface{}) {
+			fill := fills[i]
+			return []interface{}{fill.InstId, fill.TradeId, fill.InstType, parseArchiveTime(fill.Ts)}, fill
+		},
+	)
+}
+
+// SaveBills 保存账单流水
+func (r *archiveRepository) SaveBills(bills []*models.Bill) error {
+	return r.upsert(
+		`INSERT OR REPLACE INTO archive_bills (bill_id, inst_type, inst_id, ccy, type, ts, data) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		len(bills), func(i int) ([]interface{}, interface{}) {
+			bill := bills[i]
+			return []interface{}{bill.BillId, bill.InstType, bill.InstId, bill.Ccy, bill.Type, parseArchiveTime(bill.Ts)}, bill
+		},
+	)
+}
+
+// SavePositions 保存历史持仓，同一持仓每次更新保存一条
+func (r *archiveRepository) SavePositions(positions []*models.PositionHistory) error {
+	return r.upsert(
+		`INSERT OR REPLACE INTO archive_positions (pos_id, u_time, inst_type, inst_id, data) VALUES (?, ?, ?, ?, ?)`,
+		len(positions), func(i int) ([]interface{}, interface{}) {
+			pos := positions[i]
+			return []interface{}{pos.PosId, parseArchiveTime(pos.UTime), pos.InstType, pos.InstId}, pos
+		},
+	)
+}
+
+// upsert 在一个事务中写入 count 条记录，row 返回第 i 条的键列和完整记录
+func (r *archiveRepository) upsert(statement string, count int, row func(i int) ([]interface{}, interface{})) error {
+	if count == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("保存归档失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(statement)
+	if err != nil {
+		return fmt.Errorf("保存归档失败: %w", err)
+	}
+	defer stmt.Close()
+
+	for i := 0; i < count; i++ {
+		columns, record := row(i)
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("序列化归档记录失败: %w", err)
+		}
+		if _, err := stmt.Exec(append(columns, string(data))...); err != nil {
+			return fmt.Errorf("保存归档失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("保存归档失败: %w", err)
+	}
+	return nil
+}
+
+// Fills 查询成交明细
+func (r *archiveRepository) Fills(query *models.ArchiveQuery) ([]*models.Fill, error) {
+	fills := []*models.Fill{}
+	err := r.query("archive_fills", "ts", query, false, func(data []byte) error {
+		var fill models.Fill
+		if err := json.Unmarshal(data, &fill); err != nil {
+			return err
+		}
+		fills = append(fills, &fill)
+		return nil
+	})
+	return fills, err
+}
+
+// Bills 查询账单流水
+func (r *archiveRepository) Bills(query *models.ArchiveQuery) ([]*models.Bill, error) {
+	bills := []*models.Bill{}
+	err := r.query("archive_bills", "ts", query, true, func(data []byte) error {
+		var bill models.Bill
+		if err := json.Unmarshal(data, &bill); err != nil {
+			return err
+		}
+		bills = append(bills, &bill)
+		return nil
+	})
+	return bills, err
+}
+
+// Positions 查询历史持仓
+func (r *archiveRepository) Positions(query *models.ArchiveQuery) ([]*models.PositionHistory, error) {
+	positions := []*models.PositionHistory{}
+	err := r.query("archive_positions", "u_time", query, false, func(data []byte) error {
+		var pos models.PositionHistory
+		if err := json.Unmarshal(data, &pos); err != nil {
+			return err
+		}
+		positions = append(positions, &pos)
+		return nil
+	})
+	return positions, err
+}
+
+// query 按条件查询归档表，按时间倒序，每行的完整记录交给 scan 解析；币种和账单类型只用于账单表
+func (r *archiveRepository) query(table, timeColumn string, query *models.ArchiveQuery, isBills bool, scan func(data []byte) error) error {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	if query.InstType != "" {
+		conditions = append(conditions, "inst_type = ?")
+		args = append(args, query.InstType)
+	}
+	if query.InstId != "" {
+		conditions = append(conditions, "inst_id = ?")
+		args = append(args, query.InstId)
+	}
+	if isBills && query.Ccy != "" {
+		conditions = append(conditions, "ccy = ?")
+		args = append(args, query.Ccy)
+	}
+	if isBills && query.Type != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, query.Type)
+	}
+	if query.Begin > 0 {
+		conditions = append(conditions, timeColumn+" >= ?")
+		args = append(args, query.Begin)
+	}
+	if query.End > 0 {
+		conditions = append(conditions, timeColumn+" <= ?")
+		args = append(args, query.End)
+	}
+
+	statement := "SELECT data FROM " + table
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	statement += " ORDER BY " + timeColumn + " DESC, rowid DESC LIMIT ? OFFSET ?"
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultArchiveQueryLimit
+	}
+	if limit > maxArchiveQueryLimit {
+		limit = maxArchiveQueryLimit
+	}
+	args = append(args, limit, max(query.Offset, 0))
+
+	rows, err := r.db.Query(statement, args...)
+	if err != nil {
+		return fmt.Errorf("查询归档失败: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return fmt.Errorf("查询归档失败: %w", err)
+		}
+		if err := scan(data); err != nil {
+			return fmt.Errorf("解析归档记录失败: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询归档失败: %w", err)
+	}
+	return nil
+}
+
+// checkpointColumns 查询同步进度的列，顺序与 scanCheckpoint 一致
+const checkpointColumns = `source, floor, top, cursor, synced, last_error, updated_at`
+
+// Checkpoint 查询数据来源的同步进度，尚未同步过时返回空进度
+func (r *archiveRepository) Checkpoint(source string) (*models.ArchiveCheckpoint, error) {
+	row := r.db.QueryRow(`SELECT `+checkpointColumns+` FROM archive_checkpoints WHERE source = ?`, source)
+	checkpoint, err := scanCheckpoint(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &models.ArchiveCheckpoint{Source: source}, nil
+	}
+	return checkpoint, err
+}
+
+// SaveCheckpoint 保存同步进度
+func (r *archiveRepository) SaveCheckpoint(checkpoint *models.ArchiveCheckpoint) error {
+	_, err := r.db.Exec(
+		`INSERT OR REPLACE INTO archive_checkpoints (`+checkpointColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		checkpoint.Source, checkpoint.Floor, checkpoint.Top, checkpoint.Cursor, checkpoint.Synced,
+		checkpoint.LastError, checkpoint.UpdatedAt.UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("保存同步进度失败: %w", err)
+	}
+	return nil
+}
+
+// Checkpoints 所有数据来源的同步进度
+func (r *archiveRepository) Checkpoints() ([]*models.ArchiveCheckpoint, error) {
+	rows, err := r.db.Query(`SELECT ` + checkpointColumns + ` FROM archive_checkpoints ORDER BY source`)
+	if err != nil {
+		return nil, fmt.Errorf("查询同步进度失败: %w", err)
+	}
+	defer rows.Close()
+
+	checkpoints := []*models.ArchiveCheckpoint{}
+	for rows.Next() {
+		checkpoint, err := scanCheckpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询同步进度失败: %w", err)
+	}
+	return checkpoints, nil
+}
+
+// scanCheckpoint 读取一行同步进度
+func scanCheckpoint(row rowScanner) (*models.ArchiveCheckpoint, error) {
+	var (
+		checkpoint models.ArchiveCheckpoint
+		updatedAt  int64
+	)
+
+	err := row.Scan(&checkpoint.Source, &checkpoint.Floor, &checkpoint.Top, &checkpoint.Cursor,
+		&checkpoint.Synced, &checkpoint.LastError, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询同步进度失败: %w", err)
+	}
+
+	checkpoint.UpdatedAt = time.UnixMilli(updatedAt)
+	return &checkpoint, nil
+}
+
+// parseArchiveTime 解析毫秒时间戳，无法解析时为0
+func parseArchiveTime(value string) int64 {
+	ts, _ := strconv.ParseInt(value, 10, 64)
+	return ts
+}
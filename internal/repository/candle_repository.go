@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+)
+
+// CandleRepository K线缓存接口，只缓存已完结的K线
+type CandleRepository interface {
+	Save(instId, bar string, candles []*models.Candle) error
+	Range(instId, bar string, from, to int64) ([]*models.Candle, error)
+}
+
+// candleRepository 基于SQLite的K线缓存
+type candleRepository struct {
+	db *sql.DB
+}
+
+// NewCandleRepository 创建K线缓存
+func NewCandleRepository(db *sql.DB) CandleRepository {
+	return &candleRepository{db: db}
+}
+
+// Save 保存已完结的K线，未完结的K线会被忽略，重复保存时覆盖
+func (r *candleRepository) Save(instId, bar string, candles []*models.Candle) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("保存K线失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`INSERT OR REPLACE INTO candles
+		 (inst_id, bar, ts, open, high, low, close, vol, vol_ccy, vol_ccy_quote)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	)
+	if err != nil {
+		return fmt.Errorf("保存K线失败: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, candle := range candles {
+		if !candle.Confirm {
+			continue
+		}
+		_, err := stmt.Exec(instId, bar, candle.Ts, candle.Open, candle.High, candle.Low, candle.Close,
+			candle.Vol, candle.VolCcy, candle.VolCcyQuote)
+		if err != nil {
+			return fmt.Errorf("保存K线失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("保存K线失败: %w", err)
+	}
+	return nil
+}
+
+// Range 查询 [from, to) 区间内缓存的K线，按时间升序
+func (r *candleRepository) Range(instId, bar string, from, to int64) ([]*models.Candle, error) {
+	rows, err := r.db.Query(
+		`SELECT ts, open, high, low, close, vol, vol_ccy, vol_ccy_quote FROM candles
+		 WHERE inst_id = ? AND bar = ? AND ts >= ? AND ts < ? ORDER BY ts ASC`,
+		instId, bar, from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询K线缓存失败: %w", err)
+	}
+	defer rows.Close()
+
+	var candles []*models.Candle
+	for rows.Next() {
+		candle := &models.Candle{Confirm: true}
+		err := rows.Scan(&candle.Ts, &candle.Open, &candle.High, &candle.Low, &candle.Close,
+			&candle.Vol, &candle.VolCcy, &candle.VolCcyQuote)
+		if err != nil {
+			return nil, fmt.Errorf("查询K线缓存失败: %w", err)
+		}
+		candles = append(candles, candle)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询K线缓存失败: %w", err)
+	}
+	return candles, nil
+}
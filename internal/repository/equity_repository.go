@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+)
+
+// ErrSnapshotNotFound 没有可用的权益快照
+var ErrSnapshotNotFound = errors.New("未找到权益快照")
+
+// RetentionTier 降采样层级：早于 Age 的快照每个 Bucket 只保留一条
+type RetentionTier struct {
+	Age    time.Duration
+	Bucket time.Duration
+}
+
+// DefaultRetentionTiers 默认降采样策略
+// 2天内保留全部快照，2天至35天每小时一条，35天以上每天一条
+var DefaultRetentionTiers = []RetentionTier{
+	{Age: 48 * time.Hour, Bucket: time.Hour},
+	{Age: 35 * 24 * time.Hour, Bucket: 24 * time.Hour},
+}
+
+// DefaultMaxRetention 快照最长保留时间（覆盖半年盈亏周期）
+const DefaultMaxRetention = 200 * 24 * time.Hour
+
+// DefaultEquityAccountID 环境变量配置的默认账户在权益快照中的账户ID，添加的OKX账户使用各自的账户ID
+const DefaultEquityAccountID int64 = 0
+
+// EquityRepository 权益快照存储接口
+type EquityRepository interface {
+	Save(snapshot *models.EquitySnapshot) error
+	Nearest(currency models.Currency, at time.Time) (*models.EquitySnapshot, error)
+	Compact(now time.Time) (int64, error)
+}
+
+// equityRepository 基于SQLite的权益快照存储，读写限定在一个账户和交易环境的快照序列
+type equityRepository struct {
+	db           *sql.DB
+	accountID    int64
+	environment  string
+	tiers        []RetentionTier
+	maxRetention time.Duration
+}
+
+// NewEquityRepository 创建账户在指定交易环境（demo/live/paper）下的权益快照存储
+// 不同账户、不同交易环境的快照互不混合
+func NewEquityRepository(db *sql.DB, accountID int64, environment string) EquityRepository {
+	return &equityRepository{
+		db:           db,
+		accountID:    accountID,
+		environment:  environment,
+		tiers:        DefaultRetentionTiers,
+		maxRetention: DefaultMaxRetention,
+	}
+}
+
+// Save 保存一条权益快照
+func (r *equityRepository) Save(snapshot *models.EquitySnapshot) error {
+	_, err := r.db.Exec(
+		`INSERT INTO equity_snapshots (account_id, environment, currency, total_equity, recorded_at) VALUES (?, ?, ?, ?, ?)`,
+		r.accountID, r.environment, string(snapshot.Currency), snapshot.TotalEquity, snapshot.RecordedAt.UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("保存权益快照失败: %w", err)
+	}
+	return nil
+}
+
+// Nearest 查找指定币种最接近某一时间点的快照
+func (r *equityRepository) Nearest(currency models.Currency, at time.Time) (*models.EquitySnapshot, error) {
+	target := at.UnixMilli()
+
+	before, err := r.queryOne(
+		`SELECT total_equity, recorded_at FROM equity_snapshots
+		 WHERE account_id = ? AND environment = ? AND currency = ? AND recorded_at <= ?
+		 ORDER BY recorded_at DESC LIMIT 1`,
+		currency, target,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := r.queryOne(
+		`SELECT total_equity, recorded_at FROM equity_snapshots
+		 WHERE account_id = ? AND environment = ? AND currency = ? AND recorded_at > ?
+		 ORDER BY recorded_at ASC LIMIT 1`,
+		currency, target,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case before == nil && after == nil:
+		return nil, ErrSnapshotNotFound
+	case before == nil:
+		return after, nil
+	case after == nil:
+		return before, nil
+	}
+
+	if at.Sub(before.RecordedAt) <= after.RecordedAt.Sub(at) {
+		return before, nil
+	}
+	return after, nil
+}
+
+// Compact 按降采样策略压缩所有账户的历史快照，返回删除的记录数
+func (r *equityRepository) Compact(now time.Time) (int64, error) {
+	var deleted int64
+
+	// 超过最长保留时间的快照直接删除
+	result, err := r.db.Exec(
+		`DELETE FROM equity_snapshots WHERE recorded_at < ?`,
+		now.Add(-r.maxRetention).UnixMilli(),
+	)
+	if err != nil {
+		return deleted, fmt.Errorf("清理过期权益快照失败: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil {
+		deleted += n
+	}
+
+	// 每个层级内，同一账户、交易环境和币种的同一时间桶只保留最早的一条
+	for i, tier := range r.tiers {
+		upper := now.Add(-tier.Age).UnixMilli()
+		lower := now.Add(-r.maxRetention).UnixMilli()
+		if i+1 < len(r.tiers) {
+			lower = now.Add(-r.tiers[i+1].Age).UnixMilli()
+		}
+
+		result, err := r.db.Exec(
+			`DELETE FROM equity_snapshots
+			 WHERE recorded_at >= ? AND recorded_at < ?
+			   AND id NOT IN (
+			     SELECT MIN(id) FROM equity_snapshots
+			     WHERE recorded_at >= ? AND recorded_at < ?
+			     GROUP BY account_id, environment, currency, recorded_at / ?
+			   )`,
+			lower, upper, lower, upper, tier.Bucket.Milliseconds(),
+		)
+		if err != nil {
+			return deleted, fmt.Errorf("压缩权益快照失败: %w", err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			deleted += n
+		}
+	}
+
+	return deleted, nil
+}
+
+// queryOne 查询单条快照，没有记录时返回nil
+func (r *equityRepository) queryOne(query string, currency models.Currency, target int64) (*models.EquitySnapshot, error) {
+	var (
+		totalEquity string
+		recordedAt  int64
+	)
+
+	err := r.db.QueryRow(query, r.accountID, r.environment, string(currency), target).Scan(&totalEquity, &recordedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询权益快照失败: %w", err)
+	}
+
+	return &models.EquitySnapshot{
+		Currency:    currency,
+		TotalEquity: totalEquity,
+		RecordedAt:  time.UnixMilli(recordedAt),
+	}, nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+)
+
+var (
+	// ErrOKXAccountNotFound OKX账户不存在或不属于当前用户
+	ErrOKXAccountNotFound = errors.New("OKX账户不存在")
+	// ErrOKXAccountExists 账户名称已被使用
+	ErrOKXAccountExists = errors.New("OKX账户名称已存在")
+)
+
+// OKXAccountRepository OKX账户存储接口，除 ListAll 外所有查询都限定在指定用户下
+// 凭证由调用方加密后传入，存储层不接触明文
+type OKXAccountRepository interface {
+	Create(account *models.OKXAccount) error
+	List(userID int64) ([]*models.OKXAccount, error)
+	ListAll() ([]*models.OKXAccount, error)
+	Get(userID, id int64) (*models.OKXAccount, error)
+	Delete(userID, id int64) error
+}
+
+// okxAccountRepository 基于SQLite的OKX账户存储
+type okxAccountRepository struct {
+	db *sql.DB
+}
+
+// NewOKXAccountRepository 创建OKX账户存储
+func NewOKXAccountRepository(db *sql.DB) OKXAccountRepository {
+	return &okxAccountRepository{db: db}
+}
+
+// okxAccountColumns 查询OKX账户的列，顺序与 scanOKXAccount 一致
+const okxAccountColumns = `id, user_id, name, api_key, secret_key, passphrase, key_hint, is_test, created_at`
+
+// Create 创建OKX账户，成功后回填账户ID
+func (r *okxAccountRepository) Create(account *models.OKXAccount) error {
+	result, err := r.db.Exec(
+		`INSERT INTO okx_accounts (user_id, name, api_key, secret_key, passphrase, key_hint, is_test, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		account.UserID, account.Name, account.APIKey, account.SecretKey, account.Passphrase,
+		account.APIKeyHint, account.IsTest, account.CreatedAt.UnixMilli(),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return ErrOKXAccountExists
+		}
+		return fmt.Errorf("创建OKX账户失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("创建OKX账户失败: %w", err)
+	}
+	account.ID = id
+	return nil
+}
+
+// List 用户的所有OKX账户，按创建顺序排列
+func (r *okxAccountRepository) List(userID int64) ([]*models.OKXAccount, error) {
+	return r.query(`SELECT `+okxAccountColumns+` FROM okx_accounts WHERE user_id = ? ORDER BY id`, userID)
+}
+
+// ListAll 所有用户的OKX账户，按创建顺序排列，用于后台任务
+func (r *okxAccountRepository) ListAll() ([]*models.OKXAccount, error) {
+	return r.query(`SELECT ` + okxAccountColumns + ` FROM okx_accounts ORDER BY id`)
+}
+
+// query 查询OKX账户列表
+func (r *okxAccountRepository) query(query string, args ...interface{}) ([]*models.OKXAccount, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询OKX账户失败: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []*models.OKXAccount{}
+	for rows.Next() {
+		account, err := scanOKXAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询OKX账户失败: %w", err)
+	}
+	return accounts, nil
+}
+
+// Get 查询用户的指定OKX账户，账户属于其他用户时同样返回 ErrOKXAccountNotFound
+func (r *okxAccountRepository) Get(userID, id int64) (*models.OKXAccount, error) {
+	row := r.db.QueryRow(`SELECT `+okxAccountColumns+` FROM okx_accounts WHERE user_id = ? AND id = ?`, userID, id)
+	account, err := scanOKXAccount(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOKXAccountNotFound
+	}
+	return account, err
+}
+
+// Delete 删除用户的指定OKX账户
+func (r *okxAccountRepository) Delete(userID, id int64) error {
+	result, err := r.db.Exec(`DELETE FROM okx_accounts WHERE user_id = ? AND id = ?`, userID, id)
+	if err != nil {
+		return fmt.Errorf("删除OKX账户失败: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("删除OKX账户失败: %w", err)
+	}
+	if affected == 0 {
+		return ErrOKXAccountNotFound
+	}
+	return nil
+}
+
+// rowScanner *sql.Row 和 *sql.Rows 的公共接口
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanOKXAccount 读取一行OKX账户记录
+func scanOKXAccount(row rowScanner) (*models.OKXAccount, error) {
+	var (
+		account   models.OKXAccount
+		createdAt int64
+	)
+
+	err := row.Scan(&account.ID, &account.UserID, &account.Name, &account.APIKey, &account.SecretKey,
+		&account.Passphrase, &account.APIKeyHint, &account.IsTest, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询OKX账户失败: %w", err)
+	}
+
+	account.CreatedAt = time.UnixMilli(createdAt)
+	return &account, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+)
+
+// ErrSettingsNotFound 用户尚未保存过设置
+var ErrSettingsNotFound = errors.New("用户设置不存在")
+
+// SettingsRepository 用户设置存储接口
+type SettingsRepository interface {
+	Get(userID int64) (*models.UserSettings, error)
+	Save(settings *models.UserSettings) error
+}
+
+// settingsRepository 基于SQLite的用户设置存储，收藏、布局和提醒偏好以JSON文本保存
+type settingsRepository struct {
+	db *sql.DB
+}
+
+// NewSettingsRepository 创建用户设置存储
+func NewSettingsRepository(db *sql.DB) SettingsRepository {
+	return &settingsRepository{db: db}
+}
+
+// Get 查询用户设置，未保存过时返回 ErrSettingsNotFound
+func (r *settingsRepository) Get(userID int64) (*models.UserSettings, error) {
+	var (
+		settings                  models.UserSettings
+		favorites, layout, alerts string
+		updatedAt                 int64
+	)
+
+	err := r.db.QueryRow(
+		`SELECT user_id, default_currency, favorite_instruments, dashboard_layout, alert_preferences, updated_at
+		FROM user_settings WHERE user_id = ?`, userID,
+	).Scan(&settings.UserID, &settings.DefaultCurrency, &favorites, &layout, &alerts, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSettingsNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询用户设置失败: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(favorites), &settings.FavoriteInstruments); err != nil {
+		return nil, fmt.Errorf("解析收藏交易对失败: %w", err)
+	}
+	settings.DashboardLayout = json.RawMessage(layout)
+	settings.AlertPreferences = json.RawMessage(alerts)
+	settings.UpdatedAt = time.UnixMilli(updatedAt)
+	return &settings, nil
+}
+
+// Save 保存用户设置，已存在时整体覆盖
+func (r *settingsRepository) Save(settings *models.UserSettings) error {
+	favorites, err := json.Marshal(settings.FavoriteInstruments)
+	if err != nil {
+		return fmt.Errorf("序列化收藏交易对失败: %w", err)
+	}
+
+	_, err = r.db.Exec(
+		`INSERT INTO user_settings (user_id, default_currency, favorite_instruments, dashboard_layout, alert_preferences, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			default_currency = excluded.default_currency,
+			favorite_instruments = excluded.favorite_instruments,
+			dashboard_layout = excluded.dashboard_layout,
+			alert_preferences = excluded.alert_preferences,
+			updated_at = excluded.updated_at`,
+		settings.UserID, settings.DefaultCurrency, string(favorites),
+		string(settings.DashboardLayout), string(settings.AlertPreferences), settings.UpdatedAt.UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("保存用户设置失败: %w", err)
+	}
+	return nil
+}
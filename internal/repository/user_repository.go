@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+)
+
+var (
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("用户不存在")
+	// ErrUserExists 用户名已被使用
+	ErrUserExists = errors.New("用户名已存在")
+	// ErrLastAdmin 不能将最后一个管理员降级
+	ErrLastAdmin = errors.New("至少需要保留一个管理员")
+	// ErrTokenRevoked 令牌此前已被注销
+	ErrTokenRevoked = errors.New("令牌已注销")
+)
+
+// UserRepository 用户和已注销令牌的存储接口
+type UserRepository interface {
+	Create(user *models.User) error
+	FindByUsername(username string) (*models.User, error)
+	FindByID(id int64) (*models.User, error)
+	List() ([]*models.User, error)
+	UpdateRole(id int64, role models.Role) error
+	Count() (int, error)
+	RevokeToken(jti string, expiresAt time.Time) error
+	IsTokenRevoked(jti string) (bool, error)
+}
+
+// userRepository 基于SQLite的用户存储
+type userRepository struct {
+	db *sql.DB
+}
+
+// NewUserRepository 创建用户存储
+func NewUserRepository(db *sql.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+// Create 创建用户，成功后回填用户ID
+func (r *userRepository) Create(user *models.User) error {
+	result, err := r.db.Exec(
+		`INSERT INTO users (username, role, password_hash, created_at) VALUES (?, ?, ?, ?)`,
+		user.Username, user.Role, user.PasswordHash, user.CreatedAt.UnixMilli(),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return ErrUserExists
+		}
+		return fmt.Errorf("创建用户失败: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("创建用户失败: %w", err)
+	}
+	user.ID = id
+	return nil
+}
+
+// userColumns 查询用户的列，顺序与 scanUser 一致
+const userColumns = `id, username, role, password_hash, created_at`
+
+// FindByUsername 按用户名查询用户
+func (r *userRepository) FindByUsername(username string) (*models.User, error) {
+	return r.queryOne(`SELECT `+userColumns+` FROM users WHERE username = ?`, username)
+}
+
+// FindByID 按用户ID查询用户
+func (r *userRepository) FindByID(id int64) (*models.User, error) {
+	return r.queryOne(`SELECT `+userColumns+` FROM users WHERE id = ?`, id)
+}
+
+// List 所有用户，按创建顺序排列
+func (r *userRepository) List() ([]*models.User, error) {
+	rows, err := r.db.Query(`SELECT ` + userColumns + ` FROM users ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	return users, nil
+}
+
+// UpdateRole 修改用户角色，降级最后一个管理员时返回 ErrLastAdmin
+// 管理员数量的检查和修改在同一条语句中完成，并发降级不会使管理员数量降为零
+func (r *userRepository) UpdateRole(id int64, role models.Role) error {
+	result, err := r.db.Exec(
+		`UPDATE users SET role = ? WHERE id = ?
+			AND (? = ? OR role <> ? OR (SELECT COUNT(*) FROM users WHERE role = ?) > 1)`,
+		role, id, role, models.RoleAdmin, models.RoleAdmin, models.RoleAdmin,
+	)
+	if err != nil {
+		return fmt.Errorf("修改用户角色失败: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("修改用户角色失败: %w", err)
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	if _, err := r.FindByID(id); err != nil {
+		return err
+	}
+	return ErrLastAdmin
+}
+
+// Count 用户数量
+func (r *userRepository) Count() (int, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("查询用户数量失败: %w", err)
+	}
+	return count, nil
+}
+
+// RevokeToken 注销令牌，记录保留到令牌过期，同时清理已过期的记录
+// 令牌已被注销时返回 ErrTokenRevoked，并发注销同一令牌只有一次成功
+func (r *userRepository) RevokeToken(jti string, expiresAt time.Time) error {
+	if _, err := r.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at < ?`, time.Now().UnixMilli()); err != nil {
+		return fmt.Errorf("清理已注销令牌失败: %w", err)
+	}
+
+	_, err := r.db.Exec(
+		`INSERT INTO revoked_tokens (jti, expires_at) VALUES (?, ?)`,
+		jti, expiresAt.UnixMilli(),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") || strings.Contains(err.Error(), "PRIMARY KEY") {
+			return ErrTokenRevoked
+		}
+		return fmt.Errorf("注销令牌失败: %w", err)
+	}
+	return nil
+}
+
+// IsTokenRevoked 令牌是否已注销
+func (r *userRepository) IsTokenRevoked(jti string) (bool, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM revoked_tokens WHERE jti = ?`, jti).Scan(&count); err != nil {
+		return false, fmt.Errorf("查询已注销令牌失败: %w", err)
+	}
+	return count > 0, nil
+}
+
+// queryOne 查询单个用户，没有记录时返回 ErrUserNotFound
+func (r *userRepository) queryOne(query string, arg interface{}) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRow(query, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// scanUser 读取一行用户记录
+func scanUser(row rowScanner) (*models.User, error) {
+	var (
+		user      models.User
+		createdAt int64
+	)
+
+	err := row.Scan(&user.ID, &user.Username, &user.Role, &user.PasswordHash, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+
+	user.CreatedAt = time.UnixMilli(createdAt)
+	return &user, nil
+}
@@ -0,0 +1,384 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+)
+
+// AccountStream 私有WebSocket账户状态服务接口
+type AccountStream interface {
+	Start()
+	Stop()
+	Balance() (*OKXBalanceData, bool)
+	Positions() ([]OKXPositionData, bool)
+	Orders() []models.Order
+	Snapshot() *models.AccountStreamMessage
+	AddListener(listener func(*models.AccountStreamMessage)) (remove func())
+}
+
+// AccountStreamState 账户状态快照
+type AccountStreamState struct {
+	Balance        *OKXBalanceData   `json:"balance"`
+	Positions      []OKXPositionData `json:"positions"`
+	Orders         []models.Order    `json:"orders"`
+	BalanceReady   bool              `json:"balanceReady"`
+	PositionsReady bool              `json:"positionsReady"`
+}
+
+// 私有频道订阅参数
+var (
+	accountChannel            = okx.WSArg{Channel: "account"}
+	positionsChannel          = okx.WSArg{Channel: "positions", InstType: "ANY"}
+	ordersChannel             = okx.WSArg{Channel: "orders", InstType: "ANY"}
+	balanceAndPositionChannel = okx.WSArg{Channel: "balance_and_position"}
+)
+
+// accountStream 账户状态服务实现
+type accountStream struct {
+	client *okx.WSClient
+
+	mutex          sync.RWMutex
+	balance        *OKXBalanceData
+	positions      map[string]OKXPositionData // posId -> 持仓
+	pendingPage    map[string]OKXPositionData // 分页快照接收中的持仓
+	orders         map[string]models.Order    // ordId -> 未完成订单
+	balanceReady   bool
+	positionsReady bool
+
+	listenerMutex sync.RWMutex
+	listeners     map[int]func(*models.AccountStreamMessage)
+	nextListener  int
+}
+
+// NewAccountStream 创建私有WebSocket账户状态服务，登录签名与REST接口相同
+// 模拟盘模式下使用模拟盘凭证登录，WSPrivateURL需指向模拟盘地址
+func NewAccountStream(cfg *config.OKXConfig) AccountStream {
+	client := okx.NewWSClient(cfg.WSPrivateURL)
+	client.Login = func() interface{} {
+		apiKey, secretKey, passphrase := cfg.Keys()
+		return okx.NewLoginRequest(apiKey, secretKey, passphrase, time.Now())
+	}
+
+	stream := &accountStream{
+		client:    client,
+		positions: make(map[string]OKXPositionData),
+		orders:    make(map[string]models.Order),
+		listeners: make(map[int]func(*models.AccountStreamMessage)),
+	}
+
+	// 断线期间的变化无法补齐，断线后回退到REST接口直到收到新快照
+	client.OnDisconnect = stream.reset
+
+	client.Subscribe(accountChannel, stream.handleAccount)
+	client.Subscribe(positionsChannel, stream.handlePositions)
+	client.Subscribe(ordersChannel, stream.handleOrders)
+	client.Subscribe(balanceAndPositionChannel, stream.handleBalanceAndPosition)
+
+	return stream
+}
+
+// Start 建立连接并订阅私有频道
+func (s *accountStream) Start() {
+	s.client.Start()
+}
+
+// Stop 关闭连接
+func (s *accountStream) Stop() {
+	s.client.Stop()
+	s.reset()
+}
+
+// Balance 获取内存中的账户余额，未收到快照时返回false
+func (s *accountStream) Balance() (*OKXBalanceData, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if !s.balanceReady || s.balance == nil {
+		return nil, false
+	}
+	return copyBalance(s.balance), true
+}
+
+// Positions 获取内存中的持仓，未收到快照时返回false
+func (s *accountStream) Positions() ([]OKXPositionData, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if !s.positionsReady {
+		return nil, false
+	}
+	return s.sortedPositions(), true
+}
+
+// Orders 获取连接建立后有变化且仍未完成的订单
+func (s *accountStream) Orders() []models.Order {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.sortedOrders()
+}
+
+// Snapshot 获取完整账户状态
+func (s *accountStream) Snapshot() *models.AccountStreamMessage {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	state := &AccountStreamState{
+		Positions:      s.sortedPositions(),
+		Orders:         s.sortedOrders(),
+		BalanceReady:   s.balanceReady,
+		PositionsReady: s.positionsReady,
+	}
+	if s.balance != nil {
+		state.Balance = copyBalance(s.balance)
+	}
+
+	return &models.AccountStreamMessage{
+		Type:      "snapshot",
+		Data:      state,
+		Timestamp: time.Now().UnixMilli(),
+	}
+}
+
+// AddListener 注册账户变化监听，返回取消函数
+func (s *accountStream) AddListener(listener func(*models.AccountStreamMessage)) func() {
+	s.listenerMutex.Lock()
+	defer s.listenerMutex.Unlock()
+
+	id := s.nextListener
+	s.nextListener++
+	s.listeners[id] = listener
+
+	return func() {
+		s.listenerMutex.Lock()
+		defer s.listenerMutex.Unlock()
+		delete(s.listeners, id)
+	}
+}
+
+// handleAccount 处理account频道：快照整体替换，事件推送按币种合并
+func (s *accountStream) handleAccount(push *okx.WSPush) {
+	var data []OKXBalanceData
+	if err := json.Unmarshal(push.Data, &data); err != nil {
+		log.Printf("解析账户推送失败: %v", err)
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+
+	s.mutex.Lock()
+	update := data[0]
+	if s.balance == nil || push.EventType != "event_update" {
+		s.balance = &update
+	} else {
+		s.balance.TotalEq = update.TotalEq
+		s.balance.UTime = update.UTime
+		for _, detail := range update.Details {
+			s.balance.Details = mergeBalanceDetail(s.balance.Details, detail)
+		}
+	}
+	s.balanceReady = true
+	s.mutex.Unlock()
+
+	s.notify(push)
+}
+
+// handlePositions 处理positions频道：快照（可能分页）整体替换，事件推送按持仓ID合并
+func (s *accountStream) handlePositions(push *okx.WSPush) {
+	var data []OKXPositionData
+	if err := json.Unmarshal(push.Data, &data); err != nil {
+		log.Printf("解析持仓推送失败: %v", err)
+		return
+	}
+
+	s.mutex.Lock()
+	if push.EventType == "event_update" {
+		for _, pos := range data {
+			if isClosedPosition(pos.Pos) {
+				delete(s.positions, pos.PosId)
+			} else {
+				s.positions[pos.PosId] = pos
+			}
+		}
+	} else {
+		if push.CurPage <= 1 || s.pendingPage == nil {
+			s.pendingPage = make(map[string]OKXPositionData)
+		}
+		for _, pos := range data {
+			if !isClosedPosition(pos.Pos) {
+				s.pendingPage[pos.PosId] = pos
+			}
+		}
+		if push.LastPage == nil || *push.LastPage {
+			s.positions = s.pendingPage
+			s.pendingPage = nil
+			s.positionsReady = true
+		}
+	}
+	s.mutex.Unlock()
+
+	s.notify(push)
+}
+
+// handleOrders 处理orders频道，终态订单从内存中移除
+func (s *accountStream) handleOrders(push *okx.WSPush) {
+	var data []models.Order
+	if err := json.Unmarshal(push.Data, &data); err != nil {
+		log.Printf("解析订单推送失败: %v", err)
+		return
+	}
+
+	s.mutex.Lock()
+	for _, order := range data {
+		switch order.State {
+		case "live", "partially_filled":
+			s.orders[order.OrdId] = order
+		default:
+			delete(s.orders, order.OrdId)
+		}
+	}
+	s.mutex.Unlock()
+
+	s.notify(push)
+}
+
+// handleBalanceAndPosition 处理balance_and_position频道，只更新推送中包含的字段
+func (s *accountStream) handleBalanceAndPosition(push *okx.WSPush) {
+	var data []struct {
+		PTime     string             `json:"pTime"`
+		EventType string             `json:"eventType"`
+		BalData   []OKXBalanceDetail `json:"balData"`
+		PosData   []OKXPositionData  `json:"posData"`
+	}
+	if err := json.Unmarshal(push.Data, &data); err != nil {
+		log.Printf("解析余额持仓推送失败: %v", err)
+		return
+	}
+
+	s.mutex.Lock()
+	for _, item := range data {
+		if s.balance != nil {
+			for _, bal := range item.BalData {
+				s.balance.Details = mergeCashBalance(s.balance.Details, bal)
+			}
+		}
+
+		// 持仓快照未就绪时只推送变化，等待positions频道快照
+		if !s.positionsReady {
+			continue
+		}
+		for _, pos := range item.PosData {
+			if isClosedPosition(pos.Pos) {
+				delete(s.positions, pos.PosId)
+				continue
+			}
+			current, exists := s.positions[pos.PosId]
+			if !exists {
+				s.positions[pos.PosId] = pos
+				continue
+			}
+			current.Pos = pos.Pos
+			current.AvgPx = pos.AvgPx
+			current.TradeId = pos.TradeId
+			current.UTime = pos.UTime
+			s.positions[pos.PosId] = current
+		}
+	}
+	s.mutex.Unlock()
+
+	s.notify(push)
+}
+
+// notify 向监听者推送变化
+func (s *accountStream) notify(push *okx.WSPush) {
+	message := &models.AccountStreamMessage{
+		Type:      "delta",
+		Channel:   push.Arg.Channel,
+		EventType: push.EventType,
+		Data:      push.Data,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	s.listenerMutex.RLock()
+	defer s.listenerMutex.RUnlock()
+
+	for _, listener := range s.listeners {
+		listener(message)
+	}
+}
+
+// reset 清空状态，等待重新连接后的快照
+func (s *accountStream) reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.balance = nil
+	s.positions = make(map[string]OKXPositionData)
+	s.pendingPage = nil
+	s.orders = make(map[string]models.Order)
+	s.balanceReady = false
+	s.positionsReady = false
+}
+
+// sortedPositions 按持仓ID排序返回持仓，调用方需持有锁
+func (s *accountStream) sortedPositions() []OKXPositionData {
+	positions := make([]OKXPositionData, 0, len(s.positions))
+	for _, pos := range s.positions {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].PosId < positions[j].PosId })
+	return positions
+}
+
+// sortedOrders 按订单ID排序返回订单，调用方需持有锁
+func (s *accountStream) sortedOrders() []models.Order {
+	orders := make([]models.Order, 0, len(s.orders))
+	for _, order := range s.orders {
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].OrdId < orders[j].OrdId })
+	return orders
+}
+
+// copyBalance 复制余额数据，避免调用方修改内存状态
+func copyBalance(balance *OKXBalanceData) *OKXBalanceData {
+	result := *balance
+	result.Details = append([]OKXBalanceDetail(nil), balance.Details...)
+	return &result
+}
+
+// mergeBalanceDetail 按币种替换或追加余额详情
+func mergeBalanceDetail(details []OKXBalanceDetail, detail OKXBalanceDetail) []OKXBalanceDetail {
+	for i := range details {
+		if details[i].Ccy == detail.Ccy {
+			details[i] = detail
+			return details
+		}
+	}
+	return append(details, detail)
+}
+
+// mergeCashBalance 更新币种现金余额
+func mergeCashBalance(details []OKXBalanceDetail, bal OKXBalanceDetail) []OKXBalanceDetail {
+	for i := range details {
+		if details[i].Ccy == bal.Ccy {
+			details[i].CashBal = bal.CashBal
+			details[i].UTime = bal.UTime
+			return details
+		}
+	}
+	return append(details, bal)
+}
+
+// isClosedPosition 持仓数量为0表示已平仓
+func isClosedPosition(pos string) bool {
+	return pos == "" || parseRiskFloat(pos) == 0
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+)
+
+// ErrArchiveUnavailable 未配置API密钥，无法从OKX同步归档
+var ErrArchiveUnavailable = errors.New("OKX API配置不完整，无法同步成交归档")
+
+const (
+	// archiveFetchLimit OKX成交明细和账单接口单次最多返回的条数
+	archiveFetchLimit = 100
+
+	// ArchiveSourceBills 账单流水的同步进度名称
+	ArchiveSourceBills = "bills"
+	// ArchiveSourcePositions 历史持仓的同步进度名称
+	ArchiveSourcePositions = "positions"
+	// archiveSourceFillsPrefix 成交明细按产品类型分别同步，进度名称为 fills:<instType>
+	archiveSourceFillsPrefix = "fills:"
+)
+
+// ArchiveService 本地成交归档服务接口
+// OKX接口只能查询最近3个月的成交明细和历史持仓，定期同步到本地数据库后可以长期查询
+type ArchiveService interface {
+	Start()
+	Stop()
+	SyncOnce(ctx context.Context) error
+	Fills(query *models.ArchiveQuery) ([]*models.Fill, error)
+	Bills(query *models.ArchiveQuery) ([]*models.Bill, error)
+	Positions(query *models.ArchiveQuery) ([]*models.PositionHistory, error)
+	Status() ([]*models.ArchiveCheckpoint, error)
+}
+
+// archiveService 增量同步OKX成交明细、账单和历史持仓
+// 每个数据来源从最新的数据向前翻页直到上一轮已同步的位置，每页写入后保存进度，中断后从进度继续
+type archiveService struct {
+	okxConfig *config.OKXConfig
+	instTypes []string
+	repo      repository.ArchiveRepository
+	rest      *okx.Client
+	interval  time.Duration
+
+	syncMutex sync.Mutex // 定期同步和手动同步不能同时进行
+	stopChan  chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewArchiveService 创建本地成交归档服务
+func NewArchiveService(okxCfg *config.OKXConfig, archiveCfg *config.ArchiveConfig, repo repository.ArchiveRepository) ArchiveService {
+	interval := time.Duration(archiveCfg.SyncInterval) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	return &archiveService{
+		okxConfig: okxCfg,
+		instTypes: archiveCfg.InstTypes,
+		repo:      repo,
+		rest:      NewOKXTransport(okxCfg),
+		interval:  interval,
+		stopChan:  make(chan struct{}),
+	}
+}
+
+// Start 启动后台同步，启动时立即同步一次
+func (s *archiveService) Start() {
+	go func() {
+		s.tick()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.tick()
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台同步
+func (s *archiveService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+}
+
+// tick 同步一次，单次同步不超过同步间隔，未完成的部分下次继续
+func (s *archiveService) tick() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+	defer cancel()
+
+	if err := s.SyncOnce(ctx); err != nil {
+		log.Printf("同步成交归档失败: %v", err)
+	}
+}
+
+// SyncOnce 同步所有数据来源，某个来源失败时继续同步其他来源
+func (s *archiveService) SyncOnce(ctx context.Context) error {
+	if !s.okxConfig.HasKeys() {
+		return ErrArchiveUnavailable
+	}
+
+	s.syncMutex.Lock()
+	defer s.syncMutex.Unlock()
+
+	var errs []error
+	for _, instType := range s.instTypes {
+		err := s.syncSource(archiveSourceFillsPrefix+instType, func(checkpoint *models.ArchiveCheckpoint) error {
+			return syncByID(ctx, s, checkpoint, "/api/v5/trade/fills-history", url.Values{"instType": {instType}},
+				func(fill *models.Fill) string { return fill.BillId }, s.repo.SaveFills)
+		})
+		errs = append(errs, err)
+	}
+
+	errs = append(errs, s.syncSource(ArchiveSourceBills, func(checkpoint *models.ArchiveCheckpoint) error {
+		return syncByID(ctx, s, checkpoint, "/api/v5/account/bills-archive", url.Values{},
+			func(bill *models.Bill) string { return bill.BillId }, s.repo.SaveBills)
+	}))
+
+	errs = append(errs, s.syncSource(ArchiveSourcePositions, func(checkpoint *models.ArchiveCheckpoint) error {
+		return s.syncPositions(ctx, checkpoint)
+	}))
+
+	return errors.Join(errs...)
+}
+
+// syncSource 读取数据来源的进度并同步，记录本次同步的错误
+func (s *archiveService) syncSource(source string, run func(checkpoint *models.ArchiveCheckpoint) error) error {
+	checkpoint, err := s.repo.Checkpoint(source)
+	if err != nil {
+		return fmt.Errorf("同步%s失败: %w", source, err)
+	}
+
+	syncErr := run(checkpoint)
+	checkpoint.LastError = ""
+	if syncErr != nil {
+		checkpoint.LastError = syncErr.Error()
+	}
+	if err := s.saveProgress(checkpoint); err != nil && syncErr == nil {
+		syncErr = err
+	}
+
+	if syncErr != nil {
+		return fmt.Errorf("同步%s失败: %w", source, syncErr)
+	}
+	return nil
+}
+
+// saveProgress 保存同步进度
+func (s *archiveService) saveProgress(checkpoint *models.ArchiveCheckpoint) error {
+	checkpoint.UpdatedAt = time.Now()
+	return s.repo.SaveCheckpoint(checkpoint)
+}
+
+// syncByID 同步按ID从新到旧分页的数据（成交明细、账单），ID随时间递增
+// after 翻到更早的数据，before 只请求比 Floor 更新的数据；Top 在本轮第一页时记录，本轮完成后成为 Floor
+func syncByID[T any](ctx context.Context, s *archiveService, checkpoint *models.ArchiveCheckpoint, path string, filter url.Values, id func(*T) string, save func([]*T) error) error {
+	for {
+		query := url.Values{"limit": {strconv.Itoa(archiveFetchLimit)}}
+		for key, values := range filter {
+			query[key] = values
+		}
+		if checkpoint.Cursor != "" {
+			query.Set("after", checkpoint.Cursor)
+		}
+		if checkpoint.Floor != "" {
+			query.Set("before", checkpoint.Floor)
+		}
+
+		page, err := okx.Call[[]T](ctx, s.rest, okx.Request{Path: path, Query: query, Signed: true})
+		if err != nil {
+			return err
+		}
+
+		if checkpoint.Cursor == "" && len(page) > 0 {
+			checkpoint.Top = id(&page[0])
+		}
+		records := make([]*T, 0, len(page))
+		for i := range page {
+			if archiveIDAfter(id(&page[i]), checkpoint.Floor) {
+				records = append(records, &page[i])
+			}
+		}
+		if err := save(records); err != nil {
+			return err
+		}
+		checkpoint.Synced += int64(len(records))
+
+		// 不足一页或已翻到上一轮同步的位置时本轮完成
+		if len(page) < archiveFetchLimit || len(records) < len(page) {
+			if archiveIDAfter(checkpoint.Top, checkpoint.Floor) {
+				checkpoint.Floor = checkpoint.Top
+			}
+			checkpoint.Top, checkpoint.Cursor = "", ""
+			return nil
+		}
+
+		checkpoint.Cursor = id(&page[len(page)-1])
+		if err := s.saveProgress(checkpoint); err != nil {
+			return err
+		}
+	}
+}
+
+// syncPositions 同步历史持仓，按更新时间从新到旧遍历到 Floor（含）
+// 同一毫秒可能有多条持仓，Floor 时间点上的持仓会重新写入一次，按 posId+uTime 覆盖不会重复
+func (s *archiveService) syncPositions(ctx context.Context, checkpoint *models.ArchiveCheckpoint) error {
+	from, _ := strconv.ParseInt(checkpoint.Floor, 10, 64)
+	walker, err := newPositionsHistoryWalker(s.fetchPositionsHistory, &models.PositionsHistoryRequest{
+		Cursor: checkpoint.Cursor,
+		From:   from,
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := make([]*models.PositionHistory, 0, positionsHistoryFetchLimit)
+	for {
+		pos, ok, err := walker.next(ctx)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if checkpoint.Top == "" {
+				checkpoint.Top = pos.UTime
+			}
+			batch = append(batch, toPositionHistory(pos, models.Currency(pos.Ccy)))
+		}
+
+		if len(batch) >= positionsHistoryFetchLimit || (!ok && len(batch) > 0) {
+			if err := s.repo.SavePositions(batch); err != nil {
+				return err
+			}
+			checkpoint.Synced += int64(len(batch))
+			checkpoint.Cursor = encodePositionsHistoryCursor(walker.cursor)
+			if err := s.saveProgress(checkpoint); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+
+		if !ok {
+			if archiveIDAfter(checkpoint.Top, checkpoint.Floor) {
+				checkpoint.Floor = checkpoint.Top
+			}
+			checkpoint.Top, checkpoint.Cursor = "", ""
+			return nil
+		}
+	}
+}
+
+// fetchPositionsHistory 请求一页历史持仓
+func (s *archiveService) fetchPositionsHistory(ctx context.Context, req *models.PositionsHistoryRequest) ([]OKXPositionHistoryData, error) {
+	response, err := fetchOKXPositionsHistory(ctx, s.rest, req)
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// archiveIDAfter 数字ID或时间戳 id 是否比 floor 更新，floor 为空时总是更新
+func archiveIDAfter(id, floor string) bool {
+	if floor == "" {
+		return id != ""
+	}
+	if len(id) != len(floor) {
+		return len(id) > len(floor)
+	}
+	return id > floor
+}
+
+// Fills 查询本地归档的成交明细
+func (s *archiveService) Fills(query *models.ArchiveQuery) ([]*models.Fill, error) {
+	return s.repo.Fills(query)
+}
+
+// Bills 查询本地归档的账单流水
+func (s *archiveService) Bills(query *models.ArchiveQuery) ([]*models.Bill, error) {
+	return s.repo.Bills(query)
+}
+
+// Positions 查询本地归档的历史持仓
+func (s *archiveService) Positions(query *models.ArchiveQuery) ([]*models.PositionHistory, error) {
+	return s.repo.Positions(query)
+}
+
+// Status 各数据来源的同步进度
+func (s *archiveService) Status() ([]*models.ArchiveCheckpoint, error) {
+	return s.repo.Checkpoints()
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/jwt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minPasswordLength 密码最小长度
+const minPasswordLength = 8
+
+var (
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	// ErrUnauthorized 令牌无效、过期或已注销
+	ErrUnauthorized = errors.New("未登录或登录已失效")
+	// ErrInvalidUser 用户名为空或密码过短
+	ErrInvalidUser = errors.New("用户名或密码不符合要求")
+	// ErrInvalidRole 未知的用户角色
+	ErrInvalidRole = errors.New("无效的角色，可选值为 viewer / operator / admin")
+	// ErrLastAdmin 不能将最后一个管理员降级
+	ErrLastAdmin = repository.ErrLastAdmin
+)
+
+// dummyPasswordHash 用户不存在时参与比较的哈希，使响应时间与密码错误时一致，避免枚举用户名
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// AuthService 用户认证服务接口
+type AuthService interface {
+	CreateUser(ctx context.Context, username, password string, role models.Role) (*models.User, error)
+	ListUsers(ctx context.Context) ([]*models.User, error)
+	SetUserRole(ctx context.Context, id int64, role models.Role) (*models.User, error)
+	Bootstrap(ctx context.Context, username, password string) error
+	Login(ctx context.Context, username, password string) (*models.TokenResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (*models.TokenResponse, error)
+	Logout(ctx context.Context, claims *jwt.Claims, refreshToken string) error
+	Authenticate(ctx context.Context, accessToken string) (*jwt.Claims, error)
+}
+
+// authService 用户认证服务实现，使用HS256签名的访问令牌和刷新令牌
+type authService struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	users      repository.UserRepository
+}
+
+// NewAuthService 创建用户认证服务，令牌使用 secret 签名
+func NewAuthService(secret string, cfg *config.AuthConfig, users repository.UserRepository) AuthService {
+	accessTTL := time.Duration(cfg.AccessTokenTTL) * time.Minute
+	if accessTTL <= 0 {
+		accessTTL = 15 * time.Minute
+	}
+	refreshTTL := time.Duration(cfg.RefreshTokenTTL) * time.Minute
+	if refreshTTL <= 0 {
+		refreshTTL = 7 * 24 * time.Hour
+	}
+
+	return &authService{
+		secret:     []byte(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		users:      users,
+	}
+}
+
+// CreateUser 创建用户，密码使用bcrypt哈希后保存
+func (s *authService) CreateUser(ctx context.Context, username, password string, role models.Role) (*models.User, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, fmt.Errorf("%w: 用户名不能为空", ErrInvalidUser)
+	}
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("%w: 密码长度不能少于%d位", ErrInvalidUser, minPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("密码哈希失败: %w", err)
+	}
+
+	user := &models.User{Username: username, Role: role, PasswordHash: string(hash), CreatedAt: time.Now()}
+	if err := s.users.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListUsers 所有用户
+func (s *authService) ListUsers(ctx context.Context) ([]*models.User, error) {
+	return s.users.List()
+}
+
+// SetUserRole 修改用户角色，立即对该用户已签发的令牌生效
+func (s *authService) SetUserRole(ctx context.Context, id int64, role models.Role) (*models.User, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+
+	if err := s.users.UpdateRole(id, role); err != nil {
+		return nil, err
+	}
+	return s.users.FindByID(id)
+}
+
+// Bootstrap 没有任何用户时创建初始管理员，用户名或密码为空时跳过
+func (s *authService) Bootstrap(ctx context.Context, username, password string) error {
+	if username == "" || password == "" {
+		return nil
+	}
+
+	count, err := s.users.Count()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err = s.CreateUser(ctx, username, password, models.RoleAdmin)
+	return err
+}
+
+// Login 校验用户名和密码，签发访问令牌和刷新令牌
+func (s *authService) Login(ctx context.Context, username, password string) (*models.TokenResponse, error) {
+	user, err := s.users.FindByUsername(strings.TrimSpace(username))
+	if errors.Is(err, repository.ErrUserNotFound) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return s.issueTokens(user)
+}
+
+// Refresh 使用刷新令牌换取新的令牌，旧的刷新令牌随即失效
+// 注销旧令牌成功后才签发新令牌，同一刷新令牌并发使用时只有一次成功
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*models.TokenResponse, error) {
+	claims, err := s.parse(refreshToken, jwt.TypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userOf(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.users.RevokeToken(claims.ID, claims.ExpiresTime())
+	if errors.Is(err, repository.ErrTokenRevoked) {
+		return nil, fmt.Errorf("%w: 令牌已注销", ErrUnauthorized)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user)
+}
+
+// Logout 注销当前访问令牌，refreshToken 不为空时一并注销
+func (s *authService) Logout(ctx context.Context, claims *jwt.Claims, refreshToken string) error {
+	if refreshToken != "" {
+		refreshClaims, err := s.parse(refreshToken, jwt.TypeRefresh)
+		if err != nil {
+			return err
+		}
+		if refreshClaims.Subject != claims.Subject {
+			return ErrUnauthorized
+		}
+		if err := s.revoke(refreshClaims); err != nil {
+			return err
+		}
+	}
+
+	return s.revoke(claims)
+}
+
+// revoke 注销令牌，令牌已被注销时视为成功
+func (s *authService) revoke(claims *jwt.Claims) error {
+	err := s.users.RevokeToken(claims.ID, claims.ExpiresTime())
+	if errors.Is(err, repository.ErrTokenRevoked) {
+		return nil
+	}
+	return err
+}
+
+// Authenticate 校验访问令牌，返回令牌载荷
+// 角色以数据库中的当前值为准，修改角色或删除用户后无需等待令牌过期
+func (s *authService) Authenticate(ctx context.Context, accessToken string) (*jwt.Claims, error) {
+	claims, err := s.parse(accessToken, jwt.TypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userOf(claims)
+	if err != nil {
+		return nil, err
+	}
+	claims.Role = string(user.Role)
+	return claims, nil
+}
+
+// parse 校验令牌签名、有效期、类型和注销状态
+func (s *authService) parse(token, tokenType string) (*jwt.Claims, error) {
+	claims, err := jwt.Parse(token, s.secret, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+	if claims.Type != tokenType {
+		return nil, fmt.Errorf("%w: 令牌类型错误", ErrUnauthorized)
+	}
+
+	revoked, err := s.users.IsTokenRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, fmt.Errorf("%w: 令牌已注销", ErrUnauthorized)
+	}
+	return claims, nil
+}
+
+// userOf 令牌对应的用户，用户已被删除时令牌无效
+func (s *authService) userOf(claims *jwt.Claims) (*models.User, error) {
+	id, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	user, err := s.users.FindByID(id)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrUnauthorized
+	}
+	return user, err
+}
+
+// issueTokens 签发一对访问令牌和刷新令牌
+func (s *authService) issueTokens(user *models.User) (*models.TokenResponse, error) {
+	now := time.Now()
+	access, accessExp, err := s.sign(user, jwt.TypeAccess, now, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, refreshExp, err := s.sign(user, jwt.TypeRefresh, now, s.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		TokenType:        "Bearer",
+		ExpiresAt:        accessExp,
+		RefreshExpiresAt: refreshExp,
+		User:             user,
+	}, nil
+}
+
+func (s *authService) sign(user *models.User, tokenType string, now time.Time, ttl time.Duration) (string, time.Time, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", time.Time{}, fmt.Errorf("生成令牌ID失败: %w", err)
+	}
+
+	expiresAt := now.Add(ttl)
+	token, err := jwt.Sign(&jwt.Claims{
+		ID:        hex.EncodeToString(id),
+		Subject:   strconv.FormatInt(user.ID, 10),
+		Username:  user.Username,
+		Role:      string(user.Role),
+		Type:      tokenType,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	}, s.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, time.Unix(expiresAt.Unix(), 0), nil
+}
@@ -0,0 +1,413 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+)
+
+// K线查询参数
+const (
+	DefaultCandleLimit = 100 // 默认返回数量
+	MaxCandleLimit     = 300 // 最大返回数量
+
+	maxCandleSourceBars    = 1440 // 聚合时单次最多使用的源K线数量
+	recentCandleBars       = 1440 // /market/candles 可查询的最近K线数量，更早的K线使用 /market/history-candles
+	candlesPageSize        = 300  // /market/candles 单次最多返回数量
+	historyCandlesPageSize = 100  // /market/history-candles 单次最多返回数量
+)
+
+// candleAlignOffset OKX的6H、12H、1D等K线按香港时间（UTC+8）对齐
+const candleAlignOffset = 8 * time.Hour
+
+// candleNativeBars OKX直接提供的K线周期，按周期从大到小排列
+var candleNativeBars = []string{"1D", "12H", "6H", "4H", "2H", "1H", "30m", "15m", "5m", "3m", "1m"}
+
+// candleBarPattern K线周期格式：数字 + 单位（m 分钟、H 小时、D 天）
+var candleBarPattern = regexp.MustCompile(`^([1-9][0-9]*)([mHD])$`)
+
+// candleBar K线周期及其数据来源
+type candleBar struct {
+	name     string
+	duration int64  // 周期（毫秒）
+	source   string // 获取数据使用的OKX原生周期
+	factor   int64  // 每根K线包含的源K线数量
+}
+
+// CandleService K线服务接口
+type CandleService interface {
+	GetCandles(ctx context.Context, req *models.CandlesRequest) (*models.CandlesResponse, error)
+}
+
+// candleService K线服务实现，已完结的K线缓存在本地，只向OKX请求缓存中缺失的部分
+type candleService struct {
+	rest *okx.Client
+	repo repository.CandleRepository
+}
+
+// NewCandleService 创建K线服务，repo为nil时不缓存
+func NewCandleService(cfg *config.OKXConfig, repo repository.CandleRepository) CandleService {
+	return &candleService{
+		rest: NewOKXTransport(cfg),
+		repo: repo,
+	}
+}
+
+// ValidateCandleBar 校验K线周期
+func ValidateCandleBar(bar string) error {
+	_, err := parseCandleBar(bar)
+	return err
+}
+
+// parseCandleBar 解析K线周期，支持OKX原生周期及其整数倍（如 10m、8H、3D），非原生周期由可整除它的最大原生周期聚合
+func parseCandleBar(bar string) (*candleBar, error) {
+	duration, ok := candleBarDuration(bar)
+	if !ok {
+		return nil, fmt.Errorf("不支持的K线周期: %s", bar)
+	}
+
+	for _, source := range candleNativeBars {
+		sourceDuration, _ := candleBarDuration(source)
+		if duration%sourceDuration != 0 {
+			continue
+		}
+
+		factor := duration / sourceDuration
+		if factor > maxCandleSourceBars {
+			return nil, fmt.Errorf("K线周期过大: %s", bar)
+		}
+		return &candleBar{name: bar, duration: duration, source: source, factor: factor}, nil
+	}
+	return nil, fmt.Errorf("不支持的K线周期: %s", bar)
+}
+
+// candleBarDuration K线周期对应的毫秒数
+func candleBarDuration(bar string) (int64, bool) {
+	match := candleBarPattern.FindStringSubmatch(bar)
+	if match == nil {
+		return 0, false
+	}
+
+	n, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	unit := time.Minute
+	switch match[2] {
+	case "H":
+		unit = time.Hour
+	case "D":
+		unit = 24 * time.Hour
+	}
+	return n * unit.Milliseconds(), true
+}
+
+// GetCandles 获取K线，按时间升序返回，after/before 与OKX含义相同（早于/晚于该时间戳）
+func (s *candleService) GetCandles(ctx context.Context, req *models.CandlesRequest) (*models.CandlesResponse, error) {
+	bar, err := parseCandleBar(req.Bar)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultCandleLimit
+	}
+	if limit > MaxCandleLimit {
+		limit = MaxCandleLimit
+	}
+	if max := int(maxCandleSourceBars / bar.factor); limit > max {
+		limit = max
+	}
+
+	// 计算查询区间内每根K线的开始时间，默认包含未完结的最新K线
+	now := time.Now().UnixMilli()
+	end := now + 1
+	if req.After > 0 && req.After < end {
+		end = req.After
+	}
+	last := alignCandleTs(end-1, bar.duration)
+	first := last - int64(limit-1)*bar.duration
+	if req.Before > 0 {
+		if min := alignCandleTs(req.Before, bar.duration) + bar.duration; first < min {
+			first = min
+		}
+	}
+
+	response := &models.CandlesResponse{
+		InstId:     req.InstId,
+		Bar:        bar.name,
+		Candles:    []*models.Candle{},
+		Gaps:       []models.CandleGap{},
+		Aggregated: bar.factor > 1,
+		SourceBar:  bar.source,
+	}
+	if first > last {
+		return response, nil
+	}
+
+	sourceDuration := bar.duration / bar.factor
+	candles, err := s.loadCandles(ctx, req.InstId, bar.source, sourceDuration, first, last+bar.duration, now)
+	if err != nil {
+		return nil, err
+	}
+	if bar.factor > 1 {
+		candles = aggregateCandles(candles, bar.duration, now)
+	}
+
+	response.Candles = candles
+	response.Gaps = detectCandleGaps(candles, bar.duration)
+	return response, nil
+}
+
+// loadCandles 获取 [from, to) 区间内的原生周期K线，优先使用缓存，只向OKX请求缓存中缺失的区间
+func (s *candleService) loadCandles(ctx context.Context, instId, bar string, duration, from, to, now int64) ([]*models.Candle, error) {
+	byTs := make(map[int64]*models.Candle)
+	if s.repo != nil {
+		cached, err := s.repo.Range(instId, bar, from, to)
+		if err != nil {
+			log.Printf("读取K线缓存失败: %v", err)
+		}
+		for _, candle := range cached {
+			byTs[candle.Ts] = candle
+		}
+	}
+
+	// 缓存中缺失的最早和最晚K线，未完结的K线不缓存，总是重新获取
+	missingFrom, missingTo := int64(-1), int64(-1)
+	for ts := from; ts < to; ts += duration {
+		if _, exists := byTs[ts]; !exists {
+			if missingFrom < 0 {
+				missingFrom = ts
+			}
+			missingTo = ts + duration
+		}
+	}
+
+	if missingFrom >= 0 {
+		fetched, err := s.fetchCandles(ctx, instId, bar, duration, missingFrom, missingTo, now)
+		if err != nil {
+			return nil, err
+		}
+		if s.repo != nil {
+			if err := s.repo.Save(instId, bar, fetched); err != nil {
+				log.Printf("保存K线缓存失败: %v", err)
+			}
+		}
+		for _, candle := range fetched {
+			byTs[candle.Ts] = candle
+		}
+	}
+
+	candles := make([]*models.Candle, 0, len(byTs))
+	for ts, candle := range byTs {
+		if ts >= from && ts < to {
+			candles = append(candles, candle)
+		}
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Ts < candles[j].Ts })
+	return candles, nil
+}
+
+// fetchCandles 从OKX分页获取 [from, to) 区间内的K线
+// 最近的K线使用 /market/candles，更早的使用 /market/history-candles
+func (s *candleService) fetchCandles(ctx context.Context, instId, bar string, duration, from, to, now int64) ([]*models.Candle, error) {
+	var candles []*models.Candle
+
+	history := false
+	after := to
+	for after > from {
+		if after <= now-recentCandleBars*duration {
+			history = true
+		}
+
+		path, pageSize := "/api/v5/market/candles", candlesPageSize
+		if history {
+			path, pageSize = "/api/v5/market/history-candles", historyCandlesPageSize
+		}
+		// 只请求区间内需要的数量
+		if n := int((after - from + duration - 1) / duration); n < pageSize {
+			pageSize = n
+		}
+
+		rows, err := okx.Call[[][]string](ctx, s.rest, okx.Request{
+			Path: path,
+			Query: url.Values{
+				"instId": {instId},
+				"bar":    {bar},
+				"after":  {strconv.FormatInt(after, 10)},
+				"limit":  {strconv.Itoa(pageSize)},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("获取%s K线失败: %w", instId, err)
+		}
+
+		page := okx.ParseCandleRows(instId, bar, rows)
+		if len(page) == 0 {
+			if history {
+				break
+			}
+			// 超出最近K线范围，改用历史K线接口
+			history = true
+			continue
+		}
+
+		oldest := after
+		for i := range page {
+			candle, err := newCandle(&page[i])
+			if err != nil {
+				return nil, err
+			}
+			if candle.Ts < oldest {
+				oldest = candle.Ts
+			}
+			if candle.Ts >= from && candle.Ts < to {
+				candles = append(candles, candle)
+			}
+		}
+		if oldest >= after {
+			break
+		}
+		after = oldest
+	}
+
+	return candles, nil
+}
+
+// newCandle 转换OKX K线
+func newCandle(candle *okx.Candle) (*models.Candle, error) {
+	ts, err := strconv.ParseInt(candle.Ts, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("解析K线时间戳失败: %w", err)
+	}
+
+	return &models.Candle{
+		Ts:          ts,
+		Open:        candle.Open,
+		High:        candle.High,
+		Low:         candle.Low,
+		Close:       candle.Close,
+		Vol:         candle.Vol,
+		VolCcy:      candle.VolCcy,
+		VolCcyQuote: candle.VolCcyQuote,
+		Confirm:     candle.Confirm,
+	}, nil
+}
+
+// alignCandleTs 时间戳所在K线的开始时间
+func alignCandleTs(ts, duration int64) int64 {
+	offset := candleAlignOffset.Milliseconds()
+	return (ts+offset)/duration*duration - offset
+}
+
+// aggregateCandles 将按时间升序的小周期K线聚合为大周期K线，周期结束且最后一根源K线已完结时为已完结
+func aggregateCandles(source []*models.Candle, duration, now int64) []*models.Candle {
+	var candles []*models.Candle
+	var group []*models.Candle
+
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+
+		first, last := group[0], group[len(group)-1]
+		candle := &models.Candle{
+			Ts:      alignCandleTs(first.Ts, duration),
+			Open:    first.Open,
+			High:    first.High,
+			Low:     first.Low,
+			Close:   last.Close,
+			Confirm: last.Confirm,
+		}
+		var vol, volCcy, volCcyQuote []string
+		for _, c := range group {
+			if compareDecimals(c.High, candle.High) > 0 {
+				candle.High = c.High
+			}
+			if compareDecimals(c.Low, candle.Low) < 0 {
+				candle.Low = c.Low
+			}
+			vol = append(vol, c.Vol)
+			volCcy = append(volCcy, c.VolCcy)
+			volCcyQuote = append(volCcyQuote, c.VolCcyQuote)
+		}
+		candle.Vol = sumDecimals(vol)
+		candle.VolCcy = sumDecimals(volCcy)
+		candle.VolCcyQuote = sumDecimals(volCcyQuote)
+		if candle.Ts+duration > now {
+			candle.Confirm = false
+		}
+
+		candles = append(candles, candle)
+		group = nil
+	}
+
+	for _, c := range source {
+		if len(group) > 0 && alignCandleTs(c.Ts, duration) != alignCandleTs(group[0].Ts, duration) {
+			flush()
+		}
+		group = append(group, c)
+	}
+	flush()
+
+	if candles == nil {
+		candles = []*models.Candle{}
+	}
+	return candles
+}
+
+// detectCandleGaps 检测按时间升序的K线之间的缺口
+func detectCandleGaps(candles []*models.Candle, duration int64) []models.CandleGap {
+	gaps := []models.CandleGap{}
+	for i := 1; i < len(candles); i++ {
+		prev, next := candles[i-1].Ts, candles[i].Ts
+		if next-prev > duration {
+			gaps = append(gaps, models.CandleGap{
+				Start:   prev + duration,
+				End:     next,
+				Missing: int((next-prev)/duration - 1),
+			})
+		}
+	}
+	return gaps
+}
+
+// parseDecimal 解析十进制数，无效时返回0
+func parseDecimal(value string) *big.Rat {
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+// compareDecimals 精确比较两个十进制数
+func compareDecimals(a, b string) int {
+	return parseDecimal(a).Cmp(parseDecimal(b))
+}
+
+// sumDecimals 精确求和，结果保留输入中最多的小数位数
+func sumDecimals(values []string) string {
+	sum := new(big.Rat)
+	places := 0
+	for _, value := range values {
+		sum.Add(sum, parseDecimal(value))
+		if p := decimalPlaces(value); p > places {
+			places = p
+		}
+	}
+	return sum.FloatString(places)
+}
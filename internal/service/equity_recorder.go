@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+)
+
+// EquityRecorder 权益快照记录器接口
+type EquityRecorder interface {
+	Start()
+	Stop()
+	RecordOnce(ctx context.Context) error
+}
+
+// equityRecorder 定期记录各显示币种的总资产
+type equityRecorder struct {
+	accountService AccountService
+	repo           repository.EquityRepository
+	interval       time.Duration
+	stopChan       chan struct{}
+	stopOnce       sync.Once
+}
+
+// NewEquityRecorder 创建权益快照记录器
+func NewEquityRecorder(accountService AccountService, repo repository.EquityRepository, interval time.Duration) EquityRecorder {
+	return &equityRecorder{
+		accountService: accountService,
+		repo:           repo,
+		interval:       interval,
+		stopChan:       make(chan struct{}),
+	}
+}
+
+// Start 启动后台记录，启动时立即记录一次
+func (r *equityRecorder) Start() {
+	go func() {
+		r.tick()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.tick()
+			case <-r.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台记录
+func (r *equityRecorder) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+	})
+}
+
+// RecordOnce 记录一次所有显示币种的总资产
+// 账户余额只获取一次，再按各显示币种换算；某个币种换算失败时跳过该币种，不影响其他币种
+func (r *equityRecorder) RecordOnce(ctx context.Context) error {
+	now := time.Now()
+
+	balance, err := r.accountService.GetAccountBalance(ctx, models.CurrencyUSDT)
+	if err != nil {
+		return fmt.Errorf("获取账户余额失败: %w", err)
+	}
+
+	for _, currency := range r.accountService.SupportedCurrencies() {
+		totalEquity, err := r.accountService.ConvertCurrency(ctx, balance.TotalEquity, models.CurrencyUSDT, currency)
+		if err != nil {
+			log.Printf("总资产换算为%s失败，跳过该币种: %v", currency, err)
+			continue
+		}
+
+		snapshot := &models.EquitySnapshot{
+			Currency:    currency,
+			TotalEquity: totalEquity.String(),
+			RecordedAt:  now,
+		}
+		if err := r.repo.Save(snapshot); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tick 记录快照并压缩历史数据
+func (r *equityRecorder) tick() {
+	// 单次记录不超过记录间隔，避免请求堆积
+	ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+	defer cancel()
+
+	if err := r.RecordOnce(ctx); err != nil {
+		log.Printf("记录权益快照失败: %v", err)
+	}
+
+	if _, err := r.repo.Compact(time.Now()); err != nil {
+		log.Printf("压缩权益快照失败: %v", err)
+	}
+}
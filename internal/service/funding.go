@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+)
+
+const (
+	// DefaultFundingHistoryLimit 未指定 historyLimit 时每个合约返回的历史费率条数
+	DefaultFundingHistoryLimit = 24
+	// MaxFundingHistoryLimit OKX历史资金费率接口单次最多返回的条数
+	MaxFundingHistoryLimit = 100
+	// maxFundingBillPages 查询资金费结算时最多请求的账单页数，每页100条
+	maxFundingBillPages = 20
+	// defaultFundingInterval OKX未返回下下次结算时间时使用的结算周期
+	defaultFundingInterval = 8 * time.Hour
+	// fundingDecimals 除法得到的资金费金额和年化费率保留的小数位数
+	fundingDecimals = 8
+)
+
+// millisecondsPerYear 一年的毫秒数，用于按结算周期换算年化
+var millisecondsPerYear = decimal.New(int64(365 * 24 * time.Hour / time.Millisecond))
+
+// GetFunding 获取永续合约持仓的资金费：开仓以来的结算记录、当前和历史费率、下次结算的预计金额和年化成本
+func (s *accountService) GetFunding(ctx context.Context, req *models.FundingRequest, currency models.Currency) (*models.FundingResponse, error) {
+	historyLimit := DefaultFundingHistoryLimit
+	if req.HistoryLimit != nil {
+		historyLimit = min(max(*req.HistoryLimit, 0), MaxFundingHistoryLimit)
+	}
+
+	data, err := s.currentPositions(ctx, &models.PositionsRequest{InstType: "SWAP", InstId: req.InstId})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.FundingResponse{
+		Positions: []*models.PositionFunding{},
+		Currency:  currency,
+		Summary:   &models.FundingSummary{},
+	}
+
+	// 最早的开仓时间，只查询此后的资金费结算
+	var since int64
+	for _, pos := range data {
+		if parseAmount(pos.Pos).IsZero() {
+			continue
+		}
+		cTime, _ := strconv.ParseInt(pos.CTime, 10, 64)
+		if since == 0 || cTime < since {
+			since = cTime
+		}
+	}
+	if since == 0 {
+		return response, nil
+	}
+
+	settlements, err := s.fundingSettlements(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make(map[string]*models.FundingRate)
+	histories := make(map[string][]models.FundingRateRecord)
+	for _, pos := range data {
+		if parseAmount(pos.Pos).IsZero() {
+			continue
+		}
+
+		funding, err := s.positionFunding(ctx, &pos, rates, histories, historyLimit)
+		if err != nil {
+			return nil, err
+		}
+		response.Positions = append(response.Positions, funding)
+	}
+
+	assignFundingSettlements(response.Positions, data, settlements)
+
+	// 更新汇率，用于按显示币种汇总
+	if err := s.updateExchangeRates(ctx); err != nil {
+		log.Printf("更新汇率失败: %v", err)
+	}
+	decimals := s.displayDecimals(ctx, currency)
+	for _, funding := range response.Positions {
+		s.addToFundingSummary(response.Summary, funding, currency, decimals)
+	}
+
+	return response, nil
+}
+
+// positionFunding 计算单个持仓的资金费率、预计收付和年化成本，同一合约的费率只请求一次
+func (s *accountService) positionFunding(ctx context.Context, pos *OKXPositionData, rates map[string]*models.FundingRate, histories map[string][]models.FundingRateRecord, historyLimit int) (*models.PositionFunding, error) {
+	inst, err := s.instruments.Get(ctx, pos.InstId)
+	if err != nil {
+		return nil, fmt.Errorf("获取%s合约信息失败: %w", pos.InstId, err)
+	}
+
+	rate, exists := rates[pos.InstId]
+	if !exists {
+		rate, err = fetchFundingRate(ctx, s.rest, pos.InstId)
+		if err != nil {
+			return nil, fmt.Errorf("获取%s资金费率失败: %w", pos.InstId, err)
+		}
+		rates[pos.InstId] = rate
+	}
+
+	history, exists := histories[pos.InstId]
+	if !exists {
+		history = []models.FundingRateRecord{}
+		if historyLimit > 0 {
+			history, err = fetchFundingRateHistory(ctx, s.rest, pos.InstId, historyLimit)
+			if err != nil {
+				return nil, fmt.Errorf("获取%s历史资金费率失败: %w", pos.InstId, err)
+			}
+		}
+		histories[pos.InstId] = history
+	}
+
+	size := parseAmount(pos.Pos)
+	markPx := parseAmount(pos.MarkPx)
+	notional := fundingNotional(inst, size, markPx)
+
+	// 费率为正时多头支付、空头收取
+	direction := decimal.New(1)
+	if pos.PosSide == "short" || (pos.PosSide != "long" && size.Sign() < 0) {
+		direction = decimal.New(-1)
+	}
+	fundingRate := parseAmount(rate.FundingRate)
+	expected := notional.Mul(fundingRate).Mul(direction).Neg()
+
+	fundingTime, _ := strconv.ParseInt(rate.FundingTime, 10, 64)
+	nextFundingTime, _ := strconv.ParseInt(rate.NextFundingTime, 10, 64)
+	interval := nextFundingTime - fundingTime
+	if fundingTime <= 0 || interval <= 0 {
+		interval = defaultFundingInterval.Milliseconds()
+	}
+	periodsPerYear := millisecondsPerYear.Quo(decimal.New(interval))
+
+	funding := &models.PositionFunding{
+		InstId:          pos.InstId,
+		PosId:           pos.PosId,
+		MgnMode:         pos.MgnMode,
+		PosSide:         pos.PosSide,
+		Pos:             pos.Pos,
+		MarkPx:          pos.MarkPx,
+		SettleCcy:       inst.SettleCcy,
+		Notional:        notional,
+		FundingFee:      pos.FundingFee,
+		Accrued:         decimal.Zero,
+		Settlements:     []*models.FundingSettlement{},
+		FundingRate:     rate.FundingRate,
+		NextFundingRate: rate.NextFundingRate,
+		FundingInterval: interval,
+		ExpectedPayment: expected,
+		AnnualizedRate:  fundingRate.Mul(periodsPerYear).Round(fundingDecimals),
+		AnnualizedCost:  expected.Neg().Mul(periodsPerYear).Round(fundingDecimals),
+		RateHistory:     history,
+	}
+	if fundingTime > 0 {
+		funding.FundingTime = time.UnixMilli(fundingTime)
+	}
+	return funding, nil
+}
+
+// fundingNotional 按标记价格计算持仓价值（结算币种）
+// 正向合约为 张数 × 面值 × 乘数 × 标记价格，反向合约面值以计价货币表示，为 张数 × 面值 × 乘数 ÷ 标记价格
+func fundingNotional(inst *models.Instrument, size, markPx decimal.Decimal) decimal.Decimal {
+	if size.Sign() < 0 {
+		size = size.Neg()
+	}
+	contracts := size.Mul(parseAmount(inst.CtVal))
+	if ctMult := parseAmount(inst.CtMult); !ctMult.IsZero() {
+		contracts = contracts.Mul(ctMult)
+	}
+
+	if inst.CtType == "inverse" {
+		if markPx.IsZero() {
+			return decimal.Zero
+		}
+		return contracts.Quo(markPx).Round(fundingDecimals)
+	}
+	return contracts.Mul(markPx)
+}
+
+// assignFundingSettlements 将资金费结算归属到同一合约、同一保证金模式且在结算前开仓的持仓
+// 账单不区分持仓方向，双向持仓同时持有多空时归属到列表中的第一个持仓
+func assignFundingSettlements(fundings []*models.PositionFunding, data []OKXPositionData, settlements []models.Bill) {
+	cTimes := make(map[string]int64, len(data))
+	for _, pos := range data {
+		cTime, _ := strconv.ParseInt(pos.CTime, 10, 64)
+		cTimes[pos.PosId] = cTime
+	}
+
+	for _, bill := range settlements {
+		ts, _ := strconv.ParseInt(bill.Ts, 10, 64)
+		for _, funding := range fundings {
+			if funding.InstId != bill.InstId || funding.MgnMode != bill.MgnMode || ts < cTimes[funding.PosId] {
+				continue
+			}
+
+			amount := parseAmount(bill.BalChg)
+			funding.Settlements = append(funding.Settlements, &models.FundingSettlement{
+				BillId:  bill.BillId,
+				InstId:  bill.InstId,
+				MgnMode: bill.MgnMode,
+				Ccy:     bill.Ccy,
+				Amount:  amount,
+				Ts:      bill.Ts,
+			})
+			funding.Accrued = funding.Accrued.Add(amount)
+			break
+		}
+	}
+}
+
+// addToFundingSummary 将持仓的资金费按结算币种换算为显示币种后累加
+func (s *accountService) addToFundingSummary(summary *models.FundingSummary, funding *models.PositionFunding, currency models.Currency, decimals int) {
+	ccy := models.Currency(funding.SettleCcy)
+	values := []decimal.Decimal{funding.Accrued, funding.ExpectedPayment, funding.AnnualizedCost}
+	for i, value := range values {
+		converted, err := s.convertCurrency(value, ccy, currency, decimals)
+		if err != nil {
+			summary.Unconverted = append(summary.Unconverted, funding.PosId)
+			return
+		}
+		values[i] = converted
+	}
+
+	summary.Accrued = summary.Accrued.Add(values[0])
+	summary.ExpectedPayment = summary.ExpectedPayment.Add(values[1])
+	summary.AnnualizedCost = summary.AnnualizedCost.Add(values[2])
+}
+
+// fundingSettlements 获取 since 以来的永续合约资金费结算账单，从新到旧
+// OKX账单接口只能查询最近3个月，更早开仓的持仓只统计3个月内的结算；本地模拟交易没有资金费结算
+func (s *accountService) fundingSettlements(ctx context.Context, since int64) ([]models.Bill, error) {
+	if _, ok := s.accountStream.(positionsHistorySource); ok || !s.config.HasKeys() {
+		return nil, nil
+	}
+
+	var bills []models.Bill
+	after := ""
+	for page := 0; page < maxFundingBillPages; page++ {
+		query := url.Values{
+			"instType": {"SWAP"},
+			"type":     {models.BillTypeFunding},
+			"begin":    {strconv.FormatInt(since, 10)},
+			"limit":    {strconv.Itoa(archiveFetchLimit)},
+		}
+		if after != "" {
+			query.Set("after", after)
+		}
+
+		data, err := okx.Call[[]models.Bill](ctx, s.rest, okx.Request{
+			Path:   "/api/v5/account/bills-archive",
+			Query:  query,
+			Signed: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("获取资金费结算记录失败: %w", err)
+		}
+
+		bills = append(bills, data...)
+		if len(data) < archiveFetchLimit {
+			break
+		}
+		after = data[len(data)-1].BillId
+	}
+	return bills, nil
+}
+
+// fetchFundingRate 获取永续合约当前和预测资金费率
+func fetchFundingRate(ctx context.Context, rest *okx.Client, instId string) (*models.FundingRate, error) {
+	rates, err := okx.Call[[]models.FundingRate](ctx, rest, okx.Request{
+		Path:  "/api/v5/public/funding-rate",
+		Query: url.Values{"instId": {instId}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("未找到合约 %s 的资金费率", instId)
+	}
+
+	return &rates[0], nil
+}
+
+// fetchFundingRateHistory 获取永续合约最近 limit 期的历史资金费率，从新到旧
+func fetchFundingRateHistory(ctx context.Context, rest *okx.Client, instId string, limit int) ([]models.FundingRateRecord, error) {
+	history, err := okx.Call[[]models.FundingRateRecord](ctx, rest, okx.Request{
+		Path:  "/api/v5/public/funding-rate-history",
+		Query: url.Values{"instId": {instId}, "limit": {strconv.Itoa(limit)}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []models.FundingRateRecord{}
+	}
+	return history, nil
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/indicator"
+)
+
+// 技术指标查询参数
+const (
+	DefaultIndicatorLookback = 100 // 默认返回数量
+	MaxIndicatorLookback     = 500 // 最大返回数量
+)
+
+// indicatorLoadTimeout 加载历史K线的超时时间
+const indicatorLoadTimeout = 30 * time.Second
+
+// CandleFeed K线推送源，*okx.MarketFeed 实现了该接口
+type CandleFeed interface {
+	SubscribeCandles(bar, instId string, handler func([]okx.Candle)) error
+	Unsubscribe(channel, instId string) error
+}
+
+// IndicatorService 技术指标服务接口
+type IndicatorService interface {
+	GetIndicators(ctx context.Context, req *models.IndicatorsRequest) (*models.IndicatorsResponse, error)
+	StartIndicatorStream(instId, bar string, callback func(*indicator.Snapshot))
+	StopIndicatorStream(instId, bar string)
+}
+
+// indicatorKey 指标推送索引
+type indicatorKey struct {
+	instId string
+	bar    string
+}
+
+// indicatorStream 单个交易对和周期的指标推送状态
+type indicatorStream struct {
+	mutex    sync.Mutex
+	set      *indicator.Set
+	duration int64 // K线周期（毫秒）
+	lastTs   int64 // 最后一根已计算K线的开始时间
+}
+
+// indicatorService 技术指标服务实现，历史数据来自K线服务，实时更新来自OKX K线频道
+type indicatorService struct {
+	config    *config.OKXConfig
+	candles   CandleService
+	mutex     sync.Mutex
+	feed      CandleFeed
+	callbacks map[indicatorKey][]func(*indicator.Snapshot)
+	streams   map[indicatorKey]*indicatorStream
+}
+
+// NewIndicatorService 创建技术指标服务，首次推送时连接OKX K线频道
+func NewIndicatorService(cfg *config.OKXConfig, candles CandleService) IndicatorService {
+	return &indicatorService{
+		config:    cfg,
+		candles:   candles,
+		callbacks: make(map[indicatorKey][]func(*indicator.Snapshot)),
+		streams:   make(map[indicatorKey]*indicatorStream),
+	}
+}
+
+// NewIndicatorServiceWithFeed 使用指定K线推送源创建技术指标服务
+func NewIndicatorServiceWithFeed(candles CandleService, feed CandleFeed) IndicatorService {
+	return &indicatorService{
+		candles:   candles,
+		feed:      feed,
+		callbacks: make(map[indicatorKey][]func(*indicator.Snapshot)),
+		streams:   make(map[indicatorKey]*indicatorStream),
+	}
+}
+
+// IsNativeCandleBar 是否为OKX直接提供的K线周期，只有原生周期支持实时推送
+func IsNativeCandleBar(bar string) bool {
+	for _, native := range candleNativeBars {
+		if bar == native {
+			return true
+		}
+	}
+	return false
+}
+
+// GetIndicators 计算最近lookback根已完结K线的技术指标，额外加载预热所需的K线
+func (s *indicatorService) GetIndicators(ctx context.Context, req *models.IndicatorsRequest) (*models.IndicatorsResponse, error) {
+	set, err := indicator.NewSet(req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	lookback := req.Lookback
+	if lookback <= 0 {
+		lookback = DefaultIndicatorLookback
+	}
+	if lookback > MaxIndicatorLookback {
+		lookback = MaxIndicatorLookback
+	}
+
+	bars, err := s.loadBars(ctx, req.InstId, req.Bar, lookback+req.Params.Warmup(), 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]indicator.Snapshot, 0, len(bars))
+	for _, bar := range bars {
+		values = append(values, set.Update(bar))
+	}
+	if len(values) > lookback {
+		values = values[len(values)-lookback:]
+	}
+
+	return &models.IndicatorsResponse{
+		InstId: req.InstId,
+		Bar:    req.Bar,
+		Params: req.Params,
+		Values: values,
+	}, nil
+}
+
+// loadBars 向前分页加载最多count根已完结的K线，按时间升序返回
+func (s *indicatorService) loadBars(ctx context.Context, instId, bar string, count int, after, before int64) ([]indicator.Bar, error) {
+	var bars []indicator.Bar
+	for len(bars) < count {
+		// 多取一根，补偿被跳过的未完结K线
+		limit := count - len(bars) + 1
+		if limit > MaxCandleLimit {
+			limit = MaxCandleLimit
+		}
+
+		resp, err := s.candles.GetCandles(ctx, &models.CandlesRequest{
+			InstId: instId,
+			Bar:    bar,
+			After:  after,
+			Before: before,
+			Limit:  limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Candles) == 0 {
+			break
+		}
+
+		page := make([]indicator.Bar, 0, len(resp.Candles))
+		for _, candle := range resp.Candles {
+			if !candle.Confirm {
+				continue
+			}
+			b, err := newIndicatorBar(candle)
+			if err != nil {
+				return nil, err
+			}
+			page = append(page, b)
+		}
+		bars = append(page, bars...)
+		after = resp.Candles[0].Ts
+	}
+
+	if len(bars) > count {
+		bars = bars[len(bars)-count:]
+	}
+	return bars, nil
+}
+
+// StartIndicatorStream 订阅指标推送（默认参数），加载历史K线后推送最新指标，之后每根K线完结时推送一次
+// 同一交易对和周期只订阅一次上游K线频道
+func (s *indicatorService) StartIndicatorStream(instId, bar string, callback func(*indicator.Snapshot)) {
+	key := indicatorKey{instId: instId, bar: bar}
+
+	s.mutex.Lock()
+	s.callbacks[key] = append(s.callbacks[key], callback)
+	if len(s.callbacks[key]) > 1 {
+		s.mutex.Unlock()
+		return
+	}
+
+	stream := &indicatorStream{}
+	s.streams[key] = stream
+	if s.feed == nil {
+		s.feed = okx.NewMarketFeed(s.config.WSPublicURL, s.config.WSBusinessURL)
+	}
+	s.mutex.Unlock()
+
+	go s.runStream(key, stream)
+}
+
+// runStream 加载历史K线预热指标，然后订阅上游K线频道
+func (s *indicatorService) runStream(key indicatorKey, stream *indicatorStream) {
+	duration, ok := candleBarDuration(key.bar)
+	if !ok {
+		log.Printf("不支持的K线周期: %s", key.bar)
+		return
+	}
+	set, _ := indicator.NewSet(indicator.DefaultParams())
+
+	ctx, cancel := context.WithTimeout(context.Background(), indicatorLoadTimeout)
+	bars, err := s.loadBars(ctx, key.instId, key.bar, set.Params().Warmup()*2, 0, 0)
+	cancel()
+	if err != nil {
+		log.Printf("加载%s %s历史K线失败: %v", key.instId, key.bar, err)
+	}
+
+	stream.mutex.Lock()
+	stream.set = set
+	stream.duration = duration
+	var latest *indicator.Snapshot
+	for _, bar := range bars {
+		snapshot := set.Update(bar)
+		latest = &snapshot
+		stream.lastTs = bar.Ts
+	}
+	stream.mutex.Unlock()
+
+	if latest != nil {
+		s.emit(key, latest)
+	}
+
+	// 加载期间已取消订阅时不再订阅上游
+	s.mutex.Lock()
+	if s.streams[key] != stream {
+		s.mutex.Unlock()
+		return
+	}
+	err = s.feed.SubscribeCandles(key.bar, key.instId, func(candles []okx.Candle) {
+		s.onCandles(key, stream, candles)
+	})
+	s.mutex.Unlock()
+	if err != nil {
+		log.Printf("订阅%s %s K线推送失败: %v", key.instId, key.bar, err)
+	}
+}
+
+// onCandles 处理K线推送，只在K线完结时更新指标；推送中断导致缺失的K线从REST补齐
+func (s *indicatorService) onCandles(key indicatorKey, stream *indicatorStream, candles []okx.Candle) {
+	for i := range candles {
+		if !candles[i].Confirm {
+			continue
+		}
+		candle, err := newCandle(&candles[i])
+		if err != nil {
+			log.Printf("解析K线推送失败: %v", err)
+			continue
+		}
+		bar, err := newIndicatorBar(candle)
+		if err != nil {
+			log.Printf("解析K线推送失败: %v", err)
+			continue
+		}
+
+		stream.mutex.Lock()
+		if bar.Ts <= stream.lastTs {
+			stream.mutex.Unlock()
+			continue
+		}
+		if stream.lastTs > 0 && bar.Ts > stream.lastTs+stream.duration {
+			s.backfill(key, stream, bar.Ts)
+		}
+		snapshot := stream.set.Update(bar)
+		stream.lastTs = bar.Ts
+		stream.mutex.Unlock()
+
+		s.emit(key, &snapshot)
+	}
+}
+
+// backfill 补齐 lastTs 与 ts 之间缺失的K线，调用方需持有stream锁
+func (s *indicatorService) backfill(key indicatorKey, stream *indicatorStream, ts int64) {
+	count := int((ts-stream.lastTs)/stream.duration) - 1
+	if count > MaxIndicatorLookback {
+		count = MaxIndicatorLookback
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), indicatorLoadTimeout)
+	defer cancel()
+
+	bars, err := s.loadBars(ctx, key.instId, key.bar, count, ts, stream.lastTs)
+	if err != nil {
+		log.Printf("补齐%s %s K线失败: %v", key.instId, key.bar, err)
+		return
+	}
+	for _, bar := range bars {
+		if bar.Ts > stream.lastTs && bar.Ts < ts {
+			stream.set.Update(bar)
+			stream.lastTs = bar.Ts
+		}
+	}
+}
+
+// emit 向订阅者推送指标
+func (s *indicatorService) emit(key indicatorKey, snapshot *indicator.Snapshot) {
+	s.mutex.Lock()
+	callbacks := append([]func(*indicator.Snapshot){}, s.callbacks[key]...)
+	s.mutex.Unlock()
+
+	for _, callback := range callbacks {
+		callback(snapshot)
+	}
+}
+
+// StopIndicatorStream 停止指定交易对和周期的指标推送并取消上游订阅
+func (s *indicatorService) StopIndicatorStream(instId, bar string) {
+	key := indicatorKey{instId: instId, bar: bar}
+
+	s.mutex.Lock()
+	_, exists := s.streams[key]
+	delete(s.callbacks, key)
+	delete(s.streams, key)
+	feed := s.feed
+	s.mutex.Unlock()
+
+	if !exists || feed == nil {
+		return
+	}
+	if err := feed.Unsubscribe("candle"+bar, instId); err != nil {
+		log.Printf("取消订阅%s %s K线推送失败: %v", instId, bar, err)
+	}
+}
+
+// newIndicatorBar 转换K线，成交量使用交易货币数量
+func newIndicatorBar(candle *models.Candle) (indicator.Bar, error) {
+	values := make([]float64, 5)
+	for i, value := range []string{candle.Open, candle.High, candle.Low, candle.Close, candle.VolCcy} {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return indicator.Bar{}, fmt.Errorf("解析K线数据失败: %w", err)
+		}
+		values[i] = parsed
+	}
+
+	return indicator.Bar{
+		Ts:     candle.Ts,
+		Open:   values[0],
+		High:   values[1],
+		Low:    values[2],
+		Close:  values[3],
+		Volume: values[4],
+	}, nil
+}
@@ -0,0 +1,480 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+)
+
+// 交易对搜索参数
+const (
+	DefaultInstrumentSearchLimit = 50  // 默认返回数量
+	MaxInstrumentSearchLimit     = 500 // 最大返回数量
+)
+
+const (
+	defaultInstrumentRefreshInterval = 60 * time.Minute // 默认刷新间隔
+	maxInstrumentChanges             = 1000             // 保留的变化记录数量
+)
+
+// InstrumentTypes 支持的产品类型
+var InstrumentTypes = []string{"SPOT", "MARGIN", "SWAP", "FUTURES", "OPTION"}
+
+// instrumentCatalogTypes 定时刷新完整列表的产品类型；期权需要指定交易品种，只缓存查询过的单个期权
+var instrumentCatalogTypes = []string{"SPOT", "SWAP", "FUTURES"}
+
+// InstrumentRegistry 交易对信息缓存接口
+type InstrumentRegistry interface {
+	Start()
+	Stop()
+	Refresh(ctx context.Context) error
+	Get(ctx context.Context, instId string) (*models.Instrument, error)
+	List(ctx context.Context, instType string) ([]models.Instrument, error)
+	Search(ctx context.Context, req *models.InstrumentSearchRequest) (*models.InstrumentSearchResponse, error)
+	Changes(since int64) []models.InstrumentChange
+}
+
+// instrumentRegistry 交易对信息缓存实现，按产品类型缓存完整列表并定时刷新，刷新时对比新旧列表记录变化
+type instrumentRegistry struct {
+	rest     *okx.Client
+	interval time.Duration
+
+	mutex       sync.RWMutex
+	instruments map[string]map[string]models.Instrument // 产品类型 -> 产品ID -> 交易对
+	refreshedAt map[string]time.Time                    // 已加载完整列表的产品类型及其刷新时间
+	changes     []models.InstrumentChange
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stopChan  chan struct{}
+}
+
+// registryKey 共享交易对信息缓存的键，交易对信息只与接口地址和交易环境有关，与账户凭证无关
+type registryKey struct {
+	baseURL string
+	isTest  bool
+}
+
+var (
+	registryMutex sync.Mutex
+	registries    = make(map[registryKey]InstrumentRegistry)
+)
+
+// NewInstrumentRegistry 创建交易对信息缓存
+func NewInstrumentRegistry(cfg *config.OKXConfig) InstrumentRegistry {
+	interval := time.Duration(cfg.InstrumentRefreshInterval) * time.Minute
+	if interval <= 0 {
+		interval = defaultInstrumentRefreshInterval
+	}
+
+	return &instrumentRegistry{
+		rest:        okx.SharedClient(cfg.BaseURL, okx.Credentials{Simulated: cfg.IsTest}),
+		interval:    interval,
+		instruments: make(map[string]map[string]models.Instrument),
+		refreshedAt: make(map[string]time.Time),
+		stopChan:    make(chan struct{}),
+	}
+}
+
+// SharedInstrumentRegistry 获取接口地址和交易环境对应的共享交易对信息缓存，交易、行情和模拟撮合共用同一份数据
+// 同一环境的所有账户共用一份缓存，添加账户不会创建新的缓存
+func SharedInstrumentRegistry(cfg *config.OKXConfig) InstrumentRegistry {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	key := registryKey{baseURL: cfg.BaseURL, isTest: cfg.IsTest}
+	registry, exists := registries[key]
+	if !exists {
+		registry = NewInstrumentRegistry(cfg)
+		registries[key] = registry
+	}
+	return registry
+}
+
+// InstTypeFromInstID 根据产品ID推断产品类型
+// BTC-USDT -> SPOT, BTC-USDT-SWAP -> SWAP, BTC-USD-250328 -> FUTURES, BTC-USD-250328-50000-C -> OPTION
+func InstTypeFromInstID(instId string) string {
+	parts := strings.Split(instId, "-")
+	switch {
+	case len(parts) == 3 && parts[2] == "SWAP":
+		return "SWAP"
+	case len(parts) == 3:
+		return "FUTURES"
+	case len(parts) == 5:
+		return "OPTION"
+	default:
+		return "SPOT"
+	}
+}
+
+// Start 启动定时刷新，启动时立即刷新一次，重复调用无效
+func (r *instrumentRegistry) Start() {
+	r.startOnce.Do(func() {
+		go func() {
+			r.tick()
+
+			ticker := time.NewTicker(r.interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					r.tick()
+				case <-r.stopChan:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Stop 停止定时刷新
+func (r *instrumentRegistry) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+	})
+}
+
+// tick 执行一次定时刷新
+func (r *instrumentRegistry) tick() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := r.Refresh(ctx); err != nil {
+		log.Printf("刷新交易对信息失败: %v", err)
+	}
+}
+
+// Refresh 刷新全部已加载的产品类型，单个类型失败时保留旧数据并继续刷新其余类型
+func (r *instrumentRegistry) Refresh(ctx context.Context) error {
+	types := append([]string{}, instrumentCatalogTypes...)
+	r.mutex.RLock()
+	for instType := range r.refreshedAt {
+		if !containsString(types, instType) {
+			types = append(types, instType)
+		}
+	}
+	r.mutex.RUnlock()
+
+	var firstErr error
+	for _, instType := range types {
+		if _, err := r.load(ctx, instType); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// load 获取某个产品类型的完整列表并替换缓存，之前已加载过时记录变化
+func (r *instrumentRegistry) load(ctx context.Context, instType string) ([]models.Instrument, error) {
+	instruments, err := r.fetch(ctx, url.Values{"instType": {instType}})
+	if err != nil {
+		return nil, fmt.Errorf("获取%s交易对信息失败: %w", instType, err)
+	}
+
+	now := time.Now()
+	next := make(map[string]models.Instrument, len(instruments))
+	for _, inst := range instruments {
+		next[inst.InstID] = inst
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if lastRefresh, loaded := r.refreshedAt[instType]; loaded {
+		r.detectChanges(r.instruments[instType], next, lastRefresh, now)
+	}
+	r.instruments[instType] = next
+	r.refreshedAt[instType] = now
+	return instruments, nil
+}
+
+// detectChanges 对比新旧列表，记录上线、下线、到期和状态变化，调用方需持有写锁
+func (r *instrumentRegistry) detectChanges(prev, next map[string]models.Instrument, lastRefresh, now time.Time) {
+	for _, instId := range sortedInstIds(next) {
+		inst := next[instId]
+		old, exists := prev[instId]
+		switch {
+		case !exists:
+			r.record(models.InstrumentListed, &inst, "", now)
+		case old.State != inst.State:
+			r.record(models.InstrumentState, &inst, old.State, now)
+		}
+		if expiredBetween(inst.ExpTime, lastRefresh, now) {
+			r.record(models.InstrumentExpired, &inst, "", now)
+		}
+	}
+
+	for _, instId := range sortedInstIds(prev) {
+		if _, exists := next[instId]; exists {
+			continue
+		}
+		old := prev[instId]
+		switch {
+		case expiredBetween(old.ExpTime, lastRefresh, now):
+			r.record(models.InstrumentExpired, &old, "", now)
+		case !expiredBetween(old.ExpTime, time.Time{}, lastRefresh):
+			// 到期后已经记录过的不再记录下线
+			r.record(models.InstrumentDelisted, &old, old.State, now)
+		}
+	}
+}
+
+// record 记录一条变化，超出保留数量时丢弃最早的记录，调用方需持有写锁
+func (r *instrumentRegistry) record(kind string, inst *models.Instrument, prevState string, now time.Time) {
+	change := models.InstrumentChange{
+		Kind:       kind,
+		InstType:   inst.InstType,
+		InstId:     inst.InstID,
+		State:      inst.State,
+		PrevState:  prevState,
+		ExpTime:    inst.ExpTime,
+		DetectedAt: now.UnixMilli(),
+	}
+	if kind == models.InstrumentDelisted {
+		change.State = ""
+	}
+
+	log.Printf("交易对变化: %s %s", kind, inst.InstID)
+	r.changes = append(r.changes, change)
+	if len(r.changes) > maxInstrumentChanges {
+		r.changes = r.changes[len(r.changes)-maxInstrumentChanges:]
+	}
+}
+
+// fetch 请求OKX交易对接口
+func (r *instrumentRegistry) fetch(ctx context.Context, query url.Values) ([]models.Instrument, error) {
+	return okx.Call[[]models.Instrument](ctx, r.rest, okx.Request{
+		Path:  "/api/v5/public/instruments",
+		Query: query,
+	})
+}
+
+// Get 按产品ID查询交易对，缓存中没有时单独请求并缓存
+func (r *instrumentRegistry) Get(ctx context.Context, instId string) (*models.Instrument, error) {
+	instType := InstTypeFromInstID(instId)
+
+	r.mutex.RLock()
+	inst, exists := r.instruments[instType][instId]
+	r.mutex.RUnlock()
+	if exists {
+		return &inst, nil
+	}
+
+	query := url.Values{"instType": {instType}, "instId": {instId}}
+	if instType == "OPTION" {
+		// 期权必须指定交易品种，如 BTC-USD-250328-50000-C -> BTC-USD
+		parts := strings.Split(instId, "-")
+		query.Set("instFamily", parts[0]+"-"+parts[1])
+	}
+	instruments, err := r.fetch(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(instruments) == 0 {
+		return nil, fmt.Errorf("未找到交易对: %s", instId)
+	}
+
+	inst = instruments[0]
+	r.mutex.Lock()
+	if r.instruments[instType] == nil {
+		r.instruments[instType] = make(map[string]models.Instrument)
+	}
+	r.instruments[instType][instId] = inst
+	r.mutex.Unlock()
+	return &inst, nil
+}
+
+// List 获取某个产品类型的全部交易对，按产品ID排序，尚未加载时先加载
+func (r *instrumentRegistry) List(ctx context.Context, instType string) ([]models.Instrument, error) {
+	r.mutex.RLock()
+	_, loaded := r.refreshedAt[instType]
+	r.mutex.RUnlock()
+
+	if !loaded {
+		if _, err := r.load(ctx, instType); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	byId := r.instruments[instType]
+	result := make([]models.Instrument, 0, len(byId))
+	for _, instId := range sortedInstIds(byId) {
+		result = append(result, byId[instId])
+	}
+	return result, nil
+}
+
+// Search 按关键字、产品类型和状态搜索交易对，完全匹配和前缀匹配的排在前面
+func (r *instrumentRegistry) Search(ctx context.Context, req *models.InstrumentSearchRequest) (*models.InstrumentSearchResponse, error) {
+	types := instrumentCatalogTypes
+	if req.Type != "" {
+		types = []string{req.Type}
+	}
+
+	// 期权不加载完整列表，只搜索已缓存的期权
+	var candidates []models.Instrument
+	for _, instType := range types {
+		if instType == "OPTION" {
+			candidates = append(candidates, r.cached(instType)...)
+			continue
+		}
+		instruments, err := r.List(ctx, instType)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, instruments...)
+	}
+
+	query := strings.ToUpper(strings.TrimSpace(req.Query))
+	type match struct {
+		inst models.Instrument
+		rank int
+	}
+	var matches []match
+	for _, inst := range candidates {
+		if req.State != "" && inst.State != req.State {
+			continue
+		}
+		if rank, ok := instrumentMatchRank(&inst, query); ok {
+			matches = append(matches, match{inst: inst, rank: rank})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return matches[i].inst.InstID < matches[j].inst.InstID
+	})
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultInstrumentSearchLimit
+	}
+	resp := &models.InstrumentSearchResponse{Instruments: []models.Instrument{}, Total: len(matches)}
+	for i := 0; i < len(matches) && i < limit; i++ {
+		resp.Instruments = append(resp.Instruments, matches[i].inst)
+	}
+
+	r.mutex.RLock()
+	for _, refreshedAt := range r.refreshedAt {
+		if ms := refreshedAt.UnixMilli(); ms > resp.UpdatedAt {
+			resp.UpdatedAt = ms
+		}
+	}
+	r.mutex.RUnlock()
+	return resp, nil
+}
+
+// cached 已缓存的某个产品类型的交易对
+func (r *instrumentRegistry) cached(instType string) []models.Instrument {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	byId := r.instruments[instType]
+	result := make([]models.Instrument, 0, len(byId))
+	for _, instId := range sortedInstIds(byId) {
+		result = append(result, byId[instId])
+	}
+	return result
+}
+
+// Changes 返回detectedAt晚于since（毫秒）的变化记录，按时间顺序排列
+func (r *instrumentRegistry) Changes(since int64) []models.InstrumentChange {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := []models.InstrumentChange{}
+	for _, change := range r.changes {
+		if change.DetectedAt > since {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+// instrumentMatchRank 关键字匹配等级：0 产品ID完全匹配，1 产品ID前缀匹配，2 其他匹配；关键字为空时全部匹配
+func instrumentMatchRank(inst *models.Instrument, query string) (int, bool) {
+	switch {
+	case query == "":
+		return 2, true
+	case inst.InstID == query:
+		return 0, true
+	case strings.HasPrefix(inst.InstID, query):
+		return 1, true
+	case strings.Contains(inst.InstID, query),
+		strings.Contains(inst.InstFamily, query),
+		strings.Contains(inst.Uly, query),
+		inst.BaseCcy == query, inst.QuoteCcy == query,
+		inst.SettleCcy == query, inst.CtValCcy == query:
+		return 2, true
+	default:
+		return 0, false
+	}
+}
+
+// expiredBetween 到期时间是否在 (from, to] 之间，没有到期时间时返回false
+func expiredBetween(expTime string, from, to time.Time) bool {
+	ms, err := strconv.ParseInt(expTime, 10, 64)
+	if err != nil || ms <= 0 {
+		return false
+	}
+	return ms > from.UnixMilli() && ms <= to.UnixMilli()
+}
+
+// sortedInstIds 按产品ID排序的键
+func sortedInstIds(instruments map[string]models.Instrument) []string {
+	ids := make([]string, 0, len(instruments))
+	for instId := range instruments {
+		ids = append(ids, instId)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// containsString 切片是否包含指定字符串
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// RoundPrice 将价格取整到下单价格精度 TickSz 的整数倍，roundUp为true时向上取整，否则向下取整
+func RoundPrice(inst *models.Instrument, px string, roundUp bool) (string, error) {
+	return roundToIncrement(px, inst.TickSz, roundUp)
+}
+
+// RoundSize 将数量向下取整到下单数量精度 LotSz 的整数倍
+func RoundSize(inst *models.Instrument, sz string) (string, error) {
+	return roundToIncrement(sz, inst.LotSz, false)
+}
+
+// roundToIncrement 将数值取整到精度的整数倍，小数位数与精度一致
+func roundToIncrement(value, increment string, roundUp bool) (string, error) {
+	v, ok := new(big.Rat).SetString(value)
+	if !ok || v.Sign() < 0 {
+		return "", fmt.Errorf("无效的数值: %s", value)
+	}
+	step, ok := new(big.Rat).SetString(increment)
+	if !ok || step.Sign() <= 0 {
+		return "", fmt.Errorf("无效的精度: %s", increment)
+	}
+	return roundToStep(v, step, roundUp).FloatString(decimalPlaces(increment)), nil
+}
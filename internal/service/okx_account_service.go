@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/secretbox"
+)
+
+var (
+	// ErrCredentialsKeyMissing 未配置主密钥，无法加密或解密OKX凭证
+	ErrCredentialsKeyMissing = errors.New("未配置 CREDENTIALS_MASTER_KEY，不能使用OKX账户")
+	// ErrInvalidOKXCredentials OKX拒绝了添加的凭证
+	ErrInvalidOKXCredentials = errors.New("OKX凭证无效")
+)
+
+// OKXAccountService 用户OKX账户管理服务接口，凭证使用主密钥加密后保存
+type OKXAccountService interface {
+	Create(ctx context.Context, userID int64, req *models.CreateOKXAccountRequest) (*models.OKXAccount, error)
+	List(ctx context.Context, userID int64) ([]*models.OKXAccount, error)
+	ListAll(ctx context.Context) ([]*models.OKXAccount, error)
+	Delete(ctx context.Context, userID, id int64) error
+	Config(ctx context.Context, userID, id int64) (*config.OKXConfig, error)
+}
+
+// okxAccountService OKX账户管理服务实现
+type okxAccountService struct {
+	base     *config.OKXConfig
+	box      *secretbox.Box // 未配置主密钥时为nil
+	accounts repository.OKXAccountRepository
+}
+
+// NewOKXAccountService 创建OKX账户管理服务
+// 账户的接口地址等配置沿用 base，只替换凭证和模拟盘开关；masterKey 为空时只能查询和删除账户
+func NewOKXAccountService(base *config.OKXConfig, masterKey string, accounts repository.OKXAccountRepository) OKXAccountService {
+	// 主密钥为空时 box 为nil，添加账户和解密凭证返回 ErrCredentialsKeyMissing
+	box, _ := secretbox.New(masterKey)
+
+	return &okxAccountService{
+		base:     base,
+		box:      box,
+		accounts: accounts,
+	}
+}
+
+// Create 校验凭证后加密保存
+func (s *okxAccountService) Create(ctx context.Context, userID int64, req *models.CreateOKXAccountRequest) (*models.OKXAccount, error) {
+	if s.box == nil {
+		return nil, ErrCredentialsKeyMissing
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("账户名称不能为空")
+	}
+
+	account := &models.OKXAccount{
+		UserID:     userID,
+		Name:       name,
+		APIKeyHint: okx.MaskAPIKey(req.APIKey),
+		IsTest:     req.IsTest,
+		CreatedAt:  time.Now(),
+	}
+	cfg := s.configFor(account, req.APIKey, req.SecretKey, req.Passphrase)
+	if err := verifyOKXCredentials(ctx, cfg); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if account.APIKey, err = s.box.Seal(req.APIKey); err != nil {
+		return nil, err
+	}
+	if account.SecretKey, err = s.box.Seal(req.SecretKey); err != nil {
+		return nil, err
+	}
+	if account.Passphrase, err = s.box.Seal(req.Passphrase); err != nil {
+		return nil, err
+	}
+
+	if err := s.accounts.Create(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// List 用户的所有OKX账户
+func (s *okxAccountService) List(ctx context.Context, userID int64) ([]*models.OKXAccount, error) {
+	return s.accounts.List(userID)
+}
+
+// ListAll 所有用户的OKX账户，用于为每个账户记录权益快照等后台任务
+func (s *okxAccountService) ListAll(ctx context.Context) ([]*models.OKXAccount, error) {
+	return s.accounts.ListAll()
+}
+
+// Delete 删除用户的OKX账户
+func (s *okxAccountService) Delete(ctx context.Context, userID, id int64) error {
+	return s.accounts.Delete(userID, id)
+}
+
+// Config 解密用户OKX账户的凭证，返回该账户使用的OKX配置
+func (s *okxAccountService) Config(ctx context.Context, userID, id int64) (*config.OKXConfig, error) {
+	account, err := s.accounts.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.box == nil {
+		return nil, ErrCredentialsKeyMissing
+	}
+
+	apiKey, err := s.box.Open(account.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("解密OKX凭证失败: %w", err)
+	}
+	secretKey, err := s.box.Open(account.SecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("解密OKX凭证失败: %w", err)
+	}
+	passphrase, err := s.box.Open(account.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("解密OKX凭证失败: %w", err)
+	}
+
+	return s.configFor(account, apiKey, secretKey, passphrase), nil
+}
+
+// configFor 基于默认配置生成账户的OKX配置
+// 不启用私有WebSocket，账户数据通过REST接口获取；不使用 OKX_DEMO_* 凭证，也不允许模拟盘账户请求实盘交易
+func (s *okxAccountService) configFor(account *models.OKXAccount, apiKey, secretKey, passphrase string) *config.OKXConfig {
+	cfg := *s.base
+	cfg.APIKey = apiKey
+	cfg.SecretKey = secretKey
+	cfg.Passphrase = passphrase
+	cfg.IsTest = account.IsTest
+	cfg.DemoAPIKey = ""
+	cfg.DemoSecretKey = ""
+	cfg.DemoPassphrase = ""
+	cfg.AllowLiveTrading = false
+	cfg.WSPrivateURL = ""
+	return &cfg
+}
+
+// verifyOKXCredentials 请求账户配置接口校验凭证
+// 使用独立的REST传输层，校验失败的凭证不会留在共享实例中
+func verifyOKXCredentials(ctx context.Context, cfg *config.OKXConfig) error {
+	apiKey, secretKey, passphrase := cfg.Keys()
+	rest := okx.NewClient(cfg.BaseURL, okx.Credentials{
+		APIKey:     apiKey,
+		SecretKey:  secretKey,
+		Passphrase: passphrase,
+		Simulated:  cfg.IsTest,
+	})
+
+	_, err := okx.Call[[]json.RawMessage](ctx, rest, okx.Request{
+		Path:   "/api/v5/account/config",
+		Signed: true,
+	})
+	if okx.IsAuthError(err) {
+		return fmt.Errorf("%w: %v", ErrInvalidOKXCredentials, err)
+	}
+	if err != nil {
+		return fmt.Errorf("校验OKX凭证失败: %w", err)
+	}
+	return nil
+}
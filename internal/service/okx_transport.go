@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+)
+
+// NewOKXTransport 获取配置对应的共享REST传输层，所有服务共用连接池和服务器时间偏移
+// 模拟盘模式下使用模拟盘凭证，私有接口请求附带 x-simulated-trading 头
+func NewOKXTransport(cfg *config.OKXConfig) *okx.Client {
+	apiKey, secretKey, passphrase := cfg.Keys()
+	return okx.SharedClient(cfg.BaseURL, okx.Credentials{
+		APIKey:     apiKey,
+		SecretKey:  secretKey,
+		Passphrase: passphrase,
+		Simulated:  cfg.IsTest,
+	})
+}
+
+// fetchTicker 获取单个交易对的行情
+func fetchTicker(ctx context.Context, rest *okx.Client, instId string) (*Ticker, error) {
+	tickers, err := okx.Call[[]Ticker](ctx, rest, okx.Request{
+		Path:  "/api/v5/market/ticker",
+		Query: url.Values{"instId": {instId}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tickers) == 0 {
+		return nil, fmt.Errorf("未找到交易对 %s 的价格数据", instId)
+	}
+
+	return &tickers[0], nil
+}
@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+)
+
+// 订单簿查询参数
+const (
+	DefaultOrderBookDepth = 20    // 默认每侧档位数量
+	MaxOrderBookDepth     = 400   // 最大每侧档位数量
+	MaxOrderBookStep      = 10000 // 最大聚合步长（TickSz的倍数）
+	MaxOrderBookBps       = 10000 // 深度统计的最大价格范围（基点）
+
+	orderBookChannel   = "books" // 增量订单簿频道（400档）
+	orderBookRESTDepth = "400"   // REST快照的档位数量
+)
+
+const (
+	orderBookIdleTimeout = 5 * time.Minute  // 超过该时间无人查询时取消订阅
+	orderBookMaxAge      = 30 * time.Second // 本地订单簿超过该时间未更新时视为失效，改用REST快照
+)
+
+// DefaultOrderBookBps 默认统计的深度范围（基点）
+var DefaultOrderBookBps = []float64{10, 50, 100}
+
+// 订单簿数据来源
+const (
+	OrderBookSourceWebSocket = "websocket"
+	OrderBookSourceREST      = "rest"
+)
+
+// BookFeed 订单簿推送源，*okx.MarketFeed 实现了该接口
+type BookFeed interface {
+	SubscribeBooks(channel, instId string, handler func(*okx.OrderBookSnapshot)) error
+	Unsubscribe(channel, instId string) error
+}
+
+// TickSizeLookup 查询交易对的下单价格精度
+type TickSizeLookup func(ctx context.Context, instId string) (string, error)
+
+// OrderBookService 订单簿服务接口
+type OrderBookService interface {
+	GetOrderBook(ctx context.Context, req *models.OrderBookRequest) (*models.OrderBookResponse, error)
+}
+
+// localOrderBook 通过WebSocket增量维护的订单簿
+type localOrderBook struct {
+	snapshot *okx.OrderBookSnapshot
+	received time.Time
+	idle     *time.Timer
+}
+
+// orderBookService 订单簿服务实现，首次查询某个交易对时订阅增量频道，之后优先使用本地订单簿
+type orderBookService struct {
+	rest      *okx.Client
+	config    *config.OKXConfig
+	tickSize  TickSizeLookup
+	mutex     sync.Mutex
+	feed      BookFeed
+	books     map[string]*localOrderBook
+	tickSizes map[string]string
+}
+
+// NewOrderBookService 创建订单簿服务，首次查询时连接OKX公共频道
+func NewOrderBookService(cfg *config.OKXConfig, tickSize TickSizeLookup) OrderBookService {
+	return NewOrderBookServiceWithFeed(cfg, tickSize, nil)
+}
+
+// NewOrderBookServiceWithFeed 使用指定订单簿推送源创建订单簿服务
+func NewOrderBookServiceWithFeed(cfg *config.OKXConfig, tickSize TickSizeLookup, feed BookFeed) OrderBookService {
+	return &orderBookService{
+		rest:      NewOKXTransport(cfg),
+		config:    cfg,
+		tickSize:  tickSize,
+		feed:      feed,
+		books:     make(map[string]*localOrderBook),
+		tickSizes: make(map[string]string),
+	}
+}
+
+// GetOrderBook 获取订单簿，按 step × TickSz 聚合价格档位并计算衍生指标
+func (s *orderBookService) GetOrderBook(ctx context.Context, req *models.OrderBookRequest) (*models.OrderBookResponse, error) {
+	tickSz, err := s.getTickSize(ctx, req.InstId)
+	if err != nil {
+		return nil, err
+	}
+	tick, ok := new(big.Rat).SetString(tickSz)
+	if !ok || tick.Sign() <= 0 {
+		return nil, fmt.Errorf("交易对 %s 的价格精度无效: %s", req.InstId, tickSz)
+	}
+
+	stepCount := req.Step
+	if stepCount <= 0 {
+		stepCount = 1
+	}
+	step := new(big.Rat).Mul(tick, big.NewRat(int64(stepCount), 1))
+	places := decimalPlaces(tickSz)
+
+	source := OrderBookSourceWebSocket
+	snapshot := s.localSnapshot(req.InstId)
+	if snapshot == nil {
+		source = OrderBookSourceREST
+		if snapshot, err = s.fetchOrderBook(ctx, req.InstId); err != nil {
+			return nil, err
+		}
+	}
+
+	depth := req.Depth
+	if depth <= 0 {
+		depth = DefaultOrderBookDepth
+	}
+	asks := truncateLevels(bucketLevels(snapshot.Asks, step, places, true), depth)
+	bids := truncateLevels(bucketLevels(snapshot.Bids, step, places, false), depth)
+
+	bps := req.Bps
+	if len(bps) == 0 {
+		bps = DefaultOrderBookBps
+	}
+
+	return &models.OrderBookResponse{
+		InstId:  req.InstId,
+		Ts:      snapshot.Ts,
+		SeqId:   snapshot.SeqId,
+		Source:  source,
+		TickSz:  tickSz,
+		Step:    step.FloatString(places),
+		Asks:    asks,
+		Bids:    bids,
+		Metrics: orderBookMetrics(snapshot, asks, bids, bps),
+	}, nil
+}
+
+// getTickSize 查询并缓存交易对的价格精度
+func (s *orderBookService) getTickSize(ctx context.Context, instId string) (string, error) {
+	s.mutex.Lock()
+	tickSz, exists := s.tickSizes[instId]
+	s.mutex.Unlock()
+	if exists {
+		return tickSz, nil
+	}
+
+	tickSz, err := s.tickSize(ctx, instId)
+	if err != nil {
+		return "", fmt.Errorf("获取交易对价格精度失败: %w", err)
+	}
+
+	s.mutex.Lock()
+	s.tickSizes[instId] = tickSz
+	s.mutex.Unlock()
+	return tickSz, nil
+}
+
+// localSnapshot 返回本地订单簿，尚未订阅时订阅增量频道；本地订单簿未就绪或已失效时返回nil
+func (s *orderBookService) localSnapshot(instId string) *okx.OrderBookSnapshot {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if book, exists := s.books[instId]; exists {
+		book.idle.Reset(orderBookIdleTimeout)
+		if book.snapshot == nil || time.Since(book.received) > orderBookMaxAge {
+			return nil
+		}
+		return book.snapshot
+	}
+
+	if s.feed == nil {
+		s.feed = okx.NewMarketFeed(s.config.WSPublicURL, s.config.WSBusinessURL)
+	}
+
+	book := &localOrderBook{}
+	err := s.feed.SubscribeBooks(orderBookChannel, instId, func(snapshot *okx.OrderBookSnapshot) {
+		s.onBook(instId, book, snapshot)
+	})
+	if err != nil {
+		log.Printf("订阅%s订单簿失败: %v", instId, err)
+		return nil
+	}
+
+	book.idle = time.AfterFunc(orderBookIdleTimeout, func() {
+		s.release(instId, book)
+	})
+	s.books[instId] = book
+	return nil
+}
+
+// onBook 保存经过校验的订单簿推送
+func (s *orderBookService) onBook(instId string, book *localOrderBook, snapshot *okx.OrderBookSnapshot) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.books[instId] != book {
+		return
+	}
+	book.snapshot = snapshot
+	book.received = time.Now()
+}
+
+// release 长时间无人查询时取消订阅
+func (s *orderBookService) release(instId string, book *localOrderBook) {
+	s.mutex.Lock()
+	if s.books[instId] != book {
+		s.mutex.Unlock()
+		return
+	}
+	delete(s.books, instId)
+	feed := s.feed
+	s.mutex.Unlock()
+
+	if err := feed.Unsubscribe(orderBookChannel, instId); err != nil {
+		log.Printf("取消订阅%s订单簿失败: %v", instId, err)
+	}
+}
+
+// fetchOrderBook 通过REST接口获取订单簿快照
+func (s *orderBookService) fetchOrderBook(ctx context.Context, instId string) (*okx.OrderBookSnapshot, error) {
+	data, err := okx.Call[[]okx.BookData](ctx, s.rest, okx.Request{
+		Path:  "/api/v5/market/books",
+		Query: url.Values{"instId": {instId}, "sz": {orderBookRESTDepth}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取订单簿失败: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("未找到交易对 %s 的订单簿", instId)
+	}
+
+	book := okx.NewOrderBook(instId)
+	if err := book.Apply("snapshot", &data[0]); err != nil {
+		return nil, err
+	}
+	return book.Snapshot(0), nil
+}
+
+// bucketLevels 按步长聚合价格档位，卖单向上取整、买单向下取整到步长的整数倍
+func bucketLevels(levels []okx.BookLevel, step *big.Rat, places int, roundUp bool) []*models.OrderBookLevel {
+	result := make([]*models.OrderBookLevel, 0, len(levels))
+	var bucket *big.Rat
+	var sizes []string
+	var orders int
+
+	flush := func() {
+		if bucket != nil {
+			result = append(result, &models.OrderBookLevel{Px: bucket.FloatString(places), Sz: sumDecimals(sizes), Orders: orders})
+		}
+	}
+
+	for _, level := range levels {
+		px := roundToStep(parseDecimal(level.Px), step, roundUp)
+		if bucket == nil || px.Cmp(bucket) != 0 {
+			flush()
+			bucket, sizes, orders = px, nil, 0
+		}
+		sizes = append(sizes, level.Sz)
+		if n, err := strconv.Atoi(level.Orders); err == nil {
+			orders += n
+		}
+	}
+	flush()
+	return result
+}
+
+// roundToStep 将价格取整到步长的整数倍
+func roundToStep(px, step *big.Rat, roundUp bool) *big.Rat {
+	quotient := new(big.Rat).Quo(px, step)
+	n, rem := new(big.Int).QuoRem(quotient.Num(), quotient.Denom(), new(big.Int))
+	if roundUp && rem.Sign() > 0 {
+		n.Add(n, big.NewInt(1))
+	}
+	return new(big.Rat).Mul(new(big.Rat).SetInt(n), step)
+}
+
+// truncateLevels 保留前depth档
+func truncateLevels(levels []*models.OrderBookLevel, depth int) []*models.OrderBookLevel {
+	if len(levels) > depth {
+		return levels[:depth]
+	}
+	return levels
+}
+
+// orderBookMetrics 计算价差、中间价、失衡度和各价格范围内的深度，任一侧为空时返回nil
+func orderBookMetrics(snapshot *okx.OrderBookSnapshot, asks, bids []*models.OrderBookLevel, bps []float64) *models.OrderBookMetrics {
+	if len(snapshot.Asks) == 0 || len(snapshot.Bids) == 0 {
+		return nil
+	}
+
+	bestBid, bestAsk := snapshot.Bids[0].Px, snapshot.Asks[0].Px
+	bid, ask := parseDecimal(bestBid), parseDecimal(bestAsk)
+	places := decimalPlaces(bestBid)
+	if p := decimalPlaces(bestAsk); p > places {
+		places = p
+	}
+
+	spread := new(big.Rat).Sub(ask, bid)
+	mid := new(big.Rat).Add(ask, bid)
+	mid.Quo(mid, big.NewRat(2, 1))
+
+	metrics := &models.OrderBookMetrics{
+		BestBid: bestBid,
+		BestAsk: bestAsk,
+		Spread:  spread.FloatString(places),
+		Mid:     mid.FloatString(places + 1),
+		Depth:   make([]models.OrderBookDepth, 0, len(bps)),
+	}
+	if mid.Sign() > 0 {
+		metrics.SpreadBps, _ = new(big.Rat).Quo(new(big.Rat).Mul(spread, big.NewRat(10000, 1)), mid).Float64()
+	}
+
+	bidSz, askSz := sumLevelSizes(bids), sumLevelSizes(asks)
+	if total := new(big.Rat).Add(bidSz, askSz); total.Sign() > 0 {
+		metrics.Imbalance, _ = new(big.Rat).Quo(new(big.Rat).Sub(bidSz, askSz), total).Float64()
+	}
+
+	for _, b := range bps {
+		width := new(big.Rat).Mul(mid, new(big.Rat).SetFloat64(b/10000))
+		low := new(big.Rat).Sub(mid, width)
+		high := new(big.Rat).Add(mid, width)
+
+		depth := models.OrderBookDepth{Bps: b}
+		depth.BidSz, depth.BidNotional = sumLevelsWithin(snapshot.Bids, func(px *big.Rat) bool { return px.Cmp(low) >= 0 })
+		depth.AskSz, depth.AskNotional = sumLevelsWithin(snapshot.Asks, func(px *big.Rat) bool { return px.Cmp(high) <= 0 })
+		metrics.Depth = append(metrics.Depth, depth)
+	}
+	return metrics
+}
+
+// sumLevelSizes 档位数量之和
+func sumLevelSizes(levels []*models.OrderBookLevel) *big.Rat {
+	sum := new(big.Rat)
+	for _, level := range levels {
+		sum.Add(sum, parseDecimal(level.Sz))
+	}
+	return sum
+}
+
+// sumLevelsWithin 从最优价开始累计满足条件的档位数量和金额，档位按价格由优到劣排列
+func sumLevelsWithin(levels []okx.BookLevel, within func(px *big.Rat) bool) (string, string) {
+	size, notional := new(big.Rat), new(big.Rat)
+	szPlaces, pxPlaces := 0, 0
+	for _, level := range levels {
+		px := parseDecimal(level.Px)
+		if !within(px) {
+			break
+		}
+		sz := parseDecimal(level.Sz)
+		size.Add(size, sz)
+		notional.Add(notional, new(big.Rat).Mul(px, sz))
+
+		if p := decimalPlaces(level.Sz); p > szPlaces {
+			szPlaces = p
+		}
+		if p := decimalPlaces(level.Px); p > pxPlaces {
+			pxPlaces = p
+		}
+	}
+	return size.FloatString(szPlaces), notional.FloatString(szPlaces + pxPlaces)
+}
+
+// decimalPlaces 十进制字符串的小数位数
+func decimalPlaces(value string) int {
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		return len(value) - i - 1
+	}
+	return 0
+}
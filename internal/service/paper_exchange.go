@@ -0,0 +1,1182 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+)
+
+// maxPaperHistory 保留的历史订单和历史持仓数量
+const maxPaperHistory = 1000
+
+// 模拟账户金额的精度
+const (
+	paperQuoPlaces     = 18 // 除法结果（均价、按计价币种换算的数量、保证金）保留的小数位数，避免有理数分母无限增长
+	paperDisplayPlaces = 8  // 余额、持仓和订单输出的小数位数
+)
+
+// 模拟交易的OKX错误码
+const (
+	paperCodeParamError          = "51000" // 参数错误
+	paperCodeInsufficientBalance = "51008" // 余额不足
+	paperCodeNoPositionToReduce  = "51169" // 只减仓订单没有可平的反向持仓
+	paperCodeOrderNotExist       = "51603" // 订单不存在
+)
+
+// PaperInstrument 模拟撮合所需的交易对信息
+type PaperInstrument struct {
+	InstType  string          // 产品类型 SPOT/SWAP/FUTURES
+	BaseCcy   string          // 交易货币（现货）
+	QuoteCcy  string          // 计价货币（现货）
+	SettleCcy string          // 结算币种（合约）
+	CtVal     decimal.Decimal // 合约面值
+	CtValCcy  string          // 合约面值计价币种
+}
+
+// InstrumentLookup 查询交易对信息
+type InstrumentLookup func(ctx context.Context, instId string) (*PaperInstrument, error)
+
+// PaperExchange 本地模拟交易所，下单接口与OKX交易接口一致，按实时行情的买一/卖一价撮合
+// 同时实现AccountStream，账户服务和账户推送直接使用模拟账户的余额、持仓和订单
+type PaperExchange interface {
+	AccountStream
+	PlaceOrder(ctx context.Context, order *models.OrderRequest) (*models.OrderResultResponse, error)
+	PlaceBatchOrders(ctx context.Context, orders []models.OrderRequest) (*models.OrderResultResponse, error)
+	CancelOrder(ctx context.Context, cancel *models.CancelOrderRequest) (*models.OrderResultResponse, error)
+	CancelBatchOrders(ctx context.Context, cancels []models.CancelOrderRequest) (*models.OrderResultResponse, error)
+	AmendOrder(ctx context.Context, amend *models.AmendOrderRequest) (*models.OrderResultResponse, error)
+	GetOrder(ctx context.Context, instId, ordId, clOrdId string) (*models.Order, error)
+	GetPendingOrders(ctx context.Context, req *models.OrdersRequest) ([]models.Order, error)
+	GetOrdersHistory(ctx context.Context, req *models.OrdersRequest) ([]models.Order, error)
+	PositionsHistory(req *models.PositionsHistoryRequest) []OKXPositionHistoryData
+	Reset()
+}
+
+// paperQuote 最新行情
+type paperQuote struct {
+	last decimal.Decimal
+	bid  decimal.Decimal
+	ask  decimal.Decimal
+}
+
+// paperOrder 模拟订单
+type paperOrder struct {
+	order     models.Order
+	inst      *PaperInstrument
+	sz        decimal.Decimal // 委托数量（现货市价买单按计价币种下单时为金额）
+	px        decimal.Decimal // 委托价格，市价单为0
+	frozenCcy string          // 冻结资金的币种
+	frozen    decimal.Decimal // 冻结资金
+}
+
+// paperPosition 模拟合约持仓（买卖模式，每个产品一个净持仓）
+// 持仓张数和金额都使用精确十进制，多次部分平仓的数量之和等于持仓时恰好归零，现金余额与各笔手续费和收益之和一致
+type paperPosition struct {
+	inst          *PaperInstrument
+	instId        string
+	posId         string
+	mgnMode       string
+	direction     string // 持仓方向 long/short
+	lever         decimal.Decimal
+	pos           decimal.Decimal // 持仓张数，负数为空仓
+	avgPx         decimal.Decimal // 开仓均价
+	margin        decimal.Decimal // 占用保证金
+	openMaxPos    decimal.Decimal // 最大持仓量
+	closeTotalPos decimal.Decimal // 累计平仓量
+	closeValue    decimal.Decimal // 累计平仓价值，用于计算平仓均价
+	pnl           decimal.Decimal // 平仓收益
+	fee           decimal.Decimal // 累计手续费（负数）
+	cTime         int64
+	uTime         int64
+}
+
+// paperExchange 本地模拟交易所实现
+type paperExchange struct {
+	config       *config.PaperConfig
+	priceService PriceService
+	instruments  InstrumentLookup
+
+	mutex        sync.Mutex
+	cash         map[string]decimal.Decimal // 币种 -> 现金余额
+	orders       map[string]*paperOrder
+	history      []models.Order // 已完成订单，最新的在前
+	positions    map[string]*paperPosition
+	closed       []OKXPositionHistoryData // 已平仓持仓，最新的在前
+	quotes       map[string]paperQuote
+	instCache    map[string]*PaperInstrument
+	watching     map[string]bool
+	nextId       int64
+	lastUpdateMs int64
+
+	listenerMutex sync.RWMutex
+	listeners     map[int]func(*models.AccountStreamMessage)
+	nextListener  int
+}
+
+// NewPaperExchange 创建本地模拟交易所，初始账户为配置的USDT余额
+func NewPaperExchange(cfg *config.PaperConfig, priceService PriceService, instruments InstrumentLookup) PaperExchange {
+	exchange := &paperExchange{
+		config:       cfg,
+		priceService: priceService,
+		instruments:  instruments,
+		quotes:       make(map[string]paperQuote),
+		instCache:    make(map[string]*PaperInstrument),
+		watching:     make(map[string]bool),
+		listeners:    make(map[int]func(*models.AccountStreamMessage)),
+	}
+	exchange.resetState()
+	return exchange
+}
+
+// resetState 恢复初始账户，调用方需持有锁或在初始化时调用
+func (s *paperExchange) resetState() {
+	s.cash = map[string]decimal.Decimal{"USDT": paperDecimal(s.config.InitialBalance)}
+	s.orders = make(map[string]*paperOrder)
+	s.history = nil
+	s.positions = make(map[string]*paperPosition)
+	s.closed = nil
+	s.nextId = time.Now().UnixMilli() * 1000
+	s.lastUpdateMs = time.Now().UnixMilli()
+}
+
+// Start 模拟账户无需连接
+func (s *paperExchange) Start() {}
+
+// Stop 停止行情订阅
+func (s *paperExchange) Stop() {
+	s.mutex.Lock()
+	s.watching = make(map[string]bool)
+	s.mutex.Unlock()
+
+	s.priceService.StopPriceStream()
+}
+
+// Reset 撤销所有订单、清空持仓并恢复初始余额
+func (s *paperExchange) Reset() {
+	s.mutex.Lock()
+	s.resetState()
+	s.mutex.Unlock()
+
+	s.broadcast(s.Snapshot())
+}
+
+// PlaceOrder 下单
+func (s *paperExchange) PlaceOrder(ctx context.Context, order *models.OrderRequest) (*models.OrderResultResponse, error) {
+	return s.PlaceBatchOrders(ctx, []models.OrderRequest{*order})
+}
+
+// PlaceBatchOrders 批量下单，逐条撮合，部分失败时返回code为"2"
+func (s *paperExchange) PlaceBatchOrders(ctx context.Context, orders []models.OrderRequest) (*models.OrderResultResponse, error) {
+	results := make([]models.OrderResult, 0, len(orders))
+	for i := range orders {
+		results = append(results, s.placeOrder(ctx, &orders[i]))
+	}
+	return newPaperResult(results), nil
+}
+
+// placeOrder 校验、冻结资金并撮合单个订单
+func (s *paperExchange) placeOrder(ctx context.Context, req *models.OrderRequest) models.OrderResult {
+	result := models.OrderResult{ClOrdId: req.ClOrdId, Tag: req.Tag}
+
+	sz, err := decimal.Parse(req.Sz)
+	if err != nil || sz.Sign() <= 0 {
+		return paperFailure(result, paperCodeParamError, "无效的委托数量: "+req.Sz)
+	}
+	px := decimal.Zero
+	if req.OrdType != "market" {
+		if px, err = decimal.Parse(req.Px); err != nil || px.Sign() <= 0 {
+			return paperFailure(result, paperCodeParamError, "无效的委托价格: "+req.Px)
+		}
+	}
+	switch req.OrdType {
+	case "market", "limit", "post_only", "ioc", "fok":
+	default:
+		return paperFailure(result, paperCodeParamError, "模拟交易不支持的订单类型: "+req.OrdType)
+	}
+
+	inst, err := s.instrument(ctx, req.InstId)
+	if err != nil {
+		return paperFailure(result, paperCodeParamError, err.Error())
+	}
+	if inst.InstType != "SPOT" && !isStablecoin(inst.SettleCcy) {
+		return paperFailure(result, paperCodeParamError, "模拟交易仅支持现货和USDT/USDC本位合约")
+	}
+
+	quote, err := s.quote(ctx, req.InstId)
+	if err != nil {
+		return paperFailure(result, paperCodeParamError, err.Error())
+	}
+
+	s.mutex.Lock()
+	now := time.Now().UnixMilli()
+	s.nextId++
+	o := &paperOrder{
+		order: models.Order{
+			InstType:   inst.InstType,
+			InstId:     req.InstId,
+			OrdId:      strconv.FormatInt(s.nextId, 10),
+			ClOrdId:    req.ClOrdId,
+			Tag:        req.Tag,
+			Px:         req.Px,
+			Sz:         req.Sz,
+			OrdType:    req.OrdType,
+			Side:       req.Side,
+			PosSide:    "net",
+			TdMode:     req.TdMode,
+			TgtCcy:     req.TgtCcy,
+			AccFillSz:  "0",
+			State:      "live",
+			Category:   "normal",
+			ReduceOnly: strconv.FormatBool(req.ReduceOnly),
+			CTime:      strconv.FormatInt(now, 10),
+			UTime:      strconv.FormatInt(now, 10),
+		},
+		inst: inst,
+		sz:   sz,
+		px:   px,
+	}
+	if inst.InstType == "SPOT" && req.OrdType == "market" && req.Side == "buy" && req.TgtCcy != "base_ccy" {
+		o.order.TgtCcy = "quote_ccy"
+	}
+	if inst.InstType != "SPOT" {
+		o.order.Lever = formatPaperDecimal(s.leverage())
+	}
+
+	if code, msg := s.freeze(o, quote); code != "" {
+		s.mutex.Unlock()
+		return paperFailure(result, code, msg)
+	}
+
+	fillPx, marketable := s.matchPrice(o, quote)
+	switch {
+	case marketable && o.order.OrdType == "post_only":
+		// 只做maker单会立即成交时撤单
+		s.cancelLocked(o, now)
+	case marketable:
+		s.fillLocked(o, fillPx, false, now)
+	case o.order.OrdType == "market" || o.order.OrdType == "ioc" || o.order.OrdType == "fok":
+		// 无对手价时立即撤单
+		s.cancelLocked(o, now)
+	default:
+		s.orders[o.order.OrdId] = o
+	}
+	s.lastUpdateMs = now
+	order := o.order
+	s.mutex.Unlock()
+
+	s.watch(req.InstId)
+	if inst.InstType == "SPOT" && !isStablecoin(inst.QuoteCcy) {
+		// 非稳定币计价的现货需要计价币种的价格计算权益
+		s.watch(inst.QuoteCcy + "-USDT")
+	}
+	s.notifyChanges([]models.Order{order})
+
+	result.OrdId = order.OrdId
+	result.Ts = order.UTime
+	result.SCode = "0"
+	result.SMsg = "Order placed"
+	return result
+}
+
+// CancelOrder 撤单
+func (s *paperExchange) CancelOrder(ctx context.Context, cancel *models.CancelOrderRequest) (*models.OrderResultResponse, error) {
+	return s.CancelBatchOrders(ctx, []models.CancelOrderRequest{*cancel})
+}
+
+// CancelBatchOrders 批量撤单
+func (s *paperExchange) CancelBatchOrders(ctx context.Context, cancels []models.CancelOrderRequest) (*models.OrderResultResponse, error) {
+	results := make([]models.OrderResult, 0, len(cancels))
+	var changed []models.Order
+
+	s.mutex.Lock()
+	now := time.Now().UnixMilli()
+	for _, cancel := range cancels {
+		result := models.OrderResult{OrdId: cancel.OrdId, ClOrdId: cancel.ClOrdId}
+		o := s.findOrder(cancel.InstId, cancel.OrdId, cancel.ClOrdId)
+		if o == nil {
+			results = append(results, paperFailure(result, paperCodeOrderNotExist, "Order does not exist"))
+			continue
+		}
+
+		s.cancelLocked(o, now)
+		changed = append(changed, o.order)
+		result.OrdId, result.ClOrdId = o.order.OrdId, o.order.ClOrdId
+		result.Ts = strconv.FormatInt(now, 10)
+		result.SCode = "0"
+		results = append(results, result)
+	}
+	if len(changed) > 0 {
+		s.lastUpdateMs = now
+	}
+	s.mutex.Unlock()
+
+	s.notifyChanges(changed)
+	return newPaperResult(results), nil
+}
+
+// AmendOrder 修改未成交订单的数量或价格，修改后可成交时立即按吃单成交
+func (s *paperExchange) AmendOrder(ctx context.Context, amend *models.AmendOrderRequest) (*models.OrderResultResponse, error) {
+	result := models.OrderResult{OrdId: amend.OrdId, ClOrdId: amend.ClOrdId, ReqId: amend.ReqId}
+
+	quote, err := s.quote(ctx, amend.InstId)
+	if err != nil {
+		return newPaperResult([]models.OrderResult{paperFailure(result, paperCodeParamError, err.Error())}), nil
+	}
+
+	s.mutex.Lock()
+	o := s.findOrder(amend.InstId, amend.OrdId, amend.ClOrdId)
+	if o == nil {
+		s.mutex.Unlock()
+		return newPaperResult([]models.OrderResult{paperFailure(result, paperCodeOrderNotExist, "Order does not exist")}), nil
+	}
+
+	previous := *o
+	if amend.NewSz != "" {
+		sz, err := decimal.Parse(amend.NewSz)
+		if err != nil || sz.Sign() <= 0 {
+			s.mutex.Unlock()
+			return newPaperResult([]models.OrderResult{paperFailure(result, paperCodeParamError, "无效的委托数量: "+amend.NewSz)}), nil
+		}
+		o.sz, o.order.Sz = sz, amend.NewSz
+	}
+	if amend.NewPx != "" {
+		px, err := decimal.Parse(amend.NewPx)
+		if err != nil || px.Sign() <= 0 {
+			*o = previous
+			s.mutex.Unlock()
+			return newPaperResult([]models.OrderResult{paperFailure(result, paperCodeParamError, "无效的委托价格: "+amend.NewPx)}), nil
+		}
+		o.px, o.order.Px = px, amend.NewPx
+	}
+
+	// 按新数量和价格重新冻结资金
+	o.frozen = decimal.Zero
+	if code, msg := s.freeze(o, quote); code != "" {
+		*o = previous
+		s.mutex.Unlock()
+		if amend.CxlOnFail {
+			s.CancelOrder(ctx, &models.CancelOrderRequest{InstId: amend.InstId, OrdId: o.order.OrdId})
+		}
+		return newPaperResult([]models.OrderResult{paperFailure(result, code, msg)}), nil
+	}
+
+	now := time.Now().UnixMilli()
+	o.order.UTime = strconv.FormatInt(now, 10)
+	if fillPx, marketable := s.matchPrice(o, quote); marketable {
+		delete(s.orders, o.order.OrdId)
+		s.fillLocked(o, fillPx, false, now)
+	}
+	s.lastUpdateMs = now
+	order := o.order
+	s.mutex.Unlock()
+
+	s.notifyChanges([]models.Order{order})
+
+	result.OrdId, result.ClOrdId = order.OrdId, order.ClOrdId
+	result.Ts = strconv.FormatInt(now, 10)
+	result.SCode = "0"
+	return newPaperResult([]models.OrderResult{result}), nil
+}
+
+// GetOrder 按订单ID或客户自定义订单ID查询订单，先查未成交订单再查历史订单，不存在时返回nil
+func (s *paperExchange) GetOrder(ctx context.Context, instId, ordId, clOrdId string) (*models.Order, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if o := s.findOrder(instId, ordId, clOrdId); o != nil {
+		order := o.order
+		return &order, nil
+	}
+	for _, order := range s.history {
+		if (instId == "" || order.InstId == instId) && ((ordId != "" && order.OrdId == ordId) || (ordId == "" && clOrdId != "" && order.ClOrdId == clOrdId)) {
+			return &order, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetPendingOrders 获取未成交订单，最新的在前，按 after（订单ID）分页
+func (s *paperExchange) GetPendingOrders(ctx context.Context, req *models.OrdersRequest) ([]models.Order, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	orders := make([]models.Order, 0, len(s.orders))
+	for _, o := range s.orders {
+		if matchOrderFilter(&o.order, req) && (req.After == "" || o.order.OrdId < req.After) {
+			orders = append(orders, o.order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].OrdId > orders[j].OrdId })
+	return limitOrders(orders, req.Limit), nil
+}
+
+// GetOrdersHistory 获取已成交或已撤销的订单，最新的在前
+func (s *paperExchange) GetOrdersHistory(ctx context.Context, req *models.OrdersRequest) ([]models.Order, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	orders := make([]models.Order, 0)
+	for i := range s.history {
+		if matchOrderFilter(&s.history[i], req) && (req.State == "" || s.history[i].State == req.State) {
+			orders = append(orders, s.history[i])
+		}
+	}
+	return limitOrders(orders, req.Limit), nil
+}
+
+// PositionsHistory 获取已平仓持仓，最新的在前，按 after（更新时间）分页
+func (s *paperExchange) PositionsHistory(req *models.PositionsHistoryRequest) []OKXPositionHistoryData {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	limit := 100
+	if n, err := strconv.Atoi(req.Limit); err == nil && n > 0 && n < limit {
+		limit = n
+	}
+
+	history := make([]OKXPositionHistoryData, 0)
+	for _, pos := range s.closed {
+		switch {
+		case req.InstType != "" && pos.InstType != req.InstType,
+			req.InstId != "" && pos.InstId != req.InstId,
+			req.PosId != "" && pos.PosId != req.PosId,
+			req.After != "" && pos.UTime >= req.After,
+			req.Before != "" && pos.UTime <= req.Before:
+			continue
+		}
+		history = append(history, pos)
+		if len(history) >= limit {
+			break
+		}
+	}
+	return history
+}
+
+// Balance 获取模拟账户余额，权益按最新行情计算
+func (s *paperExchange) Balance() (*OKXBalanceData, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.balanceLocked(), true
+}
+
+// Positions 获取模拟持仓
+func (s *paperExchange) Positions() ([]OKXPositionData, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.positionsLocked(), true
+}
+
+// Orders 获取未成交订单
+func (s *paperExchange) Orders() []models.Order {
+	orders, _ := s.GetPendingOrders(context.Background(), &models.OrdersRequest{})
+	return orders
+}
+
+// Snapshot 获取完整模拟账户状态
+func (s *paperExchange) Snapshot() *models.AccountStreamMessage {
+	balance, _ := s.Balance()
+	positions, _ := s.Positions()
+
+	return &models.AccountStreamMessage{
+		Type: "snapshot",
+		Data: &AccountStreamState{
+			Balance:        balance,
+			Positions:      positions,
+			Orders:         s.Orders(),
+			BalanceReady:   true,
+			PositionsReady: true,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	}
+}
+
+// AddListener 注册账户变化监听，返回取消函数
+func (s *paperExchange) AddListener(listener func(*models.AccountStreamMessage)) func() {
+	s.listenerMutex.Lock()
+	defer s.listenerMutex.Unlock()
+
+	id := s.nextListener
+	s.nextListener++
+	s.listeners[id] = listener
+
+	return func() {
+		s.listenerMutex.Lock()
+		defer s.listenerMutex.Unlock()
+		delete(s.listeners, id)
+	}
+}
+
+// instrument 获取交易对信息，结果缓存
+func (s *paperExchange) instrument(ctx context.Context, instId string) (*PaperInstrument, error) {
+	s.mutex.Lock()
+	inst, exists := s.instCache[instId]
+	s.mutex.Unlock()
+	if exists {
+		return inst, nil
+	}
+
+	inst, err := s.instruments(ctx, instId)
+	if err != nil {
+		return nil, fmt.Errorf("获取交易对信息失败: %w", err)
+	}
+
+	s.mutex.Lock()
+	s.instCache[instId] = inst
+	s.mutex.Unlock()
+	return inst, nil
+}
+
+// quote 获取最新买一/卖一价
+func (s *paperExchange) quote(ctx context.Context, instId string) (paperQuote, error) {
+	priceData, err := s.priceService.GetPrice(ctx, instId)
+	if err != nil {
+		return paperQuote{}, fmt.Errorf("获取%s行情失败: %w", instId, err)
+	}
+
+	quote := newPaperQuote(priceData)
+	if quote.last.Sign() <= 0 {
+		return paperQuote{}, fmt.Errorf("%s暂无有效行情", instId)
+	}
+
+	s.mutex.Lock()
+	s.quotes[instId] = quote
+	s.mutex.Unlock()
+	return quote, nil
+}
+
+// watch 订阅交易对实时行情，用于撮合挂单和计算未实现盈亏
+func (s *paperExchange) watch(instId string) {
+	s.mutex.Lock()
+	if s.watching[instId] {
+		s.mutex.Unlock()
+		return
+	}
+	s.watching[instId] = true
+	s.mutex.Unlock()
+
+	s.priceService.StartPriceStream(instId, func(priceData *PriceData) {
+		s.onPrice(instId, priceData)
+	})
+}
+
+// onPrice 行情更新时撮合该交易对的挂单，买单在卖一价不高于委托价时成交，卖单在买一价不低于委托价时成交
+func (s *paperExchange) onPrice(instId string, priceData *PriceData) {
+	quote := newPaperQuote(priceData)
+	if quote.last.Sign() <= 0 {
+		return
+	}
+
+	s.mutex.Lock()
+	s.quotes[instId] = quote
+	now := time.Now().UnixMilli()
+
+	var filled []models.Order
+	for _, o := range s.sortedOrdersLocked() {
+		if o.order.InstId != instId {
+			continue
+		}
+		if _, marketable := s.matchPrice(o, quote); marketable {
+			delete(s.orders, o.order.OrdId)
+			// 挂单按委托价成交，收取挂单手续费
+			s.fillLocked(o, o.px, true, now)
+			filled = append(filled, o.order)
+		}
+	}
+	if len(filled) > 0 {
+		s.lastUpdateMs = now
+	}
+	_, hasPosition := s.positions[instId]
+	s.mutex.Unlock()
+
+	if len(filled) > 0 {
+		s.notifyChanges(filled)
+	} else if hasPosition {
+		// 行情变化只影响持仓的未实现盈亏
+		positions, _ := s.Positions()
+		s.notify("positions", positions)
+	}
+}
+
+// matchPrice 按当前行情判断订单是否可立即成交，返回吃单成交价
+func (s *paperExchange) matchPrice(o *paperOrder, quote paperQuote) (decimal.Decimal, bool) {
+	if o.order.Side == "buy" {
+		if o.order.OrdType == "market" || o.px.Cmp(quote.ask) >= 0 {
+			return quote.ask, true
+		}
+		return decimal.Zero, false
+	}
+	if o.order.OrdType == "market" || o.px.Cmp(quote.bid) <= 0 {
+		return quote.bid, true
+	}
+	return decimal.Zero, false
+}
+
+// freeze 检查可用余额并冻结订单所需资金，余额不足时返回错误码，调用方需持有锁
+func (s *paperExchange) freeze(o *paperOrder, quote paperQuote) (string, string) {
+	price := o.px
+	if price.IsZero() {
+		price = quote.ask
+		if o.order.Side == "sell" {
+			price = quote.bid
+		}
+	}
+
+	var ccy string
+	var amount decimal.Decimal
+	switch {
+	case o.inst.InstType == "SPOT" && o.order.Side == "buy":
+		ccy, amount = o.inst.QuoteCcy, o.sz.Mul(price)
+		if o.order.TgtCcy == "quote_ccy" {
+			amount = o.sz
+		}
+	case o.inst.InstType == "SPOT":
+		ccy, amount = o.inst.BaseCcy, o.sz
+		if o.order.TgtCcy == "quote_ccy" {
+			amount = paperQuo(o.sz, price)
+		}
+	default:
+		// 合约只为新开仓部分冻结保证金，平仓部分无需保证金
+		ccy = o.inst.SettleCcy
+		reducible := s.reducibleLocked(o)
+		openSz := o.sz.Sub(reducible)
+		if openSz.Sign() < 0 {
+			openSz = decimal.Zero
+		}
+		if o.order.ReduceOnly == "true" {
+			if reducible.IsZero() {
+				return paperCodeNoPositionToReduce, "Order failed because you don't have any positions in this direction for this contract to reduce or close"
+			}
+			openSz = decimal.Zero
+		}
+		amount = paperQuo(openSz.Mul(o.inst.CtVal).Mul(price), s.leverage())
+	}
+
+	if available := s.availableLocked(ccy); available.Cmp(amount) < 0 {
+		return paperCodeInsufficientBalance, fmt.Sprintf("Order failed. Insufficient %s balance（可用 %s，需要 %s）", ccy, formatPaperDecimal(available), formatPaperDecimal(amount))
+	}
+
+	o.frozenCcy, o.frozen = ccy, amount
+	return "", ""
+}
+
+// fillLocked 订单全部按指定价格成交，更新余额和持仓，只减仓订单最多成交持仓数量，调用方需持有锁
+func (s *paperExchange) fillLocked(o *paperOrder, price decimal.Decimal, maker bool, now int64) {
+	feeRate := paperDecimal(s.config.TakerFeeRate)
+	if maker {
+		feeRate = paperDecimal(s.config.MakerFeeRate)
+	}
+
+	sz := o.sz
+	inst := o.inst
+	if inst.InstType != "SPOT" && o.order.ReduceOnly == "true" {
+		// 只减仓订单最多成交持仓数量，不会开仓或反手；挂单期间持仓已平时撤单
+		held := s.reducibleLocked(o)
+		if held.IsZero() {
+			s.cancelLocked(o, now)
+			return
+		}
+		if held.Cmp(sz) < 0 {
+			sz = held
+		}
+	}
+	var fee, pnl decimal.Decimal
+	var feeCcy string
+
+	switch {
+	case inst.InstType == "SPOT" && o.order.Side == "buy":
+		// 现货买入的手续费以交易货币收取
+		if o.order.TgtCcy == "quote_ccy" {
+			sz = paperQuo(o.sz, price)
+		}
+		fee, feeCcy = sz.Mul(feeRate), inst.BaseCcy
+		s.cash[inst.QuoteCcy] = s.cash[inst.QuoteCcy].Sub(sz.Mul(price))
+		s.cash[inst.BaseCcy] = s.cash[inst.BaseCcy].Add(sz.Sub(fee))
+	case inst.InstType == "SPOT":
+		// 现货卖出的手续费以计价货币收取
+		if o.order.TgtCcy == "quote_ccy" {
+			sz = paperQuo(o.sz, price)
+		}
+		fee, feeCcy = sz.Mul(price).Mul(feeRate), inst.QuoteCcy
+		s.cash[inst.BaseCcy] = s.cash[inst.BaseCcy].Sub(sz)
+		s.cash[inst.QuoteCcy] = s.cash[inst.QuoteCcy].Add(sz.Mul(price).Sub(fee))
+	default:
+		fee, feeCcy = sz.Mul(inst.CtVal).Mul(price).Mul(feeRate), inst.SettleCcy
+		s.cash[inst.SettleCcy] = s.cash[inst.SettleCcy].Sub(fee)
+		pnl = s.applyContractFill(o, sz, price, fee, now)
+	}
+
+	o.frozen = decimal.Zero
+	o.order.State = "filled"
+	o.order.AccFillSz = formatPaperDecimal(sz)
+	o.order.FillSz = o.order.AccFillSz
+	o.order.FillPx = formatPaperDecimal(price)
+	o.order.AvgPx = o.order.FillPx
+	o.order.FillTime = strconv.FormatInt(now, 10)
+	o.order.TradeId = strconv.FormatInt(now, 10)
+	o.order.Fee = formatPaperDecimal(fee.Neg())
+	o.order.FeeCcy = feeCcy
+	o.order.Pnl = formatPaperDecimal(pnl)
+	o.order.UTime = strconv.FormatInt(now, 10)
+	s.archiveLocked(o.order)
+}
+
+// applyContractFill 按买卖模式更新合约净持仓，返回平仓收益，调用方需持有锁
+func (s *paperExchange) applyContractFill(o *paperOrder, size, price, fee decimal.Decimal, now int64) decimal.Decimal {
+	inst := o.inst
+	signed := size
+	if o.order.Side == "sell" {
+		signed = size.Neg()
+	}
+
+	position := s.positions[o.order.InstId]
+	if position == nil {
+		position = s.openPositionLocked(o, signed, now)
+	}
+	position.fee = position.fee.Sub(fee)
+	position.uTime = now
+
+	pnl := decimal.Zero
+	openSz := size // 平仓后剩余的开仓数量
+	if !position.pos.IsZero() && position.pos.Sign() != signed.Sign() {
+		// 反向成交先平仓
+		short := position.pos.Sign() < 0
+		held := position.pos.Abs()
+		closeSz := size
+		if held.Cmp(size) < 0 {
+			closeSz = held
+		}
+		remaining := held.Sub(closeSz)
+
+		pnl = closeSz.Mul(inst.CtVal).Mul(price.Sub(position.avgPx))
+		if short {
+			pnl = pnl.Neg()
+		}
+		s.cash[inst.SettleCcy] = s.cash[inst.SettleCcy].Add(pnl)
+		position.pnl = position.pnl.Add(pnl)
+		position.margin = paperQuo(position.margin.Mul(remaining), held)
+		position.closeTotalPos = position.closeTotalPos.Add(closeSz)
+		position.closeValue = position.closeValue.Add(closeSz.Mul(price))
+		openSz = size.Sub(closeSz)
+
+		if remaining.IsZero() {
+			position.pos = decimal.Zero
+			s.closePositionLocked(position, now)
+			position = nil
+		} else if short {
+			position.pos = remaining.Neg()
+		} else {
+			position.pos = remaining
+		}
+	}
+
+	if openSz.Sign() > 0 {
+		if signed.Sign() < 0 {
+			openSz = openSz.Neg()
+		}
+		if position == nil {
+			// 反手开仓作为新持仓
+			position = s.openPositionLocked(o, openSz, now)
+		}
+		// 同向成交按数量加权更新开仓均价
+		held := position.pos.Abs()
+		opened := openSz.Abs()
+		position.avgPx = paperQuo(held.Mul(position.avgPx).Add(opened.Mul(price)), held.Add(opened))
+		position.pos = position.pos.Add(openSz)
+		position.margin = position.margin.Add(paperQuo(opened.Mul(inst.CtVal).Mul(price), position.lever))
+		if position.pos.Abs().Cmp(position.openMaxPos) > 0 {
+			position.openMaxPos = position.pos.Abs()
+		}
+	}
+
+	return pnl
+}
+
+// reducibleLocked 订单可平仓的数量，即反向持仓的张数，没有反向持仓时为0，调用方需持有锁
+func (s *paperExchange) reducibleLocked(o *paperOrder) decimal.Decimal {
+	position, exists := s.positions[o.order.InstId]
+	if !exists || position.pos.IsZero() || (position.pos.Sign() > 0) == (o.order.Side == "buy") {
+		return decimal.Zero
+	}
+	return position.pos.Abs()
+}
+
+// openPositionLocked 创建新持仓，调用方需持有锁
+func (s *paperExchange) openPositionLocked(o *paperOrder, signed decimal.Decimal, now int64) *paperPosition {
+	direction := "long"
+	if signed.Sign() < 0 {
+		direction = "short"
+	}
+
+	s.nextId++
+	position := &paperPosition{
+		inst:      o.inst,
+		instId:    o.order.InstId,
+		posId:     strconv.FormatInt(s.nextId, 10),
+		mgnMode:   o.order.TdMode,
+		direction: direction,
+		lever:     s.leverage(),
+		cTime:     now,
+		uTime:     now,
+	}
+	s.positions[o.order.InstId] = position
+	return position
+}
+
+// closePositionLocked 持仓全部平仓后移入历史持仓，调用方需持有锁
+func (s *paperExchange) closePositionLocked(position *paperPosition, now int64) {
+	delete(s.positions, position.instId)
+
+	realized := position.pnl.Add(position.fee)
+	openMargin := paperQuo(position.openMaxPos.Mul(position.inst.CtVal).Mul(position.avgPx), position.lever)
+
+	pnlRatio := decimal.Zero
+	if openMargin.Sign() > 0 {
+		pnlRatio = paperQuo(realized, openMargin)
+	}
+
+	closed := OKXPositionHistoryData{
+		InstType:      position.inst.InstType,
+		InstId:        position.instId,
+		MgnMode:       position.mgnMode,
+		Type:          "2", // 完全平仓
+		CTime:         strconv.FormatInt(position.cTime, 10),
+		UTime:         strconv.FormatInt(now, 10),
+		OpenAvgPx:     formatPaperDecimal(position.avgPx),
+		CloseAvgPx:    formatPaperDecimal(paperQuo(position.closeValue, position.closeTotalPos)),
+		PosId:         position.posId,
+		OpenMaxPos:    formatPaperDecimal(position.openMaxPos),
+		CloseTotalPos: formatPaperDecimal(position.closeTotalPos),
+		RealizedPnl:   formatPaperDecimal(realized),
+		PnlRatio:      formatPaperDecimal(pnlRatio),
+		Fee:           formatPaperDecimal(position.fee),
+		FundingFee:    "0",
+		LiqPenalty:    "0",
+		Pnl:           formatPaperDecimal(position.pnl),
+		PosSide:       "net",
+		Lever:         formatPaperDecimal(position.lever),
+		Direction:     position.direction,
+		Ccy:           position.inst.SettleCcy,
+	}
+
+	s.closed = append([]OKXPositionHistoryData{closed}, s.closed...)
+	if len(s.closed) > maxPaperHistory {
+		s.closed = s.closed[:maxPaperHistory]
+	}
+}
+
+// cancelLocked 撤销订单并解冻资金，调用方需持有锁
+func (s *paperExchange) cancelLocked(o *paperOrder, now int64) {
+	delete(s.orders, o.order.OrdId)
+	o.frozen = decimal.Zero
+	o.order.State = "canceled"
+	o.order.UTime = strconv.FormatInt(now, 10)
+	s.archiveLocked(o.order)
+}
+
+// archiveLocked 将已完成订单加入历史，调用方需持有锁
+func (s *paperExchange) archiveLocked(order models.Order) {
+	s.history = append([]models.Order{order}, s.history...)
+	if len(s.history) > maxPaperHistory {
+		s.history = s.history[:maxPaperHistory]
+	}
+}
+
+// findOrder 按订单ID或客户自定义订单ID查找未成交订单，调用方需持有锁
+func (s *paperExchange) findOrder(instId, ordId, clOrdId string) *paperOrder {
+	if ordId != "" {
+		if o, exists := s.orders[ordId]; exists && (instId == "" || o.order.InstId == instId) {
+			return o
+		}
+		return nil
+	}
+	for _, o := range s.orders {
+		if clOrdId != "" && o.order.ClOrdId == clOrdId && (instId == "" || o.order.InstId == instId) {
+			return o
+		}
+	}
+	return nil
+}
+
+// availableLocked 币种可用余额 = 现金 - 挂单冻结 - 持仓保证金 + 未实现亏损，调用方需持有锁
+func (s *paperExchange) availableLocked(ccy string) decimal.Decimal {
+	available := s.cash[ccy].Sub(s.frozenLocked(ccy))
+	for _, position := range s.positions {
+		if position.inst.SettleCcy == ccy {
+			if upl := s.uplLocked(position); upl.Sign() < 0 {
+				available = available.Add(upl)
+			}
+		}
+	}
+	return available
+}
+
+// frozenLocked 币种冻结金额（挂单冻结和持仓保证金），调用方需持有锁
+func (s *paperExchange) frozenLocked(ccy string) decimal.Decimal {
+	frozen := decimal.Zero
+	for _, o := range s.orders {
+		if o.frozenCcy == ccy {
+			frozen = frozen.Add(o.frozen)
+		}
+	}
+	for _, position := range s.positions {
+		if position.inst.SettleCcy == ccy {
+			frozen = frozen.Add(position.margin)
+		}
+	}
+	return frozen
+}
+
+// uplLocked 持仓未实现盈亏，按最新成交价计算，调用方需持有锁
+func (s *paperExchange) uplLocked(position *paperPosition) decimal.Decimal {
+	last := s.quotes[position.instId].last
+	if last.IsZero() {
+		return decimal.Zero
+	}
+	return position.pos.Mul(position.inst.CtVal).Mul(last.Sub(position.avgPx))
+}
+
+// usdPriceLocked 币种的USDT价格，稳定币为1，无行情时返回0，调用方需持有锁
+func (s *paperExchange) usdPriceLocked(ccy string) decimal.Decimal {
+	if isStablecoin(ccy) {
+		return decimal.New(1)
+	}
+	return s.quotes[ccy+"-USDT"].last
+}
+
+// balanceLocked 构建OKX格式的账户余额，调用方需持有锁
+// 总权益按各币种输出的权益累加，稳定币账户的总权益与明细之和完全一致
+func (s *paperExchange) balanceLocked() *OKXBalanceData {
+	uTime := strconv.FormatInt(s.lastUpdateMs, 10)
+
+	currencies := make([]string, 0, len(s.cash))
+	for ccy := range s.cash {
+		currencies = append(currencies, ccy)
+	}
+	sort.Strings(currencies)
+
+	totalEq := decimal.Zero
+	details := make([]OKXBalanceDetail, 0, len(currencies))
+	for _, ccy := range currencies {
+		equity := s.cash[ccy]
+		for _, position := range s.positions {
+			if position.inst.SettleCcy == ccy {
+				equity = equity.Add(s.uplLocked(position))
+			}
+		}
+		totalEq = totalEq.Add(equity.Round(paperDisplayPlaces).Mul(s.usdPriceLocked(ccy)))
+
+		available := s.availableLocked(ccy)
+		if available.Sign() < 0 {
+			available = decimal.Zero
+		}
+		details = append(details, OKXBalanceDetail{
+			Ccy:       ccy,
+			Bal:       formatPaperDecimal(equity),
+			CashBal:   formatPaperDecimal(s.cash[ccy]),
+			AvailBal:  formatPaperDecimal(available),
+			FrozenBal: formatPaperDecimal(s.frozenLocked(ccy)),
+			UTime:     uTime,
+		})
+	}
+
+	return &OKXBalanceData{
+		Details: details,
+		TotalEq: formatPaperDecimal(totalEq),
+		UTime:   uTime,
+	}
+}
+
+// positionsLocked 构建OKX格式的持仓，调用方需持有锁
+func (s *paperExchange) positionsLocked() []OKXPositionData {
+	positions := make([]OKXPositionData, 0, len(s.positions))
+	for _, position := range s.positions {
+		last := s.quotes[position.instId].last
+		upl := s.uplLocked(position)
+
+		uplRatio := decimal.Zero
+		if position.margin.Sign() > 0 {
+			uplRatio = paperQuo(upl, position.margin)
+		}
+
+		positions = append(positions, OKXPositionData{
+			InstType:       position.inst.InstType,
+			InstId:         position.instId,
+			MgnMode:        position.mgnMode,
+			PosId:          position.posId,
+			PosSide:        "net",
+			Pos:            formatPaperDecimal(position.pos),
+			AvailPos:       formatPaperDecimal(position.pos.Abs()),
+			AvgPx:          formatPaperDecimal(position.avgPx),
+			Upl:            formatPaperDecimal(upl),
+			UplRatio:       formatPaperDecimal(uplRatio),
+			UplLastPx:      formatPaperDecimal(upl),
+			UplRatioLastPx: formatPaperDecimal(uplRatio),
+			Lever:          formatPaperDecimal(position.lever),
+			MarkPx:         formatPaperDecimal(last),
+			Last:           formatPaperDecimal(last),
+			Imr:            formatPaperDecimal(position.margin),
+			Margin:         formatPaperDecimal(position.margin),
+			NotionalUsd:    formatPaperDecimal(position.pos.Abs().Mul(position.inst.CtVal).Mul(last).Mul(s.usdPriceLocked(position.inst.SettleCcy))),
+			Ccy:            position.inst.SettleCcy,
+			RealizedPnl:    formatPaperDecimal(position.pnl.Add(position.fee)),
+			Pnl:            formatPaperDecimal(position.pnl),
+			Fee:            formatPaperDecimal(position.fee),
+			FundingFee:     "0",
+			LiqPenalty:     "0",
+			CTime:          strconv.FormatInt(position.cTime, 10),
+			UTime:          strconv.FormatInt(position.uTime, 10),
+		})
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].PosId < positions[j].PosId })
+	return positions
+}
+
+// sortedOrdersLocked 按订单ID顺序返回未成交订单，调用方需持有锁
+func (s *paperExchange) sortedOrdersLocked() []*paperOrder {
+	orders := make([]*paperOrder, 0, len(s.orders))
+	for _, o := range s.orders {
+		orders = append(orders, o)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].order.OrdId < orders[j].order.OrdId })
+	return orders
+}
+
+// notifyChanges 推送订单变化，并推送变化后的余额和持仓
+func (s *paperExchange) notifyChanges(orders []models.Order) {
+	if len(orders) == 0 {
+		return
+	}
+
+	balance, _ := s.Balance()
+	positions, _ := s.Positions()
+	s.notify("orders", orders)
+	s.notify("account", []OKXBalanceData{*balance})
+	s.notify("positions", positions)
+}
+
+// notify 以OKX私有频道格式向监听者推送增量变化
+func (s *paperExchange) notify(channel string, data interface{}) {
+	s.broadcast(&models.AccountStreamMessage{
+		Type:      "delta",
+		Channel:   channel,
+		EventType: "event_update",
+		Data:      data,
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// broadcast 向所有监听者推送消息
+func (s *paperExchange) broadcast(message *models.AccountStreamMessage) {
+	s.listenerMutex.RLock()
+	defer s.listenerMutex.RUnlock()
+
+	for _, listener := range s.listeners {
+		listener(message)
+	}
+}
+
+// newPaperQuote 从价格数据解析行情，缺少买一/卖一价时使用最新成交价
+func newPaperQuote(priceData *PriceData) paperQuote {
+	quote := paperQuote{last: parseAmount(priceData.Price)}
+	quote.bid = parseAmount(priceData.BidPx)
+	quote.ask = parseAmount(priceData.AskPx)
+	if quote.bid.Sign() <= 0 {
+		quote.bid = quote.last
+	}
+	if quote.ask.Sign() <= 0 {
+		quote.ask = quote.last
+	}
+	return quote
+}
+
+// newPaperResult 构建下单/撤单/改单响应，全部失败时code为"1"，部分失败时为"2"
+func newPaperResult(results []models.OrderResult) *models.OrderResultResponse {
+	failed := 0
+	for _, result := range results {
+		if result.SCode != "0" {
+			failed++
+		}
+	}
+
+	response := &models.OrderResultResponse{Code: "0", Data: results}
+	switch {
+	case failed == 0:
+	case failed == len(results):
+		response.Code, response.Msg = "1", "All operations failed"
+	default:
+		response.Code, response.Msg = "2", "Batch operation partially succeeded"
+	}
+	return response
+}
+
+// paperFailure 构建失败的单条结果
+func paperFailure(result models.OrderResult, code, msg string) models.OrderResult {
+	result.SCode = code
+	result.SMsg = msg
+	return result
+}
+
+// matchOrderFilter 按产品类型、产品ID和订单类型过滤订单
+func matchOrderFilter(order *models.Order, req *models.OrdersRequest) bool {
+	return (req.InstType == "" || order.InstType == req.InstType) &&
+		(req.InstId == "" || order.InstId == req.InstId) &&
+		(req.OrdType == "" || order.OrdType == req.OrdType)
+}
+
+// limitOrders 按limit截断订单列表，默认100条
+func limitOrders(orders []models.Order, limit string) []models.Order {
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 || n > 100 {
+		n = 100
+	}
+	if len(orders) > n {
+		return orders[:n]
+	}
+	return orders
+}
+
+// isStablecoin 是否为美元稳定币
+func isStablecoin(ccy string) bool {
+	switch ccy {
+	case "USDT", "USDC", "USD":
+		return true
+	}
+	return false
+}
+
+// leverage 合约杠杆倍数，未配置时按1倍
+func (s *paperExchange) leverage() decimal.Decimal {
+	if s.config.DefaultLeverage <= 0 {
+		return decimal.New(1)
+	}
+	return paperDecimal(s.config.DefaultLeverage)
+}
+
+// paperDecimal 将配置中的浮点数按最短十进制表示转换，如 0.0005 不会带上二进制浮点误差
+func paperDecimal(value float64) decimal.Decimal {
+	return decimal.MustParse(strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// paperQuo 除法，结果保留 paperQuoPlaces 位小数
+func paperQuo(value, divisor decimal.Decimal) decimal.Decimal {
+	return value.Quo(divisor).Round(paperQuoPlaces)
+}
+
+// formatPaperDecimal 格式化金额，保留8位小数并去掉多余的0
+func formatPaperDecimal(value decimal.Decimal) string {
+	formatted := value.Round(paperDisplayPlaces).String()
+	if strings.Contains(formatted, ".") {
+		formatted = strings.TrimSuffix(strings.TrimRight(formatted, "0"), ".")
+	}
+	return formatted
+}
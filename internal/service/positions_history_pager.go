@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"strconv"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+)
+
+// ErrInvalidCursor 分页游标无法解析
+var ErrInvalidCursor = errors.New("无效的分页游标")
+
+const (
+	// positionsHistoryFetchLimit OKX历史持仓接口单次最多返回的条数
+	positionsHistoryFetchLimit = 100
+	// DefaultPositionsHistoryPageSize 未指定 pageSize 时每页的条数
+	DefaultPositionsHistoryPageSize = 100
+	// MaxPositionsHistoryPageSize 游标分页每页最多的条数
+	MaxPositionsHistoryPageSize = 500
+	// MaxPositionsHistoryAll all=true 时一次最多返回的条数，超过时返回 nextCursor 继续获取
+	MaxPositionsHistoryAll = 10000
+)
+
+// positionsHistoryCursor 游标分页的位置：已返回到的更新时间，以及该时间点上已返回的持仓
+// 同一毫秒可能有多条持仓，只记录时间会在翻页时重复或遗漏
+type positionsHistoryCursor struct {
+	UTime  int64    `json:"u"`
+	PosIDs []string `json:"p,omitempty"`
+}
+
+// encodePositionsHistoryCursor 编码为URL安全的不透明字符串
+func encodePositionsHistoryCursor(cursor *positionsHistoryCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePositionsHistoryCursor 解析 encodePositionsHistoryCursor 生成的游标
+func decodePositionsHistoryCursor(value string) (*positionsHistoryCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor positionsHistoryCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.UTime <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// positionsHistoryFetcher 请求一页历史持仓
+type positionsHistoryFetcher func(ctx context.Context, req *models.PositionsHistoryRequest) ([]OKXPositionHistoryData, error)
+
+// positionsHistoryWalker 按更新时间从新到旧遍历历史持仓，每次向OKX请求一页
+// 请求经过共享REST传输层，按OKX接口限速排队
+type positionsHistoryWalker struct {
+	fetchPage positionsHistoryFetcher
+	filter    models.PositionsHistoryRequest // 产品类型等过滤条件
+	from      int64                          // 更新时间下限（含），0表示不限
+	cursor    *positionsHistoryCursor        // 已返回到的位置，nil表示从 to 开始
+
+	buffered  []OKXPositionHistoryData
+	exhausted bool // OKX已没有更早的数据
+}
+
+// newPositionsHistoryWalker 从游标位置开始遍历，没有游标时从 req.To 开始
+func newPositionsHistoryWalker(fetchPage positionsHistoryFetcher, req *models.PositionsHistoryRequest) (*positionsHistoryWalker, error) {
+	walker := &positionsHistoryWalker{
+		fetchPage: fetchPage,
+		filter: models.PositionsHistoryRequest{
+			InstType: req.InstType,
+			InstId:   req.InstId,
+			MgnMode:  req.MgnMode,
+			Type:     req.Type,
+			PosId:    req.PosId,
+		},
+		from: req.From,
+	}
+
+	if req.Cursor != "" {
+		cursor, err := decodePositionsHistoryCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		walker.cursor = cursor
+	} else if req.To > 0 {
+		walker.cursor = &positionsHistoryCursor{UTime: req.To}
+	}
+	return walker, nil
+}
+
+// next 下一条历史持仓，没有更多数据时返回 false
+func (w *positionsHistoryWalker) next(ctx context.Context) (*OKXPositionHistoryData, bool, error) {
+	for len(w.buffered) == 0 {
+		if w.exhausted {
+			return nil, false, nil
+		}
+		if err := w.fetch(ctx); err != nil {
+			return nil, false, err
+		}
+	}
+
+	pos := w.buffered[0]
+	w.buffered = w.buffered[1:]
+
+	uTime, _ := strconv.ParseInt(pos.UTime, 10, 64)
+	if w.cursor == nil || uTime < w.cursor.UTime {
+		w.cursor = &positionsHistoryCursor{UTime: uTime}
+	}
+	w.cursor.PosIDs = append(w.cursor.PosIDs, pos.PosId)
+	return &pos, true, nil
+}
+
+// more 是否可能还有数据；恰好在最后一条处分页时会多返回一个空页
+func (w *positionsHistoryWalker) more() bool {
+	return len(w.buffered) > 0 || !w.exhausted
+}
+
+// fetch 请求游标位置及更早的一页，跳过该时间点上已返回的持仓
+func (w *positionsHistoryWalker) fetch(ctx context.Context) error {
+	req := w.filter
+	req.Limit = strconv.Itoa(positionsHistoryFetchLimit)
+	if w.cursor != nil {
+		// after 返回更新时间早于该值的数据，加1以包含游标时间点上尚未返回的持仓
+		req.After = strconv.FormatInt(w.cursor.UTime+1, 10)
+	}
+	if w.from > 0 {
+		req.Before = strconv.FormatInt(w.from-1, 10)
+	}
+
+	data, err := w.fetchPage(ctx, &req)
+	if err != nil {
+		return err
+	}
+	w.exhausted = len(data) < positionsHistoryFetchLimit
+
+	returned := make(map[string]bool)
+	if w.cursor != nil {
+		for _, posID := range w.cursor.PosIDs {
+			returned[posID] = true
+		}
+	}
+	for _, pos := range data {
+		if w.cursor != nil && pos.UTime == strconv.FormatInt(w.cursor.UTime, 10) && returned[pos.PosId] {
+			continue
+		}
+		w.buffered = append(w.buffered, pos)
+	}
+
+	// 同一毫秒的持仓超过一页且都已返回时跳过该时间点，避免重复请求同一页
+	if len(w.buffered) == 0 && !w.exhausted {
+		w.cursor = &positionsHistoryCursor{UTime: w.cursor.UTime - 1}
+	}
+	return nil
+}
+
+// GetPositionsHistoryPage 游标分页获取历史持仓，服务端按需向OKX请求多页
+// All 为 true 时返回 From/To 范围内的全部持仓，超过 MaxPositionsHistoryAll 条时返回 nextCursor
+func (s *accountService) GetPositionsHistoryPage(ctx context.Context, req *models.PositionsHistoryRequest, currency models.Currency) (*models.PositionsHistoryResponse, error) {
+	pageSize := req.PageSize
+	switch {
+	case req.All:
+		pageSize = MaxPositionsHistoryAll
+	case pageSize <= 0:
+		pageSize = DefaultPositionsHistoryPageSize
+	case pageSize > MaxPositionsHistoryPageSize:
+		pageSize = MaxPositionsHistoryPageSize
+	}
+
+	walker, err := newPositionsHistoryWalker(s.positionsHistoryData, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []OKXPositionHistoryData
+	for len(data) < pageSize {
+		pos, ok, err := walker.next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		data = append(data, *pos)
+	}
+
+	response := s.positionsHistoryResponse(ctx, data, currency)
+	if walker.more() && walker.cursor != nil {
+		response.HasMore = true
+		response.NextCursor = encodePositionsHistoryCursor(walker.cursor)
+	}
+	return response, nil
+}
+
+// AllPositionsHistory 遍历 req.From 到 req.To 范围内的全部历史持仓，从新到旧，供报表等内部调用
+// 出错时产出一次错误后结束；调用方提前退出时不再请求后续页
+func (s *accountService) AllPositionsHistory(ctx context.Context, req *models.PositionsHistoryRequest, currency models.Currency) iter.Seq2[*models.PositionHistory, error] {
+	return func(yield func(*models.PositionHistory, error) bool) {
+		walker, err := newPositionsHistoryWalker(s.positionsHistoryData, req)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for {
+			pos, ok, err := walker.next(ctx)
+			if err != nil {
+				yield(nil, fmt.Errorf("遍历历史持仓失败: %w", err))
+				return
+			}
+			if !ok || !yield(toPositionHistory(pos, currency), nil) {
+				return
+			}
+		}
+	}
+}
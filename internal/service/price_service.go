@@ -1,21 +1,23 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
 )
 
 // PriceData 价格数据结构
 type PriceData struct {
 	Symbol          string  `json:"symbol"`
 	Price           string  `json:"price"`
+	BidPx           string  `json:"bidPx,omitempty"` // 买一价
+	AskPx           string  `json:"askPx,omitempty"` // 卖一价
 	Change24h       string  `json:"change24h,omitempty"`
 	ChangePercent24h string  `json:"changePercent24h,omitempty"`
 	Volume24h       string  `json:"volume24h,omitempty"`
@@ -26,89 +28,45 @@ type PriceData struct {
 
 // PriceService 价格服务接口
 type PriceService interface {
-	GetPrice(symbol string) (*PriceData, error)
+	GetPrice(ctx context.Context, symbol string) (*PriceData, error)
 	StartPriceStream(symbol string, callback func(*PriceData))
+	StopSymbolStream(symbol string)
 	StopPriceStream()
 }
 
 // priceService 价格服务实现
 type priceService struct {
-	config   *config.OKXConfig
-	stopChan chan struct{}
+	config    *config.OKXConfig
+	rest      *okx.Client
+	mutex     sync.Mutex
+	feed      *okx.MarketFeed
+	callbacks map[string][]func(*PriceData)
 }
 
 // NewPriceService 创建价格服务实例
 func NewPriceService(cfg *config.OKXConfig) PriceService {
 	return &priceService{
-		config:   cfg,
-		stopChan: make(chan struct{}),
+		config:    cfg,
+		rest:      NewOKXTransport(cfg),
+		callbacks: make(map[string][]func(*PriceData)),
 	}
 }
 
 // Ticker OKX行情数据结构
-type Ticker struct {
-	InstType  string `json:"instType"`
-	InstId    string `json:"instId"`
-	Last      string `json:"last"`
-	LastSz    string `json:"lastSz"`
-	AskPx     string `json:"askPx"`
-	AskSz     string `json:"askSz"`
-	BidPx     string `json:"bidPx"`
-	BidSz     string `json:"bidSz"`
-	Open24h   string `json:"open24h"`
-	High24h   string `json:"high24h"`
-	Low24h    string `json:"low24h"`
-	Vol24h    string `json:"vol24h"`
-	VolCcy24h string `json:"volCcy24h"`
-	SodUtc0   string `json:"sodUtc0"`
-	SodUtc8   string `json:"sodUtc8"`
-	Ts        string `json:"ts"`
-}
-
-// TickerResponse OKX行情响应
-type TickerResponse struct {
-	Code string   `json:"code"`
-	Msg  string   `json:"msg"`
-	Data []Ticker `json:"data"`
-}
+type Ticker = okx.Ticker
 
 // GetPrice 获取指定交易对的当前价格
-func (s *priceService) GetPrice(symbol string) (*PriceData, error) {
-	// 调用OKX API获取ticker数据
-	url := fmt.Sprintf("%s/api/v5/market/ticker?instId=%s", s.config.BaseURL, symbol)
-	
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+func (s *priceService) GetPrice(ctx context.Context, symbol string) (*PriceData, error) {
+	ticker, err := fetchTicker(ctx, s.rest, symbol)
 	if err != nil {
-		return nil, fmt.Errorf("请求失败: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %w", err)
-	}
-
-	var result TickerResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %w", err)
-	}
-
-	if len(result.Data) == 0 {
-		return nil, fmt.Errorf("未找到交易对 %s 的价格数据", symbol)
-	}
+	return newPriceData(symbol, ticker), nil
+}
 
-	ticker := result.Data[0]
-	
-	// 计算24小时变化
+// newPriceData 根据行情数据计算价格和24小时变化
+func newPriceData(symbol string, ticker *Ticker) *PriceData {
 	price, _ := strconv.ParseFloat(ticker.Last, 64)
 	open24h, _ := strconv.ParseFloat(ticker.Open24h, 64)
 	change24h := price - open24h
@@ -120,41 +78,72 @@ func (s *priceService) GetPrice(symbol string) (*PriceData, error) {
 	return &PriceData{
 		Symbol:           symbol,
 		Price:            ticker.Last,
+		BidPx:            ticker.BidPx,
+		AskPx:            ticker.AskPx,
 		Change24h:        fmt.Sprintf("%.2f", change24h),
 		ChangePercent24h: fmt.Sprintf("%.2f", changePercent),
 		Volume24h:        ticker.Vol24h,
 		High24h:          ticker.High24h,
 		Low24h:           ticker.Low24h,
 		Timestamp:        time.Now().Unix(),
-	}, nil
+	}
 }
 
-// StartPriceStream 启动价格数据流
+// StartPriceStream 通过OKX公共WebSocket订阅tickers频道推送价格，同一交易对只订阅一次
 func (s *priceService) StartPriceStream(symbol string, callback func(*PriceData)) {
-	go func() {
-		ticker := time.NewTicker(5 * time.Second) // 每5秒更新一次
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				priceData, err := s.GetPrice(symbol)
-				if err != nil {
-					log.Printf("获取价格数据失败: %v", err)
-					continue
-				}
-				callback(priceData)
-			case <-s.stopChan:
-				return
-			}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.feed == nil {
+		s.feed = okx.NewMarketFeed(s.config.WSPublicURL, s.config.WSBusinessURL)
+	}
+
+	s.callbacks[symbol] = append(s.callbacks[symbol], callback)
+	if len(s.callbacks[symbol]) > 1 {
+		return
+	}
+
+	err := s.feed.SubscribeTickers(symbol, func(ticker *Ticker) {
+		priceData := newPriceData(symbol, ticker)
+
+		s.mutex.Lock()
+		callbacks := append([]func(*PriceData){}, s.callbacks[symbol]...)
+		s.mutex.Unlock()
+
+		for _, cb := range callbacks {
+			cb(priceData)
 		}
-	}()
+	})
+	if err != nil {
+		log.Printf("订阅%s价格推送失败: %v", symbol, err)
+	}
 }
 
-// StopPriceStream 停止价格数据流
+// StopSymbolStream 停止指定交易对的价格推送并取消上游订阅
+func (s *priceService) StopSymbolStream(symbol string) {
+	s.mutex.Lock()
+	_, exists := s.callbacks[symbol]
+	delete(s.callbacks, symbol)
+	feed := s.feed
+	s.mutex.Unlock()
+
+	if !exists || feed == nil {
+		return
+	}
+	if err := feed.Unsubscribe("tickers", symbol); err != nil {
+		log.Printf("取消订阅%s价格推送失败: %v", symbol, err)
+	}
+}
+
+// StopPriceStream 停止价格数据流并关闭WebSocket连接
 func (s *priceService) StopPriceStream() {
-	select {
-	case s.stopChan <- struct{}{}:
-	default:
+	s.mutex.Lock()
+	feed := s.feed
+	s.feed = nil
+	s.callbacks = make(map[string][]func(*PriceData))
+	s.mutex.Unlock()
+
+	if feed != nil {
+		feed.Stop()
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+)
+
+// rateHubs 寻找兑换路径时优先经过的资产，同样跳数下选择流动性更好的路径
+var rateHubs = []string{"USDT", "USD", "USDC", "BTC", "ETH"}
+
+// RateQuote 一条汇率报价：1 Base = Rate Quote
+type RateQuote struct {
+	Base      string
+	Quote     string
+	Rate      decimal.Decimal
+	Source    string    // 来源名称，如 okx / http / file
+	UpdatedAt time.Time // 报价时间
+	Live      bool      // 是否为本次刷新获取的实时数据，缓存和本地文件数据为false
+}
+
+// rateEdge 汇率图中的一条边
+type rateEdge struct {
+	rate  decimal.Decimal
+	quote *RateQuote // 原始报价，正反两条边共用
+}
+
+// RateGraph 汇率图，节点为资产，边为两种资产之间的兑换比率
+// 由各汇率来源的报价构建，任意两种连通的资产之间均可换算，比率和换算结果均为精确值
+type RateGraph struct {
+	rates     map[string]map[string]rateEdge // rates[a][b] 表示1个a可兑换的b数量
+	updatedAt time.Time
+}
+
+// NewRateGraph 创建空的汇率图
+func NewRateGraph() *RateGraph {
+	return &RateGraph{
+		rates:     make(map[string]map[string]rateEdge),
+		updatedAt: time.Now(),
+	}
+}
+
+// AddRate 添加 1 base = rate quote 的兑换关系，同时添加反向边，已有的兑换关系会被覆盖
+func (g *RateGraph) AddRate(base, quote string, rate decimal.Decimal) {
+	g.AddQuote(RateQuote{Base: base, Quote: quote, Rate: rate, UpdatedAt: time.Now(), Live: true})
+}
+
+// AddQuote 添加一条报价及其反向边，已有的兑换关系会被覆盖
+func (g *RateGraph) AddQuote(quote RateQuote) {
+	if quote.Base == quote.Quote || quote.Rate.Sign() <= 0 {
+		return
+	}
+	g.addEdge(quote.Base, quote.Quote, rateEdge{rate: quote.Rate, quote: &quote})
+	g.addEdge(quote.Quote, quote.Base, rateEdge{rate: decimal.New(1).Quo(quote.Rate), quote: &quote})
+}
+
+func (g *RateGraph) addEdge(from, to string, edge rateEdge) {
+	edges, ok := g.rates[from]
+	if !ok {
+		edges = make(map[string]rateEdge)
+		g.rates[from] = edges
+	}
+	edges[to] = edge
+}
+
+// HasRate 两种资产之间是否有直接的兑换关系
+func (g *RateGraph) HasRate(base, quote string) bool {
+	_, ok := g.rates[base][quote]
+	return ok
+}
+
+// Assets 返回图中的全部资产，按字母排序
+func (g *RateGraph) Assets() []string {
+	assets := make([]string, 0, len(g.rates))
+	for asset := range g.rates {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+	return assets
+}
+
+// UpdatedAt 汇率图的构建时间
+func (g *RateGraph) UpdatedAt() time.Time {
+	return g.updatedAt
+}
+
+// Rate 查找跳数最少的兑换路径，返回 1 from 可兑换的 to 数量及经过的资产
+func (g *RateGraph) Rate(from, to string) (decimal.Decimal, []string, error) {
+	path, err := g.path(from, to)
+	if err != nil {
+		return decimal.Zero, nil, err
+	}
+
+	rate := decimal.New(1)
+	for i := 1; i < len(path); i++ {
+		rate = rate.Mul(g.rates[path[i-1]][path[i]].rate)
+	}
+	return rate, path, nil
+}
+
+// ExchangeRate 查找兑换路径，返回汇率及路径上各段报价的来源和时间
+func (g *RateGraph) ExchangeRate(from, to string) (*models.ExchangeRate, error) {
+	rate, path, err := g.Rate(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ExchangeRate{
+		Base:      models.Currency(from),
+		Quote:     models.Currency(to),
+		Rate:      rate,
+		Path:      path,
+		Sources:   []string{},
+		UpdatedAt: g.updatedAt,
+		Live:      true,
+	}
+	for i := 1; i < len(path); i++ {
+		quote := g.rates[path[i-1]][path[i]].quote
+		if !containsString(result.Sources, quote.Source) {
+			result.Sources = append(result.Sources, quote.Source)
+		}
+		// 路径上最早的报价时间
+		if i == 1 || quote.UpdatedAt.Before(result.UpdatedAt) {
+			result.UpdatedAt = quote.UpdatedAt
+		}
+		result.Live = result.Live && quote.Live
+	}
+	return result, nil
+}
+
+// Convert 将 amount 个 from 换算为 to
+func (g *RateGraph) Convert(amount decimal.Decimal, from, to string) (decimal.Decimal, error) {
+	rate, _, err := g.Rate(from, to)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return amount.Mul(rate), nil
+}
+
+// path 广度优先搜索跳数最少的路径，邻居按枢纽资产优先、其余按字母顺序访问，保证路径稳定
+func (g *RateGraph) path(from, to string) ([]string, error) {
+	if from == to {
+		return []string{from}, nil
+	}
+	if _, ok := g.rates[from]; !ok {
+		return nil, fmt.Errorf("没有 %s 的汇率数据", from)
+	}
+	if _, ok := g.rates[to]; !ok {
+		return nil, fmt.Errorf("没有 %s 的汇率数据", to)
+	}
+
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 && !containsKey(prev, to) {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range g.neighbors(current) {
+			if _, visited := prev[next]; visited {
+				continue
+			}
+			prev[next] = current
+			queue = append(queue, next)
+		}
+	}
+	if !containsKey(prev, to) {
+		return nil, fmt.Errorf("找不到 %s 到 %s 的兑换路径", from, to)
+	}
+
+	path := []string{to}
+	for asset := to; asset != from; {
+		asset = prev[asset]
+		path = append([]string{asset}, path...)
+	}
+	return path, nil
+}
+
+// neighbors 按访问顺序返回可直接兑换的资产
+func (g *RateGraph) neighbors(asset string) []string {
+	edges := g.rates[asset]
+	neighbors := make([]string, 0, len(edges))
+	for _, hub := range rateHubs {
+		if _, ok := edges[hub]; ok {
+			neighbors = append(neighbors, hub)
+		}
+	}
+
+	others := make([]string, 0, len(edges))
+	for next := range edges {
+		if !containsString(rateHubs, next) {
+			others = append(others, next)
+		}
+	}
+	sort.Strings(others)
+	return append(neighbors, others...)
+}
+
+func containsKey(m map[string]string, key string) bool {
+	_, ok := m[key]
+	return ok
+}
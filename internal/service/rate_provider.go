@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+)
+
+// 汇率来源名称
+const (
+	RateSourceOKX  = "okx"  // OKX现货行情和法币汇率
+	RateSourceHTTP = "http" // 以USD为基准的法币汇率接口
+	RateSourceFile = "file" // 本地汇率文件，用于离线兜底或手动指定
+	RateSourcePeg  = "peg"  // USDT与USD按1:1锚定
+)
+
+// RateProvider 汇率来源
+type RateProvider interface {
+	// Name 来源名称，记录在每条报价中
+	Name() string
+	// Quotes 获取全部报价
+	Quotes(ctx context.Context) ([]RateQuote, error)
+}
+
+// RateSource 汇率链中的一个来源
+type RateSource struct {
+	Provider RateProvider
+	MaxAge   time.Duration // 报价的最长有效时间，超过后不再使用，0表示不限制
+}
+
+// RateChain 按优先级组合多个汇率来源构建汇率图
+// 同一对资产只使用优先级最高的来源，来源获取失败时沿用上次成功获取的报价（标记为非实时），
+// 过期的报价会被丢弃，由后面的来源补充
+type RateChain struct {
+	sources []RateSource
+	mutex   sync.Mutex
+	cached  [][]RateQuote // 各来源上次成功获取的报价
+}
+
+// NewRateChain 创建汇率链，来源按优先级从高到低排列
+func NewRateChain(sources ...RateSource) *RateChain {
+	return &RateChain{
+		sources: sources,
+		cached:  make([][]RateQuote, len(sources)),
+	}
+}
+
+// NewRateChainFromConfig 按 RATE_PROVIDERS 配置的顺序创建汇率链，未配置时依次使用 okx、http、file
+func NewRateChainFromConfig(rest *okx.Client, cfg *config.CurrencyConfig) *RateChain {
+	names := cfg.RateProviders
+	if len(names) == 0 {
+		names = []string{RateSourceOKX, RateSourceHTTP, RateSourceFile}
+	}
+
+	var sources []RateSource
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case RateSourceOKX:
+			sources = append(sources, RateSource{Provider: NewOKXRateProvider(rest), MaxAge: minutes(cfg.OKXRatesMaxAge)})
+		case RateSourceHTTP:
+			if len(cfg.FiatRatesURLs) > 0 {
+				sources = append(sources, RateSource{Provider: NewHTTPRateProvider(cfg.FiatRatesURLs), MaxAge: minutes(cfg.HTTPRatesMaxAge)})
+			}
+		case RateSourceFile:
+			if cfg.FiatRatesFile != "" {
+				sources = append(sources, RateSource{Provider: NewFileRateProvider(cfg.FiatRatesFile), MaxAge: minutes(cfg.FileRatesMaxAge)})
+			}
+		default:
+			log.Printf("未知的汇率来源: %s", name)
+		}
+	}
+	return NewRateChain(sources...)
+}
+
+func minutes(n int) time.Duration {
+	return time.Duration(n) * time.Minute
+}
+
+// Build 依次从各来源获取报价构建汇率图
+func (c *RateChain) Build(ctx context.Context) (*RateGraph, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	graph := NewRateGraph()
+	for i, source := range c.sources {
+		quotes, err := source.Provider.Quotes(ctx)
+		if err != nil {
+			log.Printf("获取%s汇率失败，使用上次的报价: %v", source.Provider.Name(), err)
+			quotes = make([]RateQuote, len(c.cached[i]))
+			for j, quote := range c.cached[i] {
+				quote.Live = false
+				quotes[j] = quote
+			}
+		} else {
+			c.cached[i] = quotes
+		}
+
+		for _, quote := range quotes {
+			if source.MaxAge > 0 && time.Since(quote.UpdatedAt) > source.MaxAge {
+				continue
+			}
+			// 已有的兑换关系以优先级更高的来源为准
+			if graph.HasRate(quote.Base, quote.Quote) {
+				continue
+			}
+			graph.AddQuote(quote)
+		}
+	}
+
+	// USDT与USD近似1:1，没有其他来源时作为法币与加密资产之间的桥梁
+	if !graph.HasRate("USDT", "USD") {
+		graph.AddQuote(RateQuote{Base: "USDT", Quote: "USD", Rate: decimal.New(1), Source: RateSourcePeg, UpdatedAt: time.Now(), Live: true})
+	}
+
+	if len(graph.Assets()) <= 2 {
+		return nil, fmt.Errorf("所有汇率来源均不可用")
+	}
+	return graph, nil
+}
+
+// okxRateProvider 使用OKX全部现货行情和OKX提供的美元人民币汇率
+type okxRateProvider struct {
+	rest *okx.Client
+}
+
+// NewOKXRateProvider 创建OKX汇率来源
+func NewOKXRateProvider(rest *okx.Client) RateProvider {
+	return &okxRateProvider{rest: rest}
+}
+
+func (p *okxRateProvider) Name() string {
+	return RateSourceOKX
+}
+
+func (p *okxRateProvider) Quotes(ctx context.Context) ([]RateQuote, error) {
+	tickers, err := okx.Call[[]Ticker](ctx, p.rest, okx.Request{
+		Path:  "/api/v5/market/tickers",
+		Query: url.Values{"instType": {"SPOT"}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取现货行情失败: %w", err)
+	}
+
+	now := time.Now()
+	quotes := make([]RateQuote, 0, len(tickers)+1)
+	for _, ticker := range tickers {
+		base, quote, ok := strings.Cut(ticker.InstId, "-")
+		if !ok || base == "" || quote == "" || strings.Contains(quote, "-") {
+			continue
+		}
+		price, err := decimal.Parse(ticker.Last)
+		if err != nil || price.Sign() <= 0 {
+			continue
+		}
+		quotes = append(quotes, RateQuote{Base: base, Quote: quote, Rate: price, Source: RateSourceOKX, UpdatedAt: parseMillis(ticker.Ts, now), Live: true})
+	}
+
+	// OKX法币汇率接口只提供美元人民币汇率，失败时由其他来源补充
+	fiat, err := okx.Call[[]struct {
+		UsdCny string `json:"usdCny"`
+	}](ctx, p.rest, okx.Request{Path: "/api/v5/market/exchange-rate"})
+	if err != nil {
+		log.Printf("获取OKX美元人民币汇率失败: %v", err)
+	} else if len(fiat) > 0 {
+		if rate, err := decimal.Parse(fiat[0].UsdCny); err == nil {
+			quotes = append(quotes, RateQuote{Base: "USD", Quote: "CNY", Rate: rate, Source: RateSourceOKX, UpdatedAt: now, Live: true})
+		}
+	}
+
+	return quotes, nil
+}
+
+// parseMillis 解析毫秒时间戳，无效时返回 fallback
+func parseMillis(value string, fallback time.Time) time.Time {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms <= 0 {
+		return fallback
+	}
+	return time.UnixMilli(ms)
+}
+
+// httpRateProvider 从HTTP接口获取法币汇率，兼容 exchangerate-api.com 和 open.er-api.com 的响应格式
+type httpRateProvider struct {
+	urls   []string
+	client *http.Client
+}
+
+// NewHTTPRateProvider 创建HTTP汇率来源，按顺序尝试各接口，使用第一个成功的结果
+// 接口返回 {"base": "USD", "rates": {"CNY": 7.2, ...}}，未指定基准币种时按USD处理
+func NewHTTPRateProvider(urls []string) RateProvider {
+	return &httpRateProvider{urls: urls, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (p *httpRateProvider) Name() string {
+	return RateSourceHTTP
+}
+
+func (p *httpRateProvider) Quotes(ctx context.Context) ([]RateQuote, error) {
+	var lastErr error
+	for _, apiURL := range p.urls {
+		quotes, err := p.fetch(ctx, apiURL)
+		if err == nil && len(quotes) > 0 {
+			return quotes, nil
+		}
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", apiURL, err)
+		}
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("接口没有返回汇率")
+	}
+	return nil, fmt.Errorf("无法从任何接口获取法币汇率: %w", lastErr)
+}
+
+func (p *httpRateProvider) fetch(ctx context.Context, apiURL string) ([]RateQuote, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var rateResp struct {
+		Base               string                     `json:"base"`
+		BaseCode           string                     `json:"base_code"`
+		Rates              map[string]decimal.Decimal `json:"rates"`
+		TimeLastUpdated    int64                      `json:"time_last_updated"`
+		TimeLastUpdateUnix int64                      `json:"time_last_update_unix"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rateResp); err != nil {
+		return nil, err
+	}
+
+	base := firstNonEmpty(rateResp.Base, rateResp.BaseCode, "USD")
+	updatedAt := time.Now()
+	if ts := max(rateResp.TimeLastUpdated, rateResp.TimeLastUpdateUnix); ts > 0 {
+		updatedAt = time.Unix(ts, 0)
+	}
+	return baseQuotes(base, rateResp.Rates, RateSourceHTTP, updatedAt, true), nil
+}
+
+// fileRateProvider 从本地JSON文件读取汇率，每次获取时重新读取，修改文件后无需重启
+type fileRateProvider struct {
+	path string
+}
+
+// NewFileRateProvider 创建本地文件汇率来源，文件格式：
+//
+//	{
+//	  "updatedAt": "2025-01-01T00:00:00Z",
+//	  "base": "USD",
+//	  "rates": {"CNY": "7.10", "EUR": "0.92"},
+//	  "pairs": {"USDT-CNY": "7.15"}
+//	}
+//
+// rates 为相对 base 的汇率，pairs 直接指定任意两种资产之间的汇率，updatedAt 为空时使用文件修改时间
+func NewFileRateProvider(path string) RateProvider {
+	return &fileRateProvider{path: path}
+}
+
+func (p *fileRateProvider) Name() string {
+	return RateSourceFile
+}
+
+func (p *fileRateProvider) Quotes(ctx context.Context) ([]RateQuote, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("读取汇率文件失败: %w", err)
+	}
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("读取汇率文件失败: %w", err)
+	}
+
+	var file struct {
+		UpdatedAt time.Time                  `json:"updatedAt"`
+		Base      string                     `json:"base"`
+		Rates     map[string]decimal.Decimal `json:"rates"`
+		Pairs     map[string]decimal.Decimal `json:"pairs"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析汇率文件失败: %w", err)
+	}
+
+	updatedAt := file.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = info.ModTime()
+	}
+
+	quotes := baseQuotes(firstNonEmpty(file.Base, "USD"), file.Rates, RateSourceFile, updatedAt, false)
+	for pair, rate := range file.Pairs {
+		base, quote, ok := strings.Cut(strings.ToUpper(pair), "-")
+		if !ok || base == "" || quote == "" {
+			return nil, fmt.Errorf("无效的汇率文件交易对: %s", pair)
+		}
+		quotes = append(quotes, RateQuote{Base: base, Quote: quote, Rate: rate, Source: RateSourceFile, UpdatedAt: updatedAt, Live: false})
+	}
+	return quotes, nil
+}
+
+// baseQuotes 将相对基准币种的汇率转换为报价
+func baseQuotes(base string, rates map[string]decimal.Decimal, source string, updatedAt time.Time, live bool) []RateQuote {
+	base = strings.ToUpper(base)
+	quotes := make([]RateQuote, 0, len(rates))
+	for ccy, rate := range rates {
+		ccy = strings.ToUpper(ccy)
+		if ccy == base || rate.Sign() <= 0 {
+			continue
+		}
+		quotes = append(quotes, RateQuote{Base: base, Quote: ccy, Rate: rate, Source: source, UpdatedAt: updatedAt, Live: live})
+	}
+	return quotes
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
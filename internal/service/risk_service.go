@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+)
+
+// maxDailyPnlPositions 统计当日已实现盈亏时最多读取的历史持仓数
+const maxDailyPnlPositions = 1000
+
+// RiskService 交易前风控服务接口
+type RiskService interface {
+	CheckOrders(ctx context.Context, orders []models.OrderRequest, notionals []float64, openOrders int) *models.RiskCheckResult
+	CheckAmend(ctx context.Context, order *models.OrderRequest, notional float64) *models.RiskCheckResult
+	GetStatus() *models.RiskStatus
+	SetKillSwitch(enabled bool, reason string)
+	SetLimits(limits models.RiskLimits) error
+	ForAccount(accountService AccountService) RiskService
+}
+
+// riskService 风控服务实现
+type riskService struct {
+	accountService AccountService
+	priceService   PriceService
+	state          *riskState // 限额和熔断状态，同一服务派生的各账户风控共享
+}
+
+// riskState 风控限额和熔断状态
+type riskState struct {
+	mutex            sync.RWMutex
+	limits           models.RiskLimits
+	killSwitch       bool
+	killSwitchReason string
+	killSwitchTime   *time.Time
+}
+
+// NewRiskService 创建风控服务实例
+func NewRiskService(cfg *config.RiskConfig, accountService AccountService, priceService PriceService) RiskService {
+	service := &riskService{
+		accountService: accountService,
+		priceService:   priceService,
+		state: &riskState{
+			limits: models.RiskLimits{
+				MaxNotionalPerInstrument: cfg.MaxNotionalPerInstrument,
+				MaxLeverage:              cfg.MaxLeverage,
+				MaxOpenOrders:            cfg.MaxOpenOrders,
+				DailyLossLimit:           cfg.DailyLossLimit,
+			},
+		},
+	}
+
+	if cfg.KillSwitch {
+		service.SetKillSwitch(true, "启动配置 RISK_KILL_SWITCH=true")
+	}
+
+	return service
+}
+
+// ForAccount 返回检查指定账户的风控服务，与当前服务共享限额和熔断状态
+// 用于实盘/模拟盘环境或多个OKX账户下单时按订单实际提交的账户检查持仓、余额和当日盈亏
+func (s *riskService) ForAccount(accountService AccountService) RiskService {
+	return &riskService{
+		accountService: accountService,
+		priceService:   s.priceService,
+		state:          s.state,
+	}
+}
+
+// CheckOrders 检查一组新订单，notionals 为各订单的名义价值（USD），openOrders 为当前挂单数量
+// 只减仓订单仅受熔断限制；账户状态获取失败时拒绝下单
+func (s *riskService) CheckOrders(ctx context.Context, orders []models.OrderRequest, notionals []float64, openOrders int) *models.RiskCheckResult {
+	return s.check(ctx, orders, notionals, openOrders, true)
+}
+
+// CheckAmend 检查改单，order 为按新价格和新数量（未修改时使用原值）重建的订单，notional 为其名义价值（USD）
+// 与新订单执行相同的检查；改单不增加挂单数量，不检查挂单数量上限
+func (s *riskService) CheckAmend(ctx context.Context, order *models.OrderRequest, notional float64) *models.RiskCheckResult {
+	return s.check(ctx, []models.OrderRequest{*order}, []float64{notional}, 0, false)
+}
+
+// check 执行风控检查，addsOrders 表示订单会新增挂单
+func (s *riskService) check(ctx context.Context, orders []models.OrderRequest, notionals []float64, openOrders int, addsOrders bool) *models.RiskCheckResult {
+	status := s.GetStatus()
+	result := &models.RiskCheckResult{Passed: true, Violations: []models.RiskViolation{}}
+
+	if status.KillSwitch {
+		addViolation(result, models.RiskViolation{
+			Rule:    models.RiskRuleKillSwitch,
+			Message: "交易熔断已开启: " + status.KillSwitchReason,
+		})
+		return result
+	}
+
+	// 统计会增加风险敞口的订单
+	orderExposure := make(map[string]float64)
+	openingCount := 0
+	for i, order := range orders {
+		if order.ReduceOnly {
+			continue
+		}
+		openingCount++
+		if i < len(notionals) {
+			orderExposure[order.InstId] += notionals[i]
+		}
+	}
+
+	if openingCount == 0 {
+		return result
+	}
+
+	limits := status.Limits
+
+	if addsOrders && limits.MaxOpenOrders > 0 && openOrders+openingCount > limits.MaxOpenOrders {
+		addViolation(result, models.RiskViolation{
+			Rule:    models.RiskRuleMaxOpenOrders,
+			Message: "挂单数量超过上限",
+			Limit:   strconv.Itoa(limits.MaxOpenOrders),
+			Actual:  strconv.Itoa(openOrders + openingCount),
+		})
+	}
+
+	if limits.MaxNotionalPerInstrument > 0 || limits.MaxLeverage > 0 {
+		positions, err := s.accountService.GetPositions(ctx, &models.PositionsRequest{}, models.CurrencyUSDT)
+		if err != nil {
+			addViolation(result, stateUnavailable("获取当前持仓失败: "+err.Error()))
+			return result
+		}
+
+		s.checkNotional(result, limits, positions.Positions, orderExposure)
+		s.checkLeverage(ctx, result, limits, positions.Positions, orderExposure)
+	}
+
+	if limits.DailyLossLimit > 0 {
+		loss, err := s.dailyRealizedLoss(ctx)
+		if err != nil {
+			addViolation(result, stateUnavailable("统计当日已实现盈亏失败: "+err.Error()))
+			return result
+		}
+		if loss >= limits.DailyLossLimit {
+			addViolation(result, models.RiskViolation{
+				Rule:    models.RiskRuleDailyLossLimit,
+				Message: "当日已实现亏损达到上限，仅允许只减仓订单",
+				Limit:   formatRiskValue(limits.DailyLossLimit),
+				Actual:  formatRiskValue(loss),
+			})
+		}
+	}
+
+	return result
+}
+
+// GetStatus 获取风控状态
+func (s *riskService) GetStatus() *models.RiskStatus {
+	state := s.state
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+
+	return &models.RiskStatus{
+		KillSwitch:       state.killSwitch,
+		KillSwitchReason: state.killSwitchReason,
+		KillSwitchTime:   state.killSwitchTime,
+		Limits:           state.limits,
+	}
+}
+
+// SetKillSwitch 开启或关闭交易熔断
+func (s *riskService) SetKillSwitch(enabled bool, reason string) {
+	state := s.state
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	state.killSwitch = enabled
+	if enabled {
+		now := time.Now()
+		state.killSwitchReason = reason
+		state.killSwitchTime = &now
+		log.Printf("交易熔断已开启: %s", reason)
+	} else {
+		state.killSwitchReason = ""
+		state.killSwitchTime = nil
+		log.Printf("交易熔断已关闭")
+	}
+}
+
+// SetLimits 更新风控限额
+func (s *riskService) SetLimits(limits models.RiskLimits) error {
+	if limits.MaxNotionalPerInstrument < 0 || limits.MaxLeverage < 0 ||
+		limits.MaxOpenOrders < 0 || limits.DailyLossLimit < 0 {
+		return fmt.Errorf("风控限额不能为负数")
+	}
+
+	s.state.mutex.Lock()
+	defer s.state.mutex.Unlock()
+
+	s.state.limits = limits
+	log.Printf("风控限额已更新: %+v", limits)
+	return nil
+}
+
+// checkNotional 检查单产品名义价值（现有持仓 + 新订单）
+func (s *riskService) checkNotional(result *models.RiskCheckResult, limits models.RiskLimits, positions []*models.Position, orderExposure map[string]float64) {
+	if limits.MaxNotionalPerInstrument <= 0 {
+		return
+	}
+
+	positionExposure := make(map[string]float64)
+	for _, pos := range positions {
+		positionExposure[pos.InstId] += math.Abs(parseRiskFloat(pos.NotionalUsd))
+	}
+
+	for _, instId := range sortedKeys(orderExposure) {
+		total := positionExposure[instId] + orderExposure[instId]
+		if total > limits.MaxNotionalPerInstrument {
+			addViolation(result, models.RiskViolation{
+				Rule:    models.RiskRuleMaxNotional,
+				Message: "持仓与订单名义价值合计超过单产品上限",
+				InstId:  instId,
+				Limit:   formatRiskValue(limits.MaxNotionalPerInstrument),
+				Actual:  formatRiskValue(total),
+			})
+		}
+	}
+}
+
+// checkLeverage 检查持仓杠杆倍数和账户整体杠杆
+func (s *riskService) checkLeverage(ctx context.Context, result *models.RiskCheckResult, limits models.RiskLimits, positions []*models.Position, orderExposure map[string]float64) {
+	if limits.MaxLeverage <= 0 {
+		return
+	}
+
+	totalNotional := 0.0
+	for _, pos := range positions {
+		totalNotional += math.Abs(parseRiskFloat(pos.NotionalUsd))
+
+		if _, ok := orderExposure[pos.InstId]; !ok {
+			continue
+		}
+		if lever := parseRiskFloat(pos.Lever); lever > limits.MaxLeverage {
+			addViolation(result, models.RiskViolation{
+				Rule:    models.RiskRuleMaxLeverage,
+				Message: "持仓杠杆倍数超过上限",
+				InstId:  pos.InstId,
+				Limit:   formatRiskValue(limits.MaxLeverage),
+				Actual:  pos.Lever,
+			})
+		}
+	}
+
+	for _, notional := range orderExposure {
+		totalNotional += notional
+	}
+
+	balance, err := s.accountService.GetAccountBalance(ctx, models.CurrencyUSDT)
+	if err != nil {
+		addViolation(result, stateUnavailable("获取账户余额失败: "+err.Error()))
+		return
+	}
+
+	equity := balance.TotalEquity.Float64()
+	if equity <= 0 {
+		addViolation(result, models.RiskViolation{
+			Rule:    models.RiskRuleMaxLeverage,
+			Message: "账户权益为零，无法开仓",
+			Actual:  balance.TotalEquity.String(),
+		})
+		return
+	}
+
+	if leverage := totalNotional / equity; leverage > limits.MaxLeverage {
+		addViolation(result, models.RiskViolation{
+			Rule:    models.RiskRuleMaxLeverage,
+			Message: "账户整体杠杆（总名义价值/总权益）超过上限",
+			Limit:   formatRiskValue(limits.MaxLeverage),
+			Actual:  formatRiskValue(leverage),
+		})
+	}
+}
+
+// dailyRealizedLoss 统计今日平仓的已实现亏损（USD），盈利时返回负数
+func (s *riskService) dailyRealizedLoss(ctx context.Context) (float64, error) {
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	pnl := 0.0
+	count := 0
+	req := &models.PositionsHistoryRequest{From: startOfDay.UnixMilli()}
+
+	for pos, err := range s.accountService.AllPositionsHistory(ctx, req, models.CurrencyUSDT) {
+		if err != nil {
+			return 0, err
+		}
+
+		// 超过上限时无法得到完整的当日盈亏，按统计失败处理，不能只统计部分持仓
+		if count++; count > maxDailyPnlPositions {
+			return 0, fmt.Errorf("当日平仓记录超过%d条", maxDailyPnlPositions)
+		}
+
+		value, err := s.toUSD(ctx, parseRiskFloat(pos.RealizedPnl), pos.Ccy)
+		if err != nil {
+			return 0, err
+		}
+		pnl += value
+	}
+
+	return -pnl, nil
+}
+
+// toUSD 将结算币种金额换算为USD（币本位合约按币种最新USDT价格换算）
+func (s *riskService) toUSD(ctx context.Context, amount float64, ccy string) (float64, error) {
+	switch ccy {
+	case "", "USD", "USDT", "USDC":
+		return amount, nil
+	}
+
+	if amount == 0 {
+		return 0, nil
+	}
+
+	price, err := s.priceService.GetPrice(ctx, ccy+"-USDT")
+	if err != nil {
+		return 0, fmt.Errorf("获取%s价格失败: %w", ccy, err)
+	}
+
+	return amount * parseRiskFloat(price.Price), nil
+}
+
+// addViolation 记录一条拒单原因
+func addViolation(result *models.RiskCheckResult, violation models.RiskViolation) {
+	result.Passed = false
+	result.Violations = append(result.Violations, violation)
+}
+
+// stateUnavailable 账户状态不可用时的拒单原因
+func stateUnavailable(message string) models.RiskViolation {
+	return models.RiskViolation{
+		Rule:    models.RiskRuleStateUnavailable,
+		Message: message,
+	}
+}
+
+// parseRiskFloat 解析数值，无效值按0处理
+func parseRiskFloat(value string) float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// formatRiskValue 格式化风控数值
+func formatRiskValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// sortedKeys 返回排序后的map键，保证拒单原因顺序稳定
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+)
+
+// ErrInvalidSettings 用户设置的值无效
+var ErrInvalidSettings = errors.New("用户设置无效")
+
+const (
+	// maxFavoriteInstruments 每个用户最多收藏的交易对数量
+	maxFavoriteInstruments = 100
+	// maxSettingsDocumentSize 仪表盘布局和提醒偏好的最大字节数
+	maxSettingsDocumentSize = 16 << 10
+)
+
+// SettingsService 用户设置服务接口
+type SettingsService interface {
+	Get(ctx context.Context, userID int64) (*models.UserSettings, error)
+	Update(ctx context.Context, userID int64, req *models.UpdateSettingsRequest) (*models.UserSettings, error)
+	DefaultCurrency(ctx context.Context, userID int64) models.Currency
+}
+
+// settingsService 用户设置服务实现
+type settingsService struct {
+	settings        repository.SettingsRepository
+	currencies      []models.Currency // 可选的显示币种
+	defaultCurrency models.Currency   // 用户未设置时使用的系统默认币种
+
+	// mutex 串行化修改，并发修改不同字段时不会互相覆盖
+	mutex sync.Mutex
+}
+
+// NewSettingsService 创建用户设置服务，默认币种只能设置为配置的显示币种
+func NewSettingsService(settings repository.SettingsRepository, currencyCfg *config.CurrencyConfig) SettingsService {
+	currencies := DisplayCurrencies(currencyCfg)
+	return &settingsService{
+		settings:        settings,
+		currencies:      currencies,
+		defaultCurrency: defaultDisplayCurrency(currencies),
+	}
+}
+
+// Get 用户设置，未保存过时返回默认设置
+func (s *settingsService) Get(ctx context.Context, userID int64) (*models.UserSettings, error) {
+	settings, err := s.settings.Get(userID)
+	if errors.Is(err, repository.ErrSettingsNotFound) {
+		return s.defaults(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 显示币种配置变化后，已保存的币种可能不再支持
+	if !s.supports(settings.DefaultCurrency) {
+		settings.DefaultCurrency = s.defaultCurrency
+	}
+	return settings, nil
+}
+
+// Update 修改请求中出现的字段，返回修改后的设置
+func (s *settingsService) Update(ctx context.Context, userID int64, req *models.UpdateSettingsRequest) (*models.UserSettings, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	settings, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.DefaultCurrency != nil {
+		currency := models.Currency(strings.ToUpper(strings.TrimSpace(*req.DefaultCurrency)))
+		if !s.supports(currency) {
+			return nil, fmt.Errorf("%w: 不支持的币种: %s", ErrInvalidSettings, *req.DefaultCurrency)
+		}
+		settings.DefaultCurrency = currency
+	}
+	if req.FavoriteInstruments != nil {
+		favorites, err := normalizeFavorites(*req.FavoriteInstruments)
+		if err != nil {
+			return nil, err
+		}
+		settings.FavoriteInstruments = favorites
+	}
+	if req.DashboardLayout != nil {
+		if settings.DashboardLayout, err = normalizeSettingsDocument("dashboardLayout", req.DashboardLayout); err != nil {
+			return nil, err
+		}
+	}
+	if req.AlertPreferences != nil {
+		if settings.AlertPreferences, err = normalizeSettingsDocument("alertPreferences", req.AlertPreferences); err != nil {
+			return nil, err
+		}
+	}
+
+	settings.UpdatedAt = time.Now()
+	if err := s.settings.Save(settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// DefaultCurrency 用户的默认显示币种，未设置或读取失败时使用系统默认币种
+func (s *settingsService) DefaultCurrency(ctx context.Context, userID int64) models.Currency {
+	settings, err := s.Get(ctx, userID)
+	if err != nil {
+		log.Printf("读取用户 %d 的设置失败，使用系统默认币种: %v", userID, err)
+		return s.defaultCurrency
+	}
+	return settings.DefaultCurrency
+}
+
+// defaults 用户未保存过设置时的默认值
+func (s *settingsService) defaults(userID int64) *models.UserSettings {
+	return &models.UserSettings{
+		UserID:              userID,
+		DefaultCurrency:     s.defaultCurrency,
+		FavoriteInstruments: []string{},
+		DashboardLayout:     json.RawMessage("{}"),
+		AlertPreferences:    json.RawMessage("{}"),
+	}
+}
+
+// supports 是否为可选的显示币种
+func (s *settingsService) supports(currency models.Currency) bool {
+	for _, supported := range s.currencies {
+		if currency == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeFavorites 交易对统一转为大写并去重，忽略空项
+func normalizeFavorites(instIDs []string) ([]string, error) {
+	favorites := []string{}
+	seen := make(map[string]bool)
+	for _, instID := range instIDs {
+		instID = strings.ToUpper(strings.TrimSpace(instID))
+		if instID == "" || seen[instID] {
+			continue
+		}
+		seen[instID] = true
+		favorites = append(favorites, instID)
+	}
+
+	if len(favorites) > maxFavoriteInstruments {
+		return nil, fmt.Errorf("%w: 收藏的交易对不能超过%d个", ErrInvalidSettings, maxFavoriteInstruments)
+	}
+	return favorites, nil
+}
+
+// normalizeSettingsDocument 校验JSON对象并压缩空白，null 表示恢复为空对象
+func normalizeSettingsDocument(field string, document json.RawMessage) (json.RawMessage, error) {
+	if len(document) > maxSettingsDocumentSize {
+		return nil, fmt.Errorf("%w: %s 不能超过%d字节", ErrInvalidSettings, field, maxSettingsDocumentSize)
+	}
+	if string(bytes.TrimSpace(document)) == "null" {
+		return json.RawMessage("{}"), nil
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(document, &object); err != nil {
+		return nil, fmt.Errorf("%w: %s 必须是JSON对象", ErrInvalidSettings, field)
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, document); err != nil {
+		return nil, fmt.Errorf("%w: %s 必须是JSON对象", ErrInvalidSettings, field)
+	}
+	return compacted.Bytes(), nil
+}
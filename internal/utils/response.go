@@ -6,28 +6,37 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// EnvironmentKey gin上下文中交易环境的键，由 middleware.TradingEnvironment 设置
+const EnvironmentKey = "tradingEnvironment"
+
+// AuthClaimsKey gin上下文中当前用户令牌载荷（*jwt.Claims）的键，由 middleware.Auth 设置
+const AuthClaimsKey = "authClaims"
+
 // Response 标准响应结构
 type Response struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	Success     bool        `json:"success"`
+	Message     string      `json:"message"`
+	Data        interface{} `json:"data,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	Environment string      `json:"environment,omitempty"` // 交易环境：demo 模拟盘 / live 实盘
 }
 
 // SuccessResponse 成功响应
 func SuccessResponse(c *gin.Context, data interface{}, message string) {
 	c.JSON(http.StatusOK, Response{
-		Success: true,
-		Message: message,
-		Data:    data,
+		Success:     true,
+		Message:     message,
+		Data:        data,
+		Environment: c.GetString(EnvironmentKey),
 	})
 }
 
 // ErrorResponse 错误响应
 func ErrorResponse(c *gin.Context, statusCode int, message string) {
 	c.JSON(statusCode, Response{
-		Success: false,
-		Error:   message,
+		Success:     false,
+		Error:       message,
+		Environment: c.GetString(EnvironmentKey),
 	})
 }
 
@@ -49,4 +58,19 @@ func InternalServerErrorResponse(c *gin.Context, message string) {
 // UnauthorizedResponse 401错误响应
 func UnauthorizedResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusUnauthorized, message)
-} 
\ No newline at end of file
+} 
+
+// ForbiddenResponse 403错误响应
+func ForbiddenResponse(c *gin.Context, message string) {
+	ErrorResponse(c, http.StatusForbidden, message)
+}
+
+// ErrorResponseWithData 带数据的错误响应（如结构化的拒绝原因）
+func ErrorResponseWithData(c *gin.Context, statusCode int, message string, data interface{}) {
+	c.JSON(statusCode, Response{
+		Success:     false,
+		Error:       message,
+		Data:        data,
+		Environment: c.GetString(EnvironmentKey),
+	})
+}
@@ -0,0 +1,200 @@
+// Package decimal 精确十进制数
+// 金额以有理数保存，加减乘除不丢失精度，只在显示时按指定小数位四舍五入，避免浮点误差导致合计与交易所数据对不上
+package decimal
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxPlaces 无法用有限小数表示的结果（如除法）最多输出的小数位数
+const maxPlaces = 18
+
+// Decimal 精确十进制数，零值为0
+// 解析得到的值会记住原始小数位数，例如 "1.50" 输出仍为 "1.50"；除法结果没有固定的小数位数，按需输出
+type Decimal struct {
+	rat   *big.Rat
+	scale int  // 输出的小数位数，fixed 为 false 时无效
+	fixed bool // 是否有固定的小数位数
+}
+
+// Zero 零
+var Zero = Decimal{}
+
+// New 创建整数值
+func New(value int64) Decimal {
+	return Decimal{rat: new(big.Rat).SetInt64(value), fixed: true}
+}
+
+// Parse 解析十进制字符串，支持科学计数法，如 "123.45"、"-0.001"、"1e-8"
+func Parse(value string) (Decimal, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.ContainsAny(value, "/xXpP") {
+		return Zero, fmt.Errorf("无效的数值: %q", value)
+	}
+
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return Zero, fmt.Errorf("无效的数值: %q", value)
+	}
+	return Decimal{rat: rat, scale: fractionDigits(value), fixed: true}, nil
+}
+
+// MustParse 解析十进制字符串，无效时panic，用于常量
+func MustParse(value string) Decimal {
+	d, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// fractionDigits 十进制字符串表示的小数位数，如 "1.50" 为2，"1e-8" 为8
+func fractionDigits(value string) int {
+	mantissa, exponent := value, 0
+	if i := strings.IndexAny(value, "eE"); i >= 0 {
+		mantissa = value[:i]
+		exponent, _ = strconv.Atoi(value[i+1:])
+	}
+
+	digits := 0
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		digits = len(mantissa) - i - 1
+	}
+	if digits -= exponent; digits < 0 {
+		digits = 0
+	}
+	return digits
+}
+
+func (d Decimal) value() *big.Rat {
+	if d.rat == nil {
+		return new(big.Rat)
+	}
+	return d.rat
+}
+
+// isFixed 零值按0位小数处理
+func (d Decimal) isFixed() bool {
+	return d.fixed || d.rat == nil
+}
+
+// Add 加法，小数位数取两者较大值
+func (d Decimal) Add(other Decimal) Decimal {
+	return Decimal{
+		rat:   new(big.Rat).Add(d.value(), other.value()),
+		scale: max(d.scale, other.scale),
+		fixed: d.isFixed() && other.isFixed(),
+	}
+}
+
+// Sub 减法，小数位数取两者较大值
+func (d Decimal) Sub(other Decimal) Decimal {
+	return d.Add(other.Neg())
+}
+
+// Mul 乘法，小数位数为两者之和
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{
+		rat:   new(big.Rat).Mul(d.value(), other.value()),
+		scale: d.scale + other.scale,
+		fixed: d.isFixed() && other.isFixed(),
+	}
+}
+
+// Quo 除法，结果精确保存，除数为0时panic
+func (d Decimal) Quo(other Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Quo(d.value(), other.value())}
+}
+
+// Neg 取相反数
+func (d Decimal) Neg() Decimal {
+	return Decimal{rat: new(big.Rat).Neg(d.value()), scale: d.scale, fixed: d.isFixed()}
+}
+
+// Abs 绝对值
+func (d Decimal) Abs() Decimal {
+	return Decimal{rat: new(big.Rat).Abs(d.value()), scale: d.scale, fixed: d.isFixed()}
+}
+
+// Round 四舍五入（0.5远离零）到指定小数位数，输出时固定显示该位数
+func (d Decimal) Round(places int) Decimal {
+	if places < 0 {
+		places = 0
+	}
+	rat, _ := new(big.Rat).SetString(d.value().FloatString(places))
+	return Decimal{rat: rat, scale: places, fixed: true}
+}
+
+// Sum 精确累加
+func Sum(values ...Decimal) Decimal {
+	sum := Zero
+	for _, value := range values {
+		sum = sum.Add(value)
+	}
+	return sum
+}
+
+// Cmp 比较大小，返回 -1、0、1
+func (d Decimal) Cmp(other Decimal) int {
+	return d.value().Cmp(other.value())
+}
+
+// Sign 符号，返回 -1、0、1
+func (d Decimal) Sign() int {
+	return d.value().Sign()
+}
+
+// IsZero 是否为0
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Float64 转换为浮点数，用于比率等不要求精确的计算
+func (d Decimal) Float64() float64 {
+	f, _ := d.value().Float64()
+	return f
+}
+
+// Rat 返回有理数副本
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).Set(d.value())
+}
+
+// String 十进制字符串，有固定小数位数时按该位数输出，否则输出精确值（无限小数最多18位）
+func (d Decimal) String() string {
+	if d.isFixed() {
+		return d.value().FloatString(d.scale)
+	}
+
+	places, exact := d.value().FloatPrec()
+	if !exact || places > maxPlaces {
+		s := d.value().FloatString(maxPlaces)
+		s = strings.TrimRight(s, "0")
+		return strings.TrimSuffix(s, ".")
+	}
+	return d.value().FloatString(places)
+}
+
+// MarshalJSON 序列化为JSON字符串，与OKX接口的数值格式一致
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON 支持JSON字符串和数字，空字符串和null按0处理
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "" || value == "null" {
+		*d = Zero
+		return nil
+	}
+
+	parsed, err := Parse(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
@@ -0,0 +1,294 @@
+// Package indicator 技术指标计算
+// 所有指标都按K线逐根增量更新，既可用于一次性计算历史序列，也可在新K线完结时实时更新，供策略代码直接使用
+package indicator
+
+import (
+	"math"
+	"time"
+)
+
+// Bar K线
+type Bar struct {
+	Ts     int64 // 开始时间（毫秒）
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+}
+
+// window 固定长度的滑动窗口
+type window struct {
+	values []float64
+	next   int
+	count  int
+	sum    float64
+}
+
+func newWindow(size int) *window {
+	return &window{values: make([]float64, size)}
+}
+
+// push 加入新值，窗口已满时移除最早的值
+func (w *window) push(value float64) {
+	if w.count == len(w.values) {
+		w.sum -= w.values[w.next]
+	} else {
+		w.count++
+	}
+	w.values[w.next] = value
+	w.sum += value
+	w.next = (w.next + 1) % len(w.values)
+}
+
+// full 窗口是否已满
+func (w *window) full() bool {
+	return w.count == len(w.values)
+}
+
+// mean 平均值
+func (w *window) mean() float64 {
+	return w.sum / float64(w.count)
+}
+
+// stddev 总体标准差
+func (w *window) stddev() float64 {
+	mean := w.mean()
+	variance := 0.0
+	for i := 0; i < w.count; i++ {
+		diff := w.values[i] - mean
+		variance += diff * diff
+	}
+	return math.Sqrt(variance / float64(w.count))
+}
+
+// SMA 简单移动平均
+type SMA struct {
+	window *window
+}
+
+// NewSMA 创建简单移动平均
+func NewSMA(period int) *SMA {
+	return &SMA{window: newWindow(period)}
+}
+
+// Update 加入新值，数据不足period个时返回false
+func (s *SMA) Update(value float64) (float64, bool) {
+	s.window.push(value)
+	if !s.window.full() {
+		return 0, false
+	}
+	return s.window.mean(), true
+}
+
+// EMA 指数移动平均，以前period个值的简单平均作为初始值
+type EMA struct {
+	alpha float64
+	seed  *SMA
+	value float64
+	ready bool
+}
+
+// NewEMA 创建指数移动平均
+func NewEMA(period int) *EMA {
+	return &EMA{alpha: 2 / float64(period+1), seed: NewSMA(period)}
+}
+
+// Update 加入新值，数据不足period个时返回false
+func (e *EMA) Update(value float64) (float64, bool) {
+	if !e.ready {
+		seed, ok := e.seed.Update(value)
+		if !ok {
+			return 0, false
+		}
+		e.value, e.ready = seed, true
+		return e.value, true
+	}
+
+	e.value += e.alpha * (value - e.value)
+	return e.value, true
+}
+
+// RSI 相对强弱指数，使用Wilder平滑
+type RSI struct {
+	period  int
+	prev    float64
+	hasPrev bool
+	count   int
+	avgGain float64
+	avgLoss float64
+}
+
+// NewRSI 创建相对强弱指数
+func NewRSI(period int) *RSI {
+	return &RSI{period: period}
+}
+
+// Update 加入新收盘价，数据不足period+1个时返回false
+func (r *RSI) Update(value float64) (float64, bool) {
+	if !r.hasPrev {
+		r.prev, r.hasPrev = value, true
+		return 0, false
+	}
+
+	change := value - r.prev
+	r.prev = value
+	gain, loss := math.Max(change, 0), math.Max(-change, 0)
+
+	period := float64(r.period)
+	if r.count < r.period {
+		// 前period个变化取简单平均
+		r.avgGain += gain
+		r.avgLoss += loss
+		r.count++
+		if r.count < r.period {
+			return 0, false
+		}
+		r.avgGain /= period
+		r.avgLoss /= period
+	} else {
+		r.avgGain = (r.avgGain*(period-1) + gain) / period
+		r.avgLoss = (r.avgLoss*(period-1) + loss) / period
+	}
+
+	if r.avgLoss == 0 {
+		if r.avgGain == 0 {
+			return 50, true
+		}
+		return 100, true
+	}
+	return 100 - 100/(1+r.avgGain/r.avgLoss), true
+}
+
+// MACDValue MACD指标值
+type MACDValue struct {
+	MACD      float64 `json:"macd"`      // 快线EMA - 慢线EMA
+	Signal    float64 `json:"signal"`    // MACD的EMA
+	Histogram float64 `json:"histogram"` // MACD - Signal
+}
+
+// MACD 指数平滑异同移动平均线
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+}
+
+// NewMACD 创建MACD，常用参数为 12/26/9
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{fast: NewEMA(fast), slow: NewEMA(slow), signal: NewEMA(signal)}
+}
+
+// Update 加入新收盘价，数据不足 slow+signal-1 个时返回false
+func (m *MACD) Update(value float64) (MACDValue, bool) {
+	fast, fastOk := m.fast.Update(value)
+	slow, slowOk := m.slow.Update(value)
+	if !fastOk || !slowOk {
+		return MACDValue{}, false
+	}
+
+	line := fast - slow
+	signal, ok := m.signal.Update(line)
+	if !ok {
+		return MACDValue{}, false
+	}
+	return MACDValue{MACD: line, Signal: signal, Histogram: line - signal}, true
+}
+
+// BollingerValue 布林带指标值
+type BollingerValue struct {
+	Upper  float64 `json:"upper"`
+	Middle float64 `json:"middle"`
+	Lower  float64 `json:"lower"`
+}
+
+// Bollinger 布林带：中轨为简单移动平均，上下轨为中轨 ± k倍总体标准差
+type Bollinger struct {
+	window *window
+	k      float64
+}
+
+// NewBollinger 创建布林带，常用参数为 20/2
+func NewBollinger(period int, k float64) *Bollinger {
+	return &Bollinger{window: newWindow(period), k: k}
+}
+
+// Update 加入新收盘价，数据不足period个时返回false
+func (b *Bollinger) Update(value float64) (BollingerValue, bool) {
+	b.window.push(value)
+	if !b.window.full() {
+		return BollingerValue{}, false
+	}
+
+	middle := b.window.mean()
+	width := b.k * b.window.stddev()
+	return BollingerValue{Upper: middle + width, Middle: middle, Lower: middle - width}, true
+}
+
+// ATR 平均真实波幅，使用Wilder平滑
+type ATR struct {
+	period    int
+	prevClose float64
+	hasPrev   bool
+	count     int
+	value     float64
+}
+
+// NewATR 创建平均真实波幅
+func NewATR(period int) *ATR {
+	return &ATR{period: period}
+}
+
+// Update 加入新K线，数据不足period根时返回false
+func (a *ATR) Update(bar Bar) (float64, bool) {
+	tr := bar.High - bar.Low
+	if a.hasPrev {
+		tr = math.Max(tr, math.Max(math.Abs(bar.High-a.prevClose), math.Abs(bar.Low-a.prevClose)))
+	}
+	a.prevClose, a.hasPrev = bar.Close, true
+
+	period := float64(a.period)
+	if a.count < a.period {
+		a.value += tr
+		a.count++
+		if a.count < a.period {
+			return 0, false
+		}
+		a.value /= period
+		return a.value, true
+	}
+
+	a.value = (a.value*(period-1) + tr) / period
+	return a.value, true
+}
+
+// VWAP 成交量加权平均价，按典型价格 (high+low+close)/3 加权，每个锚定周期（UTC对齐）重新累计
+type VWAP struct {
+	anchor  int64
+	session int64
+	started bool
+	pv      float64
+	volume  float64
+}
+
+// NewVWAP 创建成交量加权平均价，anchor如24小时表示每天重新累计
+func NewVWAP(anchor time.Duration) *VWAP {
+	return &VWAP{anchor: anchor.Milliseconds()}
+}
+
+// Update 加入新K线，当前周期内累计成交量为0时返回false
+func (v *VWAP) Update(bar Bar) (float64, bool) {
+	session := bar.Ts / v.anchor
+	if !v.started || session != v.session {
+		v.session, v.started = session, true
+		v.pv, v.volume = 0, 0
+	}
+
+	typical := (bar.High + bar.Low + bar.Close) / 3
+	v.pv += typical * bar.Volume
+	v.volume += bar.Volume
+	if v.volume == 0 {
+		return 0, false
+	}
+	return v.pv / v.volume, true
+}
@@ -0,0 +1,157 @@
+package indicator
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxPeriod 指标周期上限
+const MaxPeriod = 500
+
+// VWAPSession Set中VWAP的累计周期，按UTC每天重新累计
+const VWAPSession = 24 * time.Hour
+
+// Params 指标参数
+type Params struct {
+	SMAPeriod       int     `json:"smaPeriod" form:"sma"`
+	EMAPeriod       int     `json:"emaPeriod" form:"ema"`
+	RSIPeriod       int     `json:"rsiPeriod" form:"rsi"`
+	MACDFast        int     `json:"macdFast" form:"macdFast"`
+	MACDSlow        int     `json:"macdSlow" form:"macdSlow"`
+	MACDSignal      int     `json:"macdSignal" form:"macdSignal"`
+	BollingerPeriod int     `json:"bollingerPeriod" form:"bollinger"`
+	BollingerK      float64 `json:"bollingerK" form:"bollingerK"`
+	ATRPeriod       int     `json:"atrPeriod" form:"atr"`
+}
+
+// DefaultParams 常用指标参数
+func DefaultParams() Params {
+	return Params{
+		SMAPeriod:       20,
+		EMAPeriod:       20,
+		RSIPeriod:       14,
+		MACDFast:        12,
+		MACDSlow:        26,
+		MACDSignal:      9,
+		BollingerPeriod: 20,
+		BollingerK:      2,
+		ATRPeriod:       14,
+	}
+}
+
+// Validate 校验参数
+func (p Params) Validate() error {
+	periods := []struct {
+		name   string
+		period int
+	}{
+		{"sma", p.SMAPeriod},
+		{"ema", p.EMAPeriod},
+		{"rsi", p.RSIPeriod},
+		{"macdFast", p.MACDFast},
+		{"macdSlow", p.MACDSlow},
+		{"macdSignal", p.MACDSignal},
+		{"bollinger", p.BollingerPeriod},
+		{"atr", p.ATRPeriod},
+	}
+	for _, item := range periods {
+		if item.period < 1 || item.period > MaxPeriod {
+			return fmt.Errorf("%s周期取值范围为1-%d", item.name, MaxPeriod)
+		}
+	}
+	if p.MACDFast >= p.MACDSlow {
+		return fmt.Errorf("macdFast必须小于macdSlow")
+	}
+	if p.BollingerK <= 0 {
+		return fmt.Errorf("bollingerK必须大于0")
+	}
+	return nil
+}
+
+// Warmup 所有指标都产生数值所需的最少K线数量
+func (p Params) Warmup() int {
+	warmup := 0
+	for _, n := range []int{p.SMAPeriod, p.EMAPeriod, p.RSIPeriod + 1, p.MACDSlow + p.MACDSignal - 1, p.BollingerPeriod, p.ATRPeriod} {
+		if n > warmup {
+			warmup = n
+		}
+	}
+	return warmup
+}
+
+// Snapshot 某根K线完结时的全部指标值，数据不足的指标为nil
+type Snapshot struct {
+	Ts        int64           `json:"ts"`
+	Close     float64         `json:"close"`
+	SMA       *float64        `json:"sma"`
+	EMA       *float64        `json:"ema"`
+	RSI       *float64        `json:"rsi"`
+	MACD      *MACDValue      `json:"macd"`
+	Bollinger *BollingerValue `json:"bollinger"`
+	ATR       *float64        `json:"atr"`
+	VWAP      *float64        `json:"vwap"`
+}
+
+// Set 按同一组参数同时计算全部指标
+type Set struct {
+	params    Params
+	sma       *SMA
+	ema       *EMA
+	rsi       *RSI
+	macd      *MACD
+	bollinger *Bollinger
+	atr       *ATR
+	vwap      *VWAP
+}
+
+// NewSet 创建指标集合
+func NewSet(params Params) (*Set, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Set{
+		params:    params,
+		sma:       NewSMA(params.SMAPeriod),
+		ema:       NewEMA(params.EMAPeriod),
+		rsi:       NewRSI(params.RSIPeriod),
+		macd:      NewMACD(params.MACDFast, params.MACDSlow, params.MACDSignal),
+		bollinger: NewBollinger(params.BollingerPeriod, params.BollingerK),
+		atr:       NewATR(params.ATRPeriod),
+		vwap:      NewVWAP(VWAPSession),
+	}, nil
+}
+
+// Params 指标参数
+func (s *Set) Params() Params {
+	return s.params
+}
+
+// Update 加入一根已完结的K线，返回更新后的全部指标值
+func (s *Set) Update(bar Bar) Snapshot {
+	snapshot := Snapshot{Ts: bar.Ts, Close: bar.Close}
+
+	if value, ok := s.sma.Update(bar.Close); ok {
+		snapshot.SMA = &value
+	}
+	if value, ok := s.ema.Update(bar.Close); ok {
+		snapshot.EMA = &value
+	}
+	if value, ok := s.rsi.Update(bar.Close); ok {
+		snapshot.RSI = &value
+	}
+	if value, ok := s.macd.Update(bar.Close); ok {
+		snapshot.MACD = &value
+	}
+	if value, ok := s.bollinger.Update(bar.Close); ok {
+		snapshot.Bollinger = &value
+	}
+	if value, ok := s.atr.Update(bar); ok {
+		snapshot.ATR = &value
+	}
+	if value, ok := s.vwap.Update(bar); ok {
+		snapshot.VWAP = &value
+	}
+
+	return snapshot
+}
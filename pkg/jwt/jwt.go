@@ -0,0 +1,96 @@
+// Package jwt HS256签名的JSON Web Token
+// 只实现本项目需要的签发和校验，签名算法固定为HS256，拒绝其他算法的令牌
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 令牌类型
+const (
+	TypeAccess  = "access"  // 访问令牌，用于请求接口
+	TypeRefresh = "refresh" // 刷新令牌，只能用于换取新的访问令牌
+)
+
+var (
+	// ErrInvalidToken 令牌格式错误或签名不匹配
+	ErrInvalidToken = errors.New("无效的令牌")
+	// ErrExpiredToken 令牌已过期
+	ErrExpiredToken = errors.New("令牌已过期")
+)
+
+// header 固定的令牌头
+var header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims 令牌载荷
+type Claims struct {
+	ID        string `json:"jti"`      // 令牌ID，用于注销
+	Subject   string `json:"sub"`      // 用户ID
+	Username  string `json:"username"` // 用户名
+	Role      string `json:"role"`     // 签发时的用户角色
+	Type      string `json:"typ"`      // 令牌类型：access / refresh
+	IssuedAt  int64  `json:"iat"`      // 签发时间（秒）
+	ExpiresAt int64  `json:"exp"`      // 过期时间（秒）
+}
+
+// ExpiresTime 过期时间
+func (c *Claims) ExpiresTime() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// Sign 使用密钥签发令牌
+func Sign(claims *Claims, secret []byte) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("序列化令牌失败: %w", err)
+	}
+
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + signature(unsigned, secret), nil
+}
+
+// Parse 校验签名和有效期并返回载荷
+func Parse(token string, secret []byte, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	// 只接受HS256，避免 alg=none 等算法替换
+	var head struct {
+		Alg string `json:"alg"`
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(data, &head) != nil || head.Alg != "HS256" {
+		return nil, ErrInvalidToken
+	}
+
+	expected := signature(parts[0]+"."+parts[1], secret)
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	data, err = base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(data, &claims) != nil {
+		return nil, ErrInvalidToken
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
+
+// signature 计算 header.payload 的HMAC-SHA256签名，返回Base64URL编码
+func signature(unsigned string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
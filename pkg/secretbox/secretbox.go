@@ -0,0 +1,69 @@
+// Package secretbox 使用主密钥加密保存敏感数据
+// 算法为AES-256-GCM，密钥由主密钥经SHA-256派生，密文格式为 base64(nonce || ciphertext)
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrEmptyKey 未配置主密钥
+	ErrEmptyKey = errors.New("主密钥不能为空")
+	// ErrDecrypt 密文格式错误、被篡改或主密钥不匹配
+	ErrDecrypt = errors.New("解密失败")
+)
+
+// Box 使用同一主密钥加密和解密
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New 创建加密器，主密钥应为足够长的随机字符串
+func New(masterKey string) (*Box, error) {
+	if masterKey == "" {
+		return nil, ErrEmptyKey
+	}
+
+	key := sha256.Sum256([]byte(masterKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("创建加密器失败: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("创建加密器失败: %w", err)
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal 加密明文，每次使用随机nonce，相同明文的密文也不相同
+func (b *Box) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成nonce失败: %w", err)
+	}
+
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open 解密 Seal 生成的密文
+func (b *Box) Open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plaintext), nil
+}
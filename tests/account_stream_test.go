@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAccountStream 测试私有WebSocket登录、账户状态维护及浏览器增量推送
+func TestAccountStream(t *testing.T) {
+	url, conns := newFakeOKXWSServer(t)
+	cfg := &config.OKXConfig{APIKey: "key", SecretKey: "secret", Passphrase: "pass", WSPrivateURL: url}
+
+	stream := service.NewAccountStream(cfg)
+	stream.Start()
+	defer stream.Stop()
+
+	upstream := acceptConn(t, conns)
+
+	// 登录签名与REST接口相同
+	var login struct {
+		Op   string              `json:"op"`
+		Args []map[string]string `json:"args"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(upstream.expect(t, `"op":"login"`)), &login))
+	require.Len(t, login.Args, 1)
+	arg := login.Args[0]
+	assert.Equal(t, "key", arg["apiKey"])
+	assert.Equal(t, okx.Sign("secret", arg["timestamp"], "GET", "/users/self/verify", ""), arg["sign"])
+
+	upstream.send(t, `{"event":"login","code":"0","msg":""}`)
+	subscribe := upstream.expect(t, `"op":"subscribe"`)
+	for _, channel := range []string{"account", "positions", "orders", "balance_and_position"} {
+		assert.Contains(t, subscribe, `"channel":"`+channel+`"`)
+	}
+
+	_, ready := stream.Positions()
+	assert.False(t, ready, "未收到快照前不应使用内存状态")
+
+	upstream.send(t, `{"arg":{"channel":"account","uid":"1"},"eventType":"snapshot","data":[{"totalEq":"1000","uTime":"1","details":[{"ccy":"USDT","availBal":"900","cashBal":"1000"}]}]}`)
+	upstream.send(t, `{"arg":{"channel":"positions","uid":"1","instType":"ANY"},"eventType":"snapshot","data":[`+
+		`{"posId":"1","instId":"BTC-USDT-SWAP","instType":"SWAP","pos":"2","avgPx":"30000"},`+
+		`{"posId":"2","instId":"ETH-USDT-SWAP","instType":"SWAP","pos":"5","avgPx":"2000"}]}`)
+
+	require.Eventually(t, func() bool {
+		positions, ok := stream.Positions()
+		return ok && len(positions) == 2
+	}, 3*time.Second, 10*time.Millisecond)
+
+	balance, ok := stream.Balance()
+	require.True(t, ok)
+	assert.Equal(t, "1000", balance.TotalEq)
+
+	// REST接口直接使用内存状态
+	accountService := service.NewAccountServiceWithStream(cfg, nil, stream)
+	resp, err := accountService.GetPositions(context.Background(), &models.PositionsRequest{InstId: "ETH-USDT-SWAP"}, models.CurrencyUSDT)
+	require.NoError(t, err)
+	require.Len(t, resp.Positions, 1)
+	assert.Equal(t, "5", resp.Positions[0].Pos)
+
+	// 浏览器WebSocket：先收到快照，再收到增量
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws/account", func(c *gin.Context) {
+		api.HandleAccountWebSocket(c, stream, config.WebSocketConfig{})
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	browser, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/account", nil)
+	require.NoError(t, err)
+	defer browser.Close()
+
+	var snapshot struct {
+		Type string `json:"type"`
+		Data struct {
+			Positions      []service.OKXPositionData `json:"positions"`
+			PositionsReady bool                      `json:"positionsReady"`
+		} `json:"data"`
+	}
+	require.NoError(t, browser.SetReadDeadline(time.Now().Add(3*time.Second)))
+	require.NoError(t, browser.ReadJSON(&snapshot))
+	assert.Equal(t, "snapshot", snapshot.Type)
+	assert.True(t, snapshot.Data.PositionsReady)
+	assert.Len(t, snapshot.Data.Positions, 2)
+
+	// 平仓、余额变化和订单更新
+	upstream.send(t, `{"arg":{"channel":"balance_and_position","uid":"1"},"data":[{"eventType":"filled","balData":[{"ccy":"USDT","cashBal":"1100"}],"posData":[{"posId":"1","instId":"BTC-USDT-SWAP","pos":"0"}]}]}`)
+	upstream.send(t, `{"arg":{"channel":"orders","uid":"1","instType":"ANY"},"data":[{"ordId":"9","instId":"ETH-USDT-SWAP","state":"live"}]}`)
+
+	var delta models.AccountStreamMessage
+	require.NoError(t, browser.ReadJSON(&delta))
+	assert.Equal(t, "delta", delta.Type)
+	assert.Equal(t, "balance_and_position", delta.Channel)
+
+	require.Eventually(t, func() bool {
+		return len(stream.Orders()) == 1
+	}, 3*time.Second, 10*time.Millisecond)
+
+	positions, _ := stream.Positions()
+	require.Len(t, positions, 1)
+	assert.Equal(t, "2", positions[0].PosId)
+
+	balance, _ = stream.Balance()
+	assert.Equal(t, "1100", balance.Details[0].CashBal)
+
+	// 断线后状态失效，回退到REST直到新快照
+	upstream.conn.Close()
+	require.Eventually(t, func() bool {
+		_, ok := stream.Positions()
+		return !ok
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+// racingAccountStream 在获取快照的同时产生一条增量，模拟快照与注册监听之间到达的变化
+type racingAccountStream struct {
+	service.AccountStream
+	listeners []func(*models.AccountStreamMessage)
+}
+
+func (s *racingAccountStream) AddListener(listener func(*models.AccountStreamMessage)) func() {
+	s.listeners = append(s.listeners, listener)
+	return func() {}
+}
+
+func (s *racingAccountStream) Snapshot() *models.AccountStreamMessage {
+	for _, listener := range s.listeners {
+		listener(&models.AccountStreamMessage{Type: "delta", Channel: "positions"})
+	}
+	return &models.AccountStreamMessage{Type: "snapshot"}
+}
+
+// TestAccountWebSocketSnapshotRace 测试获取快照期间到达的增量在快照之后补发，不会丢失
+func TestAccountWebSocketSnapshotRace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws/account", func(c *gin.Context) {
+		api.HandleAccountWebSocket(c, &racingAccountStream{}, config.WebSocketConfig{})
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	browser, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/account", nil)
+	require.NoError(t, err)
+	defer browser.Close()
+
+	require.NoError(t, browser.SetReadDeadline(time.Now().Add(3*time.Second)))
+	var message models.AccountStreamMessage
+	require.NoError(t, browser.ReadJSON(&message))
+	assert.Equal(t, "snapshot", message.Type)
+
+	require.NoError(t, browser.ReadJSON(&message))
+	assert.Equal(t, "delta", message.Type)
+	assert.Equal(t, "positions", message.Channel)
+}
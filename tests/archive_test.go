@@ -0,0 +1,373 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/database"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// archiveBaseTime 模拟数据中最早一条的时间
+const archiveBaseTime = int64(1700000000000)
+
+// archiveOKX 模拟OKX成交明细、账单和历史持仓接口，数据按时间从新到旧保存
+type archiveOKX struct {
+	mutex     sync.Mutex
+	fills     []map[string]string
+	bills     []map[string]string
+	positions []map[string]string
+	requests  map[string]int
+	fail      func(path string, request int) bool // 返回 true 时该请求失败
+}
+
+// newArchiveOKX 启动模拟服务器
+func newArchiveOKX(t *testing.T) (*archiveOKX, *httptest.Server) {
+	mock := &archiveOKX{requests: make(map[string]int)}
+	server := httptest.NewServer(http.HandlerFunc(mock.serve))
+	t.Cleanup(server.Close)
+	return mock, server
+}
+
+// addFills 新增 count 条成交，billId 和时间递增，BTC 与 ETH 交替
+func (m *archiveOKX) addFills(count int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i := 0; i < count; i++ {
+		n := len(m.fills)
+		instId := "BTC-USDT-SWAP"
+		if n%2 == 1 {
+			instId = "ETH-USDT-SWAP"
+		}
+		fill := map[string]string{
+			"instType": "SWAP",
+			"instId":   instId,
+			"tradeId":  strconv.Itoa(n),
+			"billId":   strconv.Itoa(1000 + n),
+			"fillPx":   "100",
+			"fillSz":   "1",
+			"ts":       strconv.FormatInt(archiveBaseTime+int64(n)*1000, 10),
+		}
+		m.fills = append([]map[string]string{fill}, m.fills...)
+	}
+}
+
+// addBills 新增 count 条账单，USDT 与 BTC 交替
+func (m *archiveOKX) addBills(count int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i := 0; i < count; i++ {
+		n := len(m.bills)
+		ccy := "USDT"
+		if n%2 == 1 {
+			ccy = "BTC"
+		}
+		bill := map[string]string{
+			"billId": strconv.Itoa(5000 + n),
+			"ccy":    ccy,
+			"type":   "2",
+			"balChg": "-1",
+			"ts":     strconv.FormatInt(archiveBaseTime+int64(n)*1000, 10),
+		}
+		m.bills = append([]map[string]string{bill}, m.bills...)
+	}
+}
+
+// addPositions 新增 count 条历史持仓，每两条的更新时间相同
+func (m *archiveOKX) addPositions(count int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i := 0; i < count; i++ {
+		n := len(m.positions)
+		pos := map[string]string{
+			"instType":    "SWAP",
+			"instId":      "BTC-USDT-SWAP",
+			"posId":       strconv.Itoa(n),
+			"uTime":       strconv.FormatInt(archiveBaseTime+int64(n/2)*1000, 10),
+			"realizedPnl": "1",
+			"ccy":         "USDT",
+		}
+		m.positions = append([]map[string]string{pos}, m.positions...)
+	}
+}
+
+// requestCount 接口累计收到的请求数
+func (m *archiveOKX) requestCount(path string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.requests[path]
+}
+
+// serve 按 after/before/limit 分页，成交和账单按 billId，历史持仓按 uTime
+func (m *archiveOKX) serve(w http.ResponseWriter, r *http.Request) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.requests[r.URL.Path]++
+	if m.fail != nil && m.fail(r.URL.Path, m.requests[r.URL.Path]) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": "50001", "msg": "service temporarily unavailable", "data": []interface{}{}})
+		return
+	}
+
+	var (
+		records []map[string]string
+		key     = "billId"
+	)
+	switch r.URL.Path {
+	case "/api/v5/trade/fills-history":
+		if r.URL.Query().Get("instType") == "SWAP" {
+			records = m.fills
+		}
+	case "/api/v5/account/bills-archive":
+		records = m.bills
+	case "/api/v5/account/positions-history":
+		records, key = m.positions, "uTime"
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	data := []map[string]string{}
+	for _, record := range records {
+		value, _ := strconv.ParseInt(record[key], 10, 64)
+		if after, err := strconv.ParseInt(query.Get("after"), 10, 64); err == nil && value >= after {
+			continue
+		}
+		if before, err := strconv.ParseInt(query.Get("before"), 10, 64); err == nil && value <= before {
+			continue
+		}
+		if len(data) < limit {
+			data = append(data, record)
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"code": "0", "msg": "", "data": data})
+}
+
+// newArchiveService 创建使用临时数据库的归档服务
+func newArchiveService(t *testing.T, baseURL string) (service.ArchiveService, repository.ArchiveRepository) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "archive.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo := repository.NewArchiveRepository(db)
+	okxCfg := &config.OKXConfig{BaseURL: baseURL, APIKey: "archive-key", SecretKey: "secret", Passphrase: "pass"}
+	archiveCfg := &config.ArchiveConfig{SyncInterval: 60, InstTypes: []string{"SPOT", "SWAP"}}
+	return service.NewArchiveService(okxCfg, archiveCfg, repo), repo
+}
+
+// checkpointOf 查找数据来源的同步进度
+func checkpointOf(t *testing.T, archiveService service.ArchiveService, source string) *models.ArchiveCheckpoint {
+	status, err := archiveService.Status()
+	require.NoError(t, err)
+	for _, checkpoint := range status {
+		if checkpoint.Source == source {
+			return checkpoint
+		}
+	}
+	t.Fatalf("没有 %s 的同步进度", source)
+	return nil
+}
+
+// TestArchiveSync 测试首次全量同步、重复同步不产生重复数据，以及增量同步新数据
+func TestArchiveSync(t *testing.T) {
+	mock, server := newArchiveOKX(t)
+	mock.addFills(250)
+	mock.addBills(150)
+	mock.addPositions(120)
+	archiveService, _ := newArchiveService(t, server.URL)
+	ctx := context.Background()
+	all := &models.ArchiveQuery{Limit: 1000}
+
+	require.NoError(t, archiveService.SyncOnce(ctx))
+	fills, err := archiveService.Fills(all)
+	require.NoError(t, err)
+	require.Len(t, fills, 250)
+	assert.Equal(t, "1249", fills[0].BillId, "按时间倒序")
+	bills, err := archiveService.Bills(all)
+	require.NoError(t, err)
+	assert.Len(t, bills, 150)
+	positions, err := archiveService.Positions(all)
+	require.NoError(t, err)
+	assert.Len(t, positions, 120)
+
+	checkpoint := checkpointOf(t, archiveService, "fills:SWAP")
+	assert.Equal(t, "1249", checkpoint.Floor)
+	assert.Empty(t, checkpoint.Cursor)
+	assert.Empty(t, checkpoint.LastError)
+
+	// 没有新数据时每个来源只请求一次，不会重复写入
+	fillRequests := mock.requestCount("/api/v5/trade/fills-history")
+	require.NoError(t, archiveService.SyncOnce(ctx))
+	assert.Equal(t, fillRequests+2, mock.requestCount("/api/v5/trade/fills-history"), "SPOT 和 SWAP 各一次")
+	fills, err = archiveService.Fills(all)
+	require.NoError(t, err)
+	assert.Len(t, fills, 250)
+	positions, err = archiveService.Positions(all)
+	require.NoError(t, err)
+	assert.Len(t, positions, 120)
+
+	// 增量同步新数据
+	mock.addFills(130)
+	mock.addBills(5)
+	mock.addPositions(3)
+	require.NoError(t, archiveService.SyncOnce(ctx))
+	fills, err = archiveService.Fills(all)
+	require.NoError(t, err)
+	assert.Len(t, fills, 380)
+	bills, err = archiveService.Bills(all)
+	require.NoError(t, err)
+	assert.Len(t, bills, 155)
+	positions, err = archiveService.Positions(all)
+	require.NoError(t, err)
+	assert.Len(t, positions, 123)
+	assert.Equal(t, "1379", checkpointOf(t, archiveService, "fills:SWAP").Floor)
+	assert.Equal(t, strconv.FormatInt(archiveBaseTime+61*1000, 10), checkpointOf(t, archiveService, "positions").Floor)
+}
+
+// TestArchiveSyncResume 测试同步中断后从进度继续，不重新请求已同步的页
+func TestArchiveSyncResume(t *testing.T) {
+	mock, server := newArchiveOKX(t)
+	mock.addFills(250)
+	mock.addPositions(250)
+	mock.fail = func(path string, request int) bool {
+		// SWAP 成交第2页之后、历史持仓第3页失败
+		return (path == "/api/v5/trade/fills-history" && request == 4) ||
+			(path == "/api/v5/account/positions-history" && request == 3)
+	}
+	archiveService, _ := newArchiveService(t, server.URL)
+	ctx := context.Background()
+	all := &models.ArchiveQuery{Limit: 1000}
+
+	err := archiveService.SyncOnce(ctx)
+	require.Error(t, err)
+
+	fills, err := archiveService.Fills(all)
+	require.NoError(t, err)
+	assert.Len(t, fills, 200, "失败前的两页已保存")
+	checkpoint := checkpointOf(t, archiveService, "fills:SWAP")
+	assert.Equal(t, "1050", checkpoint.Cursor)
+	assert.Equal(t, "1249", checkpoint.Top)
+	assert.Empty(t, checkpoint.Floor)
+	assert.NotEmpty(t, checkpoint.LastError)
+	assert.NotEmpty(t, checkpointOf(t, archiveService, "positions").Cursor)
+	assert.Empty(t, checkpointOf(t, archiveService, "bills").LastError, "其他来源不受影响")
+
+	// 继续同步只请求剩余的页
+	mock.fail = nil
+	fillRequests := mock.requestCount("/api/v5/trade/fills-history")
+	require.NoError(t, archiveService.SyncOnce(ctx))
+	assert.Equal(t, fillRequests+2, mock.requestCount("/api/v5/trade/fills-history"), "SPOT 一次，SWAP 剩余一页")
+
+	fills, err = archiveService.Fills(all)
+	require.NoError(t, err)
+	assert.Len(t, fills, 250)
+	positions, err := archiveService.Positions(all)
+	require.NoError(t, err)
+	assert.Len(t, positions, 250)
+
+	checkpoint = checkpointOf(t, archiveService, "fills:SWAP")
+	assert.Equal(t, "1249", checkpoint.Floor)
+	assert.Empty(t, checkpoint.Cursor)
+	assert.Empty(t, checkpoint.Top)
+	assert.Empty(t, checkpoint.LastError)
+	assert.Empty(t, checkpointOf(t, archiveService, "positions").Cursor)
+}
+
+// TestArchiveQuery 测试归档查询的过滤和分页
+func TestArchiveQuery(t *testing.T) {
+	mock, server := newArchiveOKX(t)
+	mock.addFills(20)
+	mock.addBills(20)
+	archiveService, repo := newArchiveService(t, server.URL)
+	require.NoError(t, archiveService.SyncOnce(context.Background()))
+
+	fills, err := repo.Fills(&models.ArchiveQuery{InstId: "ETH-USDT-SWAP"})
+	require.NoError(t, err)
+	assert.Len(t, fills, 10)
+
+	// 第5到第9条成交
+	fills, err = repo.Fills(&models.ArchiveQuery{Begin: archiveBaseTime + 5*1000, End: archiveBaseTime + 9*1000})
+	require.NoError(t, err)
+	require.Len(t, fills, 5)
+	assert.Equal(t, "9", fills[0].TradeId)
+
+	fills, err = repo.Fills(&models.ArchiveQuery{Limit: 3, Offset: 2})
+	require.NoError(t, err)
+	require.Len(t, fills, 3)
+	assert.Equal(t, "17", fills[0].TradeId)
+
+	bills, err := repo.Bills(&models.ArchiveQuery{Ccy: "BTC"})
+	require.NoError(t, err)
+	assert.Len(t, bills, 10)
+	bills, err = repo.Bills(&models.ArchiveQuery{Type: models.BillTypeFunding})
+	require.NoError(t, err)
+	assert.Empty(t, bills, "没有资金费账单")
+	bills, err = repo.Bills(&models.ArchiveQuery{Type: "2"})
+	require.NoError(t, err)
+	assert.Len(t, bills, 20)
+
+	// 重复保存按主键覆盖
+	fill := *fills[0]
+	fill.FillPx = "200"
+	require.NoError(t, repo.SaveFills([]*models.Fill{&fill}))
+	fills, err = repo.Fills(&models.ArchiveQuery{Limit: 1000})
+	require.NoError(t, err)
+	assert.Len(t, fills, 20)
+}
+
+// TestArchiveRoutes 测试归档查询和手动同步接口
+func TestArchiveRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mock, server := newArchiveOKX(t)
+	mock.addFills(5)
+
+	cfg := newAuthConfig()
+	cfg.SQLitePath = filepath.Join(t.TempDir(), "alphaark.db")
+	cfg.OKX = config.OKXConfig{BaseURL: server.URL, APIKey: "archive-route-key", SecretKey: "secret", Passphrase: "pass"}
+	cfg.Archive = config.ArchiveConfig{Enabled: false, SyncInterval: 60, InstTypes: []string{"SWAP"}}
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+	api.SetupArchiveRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
+
+	w := authRequest(r, http.MethodPost, "/api/v1/archive/sync", token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"source":"fills:SWAP"`)
+
+	w = authRequest(r, http.MethodGet, "/api/v1/archive/fills?instId=BTC-USDT-SWAP", token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data []models.Fill `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.Data, 3)
+
+	for _, query := range []string{"begin=2&end=1", "limit=-1", "offset=x"} {
+		w = authRequest(r, http.MethodGet, "/api/v1/archive/fills?"+query, token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	// 没有API密钥时不能同步
+	noKeys := newAuthConfig()
+	r = gin.New()
+	api.SetupAuthRoutes(r, noKeys)
+	api.SetupArchiveRoutes(r, noKeys)
+	token = loginTokens(t, r, "admin", "test-password").AccessToken
+	w = authRequest(r, http.MethodPost, "/api/v1/archive/sync", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthConfig 带初始用户的测试配置
+func newAuthConfig() *config.Config {
+	return &config.Config{
+		JWTSecret: "test-secret",
+		Auth:      config.AuthConfig{AdminUsername: "admin", AdminPassword: "test-password"},
+	}
+}
+
+// authRequest 发送JSON请求，token 不为空时附带访问令牌
+func authRequest(r *gin.Engine, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// loginTokens 使用用户名和密码登录，返回令牌
+func loginTokens(t testing.TB, r *gin.Engine, username, password string) *models.TokenResponse {
+	w := authRequest(r, http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: username, Password: password})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var body struct {
+		Data models.TokenResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return &body.Data
+}
+
+// TestJWT 测试令牌签发和校验
+func TestJWT(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+	token, err := jwt.Sign(&jwt.Claims{ID: "1", Subject: "42", Type: jwt.TypeAccess, ExpiresAt: now.Add(time.Minute).Unix()}, secret)
+	require.NoError(t, err)
+
+	claims, err := jwt.Parse(token, secret, now)
+	require.NoError(t, err)
+	assert.Equal(t, "42", claims.Subject)
+
+	_, err = jwt.Parse(token, []byte("other"), now)
+	assert.ErrorIs(t, err, jwt.ErrInvalidToken)
+	_, err = jwt.Parse(token, secret, now.Add(time.Minute))
+	assert.ErrorIs(t, err, jwt.ErrExpiredToken)
+
+	// 替换为 alg=none 并去掉签名
+	parts := strings.Split(token, ".")
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	_, err = jwt.Parse(none+"."+parts[1]+".", secret, now)
+	assert.ErrorIs(t, err, jwt.ErrInvalidToken)
+	_, err = jwt.Parse("abc", secret, now)
+	assert.ErrorIs(t, err, jwt.ErrInvalidToken)
+}
+
+// TestAuthFlow 测试登录、访问受保护接口、刷新和注销
+func TestAuthFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := newAuthConfig()
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+	api.SetupAccountRoutes(r, cfg)
+
+	// 未登录不能访问账户接口
+	w := authRequest(r, http.MethodGet, "/api/v1/account/currencies", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = authRequest(r, http.MethodGet, "/api/v1/account/currencies", "invalid", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = authRequest(r, http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: "admin", Password: "wrong-password"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = authRequest(r, http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: "nobody", Password: "test-password"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = authRequest(r, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"username": "admin"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	tokens := loginTokens(t, r, "admin", "test-password")
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, "admin", tokens.User.Username)
+	assert.NotContains(t, authRequest(r, http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: "admin", Password: "test-password"}).Body.String(), "passwordHash")
+
+	w = authRequest(r, http.MethodGet, "/api/v1/account/currencies", tokens.AccessToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = authRequest(r, http.MethodGet, "/api/v1/auth/me", tokens.AccessToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":"admin"`)
+
+	// 刷新令牌不能当作访问令牌使用
+	w = authRequest(r, http.MethodGet, "/api/v1/account/currencies", tokens.RefreshToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 刷新后旧的刷新令牌失效
+	w = authRequest(r, http.MethodPost, "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	require.Equal(t, http.StatusOK, w.Code)
+	var refreshed struct {
+		Data models.TokenResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &refreshed))
+	w = authRequest(r, http.MethodPost, "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 注销后访问令牌和刷新令牌均失效
+	w = authRequest(r, http.MethodPost, "/api/v1/auth/logout", refreshed.Data.AccessToken, models.LogoutRequest{RefreshToken: refreshed.Data.RefreshToken})
+	require.Equal(t, http.StatusOK, w.Code)
+	w = authRequest(r, http.MethodGet, "/api/v1/account/currencies", refreshed.Data.AccessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = authRequest(r, http.MethodPost, "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: refreshed.Data.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 其他会话不受影响；REST接口不接受查询参数中的令牌，只有WebSocket可以通过查询参数传递令牌
+	w = authRequest(r, http.MethodGet, "/api/v1/account/currencies", tokens.AccessToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = authRequest(r, http.MethodGet, "/api/v1/account/currencies?token="+tokens.AccessToken, "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// TestConcurrentRefresh 测试同一刷新令牌并发刷新时只有一次成功
+func TestConcurrentRefresh(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := newAuthConfig()
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+
+	tokens := loginTokens(t, r, "admin", "test-password")
+
+	const attempts = 8
+	codes := make([]int, attempts)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			codes[i] = authRequest(r, http.MethodPost, "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: tokens.RefreshToken}).Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for _, code := range codes {
+		if code == http.StatusOK {
+			succeeded++
+		} else {
+			assert.Equal(t, http.StatusUnauthorized, code)
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+// TestConfigValidate 测试生产环境禁止使用默认JWT密钥
+func TestConfigValidate(t *testing.T) {
+	assert.Error(t, (&config.Config{Environment: "production", JWTSecret: config.DefaultJWTSecret}).Validate())
+	assert.Error(t, (&config.Config{Environment: "production"}).Validate())
+	assert.NoError(t, (&config.Config{Environment: "production", JWTSecret: "a-strong-secret"}).Validate())
+	assert.NoError(t, (&config.Config{Environment: "development", JWTSecret: config.DefaultJWTSecret}).Validate())
+}
@@ -0,0 +1,225 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/database"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// candleServer 模拟OKX K线接口，按请求生成K线并记录请求
+type candleServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	requests []*http.Request
+	missing  map[int64]bool // 不返回的K线
+}
+
+var candleDurations = map[string]int64{
+	"1m": time.Minute.Milliseconds(),
+	"5m": 5 * time.Minute.Milliseconds(),
+}
+
+func newCandleServer(t *testing.T) *candleServer {
+	s := &candleServer{missing: make(map[int64]bool)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		s.requests = append(s.requests, r)
+		s.mutex.Unlock()
+
+		query := r.URL.Query()
+		duration := candleDurations[query.Get("bar")]
+		after, _ := strconv.ParseInt(query.Get("after"), 10, 64)
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		now := time.Now().UnixMilli()
+
+		rows := [][]string{}
+		for ts := (after - 1) / duration * duration; len(rows) < limit; ts -= duration {
+			if s.missing[ts] {
+				continue
+			}
+			confirm := "0"
+			if ts+duration <= now {
+				confirm = "1"
+			}
+			// 最高价随时间变化，便于验证聚合结果
+			high := fmt.Sprintf("%d.5", 100+ts/duration%10)
+			rows = append(rows, []string{strconv.FormatInt(ts, 10), "100", high, "99", "100.1", "1.1", "0.02", "2.25", confirm})
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": "0", "msg": "", "data": rows})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// calls 返回记录的请求并清空
+func (s *candleServer) calls() []*http.Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
+
+func newTestCandleService(t *testing.T, server *candleServer) service.CandleService {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return service.NewCandleService(&config.OKXConfig{BaseURL: server.URL}, repository.NewCandleRepository(db))
+}
+
+// TestCandlesCache 测试已完结的K线缓存在本地，重复查询只请求未完结的最新K线
+func TestCandlesCache(t *testing.T) {
+	server := newCandleServer(t)
+	candleService := newTestCandleService(t, server)
+	minute := time.Minute.Milliseconds()
+
+	resp, err := candleService.GetCandles(context.Background(), &models.CandlesRequest{InstId: "BTC-USDT", Bar: "1m", Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, resp.Candles, 5)
+	assert.False(t, resp.Aggregated)
+	assert.Empty(t, resp.Gaps)
+	for i := 1; i < len(resp.Candles); i++ {
+		assert.Equal(t, minute, resp.Candles[i].Ts-resp.Candles[i-1].Ts)
+	}
+	assert.False(t, resp.Candles[4].Confirm)
+	assert.True(t, resp.Candles[3].Confirm)
+
+	calls := server.calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "/api/v5/market/candles", calls[0].URL.Path)
+	assert.Equal(t, "5", calls[0].URL.Query().Get("limit"))
+
+	resp, err = candleService.GetCandles(context.Background(), &models.CandlesRequest{InstId: "BTC-USDT", Bar: "1m", Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, resp.Candles, 5)
+
+	// 第二次查询只请求缓存中没有的未完结K线（跨分钟时可能多一根）
+	calls = server.calls()
+	require.Len(t, calls, 1)
+	limit, _ := strconv.Atoi(calls[0].URL.Query().Get("limit"))
+	assert.LessOrEqual(t, limit, 2)
+
+	// 查询已完结的区间完全命中缓存
+	after := resp.Candles[3].Ts
+	resp, err = candleService.GetCandles(context.Background(), &models.CandlesRequest{InstId: "BTC-USDT", Bar: "1m", After: after, Limit: 3})
+	require.NoError(t, err)
+	require.Len(t, resp.Candles, 3)
+	assert.Equal(t, after-minute, resp.Candles[2].Ts)
+	assert.Empty(t, server.calls())
+}
+
+// TestCandlesAggregation 测试非原生周期由小周期K线聚合
+func TestCandlesAggregation(t *testing.T) {
+	server := newCandleServer(t)
+	candleService := newTestCandleService(t, server)
+	tenMinutes := (10 * time.Minute).Milliseconds()
+
+	after := time.Now().UnixMilli() / tenMinutes * tenMinutes
+	resp, err := candleService.GetCandles(context.Background(), &models.CandlesRequest{InstId: "BTC-USDT", Bar: "10m", After: after, Limit: 3})
+	require.NoError(t, err)
+	assert.True(t, resp.Aggregated)
+	assert.Equal(t, "5m", resp.SourceBar)
+	require.Len(t, resp.Candles, 3)
+
+	for i, candle := range resp.Candles {
+		assert.Equal(t, after-int64(3-i)*tenMinutes, candle.Ts)
+		assert.Equal(t, "100", candle.Open)
+		assert.Equal(t, "99", candle.Low)
+		assert.Equal(t, "100.1", candle.Close)
+		assert.Equal(t, "2.2", candle.Vol)
+		assert.Equal(t, "0.04", candle.VolCcy)
+		assert.Equal(t, "4.50", candle.VolCcyQuote)
+		assert.True(t, candle.Confirm)
+
+		// 两根5分钟K线中较高的最高价
+		second := (candle.Ts/candleDurations["5m"] + 1) % 10
+		assert.Equal(t, fmt.Sprintf("%d.5", 100+second), candle.High)
+	}
+
+	for _, call := range server.calls() {
+		assert.Equal(t, "5m", call.URL.Query().Get("bar"))
+	}
+}
+
+// TestCandlesGapsAndHistory 测试缺口检测和历史K线接口
+func TestCandlesGapsAndHistory(t *testing.T) {
+	server := newCandleServer(t)
+	candleService := newTestCandleService(t, server)
+	minute := time.Minute.Milliseconds()
+
+	// 两天前的K线超出最近K线范围
+	after := time.Now().Add(-48*time.Hour).UnixMilli() / minute * minute
+	server.missing[after-3*minute] = true
+	server.missing[after-4*minute] = true
+
+	resp, err := candleService.GetCandles(context.Background(), &models.CandlesRequest{InstId: "BTC-USDT", Bar: "1m", After: after, Limit: 6})
+	require.NoError(t, err)
+	require.Len(t, resp.Candles, 4)
+	require.Len(t, resp.Gaps, 1)
+	assert.Equal(t, models.CandleGap{Start: after - 4*minute, End: after - 2*minute, Missing: 2}, resp.Gaps[0])
+
+	calls := server.calls()
+	require.NotEmpty(t, calls)
+	for _, call := range calls {
+		assert.Equal(t, "/api/v5/market/history-candles", call.URL.Path)
+	}
+}
+
+// TestCandlesEndpoint 测试K线接口参数校验
+func TestCandlesEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := newCandleServer(t)
+
+	cfg := newAuthConfig()
+	cfg.OKX = config.OKXConfig{BaseURL: server.URL}
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+	api.SetupMarketRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
+
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"bar=5m&limit=3", http.StatusOK},
+		{"bar=7x", http.StatusBadRequest},
+		{"limit=500", http.StatusBadRequest},
+		{"after=abc", http.StatusBadRequest},
+		{"after=100&before=200", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/market/candles/BTC-USDT?"+tt.query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, tt.query)
+
+		if tt.status == http.StatusOK {
+			var body struct {
+				Data models.CandlesResponse `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, "BTC-USDT", body.Data.InstId)
+			assert.Len(t, body.Data.Candles, 3)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubBalanceStream 只提供固定余额的账户状态
+type stubBalanceStream struct {
+	service.AccountStream
+	balance *service.OKXBalanceData
+}
+
+func (s *stubBalanceStream) Balance() (*service.OKXBalanceData, bool) {
+	return s.balance, true
+}
+
+// newRatesServer 模拟OKX现货行情和以USD为基准的法币汇率接口
+func newRatesServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v5/market/tickers":
+			assert.Equal(t, "SPOT", r.URL.Query().Get("instType"))
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": "0", "msg": "", "data": []map[string]string{
+				{"instId": "BTC-USDT", "last": "50000"},
+				{"instId": "ETH-BTC", "last": "0.05"},
+				{"instId": "SOL-USDC", "last": "100"},
+				{"instId": "USDC-USDT", "last": "1"},
+				{"instId": "PEPE-USDT", "last": "0.00001"},
+				{"instId": "DOGE-USDT", "last": "0"},
+			}})
+		case "/api/v5/public/instruments":
+			data := []map[string]string{}
+			if r.URL.Query().Get("instId") == "ETH-USDT" {
+				data = append(data, map[string]string{"instType": "SPOT", "instId": "ETH-USDT", "lotSz": "0.0001", "state": "live"})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": "0", "msg": "", "data": data})
+		case "/fiat":
+			json.NewEncoder(w).Encode(map[string]interface{}{"rates": map[string]float64{"USD": 1, "CNY": 7, "EUR": 0.9}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestRateGraph 测试汇率图的多跳换算和路径选择
+func TestRateGraph(t *testing.T) {
+	graph := service.NewRateGraph()
+	graph.AddRate("BTC", "USDT", decimal.MustParse("50000"))
+	graph.AddRate("ETH", "BTC", decimal.MustParse("0.05"))
+	graph.AddRate("USDT", "USD", decimal.MustParse("1"))
+	graph.AddRate("USD", "CNY", decimal.MustParse("7"))
+	graph.AddRate("ETH", "ABC", decimal.MustParse("10"))
+	graph.AddRate("ABC", "CNY", decimal.MustParse("1000"))
+
+	// 跳数最少的路径优先
+	rate, path, err := graph.Rate("ETH", "CNY")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ETH", "ABC", "CNY"}, path)
+	assert.Equal(t, "10000", rate.String())
+
+	// 反向比率精确保存，换算回原币种没有误差
+	rate, path, err = graph.Rate("CNY", "BTC")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CNY", "USD", "USDT", "BTC"}, path)
+	assert.Zero(t, rate.Cmp(decimal.New(1).Quo(decimal.New(350000))))
+	back, err := graph.Convert(rate.Mul(decimal.MustParse("0.1")), "BTC", "CNY")
+	require.NoError(t, err)
+	assert.Equal(t, "0.1", back.String())
+
+	// 同样跳数下优先经过枢纽资产
+	graph.AddRate("SOL", "AAA", decimal.MustParse("1"))
+	graph.AddRate("AAA", "USD", decimal.MustParse("99"))
+	graph.AddRate("SOL", "USDT", decimal.MustParse("100"))
+	rate, path, err = graph.Rate("SOL", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SOL", "USDT", "USD"}, path)
+	assert.Equal(t, "100", rate.String())
+
+	amount, err := graph.Convert(decimal.MustParse("0.00000001"), "BTC", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "0.00050000", amount.String())
+
+	graph.AddRate("XYZ", "QQQ", decimal.MustParse("2"))
+	_, _, err = graph.Rate("XYZ", "USDT")
+	assert.Error(t, err, "不连通的资产没有兑换路径")
+	_, _, err = graph.Rate("NONE", "USDT")
+	assert.Error(t, err)
+}
+
+// TestAccountBalanceConversion 测试账户余额按任意显示币种换算
+func TestAccountBalanceConversion(t *testing.T) {
+	server := newRatesServer(t)
+	stream := &stubBalanceStream{balance: &service.OKXBalanceData{
+		TotalEq: "1000",
+		Details: []service.OKXBalanceDetail{
+			{Ccy: "ETH", Bal: "2", AvailBal: "2"},
+			{Ccy: "SOL", Bal: "3", AvailBal: "3"},
+			{Ccy: "PEPE", Bal: "1000000", AvailBal: "1000000"},
+			{Ccy: "DOGE", Bal: "10", AvailBal: "10"},
+		},
+	}}
+	accountService := service.NewAccountServiceWithCurrencies(
+		&config.OKXConfig{BaseURL: server.URL},
+		&config.CurrencyConfig{DisplayCurrencies: []string{"eur", "ETH", "CNY", "EUR"}, FiatRatesURLs: []string{server.URL + "/missing", server.URL + "/fiat"}},
+		nil, stream,
+	)
+
+	assert.Equal(t, []models.Currency{models.CurrencyEUR, models.CurrencyETH, models.CurrencyCNY}, accountService.SupportedCurrencies())
+	assert.Equal(t, models.CurrencyEUR, accountService.GetDefaultCurrency(), "未配置USDT时默认使用第一个显示币种")
+
+	balance, err := accountService.GetAccountBalance(context.Background(), models.CurrencyEUR)
+	require.NoError(t, err)
+	assert.Equal(t, "900.00", balance.TotalEquity.String())
+
+	equity := map[string]string{}
+	for _, detail := range balance.Details {
+		equity[detail.Currency] = ""
+		if detail.Equity != nil {
+			equity[detail.Currency] = detail.Equity.String()
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"ETH":  "4500.00", // ETH -> BTC -> USDT -> USD -> EUR
+		"SOL":  "270.00",  // SOL -> USDC -> USDT -> USD -> EUR
+		"PEPE": "9.00",
+		"DOGE": "", // 没有有效价格，不按0计算
+	}, equity)
+
+	balance, err = accountService.GetAccountBalance(context.Background(), models.CurrencyETH)
+	require.NoError(t, err)
+	assert.Equal(t, "0.4000", balance.TotalEquity.String(), "按ETH-USDT的数量精度显示")
+
+	rates, err := accountService.GetExchangeRates(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, rates, "USDT_EUR")
+	assert.Equal(t, "0.9", rates["USDT_EUR"].Rate.String())
+	assert.Equal(t, []string{"USDT", "USD", "EUR"}, rates["USDT_EUR"].Path)
+	assert.Equal(t, []string{service.RateSourcePeg, service.RateSourceHTTP}, rates["USDT_EUR"].Sources)
+	assert.Equal(t, "7", rates["USDT_CNY"].Rate.String())
+	assert.Equal(t, "0.0004", rates["USDT_ETH"].Rate.String())
+	assert.Equal(t, []string{service.RateSourceOKX}, rates["USDT_ETH"].Sources)
+	assert.True(t, rates["USDT_ETH"].Live)
+}
+
+// stubHistoryStream 提供固定余额和历史持仓的账户状态
+type stubHistoryStream struct {
+	stubBalanceStream
+	history []service.OKXPositionHistoryData
+}
+
+func (s *stubHistoryStream) PositionsHistory(req *models.PositionsHistoryRequest) []service.OKXPositionHistoryData {
+	return s.history
+}
+
+// TestAccountBalanceReconciliation 测试各币种权益之和与OKX总权益精确一致，历史持仓汇总精确累加
+func TestAccountBalanceReconciliation(t *testing.T) {
+	server := newRatesServer(t)
+	stream := &stubHistoryStream{
+		stubBalanceStream: stubBalanceStream{balance: &service.OKXBalanceData{
+			TotalEq: "60123.456789012345",
+			Details: []service.OKXBalanceDetail{
+				{Ccy: "BTC", Eq: "1.00000001", AvailBal: "1", EqUsd: "50000.0005"},
+				{Ccy: "USDT", Eq: "10000.1", AvailBal: "10000.1", EqUsd: "10000.1"},
+				{Ccy: "PEPE", Eq: "12345678.9", AvailBal: "12345678.9", EqUsd: "123.356289012345"},
+			},
+		}},
+		history: []service.OKXPositionHistoryData{
+			{PosId: "1", Ccy: "USDT", RealizedPnl: "0.1", Pnl: "0.2", Fee: "-0.1", FundingFee: "0"},
+			{PosId: "2", Ccy: "USDT", RealizedPnl: "0.2", Pnl: "0.3", Fee: "-0.1", FundingFee: "-0.000001"},
+			{PosId: "3", Ccy: "BTC", RealizedPnl: "0.0001", Pnl: "0.0001", Fee: "0", FundingFee: "0"},
+			{PosId: "4", Ccy: "DOGE", RealizedPnl: "1", Pnl: "1", Fee: "0", FundingFee: "0"},
+		},
+	}
+	accountService := service.NewAccountServiceWithCurrencies(
+		&config.OKXConfig{BaseURL: server.URL},
+		&config.CurrencyConfig{FiatRatesURLs: []string{server.URL + "/fiat"}},
+		nil, stream,
+	)
+
+	// USDT显示时总权益与OKX返回值完全一致，各币种权益之和等于总权益
+	balance, err := accountService.GetAccountBalance(context.Background(), models.CurrencyUSDT)
+	require.NoError(t, err)
+	assert.Equal(t, "60123.456789012345", balance.TotalEquity.String())
+	sum := decimal.Zero
+	for _, detail := range balance.Details {
+		require.NotNil(t, detail.Equity)
+		sum = sum.Add(*detail.Equity)
+	}
+	assert.Zero(t, sum.Cmp(balance.TotalEquity))
+	assert.Equal(t, "1.00000001", balance.Details[0].Balance.String(), "没有bal时使用eq")
+
+	balance, err = accountService.GetAccountBalance(context.Background(), models.CurrencyCNY)
+	require.NoError(t, err)
+	assert.Equal(t, "420864.20", balance.TotalEquity.String())
+
+	history, err := accountService.GetPositionsHistory(context.Background(), &models.PositionsHistoryRequest{}, models.CurrencyUSDT)
+	require.NoError(t, err)
+	require.NotNil(t, history.Summary)
+	// USDT持仓保留原始精度，BTC持仓换算后按2位小数取整
+	assert.Equal(t, "5.30", history.Summary.RealizedPnl.String())
+	assert.Equal(t, "5.50", history.Summary.Pnl.String())
+	assert.Equal(t, "-0.20", history.Summary.Fee.String())
+	assert.Equal(t, "-0.000001", history.Summary.FundingFee.String())
+	assert.Equal(t, 3, history.Summary.Count)
+	assert.Equal(t, []string{"4"}, history.Summary.Unconverted)
+}
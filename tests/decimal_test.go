@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDecimal 测试精确十进制数的运算、取整和JSON序列化
+func TestDecimal(t *testing.T) {
+	// 浮点数 0.1+0.2 != 0.3
+	sum := decimal.Sum(decimal.MustParse("0.1"), decimal.MustParse("0.2"))
+	assert.Equal(t, "0.3", sum.String())
+	assert.Zero(t, sum.Cmp(decimal.MustParse("0.30")))
+
+	// 保留原始小数位数
+	assert.Equal(t, "1.50", decimal.MustParse("1.50").String())
+	assert.Equal(t, "0.00000001", decimal.MustParse("1e-8").String())
+	assert.Equal(t, "21000000.12345678", decimal.MustParse("21000000").Add(decimal.MustParse("0.12345678")).String())
+	assert.Equal(t, "0.0000000012", decimal.MustParse("0.00001").Mul(decimal.MustParse("0.00012")).String())
+
+	// 除法精确保存，按需取整
+	third := decimal.New(1).Quo(decimal.New(3))
+	assert.Equal(t, "0.333333333333333333", third.String())
+	assert.Equal(t, "1", third.Mul(decimal.New(3)).String())
+	assert.Equal(t, "0.5", decimal.New(1).Quo(decimal.New(2)).String())
+	assert.Equal(t, "0.33", third.Round(2).String())
+	assert.Equal(t, "-2.35", decimal.MustParse("-2.345").Round(2).String(), "0.5远离零")
+	assert.Equal(t, "7.00", decimal.New(7).Round(2).String())
+	assert.Equal(t, "2.50", decimal.MustParse("-2.50").Abs().String())
+
+	_, err := decimal.Parse("")
+	assert.Error(t, err)
+	_, err = decimal.Parse("1/3")
+	assert.Error(t, err)
+	_, err = decimal.Parse("abc")
+	assert.Error(t, err)
+
+	var values struct {
+		A decimal.Decimal  `json:"a"`
+		B decimal.Decimal  `json:"b"`
+		C decimal.Decimal  `json:"c"`
+		D *decimal.Decimal `json:"d"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"a":"123.4500","b":"","c":0.25,"d":null}`), &values))
+	assert.Equal(t, "123.4500", values.A.String())
+	assert.True(t, values.B.IsZero())
+	assert.Equal(t, "0.25", values.C.String())
+
+	data, err := json.Marshal(values)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":"123.4500","b":"0","c":"0.25","d":null}`, string(data))
+}
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/middleware"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDemoModeSignedRequests 测试模拟盘模式下私有接口使用模拟盘凭证并附带 x-simulated-trading 头
+func TestDemoModeSignedRequests(t *testing.T) {
+	var headers http.Header
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v5/public/time", func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("x-simulated-trading"), "公共接口不附带模拟盘头")
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"ts":"1700000000000"}]}`)
+	})
+	mux.HandleFunc("/api/v5/account/positions", func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		io.WriteString(w, `{"code":"0","msg":"","data":[]}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	demo := &config.OKXConfig{
+		APIKey: "live-key", SecretKey: "live-secret", Passphrase: "live-pass",
+		DemoAPIKey: "demo-key", DemoSecretKey: "demo-secret", DemoPassphrase: "demo-pass",
+		BaseURL: server.URL, IsTest: true,
+	}
+	_, err := api.NewOKXClient(demo).GetPositions(context.Background(), "", "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "1", headers.Get("x-simulated-trading"))
+	assert.Equal(t, "demo-key", headers.Get("OK-ACCESS-KEY"))
+	assert.Equal(t, config.TradingDemo, demo.TradingEnvironment())
+
+	// 实盘模式不附带模拟盘头，使用实盘凭证
+	live := *demo
+	live.IsTest = false
+	_, err = api.NewOKXClient(&live).GetPositions(context.Background(), "", "", "", "")
+	require.NoError(t, err)
+	assert.Empty(t, headers.Get("x-simulated-trading"))
+	assert.Equal(t, "live-key", headers.Get("OK-ACCESS-KEY"))
+
+	// 未配置模拟盘凭证时使用 OKX_API_KEY
+	fallback := &config.OKXConfig{APIKey: "key", SecretKey: "secret", Passphrase: "pass", IsTest: true}
+	apiKey, _, _ := fallback.Keys()
+	assert.Equal(t, "key", apiKey)
+	assert.True(t, fallback.HasKeys())
+}
+
+// TestTradingEnvironmentGuard 测试交易环境标识和模拟盘模式下的实盘交易守卫
+func TestTradingEnvironmentGuard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(cfg *config.OKXConfig) *gin.Engine {
+		r := gin.New()
+		r.Use(middleware.TradingEnvironment(cfg.TradingEnvironment()))
+		r.GET("/ping", func(c *gin.Context) {
+			utils.SuccessResponse(c, nil, "ok")
+		})
+		trade := r.Group("/trade", api.TradingEnvironmentGuard(cfg))
+		trade.POST("/orders", func(c *gin.Context) {
+			utils.SuccessResponse(c, nil, "ok")
+		})
+		return r
+	}
+
+	request := func(r *gin.Engine, method, path, environment string) (*httptest.ResponseRecorder, utils.Response) {
+		req := httptest.NewRequest(method, path, nil)
+		if environment != "" {
+			req.Header.Set("X-Trading-Environment", environment)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var body utils.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body
+	}
+
+	demo := newRouter(&config.OKXConfig{IsTest: true})
+
+	// 所有响应都标明交易环境
+	w, body := request(demo, http.MethodGet, "/ping", "")
+	assert.Equal(t, "demo", w.Header().Get("X-Trading-Environment"))
+	assert.Equal(t, "demo", body.Environment)
+
+	w, body = request(demo, http.MethodPost, "/trade/orders", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "demo", body.Environment)
+
+	// 模拟盘模式拒绝实盘交易
+	w, body = request(demo, http.MethodPost, "/trade/orders", "live")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "demo", body.Environment)
+
+	w, _ = request(demo, http.MethodPost, "/trade/orders", "paper")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 显式允许后放行，响应标明实盘
+	override := newRouter(&config.OKXConfig{IsTest: true, AllowLiveTrading: true})
+	w, body = request(override, http.MethodPost, "/trade/orders", "live")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "live", body.Environment)
+	assert.Equal(t, "live", w.Header().Get("X-Trading-Environment"))
+
+	// 实盘模式不支持模拟盘请求
+	live := newRouter(&config.OKXConfig{})
+	w, body = request(live, http.MethodPost, "/trade/orders", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "live", body.Environment)
+
+	w, _ = request(live, http.MethodPost, "/trade/orders", "demo")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestLiveOrdersCheckLiveAccount 测试模拟盘模式下允许的实盘订单按实盘账户的持仓做风控检查
+func TestLiveOrdersCheckLiveAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var placed []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v5/public/time", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"ts":"1700000000000"}]}`)
+	})
+	mux.HandleFunc("/api/v5/public/instruments", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"instType":"SWAP","instId":"BTC-USDT-SWAP","tickSz":"0.1","lotSz":"0.01","minSz":"0.01","state":"live"}]}`)
+	})
+	// 模拟盘账户没有持仓，实盘账户已有 900 USD 的 BTC 持仓
+	mux.HandleFunc("/api/v5/account/positions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-simulated-trading") == "1" {
+			io.WriteString(w, `{"code":"0","msg":"","data":[]}`)
+			return
+		}
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"instType":"SWAP","instId":"BTC-USDT-SWAP","posId":"1","pos":"1","notionalUsd":"900"}]}`)
+	})
+	mux.HandleFunc("/api/v5/trade/order", func(w http.ResponseWriter, r *http.Request) {
+		placed = append(placed, r.Header.Get("OK-ACCESS-KEY"))
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"ordId":"1001","clOrdId":"","sCode":"0","sMsg":""}]}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cfg := newAuthConfig()
+	cfg.OKX = config.OKXConfig{
+		APIKey: "live-risk-key", SecretKey: "live-secret", Passphrase: "live-pass",
+		DemoAPIKey: "demo-risk-key", DemoSecretKey: "demo-secret", DemoPassphrase: "demo-pass",
+		BaseURL: server.URL, IsTest: true, AllowLiveTrading: true,
+	}
+	cfg.Risk.MaxNotionalPerInstrument = 1000
+
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+	api.SetupTradeRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
+
+	// 0.02 × 43000 = 860
+	order := `{"instId":"BTC-USDT-SWAP","tdMode":"cross","side":"buy","ordType":"limit","sz":"0.02","px":"43000"}`
+	post := func(environment string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/trade/orders", strings.NewReader(order))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Trading-Environment", environment)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(config.TradingDemo)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"demo-risk-key"}, placed)
+
+	// 实盘持仓 900 + 订单 860 超过上限
+	w = post(config.TradingLive)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.Len(t, placed, 1, "被拒绝的实盘订单不会发送到OKX")
+}
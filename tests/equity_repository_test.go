@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/database"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEquityDB(t *testing.T) *sql.DB {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestEquityRepository(t *testing.T) repository.EquityRepository {
+	return repository.NewEquityRepository(newTestEquityDB(t), repository.DefaultEquityAccountID, config.TradingLive)
+}
+
+// TestEquityRepositoryNearest 测试查找最接近时间点的快照
+func TestEquityRepositoryNearest(t *testing.T) {
+	repo := newTestEquityRepository(t)
+	now := time.Now()
+
+	_, err := repo.Nearest(models.CurrencyUSDT, now)
+	assert.ErrorIs(t, err, repository.ErrSnapshotNotFound)
+
+	for i, equity := range []string{"100", "110", "120"} {
+		require.NoError(t, repo.Save(&models.EquitySnapshot{
+			Currency:    models.CurrencyUSDT,
+			TotalEquity: equity,
+			RecordedAt:  now.Add(-time.Duration(2-i) * 24 * time.Hour),
+		}))
+	}
+	require.NoError(t, repo.Save(&models.EquitySnapshot{
+		Currency:    models.CurrencyCNY,
+		TotalEquity: "720",
+		RecordedAt:  now.Add(-24 * time.Hour),
+	}))
+
+	snapshot, err := repo.Nearest(models.CurrencyUSDT, now.Add(-25*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "110", snapshot.TotalEquity)
+
+	snapshot, err = repo.Nearest(models.CurrencyUSDT, now.Add(-47*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "100", snapshot.TotalEquity)
+
+	snapshot, err = repo.Nearest(models.CurrencyCNY, now)
+	require.NoError(t, err)
+	assert.Equal(t, "720", snapshot.TotalEquity)
+}
+
+// TestEquityRepositoryAccounts 测试不同账户、不同交易环境的快照互不混合
+func TestEquityRepositoryAccounts(t *testing.T) {
+	db := newTestEquityDB(t)
+	now := time.Now()
+
+	repos := map[string]repository.EquityRepository{
+		"100": repository.NewEquityRepository(db, repository.DefaultEquityAccountID, config.TradingLive),
+		"200": repository.NewEquityRepository(db, repository.DefaultEquityAccountID, config.TradingDemo),
+		"300": repository.NewEquityRepository(db, repository.DefaultEquityAccountID, config.TradingPaper),
+		"400": repository.NewEquityRepository(db, 1, config.TradingLive),
+	}
+	for equity, repo := range repos {
+		require.NoError(t, repo.Save(&models.EquitySnapshot{Currency: models.CurrencyUSDT, TotalEquity: equity, RecordedAt: now}))
+	}
+
+	for equity, repo := range repos {
+		snapshot, err := repo.Nearest(models.CurrencyUSDT, now)
+		require.NoError(t, err)
+		assert.Equal(t, equity, snapshot.TotalEquity)
+	}
+
+	_, err := repository.NewEquityRepository(db, 2, config.TradingLive).Nearest(models.CurrencyUSDT, now)
+	assert.ErrorIs(t, err, repository.ErrSnapshotNotFound)
+
+	// 同一时间桶内不同账户的快照各自保留
+	deleted, err := repos["100"].Compact(now.Add(30 * 24 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+}
+
+// TestEquityRepositoryCompact 测试降采样和过期清理
+func TestEquityRepositoryCompact(t *testing.T) {
+	repo := newTestEquityRepository(t)
+	now := time.Now().Truncate(24 * time.Hour).Add(12 * time.Hour)
+
+	save := func(at time.Time, equity string) {
+		require.NoError(t, repo.Save(&models.EquitySnapshot{
+			Currency:    models.CurrencyUSDT,
+			TotalEquity: equity,
+			RecordedAt:  at,
+		}))
+	}
+
+	// 最近的快照全部保留
+	save(now.Add(-10*time.Minute), "recent-1")
+	save(now.Add(-5*time.Minute), "recent-2")
+
+	// 10天前同一小时内的三条快照只保留一条
+	hour := now.Add(-10 * 24 * time.Hour).Truncate(time.Hour)
+	save(hour.Add(5*time.Minute), "hourly-1")
+	save(hour.Add(10*time.Minute), "hourly-2")
+	save(hour.Add(15*time.Minute), "hourly-3")
+
+	// 90天前同一天内的两条快照只保留一条
+	day := now.Add(-90 * 24 * time.Hour).Truncate(24 * time.Hour)
+	save(day.Add(time.Hour), "daily-1")
+	save(day.Add(5*time.Hour), "daily-2")
+
+	// 超过最长保留时间的快照被删除
+	save(now.Add(-repository.DefaultMaxRetention-time.Hour), "expired")
+
+	deleted, err := repo.Compact(now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), deleted)
+
+	snapshot, err := repo.Nearest(models.CurrencyUSDT, hour.Add(15*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "hourly-1", snapshot.TotalEquity)
+
+	snapshot, err = repo.Nearest(models.CurrencyUSDT, day.Add(5*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "daily-1", snapshot.TotalEquity)
+
+	snapshot, err = repo.Nearest(models.CurrencyUSDT, now)
+	require.NoError(t, err)
+	assert.Equal(t, "recent-2", snapshot.TotalEquity)
+}
+
+// recorderAccountService 记录余额请求次数，换算到 failing 币种时失败
+type recorderAccountService struct {
+	service.AccountService
+	balanceCalls int
+	failing      models.Currency
+}
+
+func (s *recorderAccountService) GetAccountBalance(ctx context.Context, currency models.Currency) (*models.AccountBalance, error) {
+	s.balanceCalls++
+	return &models.AccountBalance{TotalEquity: decimal.MustParse("1000"), Currency: currency}, nil
+}
+
+func (s *recorderAccountService) SupportedCurrencies() []models.Currency {
+	return []models.Currency{models.CurrencyUSDT, models.CurrencyBTC, models.CurrencyCNY}
+}
+
+func (s *recorderAccountService) ConvertCurrency(ctx context.Context, amount decimal.Decimal, from, to models.Currency) (decimal.Decimal, error) {
+	switch to {
+	case s.failing:
+		return decimal.Zero, errors.New("汇率信息不可用")
+	case models.CurrencyCNY:
+		return amount.Mul(decimal.New(7)), nil
+	}
+	return amount, nil
+}
+
+// TestEquityRecorderRecordOnce 测试每次记录只获取一次余额，单个币种换算失败时其他币种照常记录
+func TestEquityRecorderRecordOnce(t *testing.T) {
+	repo := newTestEquityRepository(t)
+	accountService := &recorderAccountService{failing: models.CurrencyBTC}
+	recorder := service.NewEquityRecorder(accountService, repo, time.Minute)
+
+	require.NoError(t, recorder.RecordOnce(context.Background()))
+	assert.Equal(t, 1, accountService.balanceCalls)
+
+	snapshot, err := repo.Nearest(models.CurrencyUSDT, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "1000", snapshot.TotalEquity)
+
+	snapshot, err = repo.Nearest(models.CurrencyCNY, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "7000", snapshot.TotalEquity)
+
+	_, err = repo.Nearest(models.CurrencyBTC, time.Now())
+	assert.ErrorIs(t, err, repository.ErrSnapshotNotFound)
+}
@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fundingOpenTime 模拟持仓中最早的开仓时间
+const fundingOpenTime = int64(1700000000000)
+
+// fundingNextTime 模拟的下次结算时间，结算周期8小时
+var fundingNextTime = fundingOpenTime + 3*24*3600*1000
+
+// newFundingServer 模拟OKX持仓、合约、资金费率和资金费账单接口，记录每个请求的查询参数
+func newFundingServer(t *testing.T, queries map[string][]url.Values, mutex *sync.Mutex) *httptest.Server {
+	ms := func(offset time.Duration) string {
+		return strconv.FormatInt(fundingOpenTime+offset.Milliseconds(), 10)
+	}
+
+	positions := []map[string]string{
+		{"instType": "SWAP", "instId": "BTC-USDT-SWAP", "posId": "1", "mgnMode": "cross", "posSide": "long", "pos": "2", "markPx": "50000", "fundingFee": "-3", "cTime": ms(0)},
+		{"instType": "SWAP", "instId": "ETH-USDT-SWAP", "posId": "2", "mgnMode": "isolated", "posSide": "net", "pos": "-10", "markPx": "2000", "cTime": ms(24 * time.Hour)},
+		{"instType": "SWAP", "instId": "BTC-USD-SWAP", "posId": "3", "mgnMode": "cross", "posSide": "net", "pos": "100", "markPx": "50000", "cTime": ms(0)},
+		{"instType": "SWAP", "instId": "SOL-USDT-SWAP", "posId": "4", "mgnMode": "cross", "posSide": "net", "pos": "0", "markPx": "100", "cTime": ms(0)},
+	}
+	instruments := map[string]map[string]string{
+		"BTC-USDT-SWAP": {"instType": "SWAP", "instId": "BTC-USDT-SWAP", "ctVal": "0.01", "ctMult": "1", "ctValCcy": "BTC", "settleCcy": "USDT", "ctType": "linear"},
+		"ETH-USDT-SWAP": {"instType": "SWAP", "instId": "ETH-USDT-SWAP", "ctVal": "0.1", "ctMult": "1", "ctValCcy": "ETH", "settleCcy": "USDT", "ctType": "linear"},
+		"BTC-USD-SWAP":  {"instType": "SWAP", "instId": "BTC-USD-SWAP", "ctVal": "100", "ctMult": "1", "ctValCcy": "USD", "settleCcy": "BTC", "ctType": "inverse"},
+	}
+	rates := map[string]string{"BTC-USDT-SWAP": "0.0001", "ETH-USDT-SWAP": "-0.0002", "BTC-USD-SWAP": "0.0001"}
+	bills := []map[string]string{
+		{"billId": "13", "instType": "SWAP", "instId": "BTC-USDT-SWAP", "mgnMode": "cross", "ccy": "USDT", "type": "8", "balChg": "-1.5", "ts": ms(40 * time.Hour)},
+		{"billId": "12", "instType": "SWAP", "instId": "ETH-USDT-SWAP", "mgnMode": "isolated", "ccy": "USDT", "type": "8", "balChg": "-0.4", "ts": ms(32 * time.Hour)},
+		{"billId": "11", "instType": "SWAP", "instId": "ETH-USDT-SWAP", "mgnMode": "isolated", "ccy": "USDT", "type": "8", "balChg": "0.2", "ts": ms(16 * time.Hour)}, // 早于该持仓开仓
+		{"billId": "10", "instType": "SWAP", "instId": "BTC-USDT-SWAP", "mgnMode": "cross", "ccy": "USDT", "type": "8", "balChg": "-1.5", "ts": ms(8 * time.Hour)},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		mutex.Lock()
+		queries[r.URL.Path] = append(queries[r.URL.Path], query)
+		mutex.Unlock()
+
+		var data interface{} = []interface{}{}
+		switch r.URL.Path {
+		case "/api/v5/account/positions":
+			data = positions
+		case "/api/v5/public/instruments":
+			if inst, exists := instruments[query.Get("instId")]; exists {
+				data = []map[string]string{inst}
+			}
+		case "/api/v5/public/funding-rate":
+			data = []map[string]string{{
+				"instId":          query.Get("instId"),
+				"fundingRate":     rates[query.Get("instId")],
+				"nextFundingRate": "",
+				"fundingTime":     strconv.FormatInt(fundingNextTime, 10),
+				"nextFundingTime": strconv.FormatInt(fundingNextTime+8*3600*1000, 10),
+			}}
+		case "/api/v5/public/funding-rate-history":
+			limit, _ := strconv.Atoi(query.Get("limit"))
+			history := []map[string]string{}
+			for i := 0; i < limit; i++ {
+				history = append(history, map[string]string{
+					"fundingRate":  rates[query.Get("instId")],
+					"realizedRate": rates[query.Get("instId")],
+					"fundingTime":  strconv.FormatInt(fundingNextTime-int64(i+1)*8*3600*1000, 10),
+				})
+			}
+			data = history
+		case "/api/v5/account/bills-archive":
+			data = bills
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": "0", "msg": "", "data": data})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// assertDecimal 按数值比较，忽略小数位数
+func assertDecimal(t *testing.T, expected string, actual decimal.Decimal, msgAndArgs ...interface{}) {
+	t.Helper()
+	assert.Zero(t, decimal.MustParse(expected).Cmp(actual), append([]interface{}{"期望 %s，实际 %s", expected, actual.String()}, msgAndArgs...)...)
+}
+
+// TestGetFunding 测试资金费的预计收付、年化成本、结算记录归属和汇总
+func TestGetFunding(t *testing.T) {
+	var mutex sync.Mutex
+	queries := make(map[string][]url.Values)
+	server := newFundingServer(t, queries, &mutex)
+	accountService := service.NewAccountServiceWithCurrencies(&config.OKXConfig{BaseURL: server.URL, APIKey: "funding-key", SecretKey: "secret", Passphrase: "pass"}, &config.CurrencyConfig{}, nil, nil)
+
+	limit := 3
+	response, err := accountService.GetFunding(context.Background(), &models.FundingRequest{HistoryLimit: &limit}, models.CurrencyUSDT)
+	require.NoError(t, err)
+	require.Len(t, response.Positions, 3, "不返回数量为0的持仓")
+
+	// 正向合约多头，费率为正时支付：2张 × 0.01 × 50000 = 1000 USDT
+	btc := response.Positions[0]
+	assert.Equal(t, "USDT", btc.SettleCcy)
+	assertDecimal(t, "1000", btc.Notional)
+	assertDecimal(t, "-0.1", btc.ExpectedPayment)
+	assertDecimal(t, "0.1095", btc.AnnualizedRate, "每天3次结算")
+	assertDecimal(t, "109.5", btc.AnnualizedCost)
+	assert.Equal(t, int64(8*3600*1000), btc.FundingInterval)
+	assert.Equal(t, fundingNextTime, btc.FundingTime.UnixMilli())
+	assert.Equal(t, "-3", btc.FundingFee)
+	assertDecimal(t, "-3", btc.Accrued)
+	require.Len(t, btc.Settlements, 2)
+	assert.Equal(t, "13", btc.Settlements[0].BillId)
+	assert.Len(t, btc.RateHistory, 3)
+
+	// 单向持仓的空头，费率为负时支付：10张 × 0.1 × 2000 = 2000 USDT
+	eth := response.Positions[1]
+	assertDecimal(t, "2000", eth.Notional)
+	assertDecimal(t, "-0.4", eth.ExpectedPayment)
+	assertDecimal(t, "438", eth.AnnualizedCost)
+	require.Len(t, eth.Settlements, 1, "开仓前的结算不计入")
+	assertDecimal(t, "-0.4", eth.Accrued)
+
+	// 反向合约以币结算：100张 × 100 USD ÷ 50000 = 0.2 BTC
+	inverse := response.Positions[2]
+	assert.Equal(t, "BTC", inverse.SettleCcy)
+	assertDecimal(t, "0.2", inverse.Notional)
+	assertDecimal(t, "-0.00002", inverse.ExpectedPayment)
+	assert.Empty(t, inverse.Settlements)
+
+	// 没有BTC汇率时反向合约不参与汇总
+	assertDecimal(t, "-3.4", response.Summary.Accrued)
+	assertDecimal(t, "-0.5", response.Summary.ExpectedPayment)
+	assertDecimal(t, "547.5", response.Summary.AnnualizedCost)
+	assert.Equal(t, []string{"3"}, response.Summary.Unconverted)
+
+	// 只查询永续合约持仓和最早开仓以来的资金费账单，同一合约的费率只请求一次
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, "SWAP", queries["/api/v5/account/positions"][0].Get("instType"))
+	billQuery := queries["/api/v5/account/bills-archive"][0]
+	assert.Equal(t, "8", billQuery.Get("type"))
+	assert.Equal(t, strconv.FormatInt(fundingOpenTime, 10), billQuery.Get("begin"))
+	assert.Len(t, queries["/api/v5/public/funding-rate"], 3)
+}
+
+// TestFundingRoute 测试资金费接口的参数校验
+func TestFundingRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var mutex sync.Mutex
+	queries := make(map[string][]url.Values)
+	server := newFundingServer(t, queries, &mutex)
+
+	cfg := newAuthConfig()
+	cfg.OKX = config.OKXConfig{BaseURL: server.URL, APIKey: "funding-route-key", SecretKey: "secret", Passphrase: "pass"}
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+	api.SetupAccountRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
+
+	w := authRequest(r, http.MethodGet, "/api/v1/account/funding?instId=BTC-USDT-SWAP&historyLimit=0", token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data models.FundingResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.NotEmpty(t, body.Data.Positions)
+	assert.Empty(t, body.Data.Positions[0].RateHistory)
+	assert.Equal(t, models.CurrencyUSDT, body.Data.Currency)
+
+	mutex.Lock()
+	assert.Empty(t, queries["/api/v5/public/funding-rate-history"], "historyLimit=0 时不请求历史费率")
+	mutex.Unlock()
+
+	for _, query := range []string{"historyLimit=-1", "historyLimit=101", "historyLimit=x", "currency=DOGE"} {
+		w = authRequest(r, http.MethodGet, "/api/v1/account/funding?"+query, token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -0,0 +1,319 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/indicator"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIndicatorValues 测试各指标的计算结果
+func TestIndicatorValues(t *testing.T) {
+	sma := indicator.NewSMA(3)
+	ema := indicator.NewEMA(3)
+	var smaValues, emaValues []float64
+	for _, value := range []float64{1, 2, 3, 4, 5} {
+		if v, ok := sma.Update(value); ok {
+			smaValues = append(smaValues, v)
+		}
+		if v, ok := ema.Update(value); ok {
+			emaValues = append(emaValues, v)
+		}
+	}
+	assert.Equal(t, []float64{2, 3, 4}, smaValues)
+	assert.Equal(t, []float64{2, 3, 4}, emaValues)
+
+	// RSI：前两个变化全部上涨，之后涨跌各半
+	rsi := indicator.NewRSI(2)
+	_, ok := rsi.Update(1)
+	assert.False(t, ok)
+	_, ok = rsi.Update(2)
+	assert.False(t, ok)
+	value, ok := rsi.Update(3)
+	require.True(t, ok)
+	assert.Equal(t, 100.0, value)
+	value, _ = rsi.Update(2)
+	assert.Equal(t, 50.0, value)
+
+	bollinger := indicator.NewBollinger(3, 2)
+	bollinger.Update(1)
+	bollinger.Update(2)
+	band, ok := bollinger.Update(3)
+	require.True(t, ok)
+	assert.Equal(t, 2.0, band.Middle)
+	assert.InDelta(t, 2+2*math.Sqrt(2.0/3), band.Upper, 1e-9)
+	assert.InDelta(t, 2-2*math.Sqrt(2.0/3), band.Lower, 1e-9)
+
+	// ATR：第三根K线跳空，真实波幅取与前收盘价的距离
+	atr := indicator.NewATR(2)
+	_, ok = atr.Update(indicator.Bar{High: 10, Low: 8, Close: 9})
+	assert.False(t, ok)
+	value, ok = atr.Update(indicator.Bar{High: 11, Low: 9, Close: 10.5})
+	require.True(t, ok)
+	assert.Equal(t, 2.0, value)
+	value, _ = atr.Update(indicator.Bar{High: 14, Low: 12, Close: 13})
+	assert.Equal(t, 2.75, value)
+
+	// MACD在 slow+signal-1 个值后产生数值
+	macd := indicator.NewMACD(2, 3, 2)
+	readyAt := -1
+	for i := 0; i < 6; i++ {
+		if _, ok := macd.Update(float64(i)); ok && readyAt < 0 {
+			readyAt = i
+		}
+	}
+	assert.Equal(t, 3, readyAt)
+
+	// VWAP每天重新累计
+	day := (24 * time.Hour).Milliseconds()
+	vwap := indicator.NewVWAP(24 * time.Hour)
+	vwap.Update(indicator.Bar{Ts: 0, High: 10, Low: 10, Close: 10, Volume: 1})
+	value, _ = vwap.Update(indicator.Bar{Ts: 60000, High: 20, Low: 20, Close: 20, Volume: 3})
+	assert.Equal(t, 17.5, value)
+	value, _ = vwap.Update(indicator.Bar{Ts: day, High: 30, Low: 30, Close: 30, Volume: 2})
+	assert.Equal(t, 30.0, value)
+}
+
+// TestIndicatorSetWarmup 测试指标集合的预热长度和参数校验
+func TestIndicatorSetWarmup(t *testing.T) {
+	params := indicator.DefaultParams()
+	assert.Equal(t, 34, params.Warmup())
+
+	set, err := indicator.NewSet(params)
+	require.NoError(t, err)
+
+	var snapshot indicator.Snapshot
+	for i := 0; i < params.Warmup(); i++ {
+		snapshot = set.Update(indicator.Bar{Ts: int64(i) * 60000, Open: 100, High: 101, Low: 99, Close: 100 + float64(i%3), Volume: 1})
+		if i == params.Warmup()-2 {
+			assert.Nil(t, snapshot.MACD)
+			assert.NotNil(t, snapshot.SMA)
+		}
+	}
+	assert.NotNil(t, snapshot.MACD)
+	assert.NotNil(t, snapshot.RSI)
+	assert.NotNil(t, snapshot.ATR)
+	assert.NotNil(t, snapshot.VWAP)
+
+	params.MACDFast = 30
+	_, err = indicator.NewSet(params)
+	assert.Error(t, err)
+}
+
+// stubCandleService 按时间生成1分钟K线的K线服务，最新一根未完结
+type stubCandleService struct {
+	mutex    sync.Mutex
+	now      int64
+	requests []models.CandlesRequest
+}
+
+func (s *stubCandleService) GetCandles(ctx context.Context, req *models.CandlesRequest) (*models.CandlesResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = append(s.requests, *req)
+
+	minute := time.Minute.Milliseconds()
+	last := s.now / minute * minute
+	if req.After > 0 {
+		last = (req.After - 1) / minute * minute
+	}
+
+	resp := &models.CandlesResponse{InstId: req.InstId, Bar: req.Bar, Candles: []*models.Candle{}}
+	for ts := last - int64(req.Limit-1)*minute; ts <= last; ts += minute {
+		if ts <= req.Before {
+			continue
+		}
+		resp.Candles = append(resp.Candles, stubCandle(ts, ts+minute <= s.now))
+	}
+	return resp, nil
+}
+
+func (s *stubCandleService) setNow(now int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.now = now
+}
+
+func (s *stubCandleService) lastRequest() models.CandlesRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests[len(s.requests)-1]
+}
+
+func stubCandle(ts int64, confirm bool) *models.Candle {
+	price := strconv.FormatInt(100+ts/time.Minute.Milliseconds()%7, 10)
+	return &models.Candle{Ts: ts, Open: price, High: price, Low: price, Close: price, Vol: "1", VolCcy: "1", VolCcyQuote: price, Confirm: confirm}
+}
+
+// stubCandleFeed 手动推送K线的推送源
+type stubCandleFeed struct {
+	mutex        sync.Mutex
+	handlers     map[string]func([]okx.Candle)
+	unsubscribed []string
+}
+
+func (f *stubCandleFeed) SubscribeCandles(bar, instId string, handler func([]okx.Candle)) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.handlers["candle"+bar+":"+instId] = handler
+	return nil
+}
+
+func (f *stubCandleFeed) Unsubscribe(channel, instId string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.handlers, channel+":"+instId)
+	f.unsubscribed = append(f.unsubscribed, channel+":"+instId)
+	return nil
+}
+
+// push 推送一根已完结的K线，返回是否有订阅
+func (f *stubCandleFeed) push(instId string, candle *models.Candle) bool {
+	f.mutex.Lock()
+	handler := f.handlers["candle1m:"+instId]
+	f.mutex.Unlock()
+	if handler == nil {
+		return false
+	}
+
+	handler([]okx.Candle{{
+		InstId: instId, Bar: "1m", Ts: strconv.FormatInt(candle.Ts, 10),
+		Open: candle.Open, High: candle.High, Low: candle.Low, Close: candle.Close,
+		Vol: candle.Vol, VolCcy: candle.VolCcy, VolCcyQuote: candle.VolCcyQuote, Confirm: true,
+	}})
+	return true
+}
+
+// TestIndicatorServiceLookback 测试只返回已完结K线的指标，并额外加载预热所需的K线
+func TestIndicatorServiceLookback(t *testing.T) {
+	candles := &stubCandleService{now: time.Now().UnixMilli()}
+	indicatorService := service.NewIndicatorServiceWithFeed(candles, &stubCandleFeed{handlers: map[string]func([]okx.Candle){}})
+
+	resp, err := indicatorService.GetIndicators(context.Background(), &models.IndicatorsRequest{
+		InstId: "BTC-USDT", Bar: "1m", Lookback: 10, Params: indicator.DefaultParams(),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Values, 10)
+
+	minute := time.Minute.Milliseconds()
+	assert.Equal(t, candles.now/minute*minute-minute, resp.Values[9].Ts, "未完结的K线不参与计算")
+	for _, value := range resp.Values {
+		require.NotNil(t, value.MACD, "预热后所有指标都有数值")
+		require.NotNil(t, value.RSI)
+	}
+	assert.Equal(t, 10+indicator.DefaultParams().Warmup()+1, candles.lastRequest().Limit)
+}
+
+// TestIndicatorStream 测试指标推送的预热、K线完结推送和缺口补齐
+func TestIndicatorStream(t *testing.T) {
+	minute := time.Minute.Milliseconds()
+	start := time.Now().UnixMilli() / minute * minute
+	candles := &stubCandleService{now: start + 1}
+	feed := &stubCandleFeed{handlers: map[string]func([]okx.Candle){}}
+	indicatorService := service.NewIndicatorServiceWithFeed(candles, feed)
+
+	snapshots := make(chan *indicator.Snapshot, 10)
+	indicatorService.StartIndicatorStream("BTC-USDT", "1m", func(snapshot *indicator.Snapshot) {
+		snapshots <- snapshot
+	})
+
+	// 预热后推送最新已完结K线的指标
+	select {
+	case snapshot := <-snapshots:
+		assert.Equal(t, start-minute, snapshot.Ts)
+		assert.NotNil(t, snapshot.MACD)
+	case <-time.After(3 * time.Second):
+		t.Fatal("未收到初始指标")
+	}
+
+	require.Eventually(t, func() bool {
+		return feed.push("BTC-USDT", stubCandle(start, true))
+	}, 3*time.Second, 10*time.Millisecond)
+	snapshot := <-snapshots
+	assert.Equal(t, start, snapshot.Ts)
+
+	// 重复推送同一根K线不会重复计算
+	feed.push("BTC-USDT", stubCandle(start, true))
+
+	// 推送中断后缺失的K线从REST补齐
+	candles.setNow(start + 4*minute)
+	feed.push("BTC-USDT", stubCandle(start+3*minute, true))
+	snapshot = <-snapshots
+	assert.Equal(t, start+3*minute, snapshot.Ts)
+	backfill := candles.lastRequest()
+	assert.Equal(t, start, backfill.Before)
+	assert.Equal(t, start+3*minute, backfill.After)
+	assert.Empty(t, snapshots)
+
+	indicatorService.StopIndicatorStream("BTC-USDT", "1m")
+	assert.Equal(t, []string{"candle1m:BTC-USDT"}, feed.unsubscribed)
+}
+
+// TestIndicatorWebSocket 测试通过 /ws/price 订阅指标推送
+func TestIndicatorWebSocket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	minute := time.Minute.Milliseconds()
+	start := time.Now().UnixMilli() / minute * minute
+	feed := &stubCandleFeed{handlers: map[string]func([]okx.Candle){}}
+	indicatorService := service.NewIndicatorServiceWithFeed(&stubCandleService{now: start + 1}, feed)
+	manager := api.NewWebSocketManagerWithServices(newStubPriceService(), indicatorService, config.WebSocketConfig{})
+
+	r := gin.New()
+	r.GET("/ws/price", manager.HandleWebSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+	conn := dialPriceWS(t, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws/price")
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"op":   "subscribe",
+		"args": []map[string]string{{"channel": "indicators", "instId": "BTC-USDT", "bar": "10m"}},
+	}))
+	assert.Equal(t, "invalid_bar", readPriceWS(t, conn).Code)
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"op":   "subscribe",
+		"args": []map[string]string{{"channel": "indicators", "instId": "BTC-USDT", "bar": "1m"}},
+	}))
+	assert.Equal(t, "subscribe", readPriceWS(t, conn).Event)
+
+	var push struct {
+		Arg struct {
+			Channel string `json:"channel"`
+			Bar     string `json:"bar"`
+		} `json:"arg"`
+		Data indicator.Snapshot `json:"data"`
+	}
+	readIndicator := func() {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &push), string(data))
+	}
+
+	readIndicator()
+	assert.Equal(t, "indicators", push.Arg.Channel)
+	assert.Equal(t, "1m", push.Arg.Bar)
+	assert.Equal(t, start-minute, push.Data.Ts)
+
+	require.Eventually(t, func() bool {
+		return feed.push("BTC-USDT", stubCandle(start, true))
+	}, 3*time.Second, 10*time.Millisecond)
+	readIndicator()
+	assert.Equal(t, start, push.Data.Ts, fmt.Sprintf("%+v", push.Data))
+}
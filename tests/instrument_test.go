@@ -0,0 +1,239 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// instrumentServer 模拟OKX交易对接口，按产品类型返回可修改的列表并记录请求
+type instrumentServer struct {
+	*httptest.Server
+	mutex       sync.Mutex
+	instruments map[string][]models.Instrument
+	queries     []string
+}
+
+func newInstrumentServer(t *testing.T) *instrumentServer {
+	s := &instrumentServer{instruments: map[string][]models.Instrument{
+		"SPOT": {
+			{InstType: "SPOT", InstID: "BTC-USDT", BaseCcy: "BTC", QuoteCcy: "USDT", TickSz: "0.1", LotSz: "0.00000001", MinSz: "0.00001", State: "live"},
+			{InstType: "SPOT", InstID: "ETH-BTC", BaseCcy: "ETH", QuoteCcy: "BTC", TickSz: "0.00001", LotSz: "0.0001", MinSz: "0.001", State: "live"},
+			{InstType: "SPOT", InstID: "WBTC-USDT", BaseCcy: "WBTC", QuoteCcy: "USDT", TickSz: "0.1", LotSz: "0.0001", MinSz: "0.0001", State: "suspend"},
+		},
+		"SWAP": {
+			{InstType: "SWAP", InstID: "BTC-USDT-SWAP", InstFamily: "BTC-USDT", Uly: "BTC-USDT", SettleCcy: "USDT", CtVal: "0.01", CtValCcy: "BTC", TickSz: "0.1", LotSz: "0.01", MinSz: "0.01", State: "live"},
+		},
+		"FUTURES": {},
+	}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.queries = append(s.queries, r.URL.RawQuery)
+
+		query := r.URL.Query()
+		data := []models.Instrument{}
+		for _, inst := range s.instruments[query.Get("instType")] {
+			if instId := query.Get("instId"); instId == "" || instId == inst.InstID {
+				data = append(data, inst)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": "0", "msg": "", "data": data})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *instrumentServer) set(instType string, instruments ...models.Instrument) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.instruments[instType] = instruments
+}
+
+// calls 返回记录的请求并清空
+func (s *instrumentServer) calls() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	queries := s.queries
+	s.queries = nil
+	return queries
+}
+
+// TestInstrumentRegistrySearch 测试交易对缓存的搜索和按产品ID查询
+func TestInstrumentRegistrySearch(t *testing.T) {
+	server := newInstrumentServer(t)
+	registry := service.NewInstrumentRegistry(&config.OKXConfig{BaseURL: server.URL})
+
+	resp, err := registry.Search(context.Background(), &models.InstrumentSearchRequest{Query: "btc"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, resp.Total)
+	ids := make([]string, 0, len(resp.Instruments))
+	for _, inst := range resp.Instruments {
+		ids = append(ids, inst.InstID)
+	}
+	// 前缀匹配优先，其余按产品ID排序
+	assert.Equal(t, []string{"BTC-USDT", "BTC-USDT-SWAP", "ETH-BTC", "WBTC-USDT"}, ids)
+	assert.NotZero(t, resp.UpdatedAt)
+	assert.Len(t, server.calls(), 3, "SPOT/SWAP/FUTURES各加载一次")
+
+	resp, err = registry.Search(context.Background(), &models.InstrumentSearchRequest{Query: "BTC", Type: "SPOT", State: "live", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.Total)
+	require.Len(t, resp.Instruments, 1)
+	assert.Equal(t, "BTC-USDT", resp.Instruments[0].InstID)
+
+	// 已加载的交易对直接从缓存返回
+	inst, err := registry.Get(context.Background(), "BTC-USDT-SWAP")
+	require.NoError(t, err)
+	assert.Equal(t, "0.01", inst.CtVal)
+	assert.Empty(t, server.calls())
+
+	// 期权不在完整列表中，单独请求时附带交易品种
+	server.set("OPTION", models.Instrument{InstType: "OPTION", InstID: "BTC-USD-250328-50000-C", TickSz: "0.0005", State: "live"})
+	inst, err = registry.Get(context.Background(), "BTC-USD-250328-50000-C")
+	require.NoError(t, err)
+	assert.Equal(t, "0.0005", inst.TickSz)
+	assert.Equal(t, []string{"instFamily=BTC-USD&instId=BTC-USD-250328-50000-C&instType=OPTION"}, server.calls())
+
+	_, err = registry.Get(context.Background(), "DOGE-USDT")
+	assert.Error(t, err)
+}
+
+// TestInstrumentRegistryChanges 测试刷新时检测上线、下线、到期和状态变化
+func TestInstrumentRegistryChanges(t *testing.T) {
+	server := newInstrumentServer(t)
+	expTime := strconv.FormatInt(time.Now().Add(time.Hour).UnixMilli(), 10)
+	server.set("FUTURES",
+		models.Instrument{InstType: "FUTURES", InstID: "BTC-USD-250328", ExpTime: expTime, State: "live"},
+		models.Instrument{InstType: "FUTURES", InstID: "BTC-USD-250627", ExpTime: expTime, State: "live"},
+	)
+
+	registry := service.NewInstrumentRegistry(&config.OKXConfig{BaseURL: server.URL})
+	require.NoError(t, registry.Refresh(context.Background()))
+	assert.Empty(t, registry.Changes(0), "首次加载不记录变化")
+
+	// 第一个交割合约到期下线，第二个到期但仍在列表中，新上线一个现货，一个现货暂停交易
+	time.Sleep(5 * time.Millisecond)
+	expired := strconv.FormatInt(time.Now().UnixMilli()-1, 10)
+	server.set("FUTURES", models.Instrument{InstType: "FUTURES", InstID: "BTC-USD-250627", ExpTime: expired, State: "live"})
+	server.set("SPOT",
+		models.Instrument{InstType: "SPOT", InstID: "BTC-USDT", State: "suspend"},
+		models.Instrument{InstType: "SPOT", InstID: "ETH-BTC", State: "live"},
+		models.Instrument{InstType: "SPOT", InstID: "PEPE-USDT", State: "live"},
+	)
+	require.NoError(t, registry.Refresh(context.Background()))
+
+	kinds := map[string]string{}
+	for _, change := range registry.Changes(0) {
+		kinds[change.InstId] = change.Kind
+		if change.InstId == "BTC-USDT" {
+			assert.Equal(t, "live", change.PrevState)
+			assert.Equal(t, "suspend", change.State)
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"BTC-USDT":       models.InstrumentState,
+		"PEPE-USDT":      models.InstrumentListed,
+		"WBTC-USDT":      models.InstrumentDelisted,
+		"BTC-USD-250627": models.InstrumentExpired,
+		"BTC-USD-250328": models.InstrumentDelisted,
+	}, kinds)
+
+	changes := registry.Changes(0)
+	assert.Empty(t, registry.Changes(changes[len(changes)-1].DetectedAt))
+}
+
+// TestSharedInstrumentRegistry 测试交易对信息缓存按接口地址和交易环境共享，与账户凭证无关
+func TestSharedInstrumentRegistry(t *testing.T) {
+	first := &config.OKXConfig{BaseURL: "https://registry.example", APIKey: "first-key"}
+	second := &config.OKXConfig{BaseURL: "https://registry.example", APIKey: "second-key"}
+	demo := &config.OKXConfig{BaseURL: "https://registry.example", APIKey: "first-key", IsTest: true}
+
+	assert.Same(t, service.SharedInstrumentRegistry(first), service.SharedInstrumentRegistry(second))
+	assert.NotSame(t, service.SharedInstrumentRegistry(first), service.SharedInstrumentRegistry(demo))
+}
+
+// TestInstrumentRounding 测试按TickSz/LotSz取整
+func TestInstrumentRounding(t *testing.T) {
+	inst := &models.Instrument{TickSz: "0.05", LotSz: "0.001"}
+
+	px, err := service.RoundPrice(inst, "100.12", false)
+	require.NoError(t, err)
+	assert.Equal(t, "100.10", px)
+	px, err = service.RoundPrice(inst, "100.12", true)
+	require.NoError(t, err)
+	assert.Equal(t, "100.15", px)
+	px, _ = service.RoundPrice(inst, "100.15", true)
+	assert.Equal(t, "100.15", px)
+
+	sz, err := service.RoundSize(inst, "1.23456")
+	require.NoError(t, err)
+	assert.Equal(t, "1.234", sz)
+
+	_, err = service.RoundSize(inst, "abc")
+	assert.Error(t, err)
+	_, err = service.RoundPrice(&models.Instrument{}, "1", false)
+	assert.Error(t, err)
+
+	// 下单精度校验提示最接近的有效价格
+	err = api.ValidateOrderPrecision(&models.OrderRequest{InstId: "BTC-USDT", OrdType: "limit", Side: "buy", Sz: "1", Px: "100.12"}, &api.Instrument{TickSz: "0.05", LotSz: "1", MinSz: "1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "100.10 或 100.15")
+}
+
+// TestInstrumentEndpoints 测试交易对搜索、查询和变化记录接口
+func TestInstrumentEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := newInstrumentServer(t)
+	registry := service.NewInstrumentRegistry(&config.OKXConfig{BaseURL: server.URL})
+
+	r := gin.New()
+	r.GET("/instruments/search", func(c *gin.Context) {
+		api.SearchInstruments(c, registry)
+	})
+	r.GET("/instruments/changes", func(c *gin.Context) {
+		api.GetInstrumentChanges(c, registry)
+	})
+	r.GET("/instrument/:instId", func(c *gin.Context) {
+		api.GetInstrumentByID(c, registry)
+	})
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/instruments/search?q=BTC&type=SWAP&state=live", http.StatusOK},
+		{"/instruments/search?type=BOND", http.StatusBadRequest},
+		{"/instruments/search?limit=0", http.StatusBadRequest},
+		{"/instruments/changes?since=abc", http.StatusBadRequest},
+		{"/instruments/changes", http.StatusOK},
+		{"/instrument/BTC-USDT", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		assert.Equal(t, tt.status, w.Code, tt.path)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/instruments/search?q=BTC&type=SWAP", nil))
+	var body struct {
+		Data models.InstrumentSearchResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data.Instruments, 1)
+	assert.Equal(t, "BTC-USDT-SWAP", body.Data.Instruments[0].InstID)
+}
@@ -0,0 +1,279 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/database"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/secretbox"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newOKXAccountServer 模拟OKX私有接口，API Key 对应的账户权益见 equities，其他API Key返回鉴权错误
+func newOKXAccountServer(t *testing.T, equities map[string]string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v5/public/time", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"ts":"1700000000000"}]}`)
+	})
+	authorized := func(w http.ResponseWriter, r *http.Request) (string, bool) {
+		equity, ok := equities[r.Header.Get("OK-ACCESS-KEY")]
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"code":"50111","msg":"Invalid OK-ACCESS-KEY","data":[]}`)
+		}
+		return equity, ok
+	}
+	mux.HandleFunc("/api/v5/account/config", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := authorized(w, r); ok {
+			io.WriteString(w, `{"code":"0","msg":"","data":[{"uid":"1"}]}`)
+		}
+	})
+	mux.HandleFunc("/api/v5/account/balance", func(w http.ResponseWriter, r *http.Request) {
+		if equity, ok := authorized(w, r); ok {
+			fmt.Fprintf(w, `{"code":"0","msg":"","data":[{"totalEq":"%s","uTime":"1700000000000","details":[{"ccy":"USDT","cashBal":"%s","availBal":"%s","frozenBal":"0","eq":"%s","eqUsd":"%s"}]}]}`,
+				equity, equity, equity, equity, equity)
+		}
+	})
+	mux.HandleFunc("/api/v5/trade/orders-pending", func(w http.ResponseWriter, r *http.Request) {
+		// 订单ID为账户权益，用于区分请求发往的账户
+		if equity, ok := authorized(w, r); ok {
+			fmt.Fprintf(w, `{"code":"0","msg":"","data":[{"instId":"BTC-USDT","ordId":"%s","state":"live"}]}`, equity)
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+// TestSecretBox 测试凭证加密和解密
+func TestSecretBox(t *testing.T) {
+	box, err := secretbox.New("master-key")
+	require.NoError(t, err)
+
+	sealed, err := box.Seal("my-secret")
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "my-secret")
+	again, err := box.Seal("my-secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "每次加密使用随机nonce")
+
+	plaintext, err := box.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "my-secret", plaintext)
+
+	other, err := secretbox.New("other-key")
+	require.NoError(t, err)
+	_, err = other.Open(sealed)
+	assert.ErrorIs(t, err, secretbox.ErrDecrypt)
+	_, err = box.Open(sealed[:len(sealed)-4] + "AAAA")
+	assert.ErrorIs(t, err, secretbox.ErrDecrypt)
+
+	_, err = secretbox.New("")
+	assert.ErrorIs(t, err, secretbox.ErrEmptyKey)
+}
+
+// TestOKXAccountService 测试凭证加密保存、按用户隔离和解密后的账户配置
+func TestOKXAccountService(t *testing.T) {
+	server := newOKXAccountServer(t, map[string]string{"key-aaaa-1111": "100"})
+	defer server.Close()
+
+	db, err := database.Open(filepath.Join(t.TempDir(), "accounts.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	base := &config.OKXConfig{BaseURL: server.URL, DemoAPIKey: "env-demo-key", WSPrivateURL: "wss://example"}
+	accounts := service.NewOKXAccountService(base, "master-key", repository.NewOKXAccountRepository(db))
+	ctx := context.Background()
+
+	// OKX拒绝的凭证不会保存
+	_, err = accounts.Create(ctx, 1, &models.CreateOKXAccountRequest{Name: "bad", APIKey: "wrong-key", SecretKey: "s", Passphrase: "p"})
+	assert.ErrorIs(t, err, service.ErrInvalidOKXCredentials)
+
+	account, err := accounts.Create(ctx, 1, &models.CreateOKXAccountRequest{
+		Name: "sub-1", APIKey: "key-aaaa-1111", SecretKey: "secret-1", Passphrase: "pass-1", IsTest: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "key-****1111", account.APIKeyHint)
+
+	_, err = accounts.Create(ctx, 1, &models.CreateOKXAccountRequest{Name: "sub-1", APIKey: "key-aaaa-1111", SecretKey: "secret-1", Passphrase: "pass-1"})
+	assert.ErrorIs(t, err, repository.ErrOKXAccountExists)
+
+	// 数据库中只有密文
+	var apiKey, secretKey, passphrase string
+	require.NoError(t, db.QueryRow(`SELECT api_key, secret_key, passphrase FROM okx_accounts WHERE id = ?`, account.ID).Scan(&apiKey, &secretKey, &passphrase))
+	assert.NotContains(t, apiKey, "key-aaaa")
+	assert.NotContains(t, secretKey, "secret-1")
+	assert.NotContains(t, passphrase, "pass-1")
+
+	cfg, err := accounts.Config(ctx, 1, account.ID)
+	require.NoError(t, err)
+	key, secret, pass := cfg.Keys()
+	assert.Equal(t, []string{"key-aaaa-1111", "secret-1", "pass-1"}, []string{key, secret, pass})
+	assert.True(t, cfg.IsTest)
+	assert.Equal(t, server.URL, cfg.BaseURL)
+	assert.Empty(t, cfg.WSPrivateURL)
+
+	// 其他用户无法查看、使用或删除
+	_, err = accounts.Config(ctx, 2, account.ID)
+	assert.ErrorIs(t, err, repository.ErrOKXAccountNotFound)
+	assert.ErrorIs(t, accounts.Delete(ctx, 2, account.ID), repository.ErrOKXAccountNotFound)
+	list, err := accounts.List(ctx, 2)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	// 主密钥不同时无法解密
+	wrongKey := service.NewOKXAccountService(base, "other-key", repository.NewOKXAccountRepository(db))
+	_, err = wrongKey.Config(ctx, 1, account.ID)
+	assert.Error(t, err)
+
+	// 未配置主密钥时不能添加账户
+	noKey := service.NewOKXAccountService(base, "", repository.NewOKXAccountRepository(db))
+	_, err = noKey.Create(ctx, 1, &models.CreateOKXAccountRequest{Name: "sub-2", APIKey: "key-aaaa-1111", SecretKey: "s", Passphrase: "p"})
+	assert.ErrorIs(t, err, service.ErrCredentialsKeyMissing)
+
+	require.NoError(t, accounts.Delete(ctx, 1, account.ID))
+	_, err = accounts.Config(ctx, 1, account.ID)
+	assert.ErrorIs(t, err, repository.ErrOKXAccountNotFound)
+}
+
+// TestOKXAccountRoutes 测试添加账户后通过请求头或路径参数选择账户
+func TestOKXAccountRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := newOKXAccountServer(t, map[string]string{"key-aaaa-1111": "100", "key-bbbb-2222": "250"})
+	defer server.Close()
+
+	cfg := newAuthConfig()
+	cfg.SQLitePath = filepath.Join(t.TempDir(), "alphaark.db")
+	cfg.CredentialsKey = "master-key"
+	cfg.OKX.BaseURL = server.URL
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+	api.SetupAccountRoutes(r, cfg)
+	api.SetupOKXAccountRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
+
+	w := authRequest(r, http.MethodGet, "/api/v1/accounts", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = authRequest(r, http.MethodPost, "/api/v1/accounts", token, models.CreateOKXAccountRequest{Name: "bad", APIKey: "wrong", SecretKey: "s", Passphrase: "p"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	for _, req := range []models.CreateOKXAccountRequest{
+		{Name: "sub-1", APIKey: "key-aaaa-1111", SecretKey: "secret-1", Passphrase: "pass-1"},
+		{Name: "sub-2", APIKey: "key-bbbb-2222", SecretKey: "secret-2", Passphrase: "pass-2"},
+	} {
+		w = authRequest(r, http.MethodPost, "/api/v1/accounts", token, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.NotContains(t, w.Body.String(), req.SecretKey)
+	}
+
+	// 添加的账户按自己的账户ID和交易环境记录权益快照
+	db, err := database.Shared(cfg.SQLitePath)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		snapshot, err := repository.NewEquityRepository(db, 2, config.TradingLive).Nearest(models.CurrencyUSDT, time.Now())
+		return err == nil && snapshot.TotalEquity == "250"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	w = authRequest(r, http.MethodGet, "/api/v1/accounts", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"apiKeyHint":"key-****2222"`)
+	assert.NotContains(t, w.Body.String(), "key-bbbb-2222")
+
+	// 路径参数选择账户
+	w = authRequest(r, http.MethodGet, "/api/v1/accounts/1/balance/USDT", token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"totalEquity":"100"`)
+
+	// 请求头选择账户
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/account/balance/USDT", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(api.AccountIDHeader, "2")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"totalEquity":"250"`)
+
+	// 不存在的账户和无效的账户ID
+	w = authRequest(r, http.MethodGet, "/api/v1/accounts/99/balance/USDT", token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = authRequest(r, http.MethodGet, "/api/v1/accounts/abc/balance/USDT", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 删除后不能再选择
+	w = authRequest(r, http.MethodDelete, "/api/v1/accounts/1", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = authRequest(r, http.MethodGet, "/api/v1/accounts/1/balance/USDT", token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = authRequest(r, http.MethodDelete, "/api/v1/accounts/1", token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NotContains(t, authRequest(r, http.MethodGet, "/api/v1/accounts", token, nil).Body.String(), "sub-1")
+}
+
+// TestOKXAccountTradeRoutes 测试交易接口发往所选账户，只支持默认账户的接口拒绝选择账户
+func TestOKXAccountTradeRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := newOKXAccountServer(t, map[string]string{"key-default": "1", "key-aaaa-1111": "100"})
+	defer server.Close()
+
+	cfg := newAuthConfig()
+	cfg.SQLitePath = filepath.Join(t.TempDir(), "alphaark.db")
+	cfg.CredentialsKey = "master-key"
+	cfg.OKX.BaseURL = server.URL
+	cfg.OKX.APIKey, cfg.OKX.SecretKey, cfg.OKX.Passphrase = "key-default", "secret", "pass"
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+	api.SetupOKXAccountRoutes(r, cfg)
+	api.SetupArchiveRoutes(r, cfg)
+	api.SetupTradeRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
+
+	w := authRequest(r, http.MethodPost, "/api/v1/accounts", token, models.CreateOKXAccountRequest{Name: "sub-1", APIKey: "key-aaaa-1111", SecretKey: "secret-1", Passphrase: "pass-1"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	request := func(path, accountId, environment string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if accountId != "" {
+			req.Header.Set(api.AccountIDHeader, accountId)
+		}
+		if environment != "" {
+			req.Header.Set("X-Trading-Environment", environment)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未选择账户时使用默认账户
+	w = request("/api/v1/trade/orders", "", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"ordId":"1"`)
+
+	w = request("/api/v1/trade/orders", "1", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"ordId":"100"`)
+
+	// 请求的交易环境必须与所选账户一致
+	w = request("/api/v1/trade/orders", "1", "demo")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = request("/api/v1/trade/orders", "99", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 成交归档只支持默认账户
+	w = request("/api/v1/archive/status", "1", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = request("/api/v1/archive/status", "", "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
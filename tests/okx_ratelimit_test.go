@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// findUsage 查找指定接口的预算使用情况
+func findUsage(usage []okx.EndpointUsage, endpoint string) *okx.EndpointUsage {
+	for i := range usage {
+		if usage[i].Endpoint == endpoint {
+			return &usage[i]
+		}
+	}
+	return nil
+}
+
+// TestRateLimiterQueueing 测试超出预算的请求排队等待，context取消时退出排队
+func TestRateLimiterQueueing(t *testing.T) {
+	limiter := okx.NewRateLimiter(map[string]okx.RateLimit{
+		"/api/v5/market/ticker": {Requests: 2, Interval: 200 * time.Millisecond},
+	})
+	ctx := context.Background()
+
+	// 桶内令牌用完前不等待
+	start := time.Now()
+	require.NoError(t, limiter.Wait(ctx, "/api/v5/market/ticker", ""))
+	require.NoError(t, limiter.Wait(ctx, "/api/v5/market/ticker", ""))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	// 第三个请求需要等待一个令牌（100ms）
+	require.NoError(t, limiter.Wait(ctx, "/api/v5/market/ticker", ""))
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+
+	// 排队中context取消
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(timeout, "/api/v5/market/ticker", ""), context.DeadlineExceeded)
+
+	// 私有接口按API Key分别限速
+	require.NoError(t, limiter.Wait(ctx, "/api/v5/account/positions", "key-a"))
+	require.NoError(t, limiter.Wait(ctx, "/api/v5/account/positions", "key-b"))
+
+	usage := limiter.Usage()
+	ticker := findUsage(usage, "/api/v5/market/ticker")
+	require.NotNil(t, ticker)
+	assert.Equal(t, 2, ticker.Limit)
+	assert.EqualValues(t, 200, ticker.IntervalMs)
+	assert.EqualValues(t, 3, ticker.Requests)
+	assert.EqualValues(t, 2, ticker.Throttled)
+	assert.Equal(t, 0, ticker.Waiting)
+
+	positions := 0
+	for _, item := range usage {
+		if item.Endpoint == "/api/v5/account/positions" {
+			positions++
+			assert.Equal(t, okx.DefaultRateLimit.Requests, item.Limit)
+			assert.NotContains(t, []string{"key-a", "key-b"}, item.Account, "API Key应脱敏")
+		}
+	}
+	assert.Equal(t, 2, positions)
+}
+
+// TestRateLimiterConcurrentBudget 测试并发请求不超过接口预算
+func TestRateLimiterConcurrentBudget(t *testing.T) {
+	limiter := okx.NewRateLimiter(map[string]okx.RateLimit{
+		"/api/v5/market/ticker": {Requests: 5, Interval: 500 * time.Millisecond},
+	})
+
+	var mutex sync.Mutex
+	var times []time.Time
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, limiter.Wait(context.Background(), "/api/v5/market/ticker", ""))
+			mutex.Lock()
+			times = append(times, time.Now())
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// 10个请求中前5个立即放行，剩余请求按100ms/个的速率放行
+	start := times[0]
+	for _, at := range times {
+		if at.Before(start) {
+			start = at
+		}
+	}
+	late := 0
+	for _, at := range times {
+		if at.Sub(start) >= 80*time.Millisecond {
+			late++
+		}
+	}
+	assert.Equal(t, 5, late)
+}
+
+// TestRESTClientRateLimitBackoff 测试收到429后退避并重试
+func TestRESTClientRateLimitBackoff(t *testing.T) {
+	var calls int32
+	var lastCall atomic.Int64
+	var gaps []time.Duration
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v5/market/tickers", func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now().UnixNano()
+		if previous := lastCall.Swap(now); previous != 0 {
+			gaps = append(gaps, time.Duration(now-previous))
+		}
+		// 前两次返回限频
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(w, `{"msg":"Too Many Requests","code":"50011"}`)
+			return
+		}
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"instId":"BTC-USDT","last":"100"}]}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	limiter := okx.NewRateLimiter(okx.DefaultRateLimits)
+	client := okx.NewClientWithLimiter(server.URL, restCredentials, limiter)
+
+	tickers, err := okx.Call[[]okx.Ticker](context.Background(), client, okx.Request{Path: "/api/v5/market/tickers"})
+	require.NoError(t, err)
+	assert.Equal(t, "100", tickers[0].Last)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+
+	// 第一次退避250~500ms，第二次500ms~1s
+	require.Len(t, gaps, 2)
+	assert.GreaterOrEqual(t, gaps[0], 200*time.Millisecond)
+	assert.GreaterOrEqual(t, gaps[1], 450*time.Millisecond)
+
+	usage := findUsage(limiter.Usage(), "/api/v5/market/tickers")
+	require.NotNil(t, usage)
+	assert.EqualValues(t, 2, usage.RateLimited)
+	assert.EqualValues(t, 3, usage.Requests)
+
+	// 退避期间context取消不再重试
+	atomic.StoreInt32(&calls, 0)
+	lastCall.Store(0)
+	gaps = nil
+	limiter.Backoff("/api/v5/market/tickers", "")
+	timeout, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = okx.Call[[]okx.Ticker](timeout, client, okx.Request{Path: "/api/v5/market/tickers"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.EqualValues(t, 0, atomic.LoadInt32(&calls))
+}
+
+// TestRateLimitsEndpoint 测试限速预算管理接口
+func TestRateLimitsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := okx.NewRateLimiter(okx.DefaultRateLimits)
+	require.NoError(t, limiter.Wait(context.Background(), "/api/v5/market/ticker", ""))
+	limiter.Backoff("/api/v5/market/ticker", "")
+
+	r := gin.New()
+	r.GET("/rate-limits", func(c *gin.Context) {
+		api.GetRateLimits(c, limiter)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rate-limits", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Data []okx.EndpointUsage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data, 1)
+	assert.Equal(t, "/api/v5/market/ticker", body.Data[0].Endpoint)
+	assert.Equal(t, 20, body.Data[0].Limit)
+	assert.EqualValues(t, 1, body.Data[0].RateLimited)
+	assert.NotNil(t, body.Data[0].BackoffUntil)
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var restCredentials = okx.Credentials{APIKey: "key", SecretKey: "secret", Passphrase: "pass"}
+
+// TestRESTClientEnvelopeAndErrors 测试信封解码和错误分类
+func TestRESTClientEnvelopeAndErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v5/market/ticker", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"instId":"`+r.URL.Query().Get("instId")+`","last":"100"}]}`)
+	})
+	mux.HandleFunc("/api/v5/limited", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		io.WriteString(w, `{"msg":"Too Many Requests","code":"50011"}`)
+	})
+	mux.HandleFunc("/api/v5/gateway", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		io.WriteString(w, `<html>bad gateway</html>`)
+	})
+	mux.HandleFunc("/api/v5/account/balance", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"msg":"Invalid Sign","code":"50113"}`)
+	})
+	mux.HandleFunc("/api/v5/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := okx.NewClient(server.URL, restCredentials)
+	ctx := context.Background()
+
+	tickers, err := okx.Call[[]okx.Ticker](ctx, client, okx.Request{
+		Path:  "/api/v5/market/ticker",
+		Query: map[string][]string{"instId": {"BTC-USDT"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, tickers, 1)
+	assert.Equal(t, "BTC-USDT", tickers[0].InstId)
+
+	_, err = okx.Call[[]okx.Ticker](ctx, client, okx.Request{Path: "/api/v5/limited"})
+	var okxErr *okx.OKXError
+	require.ErrorAs(t, err, &okxErr)
+	assert.Equal(t, "50011", okxErr.Code)
+	assert.Equal(t, http.StatusTooManyRequests, okxErr.HTTPStatus)
+	assert.True(t, okx.IsRateLimited(err))
+
+	// 非JSON错误页仍返回带HTTP状态码的OKXError
+	_, err = okx.Call[[]okx.Ticker](ctx, client, okx.Request{Path: "/api/v5/gateway"})
+	require.ErrorAs(t, err, &okxErr)
+	assert.Equal(t, http.StatusBadGateway, okxErr.HTTPStatus)
+	assert.Equal(t, okx.ErrorKindUnknown, okxErr.Kind())
+
+	_, err = okx.Call[[]okx.Ticker](ctx, client, okx.Request{Path: "/api/v5/account/balance", Signed: true})
+	assert.True(t, okx.IsAuthError(err))
+
+	assert.Equal(t, okx.ErrorKindInsufficientBalance, okx.ClassifyCode("51008"))
+	assert.True(t, okx.IsInsufficientBalance(&okx.OKXError{Code: "51131"}))
+
+	// 凭证不完整时不发送私有请求
+	_, err = okx.Call[[]okx.Ticker](ctx, okx.NewClient(server.URL, okx.Credentials{}), okx.Request{Path: "/api/v5/account/balance", Signed: true})
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &okxErr))
+
+	// 请求随context取消
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = okx.Call[[]okx.Ticker](timeout, client, okx.Request{Path: "/api/v5/slow"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// TestRESTClientTimestampResync 测试时间戳错误时重新同步服务器时间并重试
+func TestRESTClientTimestampResync(t *testing.T) {
+	serverNow := time.Now().Add(time.Hour)
+	var timeCalls, balanceCalls int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v5/public/time", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&timeCalls, 1)
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"ts":"`+strconv.FormatInt(serverNow.UnixMilli(), 10)+`"}]}`)
+	})
+	mux.HandleFunc("/api/v5/account/balance", func(w http.ResponseWriter, r *http.Request) {
+		// 首次请求返回时间戳过期
+		if atomic.AddInt32(&balanceCalls, 1) == 1 {
+			io.WriteString(w, `{"code":"50102","msg":"Timestamp request expired","data":[]}`)
+			return
+		}
+
+		timestamp, err := time.Parse("2006-01-02T15:04:05.000Z", r.Header.Get("OK-ACCESS-TIMESTAMP"))
+		require.NoError(t, err)
+		assert.WithinDuration(t, serverNow, timestamp, time.Minute, "时间戳应使用服务器时间偏移")
+		assert.Equal(t, okx.Sign("secret", r.Header.Get("OK-ACCESS-TIMESTAMP"), "GET", "/api/v5/account/balance", ""), r.Header.Get("OK-ACCESS-SIGN"))
+
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"totalEq":"1000"}]}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := okx.NewClient(server.URL, restCredentials)
+	data, err := okx.Call[[]struct {
+		TotalEq string `json:"totalEq"`
+	}](context.Background(), client, okx.Request{Path: "/api/v5/account/balance", Signed: true})
+	require.NoError(t, err)
+	assert.Equal(t, "1000", data[0].TotalEq)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&balanceCalls))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&timeCalls), "首次请求前同步一次，时间戳错误后强制同步一次")
+}
+
+// TestOKXErrorResponses 测试批量下单部分失败和OKX错误的HTTP状态码映射
+func TestOKXErrorResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v5/public/time", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"ts":"`+strconv.FormatInt(time.Now().UnixMilli(), 10)+`"}]}`)
+	})
+	mux.HandleFunc("/api/v5/trade/batch-orders", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"code":"2","msg":"","data":[{"ordId":"1","sCode":"0"},{"clOrdId":"b","sCode":"51008","sMsg":"Insufficient balance"}]}`)
+	})
+	mux.HandleFunc("/api/v5/public/instruments", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		io.WriteString(w, `{"msg":"Too Many Requests","code":"50011"}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := api.NewOKXClient(&config.OKXConfig{APIKey: "key", SecretKey: "secret", Passphrase: "pass", BaseURL: server.URL})
+
+	// 部分成功返回逐条结果而不是错误
+	result, err := client.PlaceBatchOrders(context.Background(), []models.OrderRequest{{InstId: "BTC-USDT"}, {InstId: "ETH-USDT"}})
+	require.NoError(t, err)
+	assert.Equal(t, "2", result.Code)
+	require.Len(t, result.Data, 2)
+	assert.Equal(t, okx.ErrorKindInsufficientBalance, okx.ClassifyCode(result.Data[1].SCode))
+
+	// 限频映射为429并附带错误码
+	r := gin.New()
+	r.GET("/instruments", func(c *gin.Context) {
+		api.GetInstruments(c, client)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/instruments", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), `"kind":"rate_limit"`)
+}
@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWSConn 假OKX WebSocket服务端的一个连接
+type fakeWSConn struct {
+	conn     *websocket.Conn
+	received chan string
+}
+
+func (c *fakeWSConn) send(t *testing.T, message string) {
+	require.NoError(t, c.conn.WriteMessage(websocket.TextMessage, []byte(message)))
+}
+
+// expect 等待客户端发送包含指定内容的消息
+func (c *fakeWSConn) expect(t *testing.T, contains string) string {
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case message := <-c.received:
+			if strings.Contains(message, contains) {
+				return message
+			}
+		case <-timeout:
+			t.Fatalf("未收到包含 %q 的消息", contains)
+			return ""
+		}
+	}
+}
+
+// newFakeOKXWSServer 创建假OKX WebSocket服务端，每个新连接通过通道返回
+func newFakeOKXWSServer(t *testing.T) (string, chan *fakeWSConn) {
+	upgrader := websocket.Upgrader{}
+	conns := make(chan *fakeWSConn, 4)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		fake := &fakeWSConn{conn: conn, received: make(chan string, 64)}
+		conns <- fake
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				close(fake.received)
+				return
+			}
+			if string(data) == "ping" {
+				conn.WriteMessage(websocket.TextMessage, []byte("pong"))
+			}
+			fake.received <- string(data)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http"), conns
+}
+
+func acceptConn(t *testing.T, conns chan *fakeWSConn) *fakeWSConn {
+	select {
+	case conn := <-conns:
+		return conn
+	case <-time.After(3 * time.Second):
+		t.Fatal("客户端未连接")
+		return nil
+	}
+}
+
+// TestPriceStreamOverWebSocket 测试价格推送来自tickers频道
+func TestPriceStreamOverWebSocket(t *testing.T) {
+	url, conns := newFakeOKXWSServer(t)
+
+	priceService := service.NewPriceService(&config.OKXConfig{WSPublicURL: url})
+	defer priceService.StopPriceStream()
+
+	prices := make(chan *service.PriceData, 4)
+	priceService.StartPriceStream("ETH-USDT", func(data *service.PriceData) {
+		prices <- data
+	})
+
+	conn := acceptConn(t, conns)
+	subscribe := conn.expect(t, `"op":"subscribe"`)
+	assert.Contains(t, subscribe, `{"channel":"tickers","instId":"ETH-USDT"}`)
+
+	conn.send(t, `{"event":"subscribe","arg":{"channel":"tickers","instId":"ETH-USDT"},"connId":"a1"}`)
+	conn.send(t, `{"arg":{"channel":"tickers","instId":"ETH-USDT"},"data":[{"instType":"SPOT","instId":"ETH-USDT","last":"2200","open24h":"2000","high24h":"2250","low24h":"1990","vol24h":"1234"}]}`)
+
+	select {
+	case data := <-prices:
+		assert.Equal(t, "ETH-USDT", data.Symbol)
+		assert.Equal(t, "2200", data.Price)
+		assert.Equal(t, "200.00", data.Change24h)
+		assert.Equal(t, "10.00", data.ChangePercent24h)
+	case <-time.After(3 * time.Second):
+		t.Fatal("未收到价格推送")
+	}
+}
+
+// TestWSClientReconnectAndKeepalive 测试ping保活和断线后重新订阅
+func TestWSClientReconnectAndKeepalive(t *testing.T) {
+	url, conns := newFakeOKXWSServer(t)
+
+	feed := okx.NewMarketFeed(url, url)
+	feed.Public().PingInterval = 50 * time.Millisecond
+	feed.Public().ReconnectMin = 10 * time.Millisecond
+	defer feed.Stop()
+
+	require.NoError(t, feed.SubscribeTrades("BTC-USDT", func([]okx.Trade) {}))
+
+	conn := acceptConn(t, conns)
+	conn.expect(t, `"channel":"trades"`)
+	conn.expect(t, "ping")
+
+	// 服务端断开后客户端应重连并恢复订阅
+	conn.conn.Close()
+
+	conn = acceptConn(t, conns)
+	subscribe := conn.expect(t, `"op":"subscribe"`)
+	assert.Contains(t, subscribe, `{"channel":"trades","instId":"BTC-USDT"}`)
+}
+
+// bookChecksum 按OKX规则计算校验和
+func bookChecksum(bids, asks [][2]string) int32 {
+	var parts []string
+	for i := 0; i < 25; i++ {
+		if i < len(bids) {
+			parts = append(parts, bids[i][0], bids[i][1])
+		}
+		if i < len(asks) {
+			parts = append(parts, asks[i][0], asks[i][1])
+		}
+	}
+	return int32(crc32.ChecksumIEEE([]byte(strings.Join(parts, ":"))))
+}
+
+func bookPush(action string, seqId, prevSeqId int64, checksum int32, bids, asks [][2]string) string {
+	levels := func(values [][2]string) string {
+		items := make([]string, 0, len(values))
+		for _, v := range values {
+			items = append(items, fmt.Sprintf(`["%s","%s","0","1"]`, v[0], v[1]))
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	}
+	return fmt.Sprintf(`{"arg":{"channel":"books","instId":"BTC-USDT"},"action":"%s","data":[{"asks":%s,"bids":%s,"ts":"1","checksum":%d,"seqId":%d,"prevSeqId":%d}]}`,
+		action, levels(asks), levels(bids), checksum, seqId, prevSeqId)
+}
+
+// TestOrderBookSequenceAndChecksum 测试订单簿增量合并、序列号和校验和校验
+func TestOrderBookSequenceAndChecksum(t *testing.T) {
+	url, conns := newFakeOKXWSServer(t)
+
+	feed := okx.NewMarketFeed(url, url)
+	defer feed.Stop()
+
+	books := make(chan *okx.OrderBookSnapshot, 4)
+	require.NoError(t, feed.SubscribeBooks("books", "BTC-USDT", func(book *okx.OrderBookSnapshot) {
+		books <- book
+	}))
+	assert.Error(t, feed.SubscribeBooks("books-l2-tbt-unknown", "BTC-USDT", nil))
+
+	conn := acceptConn(t, conns)
+	conn.expect(t, `"channel":"books"`)
+
+	bids := [][2]string{{"100.1", "2"}, {"100", "1"}}
+	asks := [][2]string{{"100.2", "1.5"}, {"100.3", "3"}}
+	conn.send(t, bookPush("snapshot", 10, -1, bookChecksum(bids, asks), bids, asks))
+
+	book := receiveBook(t, books)
+	assert.Equal(t, "100.1", book.Bids[0].Px)
+	assert.Equal(t, "100.2", book.Asks[0].Px)
+
+	// 增量：删除100.1买档，新增100.25卖档
+	bids = [][2]string{{"100", "1"}}
+	asks = [][2]string{{"100.2", "1.5"}, {"100.25", "0.5"}, {"100.3", "3"}}
+	conn.send(t, bookPush("update", 11, 10, bookChecksum(bids, asks),
+		[][2]string{{"100.1", "0"}}, [][2]string{{"100.25", "0.5"}}))
+
+	book = receiveBook(t, books)
+	require.Len(t, book.Bids, 1)
+	require.Len(t, book.Asks, 3)
+	assert.Equal(t, "100.25", book.Asks[1].Px)
+	assert.Equal(t, int64(11), book.SeqId)
+
+	// 序列号不连续时丢弃本地订单簿并重新订阅
+	conn.send(t, bookPush("update", 13, 12, 0, nil, [][2]string{{"100.4", "1"}}))
+	conn.expect(t, `"op":"unsubscribe"`)
+	conn.expect(t, `"op":"subscribe"`)
+
+	// 校验和错误同样触发重新订阅
+	conn.send(t, bookPush("snapshot", 20, -1, bookChecksum(bids, asks), bids, asks))
+	receiveBook(t, books)
+	conn.send(t, bookPush("update", 21, 20, 12345, nil, [][2]string{{"100.4", "1"}}))
+	conn.expect(t, `"op":"unsubscribe"`)
+
+	select {
+	case <-books:
+		t.Fatal("校验失败的订单簿不应推送")
+	default:
+	}
+}
+
+func receiveBook(t *testing.T, books chan *okx.OrderBookSnapshot) *okx.OrderBookSnapshot {
+	select {
+	case book := <-books:
+		return book
+	case <-time.After(3 * time.Second):
+		t.Fatal("未收到订单簿推送")
+		return nil
+	}
+}
+
+// TestCandleFeed 测试K线推送解析
+func TestCandleFeed(t *testing.T) {
+	url, conns := newFakeOKXWSServer(t)
+
+	feed := okx.NewMarketFeed(url, url)
+	defer feed.Stop()
+
+	candles := make(chan []okx.Candle, 1)
+	require.NoError(t, feed.SubscribeCandles("1m", "BTC-USDT", func(data []okx.Candle) {
+		candles <- data
+	}))
+
+	conn := acceptConn(t, conns)
+	conn.expect(t, `"channel":"candle1m"`)
+
+	payload, _ := json.Marshal([][]string{{"1700000000000", "1", "2", "0.5", "1.5", "10", "15", "15", "1"}})
+	conn.send(t, fmt.Sprintf(`{"arg":{"channel":"candle1m","instId":"BTC-USDT"},"data":%s}`, payload))
+
+	select {
+	case data := <-candles:
+		require.Len(t, data, 1)
+		assert.Equal(t, "1.5", data[0].Close)
+		assert.True(t, data[0].Confirm)
+	case <-time.After(3 * time.Second):
+		t.Fatal("未收到K线推送")
+	}
+}
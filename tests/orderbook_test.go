@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/okx"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBookServer 模拟OKX订单簿和交易对接口
+func newBookServer(t *testing.T, requests *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data interface{}
+		switch r.URL.Path {
+		case "/api/v5/market/books":
+			atomic.AddInt32(requests, 1)
+			data = []map[string]interface{}{{
+				"asks": [][]string{{"100.1", "1", "0", "2"}, {"100.3", "2", "0", "1"}, {"100.6", "0.5", "0", "1"}},
+				"bids": [][]string{{"99.9", "1.5", "0", "3"}, {"99.8", "1", "0", "1"}, {"99.4", "2", "0", "1"}},
+				"ts":   "1700000000000",
+			}}
+		case "/api/v5/public/instruments":
+			data = []map[string]string{{"instId": r.URL.Query().Get("instId"), "tickSz": "0.1"}}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": "0", "msg": "", "data": data})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// stubBookFeed 手动推送订单簿的推送源
+type stubBookFeed struct {
+	mutex    sync.Mutex
+	handlers map[string]func(*okx.OrderBookSnapshot)
+}
+
+func (f *stubBookFeed) SubscribeBooks(channel, instId string, handler func(*okx.OrderBookSnapshot)) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.handlers[channel+":"+instId] = handler
+	return nil
+}
+
+func (f *stubBookFeed) Unsubscribe(channel, instId string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.handlers, channel+":"+instId)
+	return nil
+}
+
+func (f *stubBookFeed) push(instId string, data *okx.BookData) {
+	f.mutex.Lock()
+	handler := f.handlers["books:"+instId]
+	f.mutex.Unlock()
+
+	book := okx.NewOrderBook(instId)
+	if err := book.Apply("snapshot", data); err != nil {
+		panic(err)
+	}
+	handler(book.Snapshot(0))
+}
+
+func tickSize(tickSz string) service.TickSizeLookup {
+	return func(ctx context.Context, instId string) (string, error) {
+		return tickSz, nil
+	}
+}
+
+// TestOrderBookAggregation 测试按TickSz倍数聚合档位及衍生指标
+func TestOrderBookAggregation(t *testing.T) {
+	var requests int32
+	server := newBookServer(t, &requests)
+	feed := &stubBookFeed{handlers: map[string]func(*okx.OrderBookSnapshot){}}
+	orderBookService := service.NewOrderBookServiceWithFeed(&config.OKXConfig{BaseURL: server.URL}, tickSize("0.1"), feed)
+
+	resp, err := orderBookService.GetOrderBook(context.Background(), &models.OrderBookRequest{
+		InstId: "BTC-USDT", Step: 5, Bps: []float64{10, 50},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, service.OrderBookSourceREST, resp.Source)
+	assert.Equal(t, "0.5", resp.Step)
+
+	// 卖单向上、买单向下取整到0.5
+	assert.Equal(t, []*models.OrderBookLevel{{Px: "100.5", Sz: "3", Orders: 3}, {Px: "101.0", Sz: "0.5", Orders: 1}}, resp.Asks)
+	assert.Equal(t, []*models.OrderBookLevel{{Px: "99.5", Sz: "2.5", Orders: 4}, {Px: "99.0", Sz: "2", Orders: 1}}, resp.Bids)
+
+	metrics := resp.Metrics
+	require.NotNil(t, metrics)
+	assert.Equal(t, "99.9", metrics.BestBid)
+	assert.Equal(t, "100.1", metrics.BestAsk)
+	assert.Equal(t, "0.2", metrics.Spread)
+	assert.Equal(t, "100.00", metrics.Mid)
+	assert.InDelta(t, 20, metrics.SpreadBps, 1e-9)
+	assert.InDelta(t, 0.125, metrics.Imbalance, 1e-9)
+	assert.Equal(t, []models.OrderBookDepth{
+		{Bps: 10, BidSz: "1.5", AskSz: "1", BidNotional: "149.85", AskNotional: "100.1"},
+		{Bps: 50, BidSz: "2.5", AskSz: "3", BidNotional: "249.65", AskNotional: "300.7"},
+	}, metrics.Depth)
+
+	// depth限制聚合后的档位数量
+	resp, err = orderBookService.GetOrderBook(context.Background(), &models.OrderBookRequest{InstId: "BTC-USDT", Depth: 1, Step: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []*models.OrderBookLevel{{Px: "100.1", Sz: "1", Orders: 2}}, resp.Asks)
+	assert.Equal(t, []*models.OrderBookLevel{{Px: "99.9", Sz: "1.5", Orders: 3}}, resp.Bids)
+}
+
+// TestOrderBookLocalBook 测试收到推送后使用本地订单簿，不再请求REST接口
+func TestOrderBookLocalBook(t *testing.T) {
+	var requests int32
+	server := newBookServer(t, &requests)
+	feed := &stubBookFeed{handlers: map[string]func(*okx.OrderBookSnapshot){}}
+	orderBookService := service.NewOrderBookServiceWithFeed(&config.OKXConfig{BaseURL: server.URL}, tickSize("0.1"), feed)
+
+	_, err := orderBookService.GetOrderBook(context.Background(), &models.OrderBookRequest{InstId: "BTC-USDT"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	var data okx.BookData
+	require.NoError(t, json.Unmarshal([]byte(`{"asks":[["101","1","0","1"]],"bids":[["100","2","0","1"]],"ts":"1700000001000","seqId":42}`), &data))
+	feed.push("BTC-USDT", &data)
+
+	resp, err := orderBookService.GetOrderBook(context.Background(), &models.OrderBookRequest{InstId: "BTC-USDT"})
+	require.NoError(t, err)
+	assert.Equal(t, service.OrderBookSourceWebSocket, resp.Source)
+	assert.Equal(t, int64(42), resp.SeqId)
+	assert.Equal(t, "100.5", resp.Metrics.Mid)
+	assert.Equal(t, "101.0", resp.Asks[0].Px)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+// TestOrderBookEndpoint 测试订单簿接口参数校验
+func TestOrderBookEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var requests int32
+	server := newBookServer(t, &requests)
+
+	// 公共频道指向不可用的地址，只使用REST快照
+	cfg := newAuthConfig()
+	cfg.OKX = config.OKXConfig{BaseURL: server.URL, WSPublicURL: "ws://127.0.0.1:1"}
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+	api.SetupMarketRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
+
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"depth=2&step=2&bps=10&bps=25", http.StatusOK},
+		{"depth=0", http.StatusBadRequest},
+		{"depth=401", http.StatusBadRequest},
+		{"step=0", http.StatusBadRequest},
+		{"bps=-1", http.StatusBadRequest},
+		{"bps=abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/market/books/BTC-USDT?"+tt.query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, tt.query)
+
+		if tt.status == http.StatusOK {
+			var body struct {
+				Data models.OrderBookResponse `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, "0.1", body.Data.TickSz)
+			assert.Equal(t, "0.2", body.Data.Step)
+			assert.Len(t, body.Data.Asks, 2)
+			require.NotNil(t, body.Data.Metrics)
+			assert.Len(t, body.Data.Metrics.Depth, 2)
+		}
+	}
+}
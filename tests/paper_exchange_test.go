@@ -0,0 +1,452 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// paperPriceService 返回固定买一/卖一价的价格服务，可手动推送行情
+type paperPriceService struct {
+	mutex     sync.Mutex
+	quotes    map[string]*service.PriceData
+	callbacks map[string]func(*service.PriceData)
+}
+
+func newPaperPriceService() *paperPriceService {
+	return &paperPriceService{
+		quotes:    make(map[string]*service.PriceData),
+		callbacks: make(map[string]func(*service.PriceData)),
+	}
+}
+
+func (s *paperPriceService) set(symbol, bid, ask string) *service.PriceData {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	priceData := &service.PriceData{Symbol: symbol, Price: bid, BidPx: bid, AskPx: ask}
+	s.quotes[symbol] = priceData
+	return priceData
+}
+
+func (s *paperPriceService) GetPrice(ctx context.Context, symbol string) (*service.PriceData, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if priceData, exists := s.quotes[symbol]; exists {
+		return priceData, nil
+	}
+	return &service.PriceData{Symbol: symbol}, nil
+}
+
+func (s *paperPriceService) StartPriceStream(symbol string, callback func(*service.PriceData)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.callbacks[symbol] = callback
+}
+
+func (s *paperPriceService) StopSymbolStream(symbol string) {}
+
+func (s *paperPriceService) StopPriceStream() {}
+
+// emit 更新行情并推送给订阅者
+func (s *paperPriceService) emit(symbol, bid, ask string) {
+	priceData := s.set(symbol, bid, ask)
+	s.mutex.Lock()
+	callback := s.callbacks[symbol]
+	s.mutex.Unlock()
+	if callback != nil {
+		callback(priceData)
+	}
+}
+
+// paperInstruments 测试用交易对信息
+func paperInstruments(ctx context.Context, instId string) (*service.PaperInstrument, error) {
+	switch instId {
+	case "BTC-USDT-SWAP":
+		return &service.PaperInstrument{InstType: "SWAP", SettleCcy: "USDT", CtVal: decimal.MustParse("0.01"), CtValCcy: "BTC"}, nil
+	default:
+		return &service.PaperInstrument{InstType: "SPOT", BaseCcy: "BTC", QuoteCcy: "USDT"}, nil
+	}
+}
+
+func newTestPaperExchange(prices *paperPriceService) service.PaperExchange {
+	return service.NewPaperExchange(&config.PaperConfig{
+		Enabled:         true,
+		InitialBalance:  10000,
+		TakerFeeRate:    0.001,
+		MakerFeeRate:    0.0005,
+		DefaultLeverage: 5,
+	}, prices, paperInstruments)
+}
+
+// paperBalance 获取币种的余额明细
+func paperBalance(t *testing.T, exchange service.PaperExchange, ccy string) service.OKXBalanceDetail {
+	balance, ok := exchange.Balance()
+	require.True(t, ok)
+	for _, detail := range balance.Details {
+		if detail.Ccy == ccy {
+			return detail
+		}
+	}
+	t.Fatalf("余额中没有%s", ccy)
+	return service.OKXBalanceDetail{}
+}
+
+func TestPaperMarketOrderFillsAtAsk(t *testing.T) {
+	prices := newPaperPriceService()
+	prices.set("BTC-USDT", "49990", "50000")
+	exchange := newTestPaperExchange(prices)
+
+	resp, err := exchange.PlaceOrder(context.Background(), &models.OrderRequest{
+		InstId: "BTC-USDT", TdMode: "cash", Side: "buy", OrdType: "market", Sz: "0.1", TgtCcy: "base_ccy",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "0", resp.Code)
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, "0", resp.Data[0].SCode)
+
+	history, err := exchange.GetOrdersHistory(context.Background(), &models.OrdersRequest{InstType: "SPOT"})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "filled", history[0].State)
+	assert.Equal(t, "50000", history[0].AvgPx)
+	assert.Equal(t, "-0.0001", history[0].Fee)
+	assert.Equal(t, "BTC", history[0].FeeCcy)
+
+	// 买入按卖一价成交，手续费从买入的BTC中扣除
+	assert.Equal(t, "5000", paperBalance(t, exchange, "USDT").CashBal)
+	assert.Equal(t, "0.0999", paperBalance(t, exchange, "BTC").CashBal)
+}
+
+func TestPaperLimitOrderFillsOnPriceUpdate(t *testing.T) {
+	prices := newPaperPriceService()
+	prices.set("BTC-USDT", "49990", "50000")
+	exchange := newTestPaperExchange(prices)
+
+	resp, err := exchange.PlaceOrder(context.Background(), &models.OrderRequest{
+		InstId: "BTC-USDT", TdMode: "cash", Side: "buy", OrdType: "limit", Px: "49000", Sz: "0.1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "0", resp.Code)
+
+	pending, err := exchange.GetPendingOrders(context.Background(), &models.OrdersRequest{})
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "live", pending[0].State)
+	assert.Equal(t, "4900", paperBalance(t, exchange, "USDT").FrozenBal)
+
+	var messages []*models.AccountStreamMessage
+	remove := exchange.AddListener(func(message *models.AccountStreamMessage) {
+		messages = append(messages, message)
+	})
+	defer remove()
+
+	// 卖一价未触及委托价时不成交
+	prices.emit("BTC-USDT", "49100", "49200")
+	pending, _ = exchange.GetPendingOrders(context.Background(), &models.OrdersRequest{})
+	assert.Len(t, pending, 1)
+
+	// 卖一价触及委托价后按委托价以挂单费率成交
+	prices.emit("BTC-USDT", "48900", "48950")
+	pending, _ = exchange.GetPendingOrders(context.Background(), &models.OrdersRequest{})
+	assert.Empty(t, pending)
+
+	history, err := exchange.GetOrdersHistory(context.Background(), &models.OrdersRequest{})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "filled", history[0].State)
+	assert.Equal(t, "49000", history[0].AvgPx)
+	assert.Equal(t, "-0.00005", history[0].Fee)
+
+	usdt := paperBalance(t, exchange, "USDT")
+	assert.Equal(t, "5100", usdt.CashBal)
+	assert.Equal(t, "0", usdt.FrozenBal)
+
+	var channels []string
+	for _, message := range messages {
+		channels = append(channels, message.Channel)
+	}
+	assert.Contains(t, channels, "orders")
+	assert.Contains(t, channels, "account")
+}
+
+func TestPaperContractRoundTrip(t *testing.T) {
+	prices := newPaperPriceService()
+	prices.set("BTC-USDT-SWAP", "50000", "50000")
+	exchange := newTestPaperExchange(prices)
+
+	resp, err := exchange.PlaceOrder(context.Background(), &models.OrderRequest{
+		InstId: "BTC-USDT-SWAP", TdMode: "cross", Side: "buy", OrdType: "market", Sz: "10",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "0", resp.Code)
+
+	// 10张 * 0.01 * 50000 / 5倍 = 1000 保证金，吃单手续费 5
+	positions, ok := exchange.Positions()
+	require.True(t, ok)
+	require.Len(t, positions, 1)
+	assert.Equal(t, "10", positions[0].Pos)
+	assert.Equal(t, "50000", positions[0].AvgPx)
+	assert.Equal(t, "1000", positions[0].Margin)
+	assert.Equal(t, "9995", paperBalance(t, exchange, "USDT").CashBal)
+
+	prices.emit("BTC-USDT-SWAP", "51000", "51000")
+	positions, _ = exchange.Positions()
+	require.Len(t, positions, 1)
+	assert.Equal(t, "100", positions[0].Upl)
+
+	resp, err = exchange.PlaceOrder(context.Background(), &models.OrderRequest{
+		InstId: "BTC-USDT-SWAP", TdMode: "cross", Side: "sell", OrdType: "market", Sz: "10", ReduceOnly: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "0", resp.Code)
+
+	positions, _ = exchange.Positions()
+	assert.Empty(t, positions)
+
+	// 平仓收益 100，开平仓手续费 5 + 5.1
+	assert.Equal(t, "10089.9", paperBalance(t, exchange, "USDT").CashBal)
+
+	closed := exchange.PositionsHistory(&models.PositionsHistoryRequest{})
+	require.Len(t, closed, 1)
+	assert.Equal(t, "long", closed[0].Direction)
+	assert.Equal(t, "50000", closed[0].OpenAvgPx)
+	assert.Equal(t, "51000", closed[0].CloseAvgPx)
+	assert.Equal(t, "100", closed[0].Pnl)
+	assert.Equal(t, "89.9", closed[0].RealizedPnl)
+}
+
+// TestPaperContractPartialCloses 测试多次部分平仓的数量之和等于持仓时恰好平仓，不残留反向持仓
+func TestPaperContractPartialCloses(t *testing.T) {
+	prices := newPaperPriceService()
+	prices.set("BTC-USDT-SWAP", "50000", "50000")
+	exchange := newTestPaperExchange(prices)
+
+	place := func(side, sz string) {
+		resp, err := exchange.PlaceOrder(context.Background(), &models.OrderRequest{
+			InstId: "BTC-USDT-SWAP", TdMode: "cross", Side: side, OrdType: "market", Sz: sz,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "0", resp.Code)
+	}
+
+	// 浮点数下 0.3 - 0.1 - 0.2 不等于0
+	place("buy", "0.3")
+	place("sell", "0.1")
+	positions, _ := exchange.Positions()
+	require.Len(t, positions, 1)
+	assert.Equal(t, "0.2", positions[0].Pos)
+
+	place("sell", "0.2")
+	positions, _ = exchange.Positions()
+	assert.Empty(t, positions)
+
+	closed := exchange.PositionsHistory(&models.PositionsHistoryRequest{})
+	require.Len(t, closed, 1)
+	assert.Equal(t, "0.3", closed[0].OpenMaxPos)
+	assert.Equal(t, "0.3", closed[0].CloseTotalPos)
+	assert.Equal(t, "0", paperBalance(t, exchange, "USDT").FrozenBal)
+
+	// 反手：持有 0.1 多仓时卖出 0.3，平仓后开 0.2 空仓
+	place("buy", "0.1")
+	place("sell", "0.3")
+	positions, _ = exchange.Positions()
+	require.Len(t, positions, 1)
+	assert.Equal(t, "-0.2", positions[0].Pos)
+	assert.Len(t, exchange.PositionsHistory(&models.PositionsHistoryRequest{}), 2)
+}
+
+// TestPaperCashReconciles 测试多次开平仓后现金余额恰好等于初始余额加上各笔收益和手续费，总权益与明细一致
+func TestPaperCashReconciles(t *testing.T) {
+	prices := newPaperPriceService()
+	exchange := newTestPaperExchange(prices)
+
+	for i := 0; i < 30; i++ {
+		for _, fill := range []struct{ side, sz, px string }{
+			{"buy", "0.3", "50000.1"},
+			{"sell", "0.1", "50000.7"},
+			{"sell", "0.2", "49999.3"},
+		} {
+			prices.set("BTC-USDT-SWAP", fill.px, fill.px)
+			resp, err := exchange.PlaceOrder(context.Background(), &models.OrderRequest{
+				InstId: "BTC-USDT-SWAP", TdMode: "cross", Side: fill.side, OrdType: "market", Sz: fill.sz,
+			})
+			require.NoError(t, err)
+			require.Equal(t, "0", resp.Code)
+		}
+	}
+	positions, _ := exchange.Positions()
+	require.Empty(t, positions)
+
+	history, err := exchange.GetOrdersHistory(context.Background(), &models.OrdersRequest{})
+	require.NoError(t, err)
+	require.Len(t, history, 90)
+	expected := decimal.New(10000)
+	for _, order := range history {
+		expected = expected.Add(decimal.MustParse(order.Pnl)).Add(decimal.MustParse(order.Fee))
+	}
+
+	balance, _ := exchange.Balance()
+	usdt := paperBalance(t, exchange, "USDT")
+	assert.Zero(t, expected.Cmp(decimal.MustParse(usdt.CashBal)), "现金 %s，收益和手续费合计 %s", usdt.CashBal, expected)
+	assert.Equal(t, usdt.Bal, balance.TotalEq)
+}
+
+// TestPaperReduceOnly 测试只减仓订单不会开仓或反手
+func TestPaperReduceOnly(t *testing.T) {
+	prices := newPaperPriceService()
+	prices.set("BTC-USDT-SWAP", "50000", "50000")
+	exchange := newTestPaperExchange(prices)
+
+	place := func(side, sz string, reduceOnly bool) *models.OrderResultResponse {
+		resp, err := exchange.PlaceOrder(context.Background(), &models.OrderRequest{
+			InstId: "BTC-USDT-SWAP", TdMode: "cross", Side: side, OrdType: "market", Sz: sz, ReduceOnly: reduceOnly,
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Data, 1)
+		return resp
+	}
+
+	// 没有持仓时拒绝只减仓订单
+	resp := place("sell", "100000", true)
+	assert.Equal(t, "1", resp.Code)
+	assert.Equal(t, "51169", resp.Data[0].SCode)
+	positions, _ := exchange.Positions()
+	assert.Empty(t, positions)
+	assert.Equal(t, "10000", paperBalance(t, exchange, "USDT").CashBal)
+
+	// 同向持仓也没有可平的仓位
+	require.Equal(t, "0", place("buy", "10", false).Code)
+	assert.Equal(t, "51169", place("buy", "10", true).Data[0].SCode)
+
+	// 超过持仓的只减仓订单只平掉持仓，不反手开空
+	require.Equal(t, "0", place("sell", "100000", true).Code)
+	positions, _ = exchange.Positions()
+	assert.Empty(t, positions)
+
+	history, err := exchange.GetOrdersHistory(context.Background(), &models.OrdersRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "filled", history[0].State)
+	assert.Equal(t, "10", history[0].AccFillSz)
+
+	closed := exchange.PositionsHistory(&models.PositionsHistoryRequest{})
+	require.Len(t, closed, 1)
+	assert.Equal(t, "10", closed[0].CloseTotalPos)
+	// 开平仓手续费各 5
+	assert.Equal(t, "9990", paperBalance(t, exchange, "USDT").CashBal)
+}
+
+func TestPaperOrderRejections(t *testing.T) {
+	prices := newPaperPriceService()
+	prices.set("BTC-USDT", "49990", "50000")
+	exchange := newTestPaperExchange(prices)
+
+	resp, err := exchange.PlaceOrder(context.Background(), &models.OrderRequest{
+		InstId: "BTC-USDT", TdMode: "cash", Side: "buy", OrdType: "limit", Px: "50000", Sz: "1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "1", resp.Code)
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, "51008", resp.Data[0].SCode)
+
+	// 只做maker单会立即成交时撤单
+	resp, err = exchange.PlaceOrder(context.Background(), &models.OrderRequest{
+		InstId: "BTC-USDT", TdMode: "cash", Side: "buy", OrdType: "post_only", Px: "50100", Sz: "0.01",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "0", resp.Code)
+	history, _ := exchange.GetOrdersHistory(context.Background(), &models.OrdersRequest{})
+	require.Len(t, history, 1)
+	assert.Equal(t, "canceled", history[0].State)
+}
+
+func TestPaperCancelAndReset(t *testing.T) {
+	prices := newPaperPriceService()
+	prices.set("BTC-USDT", "49990", "50000")
+	exchange := newTestPaperExchange(prices)
+
+	resp, err := exchange.PlaceOrder(context.Background(), &models.OrderRequest{
+		InstId: "BTC-USDT", TdMode: "cash", Side: "buy", OrdType: "limit", Px: "40000", Sz: "0.1", ClOrdId: "paper1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "0", resp.Code)
+
+	order, err := exchange.GetOrder(context.Background(), "BTC-USDT", "", "paper1")
+	require.NoError(t, err)
+	require.NotNil(t, order)
+	assert.Equal(t, "live", order.State)
+	pending, _ := exchange.GetPendingOrders(context.Background(), &models.OrdersRequest{After: order.OrdId})
+	assert.Empty(t, pending)
+
+	resp, err = exchange.CancelOrder(context.Background(), &models.CancelOrderRequest{InstId: "BTC-USDT", ClOrdId: "paper1"})
+	require.NoError(t, err)
+	assert.Equal(t, "0", resp.Code)
+	assert.Equal(t, "0", paperBalance(t, exchange, "USDT").FrozenBal)
+
+	order, err = exchange.GetOrder(context.Background(), "BTC-USDT", resp.Data[0].OrdId, "")
+	require.NoError(t, err)
+	require.NotNil(t, order)
+	assert.Equal(t, "canceled", order.State)
+	order, err = exchange.GetOrder(context.Background(), "BTC-USDT", "404", "")
+	require.NoError(t, err)
+	assert.Nil(t, order)
+
+	resp, err = exchange.CancelOrder(context.Background(), &models.CancelOrderRequest{InstId: "BTC-USDT", ClOrdId: "paper1"})
+	require.NoError(t, err)
+	assert.Equal(t, "1", resp.Code)
+	assert.Equal(t, "51603", resp.Data[0].SCode)
+
+	_, err = exchange.PlaceOrder(context.Background(), &models.OrderRequest{
+		InstId: "BTC-USDT", TdMode: "cash", Side: "buy", OrdType: "market", Sz: "1000",
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, "10000", paperBalance(t, exchange, "USDT").CashBal)
+
+	exchange.Reset()
+	assert.Equal(t, "10000", paperBalance(t, exchange, "USDT").CashBal)
+	history, _ := exchange.GetOrdersHistory(context.Background(), &models.OrdersRequest{})
+	assert.Empty(t, history)
+}
+
+func TestPaperAccountService(t *testing.T) {
+	// 汇率接口失败时直接返回，避免访问真实OKX
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":"50000","msg":"unavailable","data":[]}`))
+	}))
+	defer server.Close()
+
+	prices := newPaperPriceService()
+	prices.set("BTC-USDT-SWAP", "50000", "50000")
+	exchange := newTestPaperExchange(prices)
+	_, err := exchange.PlaceOrder(context.Background(), &models.OrderRequest{
+		InstId: "BTC-USDT-SWAP", TdMode: "cross", Side: "sell", OrdType: "market", Sz: "4",
+	})
+	require.NoError(t, err)
+
+	// 未配置API Key时账户服务仍可读取模拟账户
+	accountService := service.NewAccountServiceWithStream(&config.OKXConfig{BaseURL: server.URL}, nil, exchange)
+
+	balance, err := accountService.GetAccountBalance(context.Background(), models.CurrencyUSDT)
+	require.NoError(t, err)
+	assert.Equal(t, "9998", balance.TotalEquity.String())
+	require.Len(t, balance.Details, 1)
+	assert.Equal(t, "USDT", balance.Details[0].Currency)
+
+	positions, err := accountService.GetPositions(context.Background(), &models.PositionsRequest{}, models.CurrencyUSDT)
+	require.NoError(t, err)
+	require.Len(t, positions.Positions, 1)
+	assert.Equal(t, "BTC-USDT-SWAP", positions.Positions[0].InstId)
+	assert.Equal(t, "-4", positions.Positions[0].Pos)
+
+	history, err := accountService.GetPositionsHistory(context.Background(), &models.PositionsHistoryRequest{}, models.CurrencyUSDT)
+	require.NoError(t, err)
+	assert.Empty(t, history.Positions)
+}
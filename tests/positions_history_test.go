@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"testing"
 
 	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
@@ -90,7 +91,7 @@ func TestPositionsHistoryService(t *testing.T) {
 	}
 
 	// 注意：这里会因为API配置问题而失败，但我们主要测试接口和数据结构
-	_, err := accountService.GetPositionsHistory(req, models.CurrencyUSDT)
+	_, err := accountService.GetPositionsHistory(context.Background(), req, models.CurrencyUSDT)
 	
 	// 由于没有真实的API配置，这里会失败，但我们可以验证错误类型
 	if err == nil {
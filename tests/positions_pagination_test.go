@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyBaseTime 模拟历史持仓中最新一条的更新时间
+const historyBaseTime = int64(1700000000000)
+
+// newPositionsHistoryServer 模拟OKX历史持仓接口，共 total 条，每两条的更新时间相同，按 after/before/limit 分页
+func newPositionsHistoryServer(t *testing.T, total int, requests *int32) *httptest.Server {
+	history := make([]map[string]string, total)
+	for i := range history {
+		history[i] = map[string]string{
+			"instType":    "SWAP",
+			"instId":      "BTC-USDT-SWAP",
+			"posId":       strconv.Itoa(i),
+			"uTime":       strconv.FormatInt(historyBaseTime-int64(i/2)*1000, 10),
+			"realizedPnl": "1",
+			"ccy":         "USDT",
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v5/account/positions-history" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": "0", "msg": "", "data": []interface{}{}})
+			return
+		}
+		atomic.AddInt32(requests, 1)
+
+		query := r.URL.Query()
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		data := []map[string]string{}
+		for _, pos := range history {
+			uTime, _ := strconv.ParseInt(pos["uTime"], 10, 64)
+			if after, err := strconv.ParseInt(query.Get("after"), 10, 64); err == nil && uTime >= after {
+				continue
+			}
+			if before, err := strconv.ParseInt(query.Get("before"), 10, 64); err == nil && uTime <= before {
+				continue
+			}
+			if len(data) < limit {
+				data = append(data, pos)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": "0", "msg": "", "data": data})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newHistoryOKXConfig 指向模拟服务器的OKX配置
+func newHistoryOKXConfig(baseURL string) *config.OKXConfig {
+	return &config.OKXConfig{BaseURL: baseURL, APIKey: "key", SecretKey: "secret", Passphrase: "pass"}
+}
+
+// TestPositionsHistoryCursor 测试游标翻页在同一毫秒多条持仓时不重复、不遗漏
+func TestPositionsHistoryCursor(t *testing.T) {
+	var requests int32
+	server := newPositionsHistoryServer(t, 250, &requests)
+	accountService := service.NewAccountServiceWithCurrencies(newHistoryOKXConfig(server.URL), &config.CurrencyConfig{}, nil, nil)
+	ctx := context.Background()
+
+	var posIDs []string
+	req := &models.PositionsHistoryRequest{PageSize: 25}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 20, "翻页没有结束")
+		page, err := accountService.GetPositionsHistoryPage(ctx, req, models.CurrencyUSDT)
+		require.NoError(t, err)
+		for _, pos := range page.Positions {
+			posIDs = append(posIDs, pos.PosId)
+		}
+		if page.NextCursor == "" {
+			assert.False(t, page.HasMore)
+			break
+		}
+		assert.True(t, page.HasMore)
+		req.Cursor = page.NextCursor
+	}
+
+	require.Len(t, posIDs, 250)
+	for i, posID := range posIDs {
+		assert.Equal(t, strconv.Itoa(i), posID)
+	}
+
+	_, err := accountService.GetPositionsHistoryPage(ctx, &models.PositionsHistoryRequest{Cursor: "not-a-cursor"}, models.CurrencyUSDT)
+	assert.ErrorIs(t, err, service.ErrInvalidCursor)
+}
+
+// TestPositionsHistoryAll 测试 all=true 返回时间范围内的全部持仓
+func TestPositionsHistoryAll(t *testing.T) {
+	var requests int32
+	server := newPositionsHistoryServer(t, 400, &requests)
+	accountService := service.NewAccountServiceWithCurrencies(newHistoryOKXConfig(server.URL), &config.CurrencyConfig{}, nil, nil)
+
+	// 第10秒到第159秒，每秒两条
+	from := historyBaseTime - 159*1000
+	to := historyBaseTime - 10*1000
+	page, err := accountService.GetPositionsHistoryPage(context.Background(), &models.PositionsHistoryRequest{All: true, From: from, To: to}, models.CurrencyUSDT)
+	require.NoError(t, err)
+
+	require.Len(t, page.Positions, 300)
+	assert.Equal(t, "20", page.Positions[0].PosId)
+	assert.Equal(t, "319", page.Positions[299].PosId)
+	assert.False(t, page.HasMore)
+	assert.Empty(t, page.NextCursor)
+	assert.Equal(t, 300, page.Summary.Count)
+	assert.Equal(t, "300", page.Summary.RealizedPnl.String())
+	assert.EqualValues(t, 4, atomic.LoadInt32(&requests), "每次请求100条")
+}
+
+// TestAllPositionsHistoryIterator 测试迭代器按需翻页，提前退出时不再请求
+func TestAllPositionsHistoryIterator(t *testing.T) {
+	var requests int32
+	server := newPositionsHistoryServer(t, 250, &requests)
+	accountService := service.NewAccountServiceWithCurrencies(newHistoryOKXConfig(server.URL), &config.CurrencyConfig{}, nil, nil)
+	ctx := context.Background()
+
+	count := 0
+	for pos, err := range accountService.AllPositionsHistory(ctx, &models.PositionsHistoryRequest{}, models.CurrencyUSDT) {
+		require.NoError(t, err)
+		assert.Equal(t, strconv.Itoa(count), pos.PosId)
+		count++
+	}
+	assert.Equal(t, 250, count)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&requests))
+
+	atomic.StoreInt32(&requests, 0)
+	for range accountService.AllPositionsHistory(ctx, &models.PositionsHistoryRequest{}, models.CurrencyUSDT) {
+		break
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+
+	// 请求失败时产出错误
+	failing := service.NewAccountServiceWithCurrencies(&config.OKXConfig{BaseURL: server.URL}, &config.CurrencyConfig{}, nil, nil)
+	for _, err := range failing.AllPositionsHistory(ctx, &models.PositionsHistoryRequest{}, models.CurrencyUSDT) {
+		assert.Error(t, err)
+	}
+}
+
+// TestPositionsHistoryCursorRoute 测试历史持仓接口的游标参数
+func TestPositionsHistoryCursorRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var requests int32
+	server := newPositionsHistoryServer(t, 150, &requests)
+
+	cfg := newAuthConfig()
+	cfg.OKX = *newHistoryOKXConfig(server.URL)
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+	api.SetupAccountRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
+
+	var body struct {
+		Data models.PositionsHistoryResponse `json:"data"`
+	}
+	w := authRequest(r, http.MethodGet, "/api/v1/account/positions-history?pageSize=100", token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data.Positions, 100)
+	require.NotEmpty(t, body.Data.NextCursor)
+
+	w = authRequest(r, http.MethodGet, "/api/v1/account/positions-history?pageSize=100&cursor="+body.Data.NextCursor, token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	body.Data = models.PositionsHistoryResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.Data.Positions, 50)
+	assert.Equal(t, "100", body.Data.Positions[0].PosId)
+	assert.Empty(t, body.Data.NextCursor)
+
+	for _, query := range []string{"cursor=abc", "pageSize=-1", "from=2&to=1", "pageSize=x"} {
+		w = authRequest(r, http.MethodGet, "/api/v1/account/positions-history?"+query, token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, fmt.Sprintf("%s: %s", query, w.Body.String()))
+	}
+}
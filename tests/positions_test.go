@@ -23,6 +23,8 @@ func TestGetPositions(t *testing.T) {
 	
 	// 创建测试配置
 	cfg := &config.Config{
+		JWTSecret: "test-secret",
+		Auth:      config.AuthConfig{AdminUsername: "admin", AdminPassword: "test-password"},
 		OKX: config.OKXConfig{
 			APIKey:     "test-api-key",
 			SecretKey:  "test-secret-key",
@@ -34,7 +36,9 @@ func TestGetPositions(t *testing.T) {
 
 	// 创建路由
 	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
 	api.SetupAccountRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
 
 	tests := []struct {
 		name           string
@@ -97,6 +101,7 @@ func TestGetPositions(t *testing.T) {
 			// 创建请求
 			req, err := http.NewRequest("GET", "/api/v1/account/positions"+queryString, nil)
 			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
 
 			// 创建响应记录器
 			w := httptest.NewRecorder()
@@ -134,6 +139,8 @@ func TestGetPositionsHistory(t *testing.T) {
 	
 	// 创建测试配置
 	cfg := &config.Config{
+		JWTSecret: "test-secret",
+		Auth:      config.AuthConfig{AdminUsername: "admin", AdminPassword: "test-password"},
 		OKX: config.OKXConfig{
 			APIKey:     "test-api-key",
 			SecretKey:  "test-secret-key",
@@ -145,7 +152,9 @@ func TestGetPositionsHistory(t *testing.T) {
 
 	// 创建路由
 	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
 	api.SetupAccountRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
 
 	tests := []struct {
 		name           string
@@ -200,6 +209,7 @@ func TestGetPositionsHistory(t *testing.T) {
 			// 创建请求
 			req, err := http.NewRequest("GET", "/api/v1/account/positions-history"+queryString, nil)
 			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
 
 			// 创建响应记录器
 			w := httptest.NewRecorder()
@@ -303,12 +313,7 @@ func TestAccountServiceInterface(t *testing.T) {
 	defaultCurrency := accountService.GetDefaultCurrency()
 	assert.Equal(t, models.CurrencyUSDT, defaultCurrency)
 
-	// 测试设置币种
-	err := accountService.SetDefaultCurrency(models.CurrencyUSD)
-	assert.NoError(t, err)
-
-	newDefaultCurrency := accountService.GetDefaultCurrency()
-	assert.Equal(t, models.CurrencyUSD, newDefaultCurrency)
+	// 用户的默认币种保存在用户设置中，见 settings_test.go
 }
 
 // BenchmarkGetPositions 性能测试
@@ -316,6 +321,8 @@ func BenchmarkGetPositions(b *testing.B) {
 	gin.SetMode(gin.TestMode)
 	
 	cfg := &config.Config{
+		JWTSecret: "test-secret",
+		Auth:      config.AuthConfig{AdminUsername: "admin", AdminPassword: "test-password"},
 		OKX: config.OKXConfig{
 			APIKey:     "test-api-key",
 			SecretKey:  "test-secret-key",
@@ -326,11 +333,14 @@ func BenchmarkGetPositions(b *testing.B) {
 	}
 
 	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
 	api.SetupAccountRoutes(r, cfg)
+	token := loginTokens(b, r, "admin", "test-password").AccessToken
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		req, _ := http.NewRequest("GET", "/api/v1/account/positions", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
 		w := httptest.NewRecorder()
 		r.ServeHTTP(w, req)
 	}
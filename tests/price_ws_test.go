@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubPriceService 记录上游订阅情况的价格服务
+type stubPriceService struct {
+	mutex     sync.Mutex
+	starts    map[string]int
+	stops     map[string]int
+	callbacks map[string]func(*service.PriceData)
+}
+
+func newStubPriceService() *stubPriceService {
+	return &stubPriceService{
+		starts:    make(map[string]int),
+		stops:     make(map[string]int),
+		callbacks: make(map[string]func(*service.PriceData)),
+	}
+}
+
+func (s *stubPriceService) GetPrice(ctx context.Context, symbol string) (*service.PriceData, error) {
+	return &service.PriceData{Symbol: symbol, Price: "1"}, nil
+}
+
+func (s *stubPriceService) StartPriceStream(symbol string, callback func(*service.PriceData)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.starts[symbol]++
+	s.callbacks[symbol] = callback
+}
+
+func (s *stubPriceService) StopSymbolStream(symbol string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stops[symbol]++
+	delete(s.callbacks, symbol)
+}
+
+func (s *stubPriceService) StopPriceStream() {}
+
+func (s *stubPriceService) emit(symbol, price string) {
+	s.mutex.Lock()
+	callback := s.callbacks[symbol]
+	s.mutex.Unlock()
+	if callback != nil {
+		callback(&service.PriceData{Symbol: symbol, Price: price})
+	}
+}
+
+func (s *stubPriceService) counts(symbol string) (int, int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.starts[symbol], s.stops[symbol]
+}
+
+// priceWSMessage /ws/price 推送消息
+type priceWSMessage struct {
+	Event string `json:"event"`
+	Code  string `json:"code"`
+	Arg   struct {
+		Channel string `json:"channel"`
+		InstId  string `json:"instId"`
+	} `json:"arg"`
+	Data *service.PriceData `json:"data"`
+}
+
+func dialPriceWS(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readPriceWS(t *testing.T, conn *websocket.Conn) priceWSMessage {
+	var message priceWSMessage
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &message))
+	return message
+}
+
+func subscribePrice(t *testing.T, conn *websocket.Conn, op, instId string) {
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"op":   op,
+		"args": []map[string]string{{"channel": "ticker", "instId": instId}},
+	}))
+}
+
+// TestPriceWebSocketTopics 测试按主题订阅、引用计数和错误应答
+func TestPriceWebSocketTopics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prices := newStubPriceService()
+	manager := api.NewWebSocketManagerWithService(prices, config.WebSocketConfig{})
+
+	r := gin.New()
+	r.GET("/ws/price", manager.HandleWebSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/price"
+
+	first := dialPriceWS(t, url)
+	second := dialPriceWS(t, url)
+
+	// 订阅应答后推送初始价格
+	for _, conn := range []*websocket.Conn{first, second} {
+		subscribePrice(t, conn, "subscribe", "ETH-USDT")
+		ack := readPriceWS(t, conn)
+		assert.Equal(t, "subscribe", ack.Event)
+		assert.Equal(t, "ETH-USDT", ack.Arg.InstId)
+		initial := readPriceWS(t, conn)
+		require.NotNil(t, initial.Data)
+	}
+
+	subscribePrice(t, first, "subscribe", "BTC-USDT")
+	assert.Equal(t, "subscribe", readPriceWS(t, first).Event)
+	readPriceWS(t, first)
+
+	starts, _ := prices.counts("ETH-USDT")
+	assert.Equal(t, 1, starts, "同一交易对只向上游订阅一次")
+
+	// 只有订阅了BTC-USDT的客户端收到BTC价格
+	prices.emit("BTC-USDT", "60000")
+	prices.emit("ETH-USDT", "3000")
+
+	message := readPriceWS(t, first)
+	assert.Equal(t, "BTC-USDT", message.Arg.InstId)
+	assert.Equal(t, "60000", message.Data.Price)
+	assert.Equal(t, "3000", readPriceWS(t, first).Data.Price)
+
+	message = readPriceWS(t, second)
+	assert.Equal(t, "ETH-USDT", message.Arg.InstId)
+	assert.Equal(t, "3000", message.Data.Price)
+
+	// 错误应答
+	require.NoError(t, second.WriteMessage(websocket.TextMessage, []byte("not json")))
+	assert.Equal(t, "invalid_message", readPriceWS(t, second).Code)
+	require.NoError(t, second.WriteJSON(map[string]interface{}{"op": "login"}))
+	assert.Equal(t, "invalid_op", readPriceWS(t, second).Code)
+	require.NoError(t, second.WriteJSON(map[string]interface{}{
+		"op":   "subscribe",
+		"args": []map[string]string{{"channel": "books", "instId": "ETH-USDT"}, {"channel": "ticker", "instId": "eth usdt"}},
+	}))
+	assert.Equal(t, "invalid_channel", readPriceWS(t, second).Code)
+	assert.Equal(t, "invalid_inst_id", readPriceWS(t, second).Code)
+
+	// 一个客户端取消订阅后上游仍保持，最后一个订阅者离开后才停止
+	subscribePrice(t, second, "unsubscribe", "ETH-USDT")
+	assert.Equal(t, "unsubscribe", readPriceWS(t, second).Event)
+	_, stops := prices.counts("ETH-USDT")
+	assert.Equal(t, 0, stops)
+
+	first.Close()
+	require.Eventually(t, func() bool {
+		_, ethStops := prices.counts("ETH-USDT")
+		_, btcStops := prices.counts("BTC-USDT")
+		return ethStops == 1 && btcStops == 1
+	}, 3*time.Second, 10*time.Millisecond)
+}
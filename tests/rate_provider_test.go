@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubRateProvider 返回固定报价或错误的汇率来源
+type stubRateProvider struct {
+	name   string
+	quotes []service.RateQuote
+	err    error
+}
+
+func (p *stubRateProvider) Name() string {
+	return p.name
+}
+
+func (p *stubRateProvider) Quotes(ctx context.Context) ([]service.RateQuote, error) {
+	return p.quotes, p.err
+}
+
+func usdQuote(source, ccy, rate string, updatedAt time.Time, live bool) service.RateQuote {
+	return service.RateQuote{Base: "USD", Quote: ccy, Rate: decimal.MustParse(rate), Source: source, UpdatedAt: updatedAt, Live: live}
+}
+
+// TestRateChain 测试汇率来源的优先级、失败时沿用缓存和过期后回退到下一个来源
+func TestRateChain(t *testing.T) {
+	now := time.Now()
+	primary := &stubRateProvider{name: "primary", quotes: []service.RateQuote{usdQuote("primary", "CNY", "7.1", now, true)}}
+	fallback := &stubRateProvider{name: "fallback", quotes: []service.RateQuote{
+		usdQuote("fallback", "CNY", "7.0", now.Add(-24*time.Hour), false),
+		usdQuote("fallback", "EUR", "0.9", now.Add(-24*time.Hour), false),
+	}}
+	chain := service.NewRateChain(
+		service.RateSource{Provider: primary, MaxAge: time.Minute},
+		service.RateSource{Provider: fallback},
+	)
+
+	// 同一对资产使用优先级高的来源，其余由后面的来源补充
+	graph, err := chain.Build(context.Background())
+	require.NoError(t, err)
+	rate, err := graph.ExchangeRate("USDT", "CNY")
+	require.NoError(t, err)
+	assert.Equal(t, "7.1", rate.Rate.String())
+	assert.Equal(t, []string{service.RateSourcePeg, "primary"}, rate.Sources)
+	assert.True(t, rate.Live)
+	rate, err = graph.ExchangeRate("CNY", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"primary", "fallback"}, rate.Sources)
+	assert.False(t, rate.Live)
+	assert.WithinDuration(t, now.Add(-24*time.Hour), rate.UpdatedAt, time.Second, "使用路径上最早的报价时间")
+
+	// 来源失败时沿用上次的报价，标记为非实时
+	primary.quotes, primary.err = nil, errors.New("network down")
+	graph, err = chain.Build(context.Background())
+	require.NoError(t, err)
+	rate, err = graph.ExchangeRate("USD", "CNY")
+	require.NoError(t, err)
+	assert.Equal(t, "7.1", rate.Rate.String())
+	assert.False(t, rate.Live)
+
+	// 报价过期后回退到下一个来源
+	primary.quotes, primary.err = []service.RateQuote{usdQuote("primary", "CNY", "7.1", now.Add(-2*time.Minute), true)}, nil
+	graph, err = chain.Build(context.Background())
+	require.NoError(t, err)
+	rate, err = graph.ExchangeRate("USD", "CNY")
+	require.NoError(t, err)
+	assert.Equal(t, "7.0", rate.Rate.String())
+	assert.Equal(t, []string{"fallback"}, rate.Sources)
+
+	_, err = service.NewRateChain(service.RateSource{Provider: &stubRateProvider{name: "empty", err: errors.New("failed")}}).Build(context.Background())
+	assert.Error(t, err, "没有任何报价时不能构建汇率图")
+}
+
+// TestFileRateProvider 测试本地汇率文件的解析
+func TestFileRateProvider(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"base": "usd", "rates": {"CNY": "7.10", "EUR": 0.92, "USD": 1}, "pairs": {"usdt-cny": "7.15"}}`), 0o644))
+
+	quotes, err := service.NewFileRateProvider(path).Quotes(context.Background())
+	require.NoError(t, err)
+	rates := map[string]string{}
+	for _, quote := range quotes {
+		rates[quote.Base+"-"+quote.Quote] = quote.Rate.String()
+		assert.Equal(t, service.RateSourceFile, quote.Source)
+		assert.False(t, quote.Live)
+		assert.False(t, quote.UpdatedAt.IsZero(), "没有updatedAt时使用文件修改时间")
+	}
+	assert.Equal(t, map[string]string{"USD-CNY": "7.10", "USD-EUR": "0.92", "USDT-CNY": "7.15"}, rates)
+
+	// 放在最前面时手动指定的汇率优先于其他来源
+	chain := service.NewRateChain(
+		service.RateSource{Provider: service.NewFileRateProvider(path)},
+		service.RateSource{Provider: &stubRateProvider{name: "live", quotes: []service.RateQuote{
+			{Base: "USDT", Quote: "CNY", Rate: decimal.MustParse("7.2"), Source: "live", UpdatedAt: time.Now(), Live: true},
+		}}},
+	)
+	graph, err := chain.Build(context.Background())
+	require.NoError(t, err)
+	rate, _, err := graph.Rate("USDT", "CNY")
+	require.NoError(t, err)
+	assert.Equal(t, "7.15", rate.String())
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"pairs": {"USDT": "1"}}`), 0o644))
+	_, err = service.NewFileRateProvider(path).Quotes(context.Background())
+	assert.Error(t, err)
+	_, err = service.NewFileRateProvider(filepath.Join(dir, "missing.json")).Quotes(context.Background())
+	assert.Error(t, err)
+}
+
+// TestExchangeRatesEndpoint 测试汇率接口返回每个汇率的来源和时间
+func TestExchangeRatesEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	updated := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v5/market/tickers":
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": "0", "msg": "", "data": []map[string]string{
+				{"instId": "BTC-USDT", "last": "50000", "ts": "1735776000000"},
+			}})
+		case "/api/v5/market/exchange-rate":
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": "0", "msg": "", "data": []map[string]string{{"usdCny": "7.3"}}})
+		case "/fiat":
+			json.NewEncoder(w).Encode(map[string]interface{}{"base_code": "USD", "time_last_update_unix": updated.Unix(), "rates": map[string]float64{"CNY": 7, "EUR": 0.9}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	accountService := service.NewAccountServiceWithCurrencies(
+		&config.OKXConfig{BaseURL: server.URL},
+		&config.CurrencyConfig{DisplayCurrencies: []string{"USDT", "CNY", "EUR", "BTC"}, RateProviders: []string{"okx", "http"}, FiatRatesURLs: []string{server.URL + "/fiat"}},
+		nil, &stubBalanceStream{},
+	)
+
+	r := gin.New()
+	r.GET("/exchange-rates", func(c *gin.Context) {
+		api.GetExchangeRates(c, accountService)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/exchange-rates", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Data map[string]models.ExchangeRate `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data, 3)
+
+	// OKX提供的美元人民币汇率优先于http来源
+	cny := body.Data["USDT_CNY"]
+	assert.Equal(t, "7.3", cny.Rate.String())
+	assert.Equal(t, []string{service.RateSourcePeg, service.RateSourceOKX}, cny.Sources)
+	assert.True(t, cny.Live)
+
+	eur := body.Data["USDT_EUR"]
+	assert.Equal(t, "0.9", eur.Rate.String())
+	assert.Equal(t, []string{service.RateSourcePeg, service.RateSourceHTTP}, eur.Sources)
+	assert.True(t, eur.UpdatedAt.Equal(updated), "使用接口返回的更新时间")
+
+	btc := body.Data["USDT_BTC"]
+	assert.Equal(t, "0.00002", btc.Rate.String())
+	assert.True(t, btc.UpdatedAt.Equal(time.UnixMilli(1735776000000)), "使用行情时间")
+}
@@ -0,0 +1,311 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/database"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/middleware"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// permissionMatrix 各接口需要的最低角色
+var permissionMatrix = []struct {
+	method string
+	path   string
+	body   interface{}
+	role   models.Role
+}{
+	{http.MethodGet, "/api/v1/price/BTC-USDT", nil, models.RoleViewer},
+	{http.MethodGet, "/api/v1/market/candles/BTC-USDT", nil, models.RoleViewer},
+	{http.MethodGet, "/api/v1/okx/instruments", nil, models.RoleViewer},
+	{http.MethodGet, "/api/v1/okx/system-time", nil, models.RoleViewer},
+	{http.MethodGet, "/api/v1/account/currencies", nil, models.RoleViewer},
+	{http.MethodGet, "/api/v1/account/currency", nil, models.RoleViewer},
+	{http.MethodGet, "/api/v1/account/funding", nil, models.RoleViewer},
+	{http.MethodGet, "/api/v1/accounts", nil, models.RoleViewer},
+	{http.MethodGet, "/api/v1/risk/status", nil, models.RoleViewer},
+	{http.MethodGet, "/api/v1/ws/metrics", nil, models.RoleViewer},
+	{http.MethodGet, "/api/v1/settings", nil, models.RoleViewer},
+	{http.MethodGet, "/api/v1/archive/fills", nil, models.RoleViewer},
+	{http.MethodGet, "/api/v1/archive/status", nil, models.RoleViewer},
+
+	{http.MethodPost, "/api/v1/account/currency", map[string]string{"currency": "USDT"}, models.RoleOperator},
+	{http.MethodPut, "/api/v1/settings", map[string]string{"defaultCurrency": "BTC"}, models.RoleOperator},
+	{http.MethodPost, "/api/v1/accounts", nil, models.RoleOperator},
+	{http.MethodDelete, "/api/v1/accounts/1", nil, models.RoleOperator},
+	{http.MethodGet, "/api/v1/trade/orders", nil, models.RoleOperator},
+	{http.MethodGet, "/api/v1/trade/orders/history?instType=SPOT", nil, models.RoleOperator},
+	{http.MethodPost, "/api/v1/trade/orders", nil, models.RoleOperator},
+	{http.MethodPost, "/api/v1/trade/orders/batch", nil, models.RoleOperator},
+	{http.MethodPost, "/api/v1/trade/orders/cancel-batch", nil, models.RoleOperator},
+	{http.MethodPut, "/api/v1/trade/orders/1", nil, models.RoleOperator},
+	{http.MethodDelete, "/api/v1/trade/orders/1", nil, models.RoleOperator},
+
+	{http.MethodGet, "/api/v1/okx/config", nil, models.RoleAdmin},
+	{http.MethodGet, "/api/v1/admin/rate-limits", nil, models.RoleAdmin},
+	{http.MethodPost, "/api/v1/risk/kill-switch", nil, models.RoleAdmin},
+	{http.MethodPut, "/api/v1/risk/limits", nil, models.RoleAdmin},
+	{http.MethodGet, "/api/v1/users", nil, models.RoleAdmin},
+	{http.MethodPost, "/api/v1/users", nil, models.RoleAdmin},
+	{http.MethodPut, "/api/v1/users/1/role", nil, models.RoleAdmin},
+	{http.MethodPost, "/api/v1/archive/sync", nil, models.RoleAdmin},
+}
+
+// newRBACRouter 注册全部路由，OKX接口指向返回空数据的模拟服务器
+func newRBACRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"code":"0","msg":"","data":[]}`)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := newAuthConfig()
+	cfg.OKX = config.OKXConfig{BaseURL: server.URL, WSPublicURL: "ws://127.0.0.1:1", WSBusinessURL: "ws://127.0.0.1:1"}
+	r := gin.New()
+	api.SetupRoutes(r, cfg)
+	return r
+}
+
+// createUser 管理员创建指定角色的用户并登录
+func createUser(t *testing.T, r *gin.Engine, adminToken, username string, role models.Role) string {
+	w := authRequest(r, http.MethodPost, "/api/v1/users", adminToken, models.CreateUserRequest{Username: username, Password: "password-" + username, Role: role})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	return loginTokens(t, r, username, "password-"+username).AccessToken
+}
+
+// TestPermissionMatrix 测试每个角色对每个接口的访问权限
+func TestPermissionMatrix(t *testing.T) {
+	r := newRBACRouter(t)
+	adminToken := loginTokens(t, r, "admin", "test-password").AccessToken
+	tokens := map[models.Role]string{
+		models.RoleViewer:   createUser(t, r, adminToken, "viewer", models.RoleViewer),
+		models.RoleOperator: createUser(t, r, adminToken, "operator", models.RoleOperator),
+		models.RoleAdmin:    adminToken,
+	}
+
+	for _, entry := range permissionMatrix {
+		name := entry.method + " " + entry.path
+		w := authRequest(r, entry.method, entry.path, "", entry.body)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "%s 未登录", name)
+
+		for role, token := range tokens {
+			w := authRequest(r, entry.method, entry.path, token, entry.body)
+			if role.Allows(entry.role) {
+				assert.NotContains(t, []int{http.StatusUnauthorized, http.StatusForbidden}, w.Code, "%s 角色 %s: %s", name, role, w.Body.String())
+				assert.NotContains(t, w.Body.String(), "404 page not found", "%s 未注册", name)
+			} else {
+				assert.Equal(t, http.StatusForbidden, w.Code, "%s 角色 %s", name, role)
+			}
+		}
+	}
+
+	// 健康检查、登录和刷新令牌不需要登录
+	w := authRequest(r, http.MethodGet, "/api/v1/ping", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// TestUserRoles 测试用户管理和角色修改
+func TestUserRoles(t *testing.T) {
+	r := newRBACRouter(t)
+	adminToken := loginTokens(t, r, "admin", "test-password").AccessToken
+	token := createUser(t, r, adminToken, "trader", models.RoleViewer)
+
+	w := authRequest(r, http.MethodGet, "/api/v1/auth/me", token, nil)
+	assert.Contains(t, w.Body.String(), `"role":"viewer"`)
+	w = authRequest(r, http.MethodPost, "/api/v1/account/currency", token, map[string]string{"currency": "USDT"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 修改角色后已签发的令牌立即生效
+	w = authRequest(r, http.MethodPut, "/api/v1/users/2/role", adminToken, models.UpdateUserRoleRequest{Role: models.RoleOperator})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = authRequest(r, http.MethodPost, "/api/v1/account/currency", token, map[string]string{"currency": "USDT"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = authRequest(r, http.MethodGet, "/api/v1/users", adminToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":"trader","role":"operator"`)
+	assert.NotContains(t, w.Body.String(), "password")
+
+	w = authRequest(r, http.MethodPost, "/api/v1/users", adminToken, models.CreateUserRequest{Username: "trader", Password: "password-trader", Role: models.RoleViewer})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = authRequest(r, http.MethodPost, "/api/v1/users", adminToken, models.CreateUserRequest{Username: "guest", Password: "password-guest", Role: "guest"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = authRequest(r, http.MethodPost, "/api/v1/users", adminToken, models.CreateUserRequest{Username: "short", Password: "short", Role: models.RoleViewer})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = authRequest(r, http.MethodPut, "/api/v1/users/99/role", adminToken, models.UpdateUserRoleRequest{Role: models.RoleViewer})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 不能降级最后一个管理员
+	w = authRequest(r, http.MethodPut, "/api/v1/users/1/role", adminToken, models.UpdateUserRoleRequest{Role: models.RoleViewer})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = authRequest(r, http.MethodPut, "/api/v1/users/2/role", adminToken, models.UpdateUserRoleRequest{Role: models.RoleAdmin})
+	require.Equal(t, http.StatusOK, w.Code)
+	w = authRequest(r, http.MethodPut, "/api/v1/users/1/role", token, models.UpdateUserRoleRequest{Role: models.RoleViewer})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = authRequest(r, http.MethodGet, "/api/v1/okx/config", adminToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// TestConcurrentAdminDemotion 测试两个管理员同时被降级时至少保留一个管理员
+func TestConcurrentAdminDemotion(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "users.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	auth := service.NewAuthService("test-secret", &config.AuthConfig{}, repository.NewUserRepository(db))
+	ctx := context.Background()
+	first, err := auth.CreateUser(ctx, "admin", "test-password", models.RoleAdmin)
+	require.NoError(t, err)
+	second, err := auth.CreateUser(ctx, "other-admin", "test-password", models.RoleAdmin)
+	require.NoError(t, err)
+
+	errs := make([]error, 2)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, id := range []int64{first.ID, second.ID} {
+		wg.Add(1)
+		go func(i int, id int64) {
+			defer wg.Done()
+			<-start
+			_, errs[i] = auth.SetUserRole(ctx, id, models.RoleViewer)
+		}(i, id)
+	}
+	close(start)
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, service.ErrLastAdmin)
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed)
+
+	users, err := auth.ListUsers(ctx)
+	require.NoError(t, err)
+	admins := 0
+	for _, user := range users {
+		if user.Role == models.RoleAdmin {
+			admins++
+		}
+	}
+	assert.Equal(t, 1, admins)
+}
+
+// roleAuthenticator 以令牌内容作为角色的测试认证器
+type roleAuthenticator struct{}
+
+func (roleAuthenticator) Authenticate(ctx context.Context, accessToken string) (*jwt.Claims, error) {
+	return &jwt.Claims{Subject: "1", Role: accessToken}, nil
+}
+
+// TestWebSocketTopicRoles 测试订阅频道时校验角色
+func TestWebSocketTopicRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager := api.NewWebSocketManagerWithService(newStubPriceService(), config.WebSocketConfig{})
+
+	r := gin.New()
+	r.GET("/ws/price", middleware.Auth(roleAuthenticator{}), manager.HandleWebSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/price?token="
+
+	viewer := dialPriceWS(t, url+string(models.RoleViewer))
+	subscribePrice(t, viewer, "subscribe", "ETH-USDT")
+	assert.Equal(t, "subscribe", readPriceWS(t, viewer).Event)
+
+	guest := dialPriceWS(t, url+"guest")
+	subscribePrice(t, guest, "subscribe", "ETH-USDT")
+	message := readPriceWS(t, guest)
+	assert.Equal(t, "error", message.Event)
+	assert.Equal(t, "forbidden", message.Code)
+}
+
+// emittingAccountStream 快照包含一个挂单，由测试主动推送增量的账户推送
+type emittingAccountStream struct {
+	service.AccountStream
+	mutex     sync.Mutex
+	listeners []func(*models.AccountStreamMessage)
+}
+
+func (s *emittingAccountStream) AddListener(listener func(*models.AccountStreamMessage)) func() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.listeners = append(s.listeners, listener)
+	return func() {}
+}
+
+func (s *emittingAccountStream) Snapshot() *models.AccountStreamMessage {
+	return &models.AccountStreamMessage{
+		Type: "snapshot",
+		Data: &service.AccountStreamState{Orders: []models.Order{{OrdId: "1"}}},
+	}
+}
+
+func (s *emittingAccountStream) emit(channel string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, listener := range s.listeners {
+		listener(&models.AccountStreamMessage{Type: "delta", Channel: channel})
+	}
+}
+
+// TestAccountWebSocketChannelRoles 测试账户推送的订单频道只推送给operator，viewer只能收到余额和持仓
+func TestAccountWebSocketChannelRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stream := &emittingAccountStream{}
+
+	r := gin.New()
+	r.GET("/ws/account", middleware.Auth(roleAuthenticator{}), func(c *gin.Context) {
+		api.HandleAccountWebSocket(c, stream, config.WebSocketConfig{})
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/account?token="
+
+	read := func(conn *websocket.Conn) map[string]interface{} {
+		var message map[string]interface{}
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+		require.NoError(t, conn.ReadJSON(&message))
+		return message
+	}
+	orders := func(message map[string]interface{}) []interface{} {
+		orders, _ := message["data"].(map[string]interface{})["orders"].([]interface{})
+		return orders
+	}
+
+	viewer := dialPriceWS(t, url+string(models.RoleViewer))
+	snapshot := read(viewer)
+	assert.Equal(t, "snapshot", snapshot["type"])
+	assert.Empty(t, orders(snapshot))
+
+	operator := dialPriceWS(t, url+string(models.RoleOperator))
+	snapshot = read(operator)
+	assert.Equal(t, "snapshot", snapshot["type"])
+	assert.Len(t, orders(snapshot), 1)
+
+	stream.emit("orders")
+	stream.emit("positions")
+
+	assert.Equal(t, "positions", read(viewer)["channel"])
+	assert.Equal(t, "orders", read(operator)["channel"])
+	assert.Equal(t, "positions", read(operator)["channel"])
+}
@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/cardchoosen/AlphaArk_Gin/pkg/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAccountService 返回固定账户状态的账户服务
+type stubAccountService struct {
+	service.AccountService
+	positions []*models.Position
+	history   []*models.PositionHistory
+	equity    decimal.Decimal
+	err       error
+}
+
+func (s *stubAccountService) GetAccountBalance(ctx context.Context, currency models.Currency) (*models.AccountBalance, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &models.AccountBalance{TotalEquity: s.equity, Currency: currency}, nil
+}
+
+func (s *stubAccountService) GetPositions(ctx context.Context, req *models.PositionsRequest, currency models.Currency) (*models.PositionsResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &models.PositionsResponse{Positions: s.positions, Currency: currency}, nil
+}
+
+func (s *stubAccountService) GetPositionsHistory(ctx context.Context, req *models.PositionsHistoryRequest, currency models.Currency) (*models.PositionsHistoryResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &models.PositionsHistoryResponse{Positions: s.history, Currency: currency}, nil
+}
+
+func (s *stubAccountService) AllPositionsHistory(ctx context.Context, req *models.PositionsHistoryRequest, currency models.Currency) iter.Seq2[*models.PositionHistory, error] {
+	return func(yield func(*models.PositionHistory, error) bool) {
+		if s.err != nil {
+			yield(nil, s.err)
+			return
+		}
+		for _, pos := range s.history {
+			if pos.UpdateTime.UnixMilli() >= req.From && !yield(pos, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (s *stubAccountService) GetFunding(ctx context.Context, req *models.FundingRequest, currency models.Currency) (*models.FundingResponse, error) {
+	return nil, nil
+}
+
+func newTestRiskService(account *stubAccountService) service.RiskService {
+	return service.NewRiskService(&config.RiskConfig{
+		MaxNotionalPerInstrument: 10000,
+		MaxLeverage:              3,
+		MaxOpenOrders:            5,
+		DailyLossLimit:           500,
+	}, account, nil)
+}
+
+func rules(result *models.RiskCheckResult) []string {
+	var names []string
+	for _, violation := range result.Violations {
+		names = append(names, violation.Rule)
+	}
+	return names
+}
+
+// TestRiskServicePassesWithinLimits 测试限额内的订单通过
+func TestRiskServicePassesWithinLimits(t *testing.T) {
+	account := &stubAccountService{
+		equity:    decimal.MustParse("10000"),
+		positions: []*models.Position{{InstId: "BTC-USDT-SWAP", NotionalUsd: "4000", Lever: "3"}},
+	}
+	risk := newTestRiskService(account)
+
+	order := models.OrderRequest{InstId: "BTC-USDT-SWAP", Side: "buy", Sz: "1"}
+	result := risk.CheckOrders(context.Background(), []models.OrderRequest{order}, []float64{5000}, 2)
+	assert.True(t, result.Passed, "%+v", result.Violations)
+}
+
+// TestRiskServiceRejections 测试各项风控规则
+func TestRiskServiceRejections(t *testing.T) {
+	account := &stubAccountService{
+		equity: decimal.MustParse("2000"),
+		positions: []*models.Position{
+			{InstId: "BTC-USDT-SWAP", NotionalUsd: "-8000", Lever: "10"},
+		},
+		history: []*models.PositionHistory{
+			{InstId: "ETH-USDT-SWAP", RealizedPnl: "-600", Ccy: "USDT", UpdateTime: time.Now()},
+			{InstId: "ETH-USDT-SWAP", RealizedPnl: "-900", Ccy: "USDT", UpdateTime: time.Now().Add(-48 * time.Hour)},
+		},
+	}
+	risk := newTestRiskService(account)
+
+	order := models.OrderRequest{InstId: "BTC-USDT-SWAP", Side: "buy", Sz: "1"}
+	result := risk.CheckOrders(context.Background(), []models.OrderRequest{order}, []float64{3000}, 5)
+
+	assert.False(t, result.Passed)
+	assert.ElementsMatch(t, []string{
+		models.RiskRuleMaxOpenOrders,
+		models.RiskRuleMaxNotional,
+		models.RiskRuleMaxLeverage, // 持仓杠杆
+		models.RiskRuleMaxLeverage, // 账户整体杠杆
+		models.RiskRuleDailyLossLimit,
+	}, rules(result))
+
+	// 只减仓订单不受限额约束
+	reduce := models.OrderRequest{InstId: "BTC-USDT-SWAP", Side: "sell", Sz: "1", ReduceOnly: true}
+	result = risk.CheckOrders(context.Background(), []models.OrderRequest{reduce}, []float64{3000}, 5)
+	assert.True(t, result.Passed)
+}
+
+// TestRiskServiceCheckAmend 测试改单与新订单执行相同的限额检查，但不计入挂单数量
+func TestRiskServiceCheckAmend(t *testing.T) {
+	account := &stubAccountService{
+		equity: decimal.MustParse("2000"),
+		positions: []*models.Position{
+			{InstId: "BTC-USDT-SWAP", NotionalUsd: "-8000", Lever: "10"},
+		},
+		history: []*models.PositionHistory{
+			{InstId: "ETH-USDT-SWAP", RealizedPnl: "-600", Ccy: "USDT", UpdateTime: time.Now()},
+		},
+	}
+	risk := newTestRiskService(account)
+
+	order := &models.OrderRequest{InstId: "BTC-USDT-SWAP", Side: "buy", Sz: "1", Px: "3000"}
+	result := risk.CheckAmend(context.Background(), order, 3000)
+	assert.ElementsMatch(t, []string{
+		models.RiskRuleMaxNotional,
+		models.RiskRuleMaxLeverage, // 持仓杠杆
+		models.RiskRuleMaxLeverage, // 账户整体杠杆
+		models.RiskRuleDailyLossLimit,
+	}, rules(result))
+
+	// 只减仓订单改单不受限额约束
+	order.ReduceOnly = true
+	result = risk.CheckAmend(context.Background(), order, 3000)
+	assert.True(t, result.Passed)
+}
+
+// TestRiskServiceKillSwitch 测试交易熔断
+func TestRiskServiceKillSwitch(t *testing.T) {
+	risk := newTestRiskService(&stubAccountService{equity: decimal.MustParse("10000")})
+
+	risk.SetKillSwitch(true, "测试熔断")
+	status := risk.GetStatus()
+	assert.True(t, status.KillSwitch)
+	require.NotNil(t, status.KillSwitchTime)
+
+	order := models.OrderRequest{InstId: "BTC-USDT", Side: "buy", Sz: "1", ReduceOnly: true}
+	result := risk.CheckOrders(context.Background(), []models.OrderRequest{order}, []float64{100}, 0)
+	assert.Equal(t, []string{models.RiskRuleKillSwitch}, rules(result))
+
+	amend := risk.CheckAmend(context.Background(), &models.OrderRequest{InstId: "BTC-USDT", Side: "buy", Sz: "1"}, 100)
+	assert.False(t, amend.Passed)
+
+	risk.SetKillSwitch(false, "")
+	result = risk.CheckOrders(context.Background(), []models.OrderRequest{order}, []float64{100}, 0)
+	assert.True(t, result.Passed)
+}
+
+// TestRiskServiceFailsClosed 测试账户状态不可用时拒绝下单
+func TestRiskServiceFailsClosed(t *testing.T) {
+	risk := newTestRiskService(&stubAccountService{err: errors.New("network down")})
+
+	order := models.OrderRequest{InstId: "BTC-USDT", Side: "buy", Sz: "1"}
+	result := risk.CheckOrders(context.Background(), []models.OrderRequest{order}, []float64{100}, 0)
+	assert.Equal(t, []string{models.RiskRuleStateUnavailable}, rules(result))
+
+	assert.Error(t, risk.SetLimits(models.RiskLimits{MaxLeverage: -1}))
+}
+
+// TestRiskServiceDailyLossCapFailsClosed 测试当日平仓记录超过统计上限时拒绝下单，而不是只统计部分亏损
+func TestRiskServiceDailyLossCapFailsClosed(t *testing.T) {
+	account := &stubAccountService{equity: decimal.MustParse("10000")}
+	for i := 0; i < 1000; i++ {
+		account.history = append(account.history, &models.PositionHistory{
+			InstId: "BTC-USDT-SWAP", RealizedPnl: "0", Ccy: "USDT", UpdateTime: time.Now(),
+		})
+	}
+	risk := newTestRiskService(account)
+
+	order := models.OrderRequest{InstId: "BTC-USDT", Side: "buy", Sz: "1"}
+	result := risk.CheckOrders(context.Background(), []models.OrderRequest{order}, []float64{100}, 0)
+	assert.True(t, result.Passed, "%+v", result.Violations)
+
+	account.history = append(account.history, &models.PositionHistory{
+		InstId: "BTC-USDT-SWAP", RealizedPnl: "-600", Ccy: "USDT", UpdateTime: time.Now(),
+	})
+	result = risk.CheckOrders(context.Background(), []models.OrderRequest{order}, []float64{100}, 0)
+	assert.Equal(t, []string{models.RiskRuleStateUnavailable}, rules(result))
+}
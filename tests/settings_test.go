@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/database"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/repository"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSettingsService 测试用户设置的默认值、部分修改和校验
+func TestSettingsService(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "settings.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewSettingsRepository(db)
+	settingsService := service.NewSettingsService(repo, &config.CurrencyConfig{})
+	ctx := context.Background()
+
+	settings, err := settingsService.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.CurrencyUSDT, settings.DefaultCurrency)
+	assert.Empty(t, settings.FavoriteInstruments)
+	assert.JSONEq(t, `{}`, string(settings.DashboardLayout))
+
+	currency := "btc"
+	favorites := []string{"btc-usdt", " ETH-USDT ", "BTC-USDT", ""}
+	settings, err = settingsService.Update(ctx, 1, &models.UpdateSettingsRequest{
+		DefaultCurrency:     &currency,
+		FavoriteInstruments: &favorites,
+		DashboardLayout:     json.RawMessage(`{"widgets": ["balance", "positions"]}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.CurrencyBTC, settings.DefaultCurrency)
+	assert.Equal(t, []string{"BTC-USDT", "ETH-USDT"}, settings.FavoriteInstruments)
+	assert.Equal(t, `{"widgets":["balance","positions"]}`, string(settings.DashboardLayout))
+
+	// 只修改请求中出现的字段
+	settings, err = settingsService.Update(ctx, 1, &models.UpdateSettingsRequest{AlertPreferences: json.RawMessage(`{"priceChange": 5}`)})
+	require.NoError(t, err)
+	assert.Equal(t, models.CurrencyBTC, settings.DefaultCurrency)
+	assert.Equal(t, []string{"BTC-USDT", "ETH-USDT"}, settings.FavoriteInstruments)
+	assert.JSONEq(t, `{"priceChange": 5}`, string(settings.AlertPreferences))
+
+	// null 恢复为空对象
+	settings, err = settingsService.Update(ctx, 1, &models.UpdateSettingsRequest{DashboardLayout: json.RawMessage(`null`)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(settings.DashboardLayout))
+
+	// 无效值不保存
+	invalid := "DOGE"
+	_, err = settingsService.Update(ctx, 1, &models.UpdateSettingsRequest{DefaultCurrency: &invalid})
+	assert.ErrorIs(t, err, service.ErrInvalidSettings)
+	_, err = settingsService.Update(ctx, 1, &models.UpdateSettingsRequest{AlertPreferences: json.RawMessage(`[1, 2]`)})
+	assert.ErrorIs(t, err, service.ErrInvalidSettings)
+	assert.Equal(t, models.CurrencyBTC, settingsService.DefaultCurrency(ctx, 1))
+
+	// 其他用户不受影响
+	assert.Equal(t, models.CurrencyUSDT, settingsService.DefaultCurrency(ctx, 2))
+
+	// 显示币种配置不再包含已保存的币种时使用系统默认币种
+	eurOnly := service.NewSettingsService(repo, &config.CurrencyConfig{DisplayCurrencies: []string{"EUR", "CNY"}})
+	assert.Equal(t, models.CurrencyEUR, eurOnly.DefaultCurrency(ctx, 1))
+}
+
+// TestSettingsConcurrentUpdates 测试并发修改不同字段时不会互相覆盖
+func TestSettingsConcurrentUpdates(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "settings.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	settingsService := service.NewSettingsService(repository.NewSettingsRepository(db), &config.CurrencyConfig{})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			currency := "CNY"
+			_, err := settingsService.Update(ctx, 1, &models.UpdateSettingsRequest{DefaultCurrency: &currency})
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			favorites := []string{"SOL-USDT"}
+			_, err := settingsService.Update(ctx, 1, &models.UpdateSettingsRequest{FavoriteInstruments: &favorites})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	settings, err := settingsService.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.CurrencyCNY, settings.DefaultCurrency)
+	assert.Equal(t, []string{"SOL-USDT"}, settings.FavoriteInstruments)
+}
+
+// TestSettingsRoutes 测试每个用户的默认币种互不影响，并在重启后保留
+func TestSettingsRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "alphaark.db")
+	newRouter := func() *gin.Engine {
+		cfg := newAuthConfig()
+		cfg.SQLitePath = path
+		r := gin.New()
+		api.SetupAuthRoutes(r, cfg)
+		api.SetupUserRoutes(r, cfg)
+		api.SetupAccountRoutes(r, cfg)
+		api.SetupSettingsRoutes(r, cfg)
+		return r
+	}
+
+	r := newRouter()
+	adminToken := loginTokens(t, r, "admin", "test-password").AccessToken
+	viewerToken := createUser(t, r, adminToken, "viewer", models.RoleViewer)
+	operatorToken := createUser(t, r, adminToken, "operator", models.RoleOperator)
+
+	w := authRequest(r, http.MethodGet, "/api/v1/settings", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = authRequest(r, http.MethodPost, "/api/v1/account/currency", viewerToken, map[string]string{"currency": "BTC"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = authRequest(r, http.MethodPost, "/api/v1/account/currency", operatorToken, map[string]string{"currency": "BTC"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = authRequest(r, http.MethodPost, "/api/v1/account/currency", operatorToken, map[string]string{"currency": "DOGE"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = authRequest(r, http.MethodGet, "/api/v1/account/currency", operatorToken, nil)
+	assert.Contains(t, w.Body.String(), `"currency":"BTC"`)
+	w = authRequest(r, http.MethodGet, "/api/v1/account/currency", viewerToken, nil)
+	assert.Contains(t, w.Body.String(), `"currency":"USDT"`)
+	w = authRequest(r, http.MethodGet, "/api/v1/account/currency", adminToken, nil)
+	assert.Contains(t, w.Body.String(), `"currency":"USDT"`, "其他用户的默认币种不变")
+
+	w = authRequest(r, http.MethodPut, "/api/v1/settings", adminToken, map[string]interface{}{
+		"defaultCurrency":     "CNY",
+		"favoriteInstruments": []string{"BTC-USDT"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = authRequest(r, http.MethodPut, "/api/v1/settings", adminToken, map[string]interface{}{"dashboardLayout": "grid"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 重启后设置仍然有效
+	r = newRouter()
+	w = authRequest(r, http.MethodGet, "/api/v1/settings", adminToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data models.UserSettings `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, models.CurrencyCNY, body.Data.DefaultCurrency)
+	assert.Equal(t, []string{"BTC-USDT"}, body.Data.FavoriteInstruments)
+
+	w = authRequest(r, http.MethodGet, "/api/v1/account/currency", operatorToken, nil)
+	assert.Contains(t, w.Body.String(), `"currency":"BTC"`)
+}
@@ -0,0 +1,305 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeOKXServer 创建模拟OKX交易接口的测试服务器，只有一个挂单 1001
+func newFakeOKXServer(t *testing.T, orders *[]models.OrderRequest) *httptest.Server {
+	return newFakeOKXServerWithPending(t, orders, 1)
+}
+
+// newFakeOKXServerWithPending 创建模拟OKX交易接口的测试服务器，挂单ID从 1001 开始共 pending 个，按 after 和 limit 分页
+func newFakeOKXServerWithPending(t *testing.T, orders *[]models.OrderRequest, pending int) *httptest.Server {
+	mux := http.NewServeMux()
+	pendingOrder := func(ordId int) string {
+		return fmt.Sprintf(`{"instType":"SWAP","instId":"BTC-USDT-SWAP","ordId":"%d","px":"43000","sz":"0.02","ordType":"limit","side":"buy","tdMode":"cross","state":"live","reduceOnly":"false"}`, ordId)
+	}
+
+	mux.HandleFunc("/api/v5/public/time", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"ts":"1700000000000"}]}`)
+	})
+
+	mux.HandleFunc("/api/v5/public/instruments", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("instType") {
+		case "SWAP":
+			io.WriteString(w, `{"code":"0","msg":"","data":[{"instType":"SWAP","instId":"BTC-USDT-SWAP","tickSz":"0.1","lotSz":"0.01","minSz":"0.01","state":"live"}]}`)
+		case "OPTION":
+			io.WriteString(w, `{"code":"0","msg":"","data":[{"instType":"OPTION","instId":"BTC-USD-250328-50000-C","uly":"BTC-USD","ctVal":"0.01","ctMult":"1","ctValCcy":"BTC","tickSz":"0.0005","lotSz":"1","minSz":"1","state":"live"}]}`)
+		default:
+			t.Errorf("unexpected instType %s", r.URL.Query().Get("instType"))
+		}
+	})
+
+	mux.HandleFunc("/api/v5/market/index-tickers", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "BTC-USD", r.URL.Query().Get("instId"))
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"instId":"BTC-USD","idxPx":"50000","ts":"1700000000000"}]}`)
+	})
+
+	mux.HandleFunc("/api/v5/trade/order", func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get("OK-ACCESS-SIGN"))
+		if r.Method == http.MethodGet {
+			ordId, _ := strconv.Atoi(r.URL.Query().Get("ordId"))
+			if ordId < 1001 || ordId >= 1001+pending {
+				io.WriteString(w, `{"code":"51603","msg":"Order does not exist","data":[]}`)
+				return
+			}
+			io.WriteString(w, `{"code":"0","msg":"","data":[`+pendingOrder(ordId)+`]}`)
+			return
+		}
+
+		var order models.OrderRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&order))
+		*orders = append(*orders, order)
+
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"ordId":"1001","clOrdId":"","sCode":"0","sMsg":"Order placed"}]}`)
+	})
+
+	mux.HandleFunc("/api/v5/trade/orders-pending", func(w http.ResponseWriter, r *http.Request) {
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit <= 0 || limit > 100 {
+			limit = 100
+		}
+		after := 1001 + pending
+		if value := r.URL.Query().Get("after"); value != "" {
+			after, _ = strconv.Atoi(value)
+		}
+
+		var data []string
+		for ordId := after - 1; ordId >= 1001 && len(data) < limit; ordId-- {
+			data = append(data, pendingOrder(ordId))
+		}
+		io.WriteString(w, `{"code":"0","msg":"","data":[`+strings.Join(data, ",")+`]}`)
+	})
+
+	mux.HandleFunc("/api/v5/trade/amend-order", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+
+		var amend models.AmendOrderRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&amend))
+		*orders = append(*orders, models.OrderRequest{InstId: amend.InstId, Sz: amend.NewSz, Px: amend.NewPx})
+
+		io.WriteString(w, `{"code":"0","msg":"","data":[{"ordId":"1001","clOrdId":"","sCode":"0","sMsg":""}]}`)
+	})
+
+	mux.HandleFunc("/api/v5/account/positions", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"code":"0","msg":"","data":[]}`)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestValidateOrderPrecision 测试下单精度校验
+func TestValidateOrderPrecision(t *testing.T) {
+	inst := &api.Instrument{
+		InstType: "SWAP",
+		InstID:   "BTC-USDT-SWAP",
+		TickSz:   "0.1",
+		LotSz:    "0.01",
+		MinSz:    "0.01",
+		State:    "live",
+	}
+
+	tests := []struct {
+		name    string
+		order   models.OrderRequest
+		wantErr bool
+	}{
+		{"合法限价单", models.OrderRequest{OrdType: "limit", Side: "buy", Sz: "0.05", Px: "43000.1"}, false},
+		{"合法市价单", models.OrderRequest{OrdType: "market", Side: "sell", Sz: "1"}, false},
+		{"数量小于最小下单量", models.OrderRequest{OrdType: "market", Side: "buy", Sz: "0.001"}, true},
+		{"数量不符合精度", models.OrderRequest{OrdType: "market", Side: "buy", Sz: "0.015"}, true},
+		{"价格不符合精度", models.OrderRequest{OrdType: "limit", Side: "buy", Sz: "0.01", Px: "43000.15"}, true},
+		{"限价单缺少价格", models.OrderRequest{OrdType: "limit", Side: "buy", Sz: "0.01"}, true},
+		{"无效数量", models.OrderRequest{OrdType: "market", Side: "buy", Sz: "-1"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := api.ValidateOrderPrecision(&tt.order, inst)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	// 现货市价买单按计价币种下单，不受lotSz约束
+	spot := &api.Instrument{InstType: "SPOT", InstID: "BTC-USDT", TickSz: "0.1", LotSz: "0.00000001", MinSz: "0.00001"}
+	assert.NoError(t, api.ValidateOrderPrecision(&models.OrderRequest{OrdType: "market", Side: "buy", Sz: "100.5"}, spot))
+}
+
+// TestInstTypeFromInstID 测试根据产品ID推断产品类型
+func TestInstTypeFromInstID(t *testing.T) {
+	assert.Equal(t, "SPOT", api.InstTypeFromInstID("BTC-USDT"))
+	assert.Equal(t, "SWAP", api.InstTypeFromInstID("BTC-USDT-SWAP"))
+	assert.Equal(t, "FUTURES", api.InstTypeFromInstID("BTC-USD-250328"))
+	assert.Equal(t, "OPTION", api.InstTypeFromInstID("BTC-USD-250328-50000-C"))
+}
+
+// TestPlaceOrderRoute 测试下单接口
+func TestPlaceOrderRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var received []models.OrderRequest
+	server := newFakeOKXServer(t, &received)
+
+	cfg := newAuthConfig()
+	cfg.OKX = config.OKXConfig{
+		APIKey:     "test-api-key",
+		SecretKey:  "test-secret-key",
+		Passphrase: "test-passphrase",
+		BaseURL:    server.URL,
+	}
+
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+	api.SetupTradeRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/api/v1/trade/orders", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"instId":"BTC-USDT-SWAP","tdMode":"cross","side":"buy","ordType":"limit","sz":"0.02","px":"43000.5"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, received, 1)
+	assert.Equal(t, "43000.5", received[0].Px)
+
+	// 精度不合法的订单不会发送到OKX
+	w = post(`{"instId":"BTC-USDT-SWAP","tdMode":"cross","side":"buy","ordType":"limit","sz":"0.025","px":"43000.5"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Len(t, received, 1)
+
+	w = post(`{"instId":"BTC-USDT-SWAP","tdMode":"cross","side":"hold","ordType":"limit","sz":"0.02","px":"43000.5"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestOpenOrdersPagination 测试挂单数量检查会翻页统计超过一页的挂单
+func TestOpenOrdersPagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var received []models.OrderRequest
+	server := newFakeOKXServerWithPending(t, &received, 150)
+
+	cfg := newAuthConfig()
+	cfg.OKX = config.OKXConfig{
+		APIKey:     "test-pagination-key",
+		SecretKey:  "test-secret-key",
+		Passphrase: "test-passphrase",
+		BaseURL:    server.URL,
+	}
+	cfg.Risk.MaxOpenOrders = 120
+
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+	api.SetupTradeRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
+
+	// 第一页只有100个挂单，需要继续翻页才能发现已超过上限
+	order := map[string]string{"instId": "BTC-USDT-SWAP", "tdMode": "cross", "side": "buy", "ordType": "limit", "sz": "0.02", "px": "43000"}
+	w := authRequest(r, http.MethodPost, "/api/v1/trade/orders", token, order)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), models.RiskRuleMaxOpenOrders)
+	assert.Empty(t, received)
+
+	// 第二页中的挂单可以按订单ID直接查询并修改
+	w = authRequest(r, http.MethodPut, "/api/v1/trade/orders/1010", token, map[string]string{"instId": "BTC-USDT-SWAP", "newPx": "43100"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Len(t, received, 1)
+}
+
+// TestOptionOrderNotional 测试期权按标的指数价格和合约乘数检查名义价值上限
+func TestOptionOrderNotional(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var received []models.OrderRequest
+	server := newFakeOKXServer(t, &received)
+
+	cfg := newAuthConfig()
+	cfg.OKX = config.OKXConfig{
+		APIKey:     "test-option-key",
+		SecretKey:  "test-secret-key",
+		Passphrase: "test-passphrase",
+		BaseURL:    server.URL,
+	}
+	cfg.Risk.MaxNotionalPerInstrument = 1000
+
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+	api.SetupTradeRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
+
+	// 3 × 0.01 × 1 × 50000 = 1500 超过上限，期权费 0.05 不参与计算
+	order := map[string]string{"instId": "BTC-USD-250328-50000-C", "tdMode": "cross", "side": "buy", "ordType": "limit", "sz": "3", "px": "0.05"}
+	w := authRequest(r, http.MethodPost, "/api/v1/trade/orders", token, order)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), models.RiskRuleMaxNotional)
+	assert.Empty(t, received)
+
+	order["sz"] = "1"
+	w = authRequest(r, http.MethodPost, "/api/v1/trade/orders", token, order)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Len(t, received, 1)
+}
+
+// TestAmendOrderRoute 测试改单按原挂单数量和新价格检查名义价值上限
+func TestAmendOrderRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var received []models.OrderRequest
+	server := newFakeOKXServer(t, &received)
+
+	cfg := newAuthConfig()
+	cfg.OKX = config.OKXConfig{
+		APIKey:     "test-amend-key",
+		SecretKey:  "test-secret-key",
+		Passphrase: "test-passphrase",
+		BaseURL:    server.URL,
+	}
+	cfg.Risk.MaxNotionalPerInstrument = 1000
+
+	r := gin.New()
+	api.SetupAuthRoutes(r, cfg)
+	api.SetupTradeRoutes(r, cfg)
+	token := loginTokens(t, r, "admin", "test-password").AccessToken
+
+	// 只改价格：0.02 × 60000 = 1200 超过上限
+	w := authRequest(r, http.MethodPut, "/api/v1/trade/orders/1001", token, map[string]string{"instId": "BTC-USDT-SWAP", "newPx": "60000"})
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), models.RiskRuleMaxNotional)
+	assert.Empty(t, received)
+
+	w = authRequest(r, http.MethodPut, "/api/v1/trade/orders/1001", token, map[string]string{"instId": "BTC-USDT-SWAP", "newPx": "45000"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, received, 1)
+	assert.Equal(t, "45000", received[0].Px)
+
+	w = authRequest(r, http.MethodPut, "/api/v1/trade/orders/2002", token, map[string]string{"instId": "BTC-USDT-SWAP", "newSz": "0.01"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Len(t, received, 1)
+}
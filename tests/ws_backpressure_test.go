@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cardchoosen/AlphaArk_Gin/internal/api"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/config"
+	"github.com/cardchoosen/AlphaArk_Gin/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPriceWSServer(t *testing.T, prices *stubPriceService, wsCfg config.WebSocketConfig) (string, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	manager := api.NewWebSocketManagerWithService(prices, wsCfg)
+
+	r := gin.New()
+	r.GET("/ws/price", manager.HandleWebSocket)
+	r.GET("/api/v1/ws/metrics", api.GetWebSocketMetrics)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/price", r
+}
+
+func priceWSMetrics(t *testing.T, r *gin.Engine) models.WebSocketMetrics {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/ws/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data []models.WebSocketMetrics `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	for _, metrics := range resp.Data {
+		if metrics.Endpoint == "price" {
+			return metrics
+		}
+	}
+	t.Fatal("缺少price端点统计")
+	return models.WebSocketMetrics{}
+}
+
+// TestPriceWebSocketSlowConsumer 测试发送队列满时断开慢消费者并记录丢弃数
+func TestPriceWebSocketSlowConsumer(t *testing.T) {
+	prices := newStubPriceService()
+	url, r := newPriceWSServer(t, prices, config.WebSocketConfig{
+		SendQueueSize:      1,
+		SlowConsumerPolicy: "disconnect",
+	})
+	before := priceWSMetrics(t, r)
+
+	conn := dialPriceWS(t, url)
+	subscribePrice(t, conn, "subscribe", "SOL-USDT")
+	assert.Equal(t, "subscribe", readPriceWS(t, conn).Event)
+
+	for i := 0; i < 5000; i++ {
+		prices.emit("SOL-USDT", fmt.Sprint(i))
+	}
+
+	// 客户端最终收到 1013 关闭帧
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var err error
+	for err == nil {
+		_, _, err = conn.ReadMessage()
+	}
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseTryAgainLater), "%v", err)
+
+	after := priceWSMetrics(t, r)
+	assert.Greater(t, after.MessagesDropped, before.MessagesDropped)
+	assert.Greater(t, after.SlowConsumerDisconnects, before.SlowConsumerDisconnects)
+}
+
+// TestPriceWebSocketPongTimeout 测试客户端不响应ping时断开连接
+func TestPriceWebSocketPongTimeout(t *testing.T) {
+	prices := newStubPriceService()
+	url, r := newPriceWSServer(t, prices, config.WebSocketConfig{PingInterval: 1, PongTimeout: 1})
+	before := priceWSMetrics(t, r)
+
+	conn := dialPriceWS(t, url)
+	// 不读取消息，gorilla客户端不会自动回复pong
+	require.Eventually(t, func() bool {
+		return priceWSMetrics(t, r).PongTimeouts > before.PongTimeouts
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// 正常响应pong的客户端保持连接
+	alive := dialPriceWS(t, url)
+	require.NoError(t, alive.SetReadDeadline(time.Now().Add(2500*time.Millisecond)))
+	_, _, err := alive.ReadMessage()
+	var netErr interface{ Timeout() bool }
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout(), "连接应保持到读取超时而不是被服务端关闭")
+
+	conn.Close()
+}
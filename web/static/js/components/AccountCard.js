@@ -36,7 +36,7 @@ class AccountCard {
 
     async loadSupportedCurrencies() {
         try {
-            const response = await fetch('/api/v1/account/currencies');
+            const response = await authService.fetch('/api/v1/account/currencies');
             const result = await response.json();
             
             if (result.success) {
@@ -50,7 +50,7 @@ class AccountCard {
 
     async loadDefaultCurrency() {
         try {
-            const response = await fetch('/api/v1/account/currency');
+            const response = await authService.fetch('/api/v1/account/currency');
             const result = await response.json();
             
             if (result.success) {
@@ -106,7 +106,7 @@ class AccountCard {
             this.showLoading();
             
             // 设置新的默认币种
-            const response = await fetch('/api/v1/account/currency', {
+            const response = await authService.fetch('/api/v1/account/currency', {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
@@ -149,7 +149,7 @@ class AccountCard {
         try {
             this.showLoading();
             
-            const response = await fetch(`/api/v1/account/summary/${this.currentCurrency}`);
+            const response = await authService.fetch(`/api/v1/account/summary/${this.currentCurrency}`);
             const result = await response.json();
             
             if (result.success) {
@@ -219,20 +219,13 @@ class AccountCard {
     formatNumber(number) {
         if (isNaN(number)) return '0.00';
         
-        // 根据币种格式化数字
-        switch (this.currentCurrency) {
-            case 'BTC':
-                return number.toFixed(8);
-            case 'CNY':
-            case 'USD':
-            case 'USDT':
-                return number.toLocaleString('en-US', {
-                    minimumFractionDigits: 2,
-                    maximumFractionDigits: 2
-                });
-            default:
-                return number.toFixed(2);
-        }
+        // 按币种显示精度格式化数字（法币和稳定币2位，其他加密资产8位）
+        const currency = this.supportedCurrencies.find(c => c.currency === this.currentCurrency);
+        const decimals = currency && currency.decimals !== undefined ? currency.decimals : 2;
+        return number.toLocaleString('en-US', {
+            minimumFractionDigits: decimals,
+            maximumFractionDigits: decimals
+        });
     }
 
     showLoading() {
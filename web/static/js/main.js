@@ -21,7 +21,7 @@ class AlphaArkApp {
             // 初始化WebSocket
             const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
             const wsUrl = `${protocol}//${window.location.host}/ws/price`;
-            this.wsService = new WebSocketService(wsUrl);
+            this.wsService = new WebSocketService(() => authService.withToken(wsUrl));
 
             // 设置事件监听器
             this.setupEventListeners();
@@ -32,7 +32,8 @@ class AlphaArkApp {
             // 获取初始价格数据
             await this.fetchInitialPrice();
 
-            // 连接WebSocket
+            // 连接WebSocket，需要先登录
+            await authService.ensureLogin();
             this.wsService.connect();
 
             this.isInitialized = true;
@@ -47,14 +48,25 @@ class AlphaArkApp {
         // WebSocket事件监听
         this.wsService.on('open', () => {
             this.priceCard.setConnectionStatus(true);
+            // 订阅价格卡片对应交易对的行情
+            this.wsService.send({
+                op: 'subscribe',
+                args: [{ channel: 'ticker', instId: this.priceCard.symbol }]
+            });
         });
 
         this.wsService.on('close', () => {
             this.priceCard.setConnectionStatus(false);
         });
 
-        this.wsService.on('message', (data) => {
-            this.priceCard.updatePrice(data);
+        this.wsService.on('message', (message) => {
+            if (message.event === 'error') {
+                console.error('WebSocket订阅失败:', message.msg);
+                return;
+            }
+            if (message.arg && message.arg.channel === 'ticker' && message.data) {
+                this.priceCard.updatePrice(message.data);
+            }
         });
 
         this.wsService.on('error', (error) => {
@@ -0,0 +1,129 @@
+// 认证服务类：保存令牌，请求时附带访问令牌，过期时自动刷新，未登录时提示输入用户名和密码
+class AuthService {
+    constructor(baseURL = '/api/v1/auth') {
+        this.baseURL = baseURL;
+        this.storageKey = 'alphaark.tokens';
+        this.tokens = this.loadTokens();
+        this.pendingLogin = null;
+    }
+
+    loadTokens() {
+        try {
+            return JSON.parse(localStorage.getItem(this.storageKey)) || null;
+        } catch (error) {
+            return null;
+        }
+    }
+
+    saveTokens(tokens) {
+        this.tokens = tokens;
+        if (tokens) {
+            localStorage.setItem(this.storageKey, JSON.stringify(tokens));
+        } else {
+            localStorage.removeItem(this.storageKey);
+        }
+    }
+
+    get accessToken() {
+        return this.tokens ? this.tokens.accessToken : '';
+    }
+
+    async post(path, body, token = '') {
+        const headers = { 'Content-Type': 'application/json' };
+        if (token) {
+            headers['Authorization'] = `Bearer ${token}`;
+        }
+        const response = await fetch(this.baseURL + path, {
+            method: 'POST',
+            headers,
+            body: JSON.stringify(body)
+        });
+        return response.json();
+    }
+
+    async login(username, password) {
+        const result = await this.post('/login', { username, password });
+        if (!result.success) {
+            throw new Error(result.error || '登录失败');
+        }
+        this.saveTokens(result.data);
+        return result.data;
+    }
+
+    // 使用刷新令牌换取新的令牌，失败时清除本地令牌
+    async refresh() {
+        if (!this.tokens || !this.tokens.refreshToken) {
+            return false;
+        }
+        const result = await this.post('/refresh', { refreshToken: this.tokens.refreshToken });
+        this.saveTokens(result.success ? result.data : null);
+        return result.success;
+    }
+
+    async logout() {
+        if (this.tokens) {
+            await this.post('/logout', { refreshToken: this.tokens.refreshToken }, this.accessToken);
+        }
+        this.saveTokens(null);
+    }
+
+    // 确保已登录：没有令牌时提示输入用户名和密码，多个请求同时触发时只提示一次
+    async ensureLogin() {
+        if (this.accessToken) {
+            return;
+        }
+        if (!this.pendingLogin) {
+            this.pendingLogin = (async () => {
+                while (!this.accessToken) {
+                    const username = window.prompt('请输入用户名');
+                    if (username === null) {
+                        throw new Error('未登录');
+                    }
+                    const password = window.prompt('请输入密码');
+                    try {
+                        await this.login(username, password || '');
+                    } catch (error) {
+                        window.alert(error.message);
+                    }
+                }
+            })().finally(() => {
+                this.pendingLogin = null;
+            });
+        }
+        return this.pendingLogin;
+    }
+
+    // 附带访问令牌的fetch，返回401时先刷新令牌，仍失败则重新登录后重试一次
+    async fetch(url, options = {}) {
+        await this.ensureLogin();
+
+        const send = () => fetch(url, {
+            ...options,
+            headers: { ...options.headers, 'Authorization': `Bearer ${this.accessToken}` }
+        });
+
+        let response = await send();
+        if (response.status === 401) {
+            if (!(await this.refresh())) {
+                this.saveTokens(null);
+                await this.ensureLogin();
+            }
+            response = await send();
+        }
+        return response;
+    }
+
+    // 浏览器WebSocket无法设置请求头，通过查询参数传递访问令牌
+    withToken(url) {
+        const separator = url.includes('?') ? '&' : '?';
+        return `${url}${separator}token=${encodeURIComponent(this.accessToken)}`;
+    }
+}
+
+// 导出服务
+if (typeof module !== 'undefined' && module.exports) {
+    module.exports = AuthService;
+} else {
+    window.AuthService = AuthService;
+    window.authService = new AuthService();
+}
@@ -20,7 +20,9 @@ class WebSocketService {
 
     connect() {
         try {
-            this.ws = new WebSocket(this.url);
+            // url 可以是函数，每次连接时重新生成（如附带最新的访问令牌）
+            const url = typeof this.url === 'function' ? this.url() : this.url;
+            this.ws = new WebSocket(url);
             this.setupEventListeners();
         } catch (error) {
             console.error('WebSocket连接失败:', error);